@@ -19,17 +19,21 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"math/rand"
 	"os"
 	"os/signal"
 	"runtime"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/nestybox/sysbox-fs/domain"
 	"github.com/nestybox/sysbox-fs/fuse"
 	"github.com/nestybox/sysbox-fs/handler"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
 	"github.com/nestybox/sysbox-fs/ipc"
 	"github.com/nestybox/sysbox-fs/mount"
 	"github.com/nestybox/sysbox-fs/nsenter"
@@ -168,6 +172,94 @@ func runProfiler(ctx *cli.Context) (interface{ Stop() }, error) {
 	return prof, nil
 }
 
+// Parse a captured mountinfo file and display the classification of its mounts
+// as seen by sysbox-fs' mount parser.
+func parseMountInfo(ctx *cli.Context) error {
+
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("Expected one argument: <mountinfo-file>")
+	}
+
+	data, err := ioutil.ReadFile(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+
+	css := state.NewContainerStateService()
+	cntr := css.ContainerCreate(
+		"",
+		0,
+		time.Time{},
+		0,
+		0,
+		0,
+		0,
+		ctx.StringSlice("ro-path"),
+		ctx.StringSlice("mask-path"),
+		nil,
+	)
+
+	return mount.DumpMountInfo(os.Stdout, data, ctx.String("base"), cntr)
+}
+
+// Verify that sysbox-fs can be initialized on the running kernel: all the
+// default handlers must be properly registered, and the seccomp syscall-tracer
+// must be able to resolve the monitored syscalls. Services are set up through
+// their regular initialization paths, but no server is launched. A report is
+// displayed, and an error is returned if any problem is found.
+func selfTest(ctx *cli.Context) error {
+
+	var nsenterService = nsenter.NewNSenterService()
+	var ioService = sysio.NewIOService(domain.IOOsFileService)
+	var processService = process.NewProcessService()
+	var handlerService = handler.NewHandlerService()
+	var containerStateService = state.NewContainerStateService()
+	var syscallMonitorService = seccomp.NewSyscallMonitorService()
+
+	processService.Setup(ioService)
+
+	nsenterService.Setup(processService, nil)
+
+	handlerService.Setup(
+		handler.DefaultHandlers,
+		false,
+		nil,
+		containerStateService,
+		nsenterService,
+		processService,
+		ioService,
+	)
+
+	checks := []struct {
+		name string
+		errs []error
+	}{
+		{"handlers", handler.CheckHandlers(handlerService, handler.DefaultHandlers)},
+		{"seccomp", syscallMonitorService.SelfTest()},
+	}
+
+	var failed bool
+
+	fmt.Println("sysbox-fs self-test:")
+	for _, c := range checks {
+		if len(c.errs) == 0 {
+			fmt.Printf("\t%-10s ok\n", c.name)
+			continue
+		}
+		failed = true
+		fmt.Printf("\t%-10s FAILED\n", c.name)
+		for _, err := range c.errs {
+			fmt.Printf("\t\t- %v\n", err)
+		}
+	}
+
+	if failed {
+		return fmt.Errorf("self-test failed")
+	}
+
+	return nil
+}
+
 func setupRunDir() error {
 	if err := os.MkdirAll(sysboxRunDir, 0700); err != nil {
 		return fmt.Errorf("failed to create %s: %s", sysboxRunDir, err)
@@ -175,6 +267,19 @@ func setupRunDir() error {
 	return nil
 }
 
+// procfsSubmount returns true if the given path lies within (or above) any of
+// sysbox-fs' procfs submounts.
+func procfsSubmount(path string) bool {
+	for _, mp := range append(mount.ProcfsMounts, mount.ProcfsOptionalMounts...) {
+		if path == mp ||
+			strings.HasPrefix(path, mp+"/") ||
+			strings.HasPrefix(mp, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 //
 // sysbox-fs main function
 //
@@ -199,6 +304,22 @@ func main() {
 			Name:  "allow-immutable-unmounts",
 			Usage: "sys container's initial mounts are considered immutable; this option allows them to be unmounted from within the container (default: \"true\")",
 		},
+		cli.BoolFlag{
+			Name:  "allow-observability-mounts",
+			Usage: "allows bpf, tracefs and debugfs filesystems to be mounted from within the container (default: \"false\")",
+		},
+		cli.BoolFlag{
+			Name:  "allow-all-bpf-programs",
+			Usage: "allows all eBPF program types to be loaded from within the container, subject to the kernel's own checks (by default only socket filters and cgroup programs are allowed) (default: \"false\")",
+		},
+		cli.BoolFlag{
+			Name:  "chown-ro-proc-mounts",
+			Usage: "read-only procfs mounts created within the container (e.g., by inner containers) are owned by the container's root rather than by nobody:nogroup, at the cost of extra mount operations (default: \"false\")",
+		},
+		cli.StringSliceFlag{
+			Name:  "proc-extra-path",
+			Usage: "policy for a /proc path not emulated by sysbox-fs (e.g., /proc/spl/kstat), in <path>=<policy> format; policies are \"hide\", \"passthrough\" (host's view, read-only) and \"emulate:<host-file>\" (fixed content); can be repeated",
+		},
 		cli.StringFlag{
 			Name:  "seccomp-fd-release",
 			Value: "proc-exit",
@@ -219,11 +340,20 @@ func main() {
 			Value: "text",
 			Usage: "log format; must be json or text",
 		},
+		cli.BoolFlag{
+			Name:  "self-test",
+			Usage: "verify that sysbox-fs can be initialized on this host, and exit",
+		},
 		cli.BoolFlag{
 			Name:   "ignore-handler-errors",
 			Usage:  "ignore errors during procfs / sysfs node interactions (testing purposes)",
 			Hidden: true,
 		},
+		cli.StringFlag{
+			Name:  "handler-error-log",
+			Value: "",
+			Usage: "file path where procfs / sysfs handler errors are recorded, even if ignored; empty string disables it (default: \"\")",
+		},
 		cli.BoolFlag{
 			Name:   "cpu-profiling",
 			Usage:  "enable cpu-profiling data collection",
@@ -247,7 +377,7 @@ func main() {
 			edition, c.App.Version, commitId, builtAt, builtBy)
 	}
 
-	// Nsenter command to allow 'rexec' functionality.
+	// Nsenter command to allow 'rexec' functionality, and debugging utilities.
 	app.Commands = []cli.Command{
 		{
 			Name:  "nsenter",
@@ -258,6 +388,33 @@ func main() {
 				return nil
 			},
 		},
+		{
+			Name:  "debug",
+			Usage: "Debugging utilities",
+			Subcommands: []cli.Command{
+				{
+					Name:      "parse-mountinfo",
+					Usage:     "Classify the mounts of a captured mountinfo file",
+					ArgsUsage: "<mountinfo-file>",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "base",
+							Value: "/",
+							Usage: "only display mounts under this path",
+						},
+						cli.StringSliceFlag{
+							Name:  "ro-path",
+							Usage: "procfs path configured as read-only in the sys container",
+						},
+						cli.StringSliceFlag{
+							Name:  "mask-path",
+							Usage: "procfs path configured as masked in the sys container",
+						},
+					},
+					Action: parseMountInfo,
+				},
+			},
+		},
 	}
 
 	// Define 'debug' and 'log' settings.
@@ -332,6 +489,10 @@ func main() {
 	// sysbox-fs main-loop execution.
 	app.Action = func(ctx *cli.Context) error {
 
+		if ctx.Bool("self-test") {
+			return selfTest(ctx)
+		}
+
 		logrus.Info("Initiating sysbox-fs ...")
 
 		err := libutils.CheckPidFile("sysbox-fs", sysboxFsPidFile)
@@ -350,6 +511,21 @@ func main() {
 		} else {
 			logrus.Info("Initializing with 'allow-immutable-unmounts' knob disabled")
 		}
+		if ctx.Bool("allow-observability-mounts") {
+			logrus.Info("Initializing with 'allow-observability-mounts' knob enabled")
+		} else {
+			logrus.Info("Initializing with 'allow-observability-mounts' knob disabled (default)")
+		}
+		if ctx.Bool("allow-all-bpf-programs") {
+			logrus.Info("Initializing with 'allow-all-bpf-programs' knob enabled")
+		} else {
+			logrus.Info("Initializing with 'allow-all-bpf-programs' knob disabled (default)")
+		}
+		if ctx.Bool("chown-ro-proc-mounts") {
+			logrus.Info("Initializing with 'chown-ro-proc-mounts' knob enabled")
+		} else {
+			logrus.Info("Initializing with 'chown-ro-proc-mounts' knob disabled (default)")
+		}
 		if ctx.GlobalString("seccomp-fd-release") == "cont-exit" {
 			logrus.Info("Seccomp-notify fd release policy set to container exit")
 		}
@@ -377,9 +553,43 @@ func main() {
 
 		nsenterService.Setup(processService, nil)
 
+		// Open the handler-error audit log, if requested.
+		var errAuditLog io.Writer
+		if path := ctx.String("handler-error-log"); path != "" {
+			f, err := os.OpenFile(
+				path,
+				os.O_CREATE|os.O_WRONLY|os.O_APPEND|os.O_SYNC,
+				0600,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to open handler-error log %v: %v", path, err)
+			}
+			defer f.Close()
+
+			logrus.Infof("Recording handler errors into %v", path)
+			errAuditLog = f
+		}
+
+		// Add the handlers of the extra /proc paths, if any.
+		hdlrs := append([]domain.HandlerIface{}, handler.DefaultHandlers...)
+		for _, spec := range ctx.StringSlice("proc-extra-path") {
+			h, err := implementations.NewProcExtraHandler(spec)
+			if err != nil {
+				return err
+			}
+			if procfsSubmount(h.GetPath()) {
+				return fmt.Errorf("extra proc path %s already served by sysbox-fs", h.GetPath())
+			}
+
+			logrus.Infof("Serving extra proc path %s with policy %s", h.GetPath(), h.Policy)
+			hdlrs = append(hdlrs, h)
+			mount.ProcfsOptionalMounts = append(mount.ProcfsOptionalMounts, h.GetPath())
+		}
+
 		handlerService.Setup(
-			handler.DefaultHandlers,
+			hdlrs,
 			ctx.Bool("ignore-handler-errors"),
+			errAuditLog,
 			containerStateService,
 			nsenterService,
 			processService,
@@ -416,6 +626,9 @@ func main() {
 			mountService,
 			ctx.BoolT("allow-immutable-remounts"),
 			ctx.Bool("allow-immutable-unmounts"),
+			ctx.Bool("allow-observability-mounts"),
+			ctx.Bool("allow-all-bpf-programs"),
+			ctx.Bool("chown-ro-proc-mounts"),
 			ctx.GlobalString("seccomp-fd-release"),
 		)
 
@@ -47,7 +47,13 @@ func (c FileInfo) Size() int64 {
 	return c.Fsize
 }
 
+// Mode returns the file's mode bits. Handlers building a FileInfo for an
+// emulated dir may only set FisDir, so the dir type bit is added in that case
+// for the mode to be consistent with IsDir() (e.g., for readdir's d_type).
 func (c FileInfo) Mode() os.FileMode {
+	if c.FisDir {
+		return c.Fmode | os.ModeDir
+	}
 	return c.Fmode
 }
 
@@ -56,7 +62,7 @@ func (c FileInfo) ModTime() time.Time {
 }
 
 func (c FileInfo) IsDir() bool {
-	return c.FisDir
+	return c.FisDir || c.Fmode.IsDir()
 }
 
 func (c FileInfo) Sys() interface{} {
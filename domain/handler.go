@@ -17,6 +17,8 @@
 package domain
 
 import (
+	"context"
+	"io"
 	"os"
 	"sync"
 )
@@ -90,6 +92,51 @@ type HandlerRequest struct {
 	NoCache     bool
 	Data        []byte
 	Container   ContainerIface
+
+	// Context of the FUSE request being served, if any. It's cancelled upon
+	// interruption of the request (i.e., FUSE_INTERRUPT), in which case the
+	// in-flight nsenter events launched on its behalf are aborted.
+	Ctx context.Context
+}
+
+// CntrUid returns the effective uid of the requesting process as seen from
+// within the container's user-ns. Uid/Gid fields carry the host's view of the
+// requester's credentials (as reported by the FUSE layer), so these must be
+// shifted back by the container's id-mapping offset. The second return value
+// is false for requesters whose uid is not mapped into the container.
+func (r *HandlerRequest) CntrUid() (uint32, bool) {
+	if r.Container == nil {
+		return 0, false
+	}
+
+	return hostIdToCntr(r.Uid, r.Container.UID(), r.Container.UidSize())
+}
+
+// CntrGid is the gid counterpart of CntrUid().
+func (r *HandlerRequest) CntrGid() (uint32, bool) {
+	if r.Container == nil {
+		return 0, false
+	}
+
+	return hostIdToCntr(r.Gid, r.Container.GID(), r.Container.GidSize())
+}
+
+// IsCntrRoot returns true if the requesting process runs as the container's
+// root user. Handlers can rely on this one to restrict access to sensitive
+// resources, or to tailor the content being served, based on the requester's
+// identity.
+func (r *HandlerRequest) IsCntrRoot() bool {
+	uid, ok := r.CntrUid()
+
+	return ok && uid == 0
+}
+
+func hostIdToCntr(id, first, size uint32) (uint32, bool) {
+	if id < first || uint64(id) >= uint64(first)+uint64(size) {
+		return 0, false
+	}
+
+	return id - first, true
 }
 
 // HandlerIface is the interface that each handler must implement
@@ -124,6 +171,7 @@ type HandlerServiceIface interface {
 	Setup(
 		hdlrs []HandlerIface,
 		ignoreErrors bool,
+		errAuditLog io.Writer,
 		css ContainerStateServiceIface,
 		nss NSenterServiceIface,
 		prs ProcessServiceIface,
@@ -151,4 +199,5 @@ type HandlerServiceIface interface {
 	FindUserNsInode(pid uint32) (Inode, error)
 	HostUuid() string
 	FindHostUuid() (string, error)
+	AuditError(op string, node IOnodeIface, req *HandlerRequest, err error)
 }
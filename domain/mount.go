@@ -48,6 +48,7 @@ type MountInfoParserIface interface {
 	IsSysboxfsRoSubmount(mountpoint string) bool
 	IsSysboxfsMaskedSubmount(mountpoint string) bool
 	GetSysboxfsSubMounts(basemount string) []string
+	GetSysboxfsNestedSubMounts(submount string) []string
 	HasNonSysboxfsSubmount(basemount string) bool
 	IsRecursiveBindMount(info *MountInfo) bool
 	IsSelfMount(info *MountInfo) bool
@@ -67,6 +68,7 @@ type MountHelperIface interface {
 	IsNewMount(flags uint64) bool
 	IsRemount(flags uint64) bool
 	IsBind(flags uint64) bool
+	IsBindRemount(flags uint64) bool
 	IsMove(flags uint64) bool
 	HasPropagationFlag(flags uint64) bool
 	IsReadOnlyMount(flags uint64) bool
@@ -142,4 +144,8 @@ type Mount struct {
 	FsType string `json:"fstype"`
 	Flags  uint64 `json:"flags"`
 	Data   string `json:"data"`
+
+	// Mount data of filesystems taking a binary struct rather than a string
+	// (Data is left empty for these). Kept apart as it may embed NULs.
+	BinData []byte `json:"bindata,omitempty"`
 }
@@ -16,6 +16,8 @@
 
 package domain
 
+import "context"
+
 // Aliases to leverage strong-typing.
 type NStype = string
 type NSenterMsgType = string
@@ -113,6 +115,7 @@ type NSenterServiceIface interface {
 
 	Setup(prs ProcessServiceIface, mts MountServiceIface)
 	SendRequestEvent(e NSenterEventIface) error
+	SendRequestEventWithContext(ctx context.Context, e NSenterEventIface) error
 	ReceiveResponseEvent(e NSenterEventIface) *NSenterMessage
 	TerminateRequestEvent(e NSenterEventIface) error
 	GetEventProcessID(e NSenterEventIface) uint32
@@ -130,6 +133,7 @@ type NSenterServiceIface interface {
 // message exchanges.
 type NSenterEventIface interface {
 	SendRequest() error
+	SendRequestWithContext(ctx context.Context) error
 	TerminateRequest() error
 	ReceiveResponse() *NSenterMessage
 	SetRequestMsg(m *NSenterMessage)
@@ -52,6 +52,8 @@ type ProcessIface interface {
 	NetNsInode() (Inode, error)
 	UserNsInode() (Inode, error)
 	UserNsInodeParent() (Inode, error)
+	PidNsInode() (Inode, error)
+	NsPids() ([]uint32, error)
 	UsernsRootUidGid() (uint32, uint32, error)
 	CreateNsInodes(Inode) error
 	PathAccess(path string, accessFlags AccessMode, followSymlink bool) (string, error)
@@ -70,6 +72,8 @@ type ProcessIface interface {
 type ProcessServiceIface interface {
 	Setup(ios IOServiceIface)
 	ProcessCreate(pid uint32, uid uint32, gid uint32) ProcessIface
+	HostPidToNsPid(hostPid uint32, ref ProcessIface) (uint32, error)
+	NsPidToHostPid(nsPid uint32, ref ProcessIface) (uint32, error)
 }
 
 // ProcessNsMatch returns true if the given processes are in the same namespaces.
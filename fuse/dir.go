@@ -22,6 +22,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/nestybox/sysbox-fs/domain"
@@ -127,12 +128,21 @@ func (d *Dir) Lookup(
 	// Handler execution.
 	info, err := handler.Lookup(ionode, handlerReq)
 	if err != nil {
+		// Missing nodes are business as usual.
+		if ioErr, ok := err.(IOerror); !ok || ioErr.Code != syscall.ENOENT {
+			d.server.service.hds.AuditError("lookup", ionode, handlerReq, err)
+		}
 		return nil, fuse.ENOENT
 	}
 
 	// Convert os.FileInfo attributes to fuseAttr format.
 	fuseAttrs := convertFileInfoToFuse(info)
 
+	// Emulated dirs carry no link count of their own.
+	if info.IsDir() && info.Sys() == (*syscall.Stat_t)(nil) {
+		fuseAttrs.Nlink = emulatedDirNlink(handler, ionode, handlerReq)
+	}
+
 	// Identify the root uid & gid in the requester's user-ns.
 	prs := d.server.service.hds.ProcessService()
 	process := prs.ProcessCreate(req.Pid, req.Uid, req.Gid)
@@ -242,6 +252,7 @@ func (d *Dir) Create(
 	nonSeekable, err := handler.Open(ionode, handlerReq)
 	if err != nil && err != io.EOF {
 		logrus.Debugf("Open() error: %v", err)
+		d.server.service.hds.AuditError("create", ionode, handlerReq, err)
 		return nil, nil, err
 	}
 
@@ -312,6 +323,7 @@ func (d *Dir) ReadDirAll(ctx context.Context, req *fuse.ReadRequest) ([]fuse.Dir
 	files, err := handler.ReadDirAll(ionode, handlerReq)
 	if err != nil {
 		logrus.Debugf("ReadDirAll() error: %v", err)
+		d.server.service.hds.AuditError("readdir", ionode, handlerReq, err)
 		return nil, fuse.ENOENT
 	}
 
@@ -327,15 +339,7 @@ func (d *Dir) ReadDirAll(ctx context.Context, req *fuse.ReadRequest) ([]fuse.Dir
 			}
 		}
 
-		elem := fuse.Dirent{Name: node.Name()}
-
-		if node.IsDir() {
-			elem.Type = fuse.DT_Dir
-		} else if node.Mode().IsRegular() {
-			elem.Type = fuse.DT_File
-		} else if node.Mode()&os.ModeSymlink != 0 {
-			elem.Type = fuse.DT_Link
-		}
+		elem := fuse.Dirent{Name: node.Name(), Type: direntType(node)}
 
 		children = append(children, elem)
 	}
@@ -343,6 +347,67 @@ func (d *Dir) ReadDirAll(ctx context.Context, req *fuse.ReadRequest) ([]fuse.Dir
 	return children, nil
 }
 
+// emulatedDirNlink returns the link count of the given emulated dir, which
+// (as for any dir) is two plus the number of its subdirs. Tools walking a dir
+// hierarchy (e.g., find) may rely on it to skip the stat() of the entries of
+// leaf dirs. A count of one, which these tools take as unknown, is returned
+// if the dir can't be read.
+func emulatedDirNlink(
+	handler domain.HandlerIface,
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) uint32 {
+
+	dirReq := &domain.HandlerRequest{
+		ID:        req.ID,
+		Pid:       req.Pid,
+		Uid:       req.Uid,
+		Gid:       req.Gid,
+		Container: req.Container,
+	}
+
+	entries, err := handler.ReadDirAll(n, dirReq)
+	if err != nil {
+		return 1
+	}
+
+	nlink := uint32(2)
+	for _, e := range entries {
+		if e.IsDir() {
+			nlink++
+		}
+	}
+
+	return nlink
+}
+
+// direntType returns the d_type of the dir entry associated to the given file
+// info. Tools relying on d_type to walk a dir hierarchy (e.g., find) skip or
+// mishandle entries whose type is unknown, so all the file types that may show
+// up in procfs / sysfs are covered.
+func direntType(info os.FileInfo) fuse.DirentType {
+
+	mode := info.Mode()
+
+	switch {
+	case info.IsDir():
+		return fuse.DT_Dir
+	case mode.IsRegular():
+		return fuse.DT_File
+	case mode&os.ModeSymlink != 0:
+		return fuse.DT_Link
+	case mode&os.ModeCharDevice != 0:
+		return fuse.DT_Char
+	case mode&os.ModeDevice != 0:
+		return fuse.DT_Block
+	case mode&os.ModeNamedPipe != 0:
+		return fuse.DT_FIFO
+	case mode&os.ModeSocket != 0:
+		return fuse.DT_Socket
+	}
+
+	return fuse.DT_Unknown
+}
+
 // Mkdir FS operation.
 func (d *Dir) Mkdir(ctx context.Context, req *fuse.MkdirRequest) (fs.Node, error) {
 
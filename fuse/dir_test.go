@@ -0,0 +1,108 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package fuse
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"bazil.org/fuse"
+
+	"github.com/nestybox/sysbox-fs/domain"
+)
+
+func TestDirentType(t *testing.T) {
+
+	tests := []struct {
+		name string
+		info domain.FileInfo
+		want fuse.DirentType
+	}{
+		// Test-case 1: Emulated dir, flagged as such through FisDir only.
+		{"emulated-dir", domain.FileInfo{Fname: "default", Fmode: 0555, FisDir: true}, fuse.DT_Dir},
+		// Test-case 2: Emulated dir, flagged as such through its mode only.
+		{"emulated-dir-mode", domain.FileInfo{Fname: "security", Fmode: os.ModeDir | 0755}, fuse.DT_Dir},
+		// Test-case 3: Emulated file.
+		{"emulated-file", domain.FileInfo{Fname: "gc_thresh1", Fmode: 0644}, fuse.DT_File},
+		// Test-case 4: Symlink (e.g., /proc/self).
+		{"symlink", domain.FileInfo{Fname: "self", Fmode: os.ModeSymlink | 0777}, fuse.DT_Link},
+		// Test-case 5: Char device.
+		{"char-dev", domain.FileInfo{Fname: "null", Fmode: os.ModeDevice | os.ModeCharDevice | 0666}, fuse.DT_Char},
+		// Test-case 6: Socket.
+		{"socket", domain.FileInfo{Fname: "sock", Fmode: os.ModeSocket | 0755}, fuse.DT_Socket},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := direntType(tt.info); got != tt.want {
+				t.Errorf("direntType(%s) = %v, want %v", tt.info.Fname, got, tt.want)
+			}
+		})
+	}
+}
+
+// Handler fake serving the entries of a single dir.
+type testDirHandler struct {
+	domain.HandlerIface
+	entries []os.FileInfo
+	err     error
+}
+
+func (h *testDirHandler) ReadDirAll(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]os.FileInfo, error) {
+
+	return h.entries, h.err
+}
+
+func TestEmulatedDirNlink(t *testing.T) {
+
+	// Emulated /sys/kernel, holding four emulated subdirs along with the
+	// host's entries (one subdir and two files).
+	entries := []os.FileInfo{
+		&domain.FileInfo{Fname: "config", Fmode: os.ModeDir | 0755},
+		&domain.FileInfo{Fname: "debug", Fmode: os.ModeDir | 0700},
+		&domain.FileInfo{Fname: "tracing", Fmode: os.ModeDir | 0700},
+		&domain.FileInfo{Fname: "security", FisDir: true, Fmode: 0755},
+		&domain.FileInfo{Fname: "mm", Fmode: os.ModeDir | 0755},
+		&domain.FileInfo{Fname: "uevent_seqnum", Fmode: 0444},
+		&domain.FileInfo{Fname: "profiling", Fmode: 0644},
+	}
+
+	tests := []struct {
+		name string
+		h    *testDirHandler
+		want uint32
+	}{
+		// Test-case 1: Dir with five subdirs.
+		{"subdirs", &testDirHandler{entries: entries}, 7},
+		// Test-case 2: Leaf dir.
+		{"leaf", &testDirHandler{entries: entries[5:]}, 2},
+		// Test-case 3: Unreadable dir; link count is unknown.
+		{"unreadable", &testDirHandler{err: IOerror{Code: syscall.EACCES}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &domain.HandlerRequest{Pid: 1001}
+			if got := emulatedDirNlink(tt.h, nil, req); got != tt.want {
+				t.Errorf("emulatedDirNlink() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -31,6 +31,18 @@ import (
 	"github.com/nestybox/sysbox-fs/domain"
 )
 
+// Notice that File implements no ioctl operation. Clone requests (FICLONE and
+// FICLONERANGE, as issued by "cp --reflink") are resolved by the kernel's VFS
+// with EOPNOTSUPP, as FUSE lacks remap_file_range() support, so these never
+// reach sysbox-fs. All other ioctls (e.g., FS_IOC_GETFLAGS) are answered with
+// ENOSYS by the fuse library, which the kernel reports as ENOTTY.
+//
+// Likewise, File implements no fallocate operation: the fuse library doesn't
+// decode FUSE_FALLOCATE requests, and answers them with ENOSYS. The kernel
+// reports that to fallocate() / posix_fallocate() callers as EOPNOTSUPP, and
+// stops forwarding fallocate requests for the whole mount. This matches the
+// procfs / sysfs nodes that sysbox-fs passes through, as neither filesystem
+// supports fallocate either.
 type File struct {
 	// File name.
 	name string
@@ -147,6 +159,7 @@ func (f *File) Open(
 	nonSeekable, err := handler.Open(ionode, handlerReq)
 	if err != nil && err != io.EOF {
 		logrus.Debugf("Open() error: %v", err)
+		f.server.service.hds.AuditError("open", ionode, handlerReq, err)
 		return nil, err
 	}
 
@@ -238,15 +251,28 @@ func (f *File) Read(
 		Offset:    req.Offset,
 		Data:      make([]byte, req.Size),
 		Container: f.server.container,
+		Ctx:       ctx,
 	}
 
 	// Handler execution.
 	n, err := handler.Read(ionode, handlerReq)
 	if err != nil && err != io.EOF {
 		logrus.Debugf("Read() error: %v", err)
+		f.server.service.hds.AuditError("read", ionode, handlerReq, err)
 		return err
 	}
 
+	// Handlers must serve no more than the requested window. Anything beyond
+	// it would be dropped by the kernel, so flag it as a handler bug.
+	if n > int(req.Size) || n > len(handlerReq.Data) {
+		logrus.Errorf("Read() of %v at offset %d returned %d bytes, exceeding the %d-byte read buffer",
+			f.path, req.Offset, n, req.Size)
+		n = int(req.Size)
+		if n > len(handlerReq.Data) {
+			n = len(handlerReq.Data)
+		}
+	}
+
 	resp.Data = handlerReq.Data[:n]
 	return nil
 }
@@ -284,12 +310,14 @@ func (f *File) Write(
 		Gid:       req.Gid,
 		Data:      req.Data,
 		Container: f.server.container,
+		Ctx:       ctx,
 	}
 
 	// Handler execution.
 	n, err := handler.Write(ionode, request)
 	if err != nil && err != io.EOF {
 		logrus.Debugf("Write() error: %v", err)
+		f.server.service.hds.AuditError("write", ionode, request, err)
 		return err
 	}
 
@@ -331,6 +359,7 @@ func (f *File) Readlink(
 	link, err := handler.ReadLink(ionode, request)
 	if err != nil && err != io.EOF {
 		logrus.Debugf("Readlink() error: %v", err)
+		f.server.service.hds.AuditError("readlink", ionode, request, err)
 		return "", err
 	}
 
@@ -17,6 +17,7 @@
 package fuse
 
 import (
+	"context"
 	"errors"
 	"os"
 	"sync"
@@ -30,6 +31,12 @@ import (
 	"github.com/nestybox/sysbox-fs/domain"
 )
 
+// Statfs attributes shared by procfs and sysfs.
+const (
+	statfsBlockSize = 4096
+	statfsNameLen   = 255
+)
+
 // FuseServer class in charge of running/hosting sysbox-fs' FUSE server features.
 type fuseServer struct {
 	sync.RWMutex                       // nodeDB protection
@@ -207,6 +214,29 @@ func (s *fuseServer) Root() (fs.Node, error) {
 	return s.root, nil
 }
 
+// Statfs method. Reports file-system attributes matching those of procfs and
+// sysfs (the file-systems being emulated), which don't account for any block
+// or inode usage. Tools relying on these attributes to tell apart pseudo
+// file-systems from regular ones would otherwise get Bazil's defaults.
+//
+// Notice that FUSE servers have no control over the file-system type (f_type)
+// reported by statfs(2): the kernel always fills it with FUSE_SUPER_MAGIC. That
+// is fine for the container's /proc and /sys base mounts, as these are genuine
+// procfs / sysfs mounts (hence reporting PROC_SUPER_MAGIC / SYSFS_MAGIC) on top
+// of which sysbox-fs' submounts are stacked; it's only these submounts (e.g.,
+// /proc/sys, /proc/uptime) that carry the FUSE magic.
+func (s *fuseServer) Statfs(
+	ctx context.Context,
+	req *fuse.StatfsRequest,
+	resp *fuse.StatfsResponse) error {
+
+	resp.Bsize = statfsBlockSize
+	resp.Frsize = statfsBlockSize
+	resp.Namelen = statfsNameLen
+
+	return nil
+}
+
 // Ensure that fuse-server initialization is completed before moving on
 // with sys container's pre-registration sequence.
 func (s *fuseServer) InitWait() {
@@ -0,0 +1,84 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package fuse
+
+import (
+	"context"
+	"testing"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+	"golang.org/x/sys/unix"
+)
+
+func TestFuseServer_Statfs(t *testing.T) {
+
+	var srv interface{} = &fuseServer{path: "/", mountPoint: "/var/lib/sysboxfs"}
+
+	statfser, ok := srv.(fs.FSStatfser)
+	if !ok {
+		t.Fatal("fuse server does not implement statfs")
+	}
+
+	var resp fuse.StatfsResponse
+	if err := statfser.Statfs(context.Background(), &fuse.StatfsRequest{}, &resp); err != nil {
+		t.Fatalf("Statfs() unexpected error: %v", err)
+	}
+
+	// Expected attributes as reported by procfs / sysfs.
+	want := fuse.StatfsResponse{
+		Bsize:   4096,
+		Frsize:  4096,
+		Namelen: 255,
+	}
+	if resp != want {
+		t.Errorf("Statfs() = %+v, want %+v", resp, want)
+	}
+}
+
+// Statfs attributes must match the ones of the host's genuine procfs / sysfs
+// mounts, so that tools such as df report sysbox-fs' submounts alike.
+func TestFuseServer_StatfsMatchesHost(t *testing.T) {
+
+	srv := &fuseServer{path: "/", mountPoint: "/var/lib/sysboxfs"}
+
+	var resp fuse.StatfsResponse
+	if err := srv.Statfs(context.Background(), &fuse.StatfsRequest{}, &resp); err != nil {
+		t.Fatalf("Statfs() unexpected error: %v", err)
+	}
+
+	for _, mnt := range []string{"/proc", "/sys"} {
+		var st unix.Statfs_t
+		if err := unix.Statfs(mnt, &st); err != nil {
+			t.Skipf("statfs(%s) unavailable: %v", mnt, err)
+		}
+
+		host := fuse.StatfsResponse{
+			Blocks:  st.Blocks,
+			Bfree:   st.Bfree,
+			Bavail:  st.Bavail,
+			Files:   st.Files,
+			Ffree:   st.Ffree,
+			Bsize:   uint32(st.Bsize),
+			Namelen: uint32(st.Namelen),
+			Frsize:  uint32(st.Frsize),
+		}
+		if resp != host {
+			t.Errorf("Statfs() = %+v, want %s's %+v", resp, mnt, host)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package handler
+
+import (
+	"errors"
+	"io"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+)
+
+// errorAuditor records handler errors, one json entry per error, into a sink
+// kept apart from sysbox-fs' main log. This way handler errors are available
+// to operators regardless of the main log's level, and of the handler errors
+// being ignored (see IgnoreErrors()).
+type errorAuditor struct {
+	log *logrus.Logger
+}
+
+func newErrorAuditor(w io.Writer) *errorAuditor {
+
+	log := logrus.New()
+	log.SetOutput(w)
+	log.SetLevel(logrus.ErrorLevel)
+	log.SetFormatter(&logrus.JSONFormatter{
+		TimestampFormat: "2006-01-02 15:04:05",
+	})
+
+	return &errorAuditor{log: log}
+}
+
+func (a *errorAuditor) record(
+	op string,
+	node domain.IOnodeIface,
+	req *domain.HandlerRequest,
+	err error) {
+
+	fields := logrus.Fields{
+		"op":     op,
+		"path":   node.Path(),
+		"req-id": req.ID,
+		"pid":    req.Pid,
+	}
+
+	if req.Container != nil {
+		fields["container"] = req.Container.ID()
+	}
+
+	if errno := errnoOf(err); errno != 0 {
+		fields["errno"] = int(errno)
+		fields["errname"] = unix.ErrnoName(errno)
+	}
+
+	// Errno-only errors carry no message.
+	msg := err.Error()
+	if msg == "" {
+		msg = "handler error"
+	}
+
+	a.log.WithFields(fields).Error(msg)
+}
+
+// errnoOf returns the errno carried by the given handler error, or zero if
+// none is found.
+func errnoOf(err error) syscall.Errno {
+
+	var ioErr fuse.IOerror
+	if errors.As(err, &ioErr) {
+		return ioErr.Code
+	}
+
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		return errno
+	}
+
+	return 0
+}
@@ -0,0 +1,132 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mount"
+	"github.com/nestybox/sysbox-fs/process"
+	"github.com/nestybox/sysbox-fs/state"
+	"github.com/nestybox/sysbox-fs/sysio"
+)
+
+func TestHandlerService_AuditError(t *testing.T) {
+
+	logrus.SetOutput(ioutil.Discard)
+
+	ios := sysio.NewIOService(domain.IOMemFileService)
+	prs := process.NewProcessService()
+	css := state.NewContainerStateService()
+	prs.Setup(ios)
+	css.Setup(nil, prs, ios, mount.NewMountService())
+
+	const path = "/proc/sys/fs/nr_open"
+
+	// Host's value can't be compared against the container's one, so the
+	// write to the host fails.
+	if err := ios.NewIOnode("", path, 0644).WriteFile([]byte("max\n")); err != nil {
+		t.Fatal(err)
+	}
+	defer ios.RemoveAllIOnodes()
+
+	var sink bytes.Buffer
+
+	hs := &handlerService{
+		ios:          ios,
+		ignoreErrors: true,
+		auditor:      newErrorAuditor(&sink),
+	}
+
+	h := implementations.ProcSysFs_Handler
+	h.SetService(hs)
+
+	cntr := css.ContainerCreate(
+		"c-audit",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	n := ios.NewIOnode("nr_open", path, 0)
+
+	// The write failure is ignored, so processing continues ...
+	req := &domain.HandlerRequest{
+		ID:        0x10,
+		Pid:       1001,
+		Container: cntr,
+		Data:      []byte("2097152\n"),
+	}
+	sz, err := h.Write(n, req)
+	if err != nil || sz != len("2097152\n") {
+		t.Fatalf("Write(%s) = %d, %v; want %d, nil", path, sz, err, len("2097152\n"))
+	}
+
+	// ... and so do the errors returned to the container.
+	hs.AuditError("read", n, &domain.HandlerRequest{ID: 0x11, Pid: 1001, Container: cntr},
+		fuse.IOerror{Code: syscall.EIO})
+
+	var entries []map[string]interface{}
+	dec := json.NewDecoder(&sink)
+	for dec.More() {
+		var e map[string]interface{}
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("invalid audit entry: %v", err)
+		}
+		entries = append(entries, e)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("got %d audit entries, want 2: %v", len(entries), entries)
+	}
+
+	want := []map[string]interface{}{
+		{"op": "write", "path": path, "container": "c-audit", "pid": float64(1001), "req-id": float64(0x10)},
+		{"op": "read", "path": path, "container": "c-audit", "pid": float64(1001), "req-id": float64(0x11),
+			"errno": float64(syscall.EIO), "errname": "EIO"},
+	}
+	for i, e := range entries {
+		for k, v := range want[i] {
+			if e[k] != v {
+				t.Errorf("audit entry %d: %s = %v, want %v", i, k, e[k], v)
+			}
+		}
+		if e["level"] != "error" || e["msg"] == "" {
+			t.Errorf("audit entry %d: unexpected level/msg: %v", i, e)
+		}
+	}
+
+	// No sink, no records.
+	hs.auditor = nil
+	hs.AuditError("read", n, req, fuse.IOerror{Code: syscall.EIO})
+}
@@ -22,6 +22,8 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/sirupsen/logrus"
@@ -41,22 +43,37 @@ var DefaultHandlers = []domain.HandlerIface{
 	implementations.Root_Handler,                           // /
 	implementations.ProcUptime_Handler,                     // /proc/uptime
 	implementations.ProcSwaps_Handler,                      // /proc/swaps
+	implementations.ProcConfigGz_Handler,                   // /proc/config.gz
+	implementations.ProcKeys_Handler,                       // /proc/keys
+	implementations.ProcKeyUsers_Handler,                   // /proc/key-users
+	implementations.ProcTimerList_Handler,                  // /proc/timer_list
+	implementations.ProcTimerStats_Handler,                 // /proc/timer_stats
 	implementations.ProcSys_Handler,                        // /proc/sys
+	implementations.ProcSysAbi_Handler,                     // /proc/sys/abi
+	implementations.ProcSysDebug_Handler,                   // /proc/sys/debug
 	implementations.ProcSysFs_Handler,                      // /proc/sys/fs
 	implementations.ProcSysKernel_Handler,                  // /proc/sys/kernel
+	implementations.ProcSysKernelKeys_Handler,              // /proc/sys/kernel/keys
 	implementations.ProcSysKernelRandom_Handler,            // /proc/sys/kernel/random
 	implementations.ProcSysKernelYama_Handler,              // /proc/sys/kernel/yama
 	implementations.ProcSysNetCore_Handler,                 // /proc/sys/net/core
 	implementations.ProcSysNetIpv4_Handler,                 // /proc/sys/net/ipv4
 	implementations.ProcSysNetIpv4Vs_Handler,               // /proc/sys/net/ipv4/vs
 	implementations.ProcSysNetIpv4Neigh_Handler,            // /proc/sys/net/ipv4/neigh
+	implementations.ProcSysNetIpv4Conf_Handler,             // /proc/sys/net/ipv4/conf
+	implementations.ProcSysNetIpv6Conf_Handler,             // /proc/sys/net/ipv6/conf
 	implementations.ProcSysNetNetfilter_Handler,            // /proc/sys/net/netfilter
 	implementations.ProcSysNetUnix_Handler,                 // /proc/sys/net/unix
 	implementations.ProcSysVm_Handler,                      // /proc/sys/vm
 	implementations.SysKernel_Handler,                      // /sys/kernel
+	implementations.SysKernelSecurity_Handler,              // /sys/kernel/security
+	implementations.SysBlock_Handler,                       // /sys/block
+	implementations.SysFsSelinux_Handler,                   // /sys/fs/selinux
 	implementations.SysDevicesVirtual_Handler,              // /sys/devices/virtual
 	implementations.SysDevicesVirtualDmi_Handler,           // /sys/devices/virtual/dmi
 	implementations.SysDevicesVirtualDmiId_Handler,         // /sys/devices/virtual/dmi/id
+	implementations.SysDevicesSystemCpu_Handler,            // /sys/devices/system/cpu
+	implementations.SysFirmware_Handler,                    // /sys/firmware
 	implementations.SysModuleNfconntrackParameters_Handler, // /sys/module/nf_conntrack/parameters
 }
 
@@ -92,6 +109,9 @@ type handlerService struct {
 	// Handler i/o errors should be obviated if this flag is enabled (testing
 	// purposes).
 	ignoreErrors bool
+
+	// Records handler errors (if any sink is configured).
+	auditor *errorAuditor
 }
 
 // HandlerService constructor.
@@ -102,6 +122,7 @@ func NewHandlerService() domain.HandlerServiceIface {
 func (hs *handlerService) Setup(
 	hdlrs []domain.HandlerIface,
 	ignoreErrors bool,
+	errAuditLog io.Writer,
 	css domain.ContainerStateServiceIface,
 	nss domain.NSenterServiceIface,
 	prs domain.ProcessServiceIface,
@@ -113,6 +134,10 @@ func (hs *handlerService) Setup(
 	hs.ios = ios
 	hs.ignoreErrors = ignoreErrors
 
+	if errAuditLog != nil {
+		hs.auditor = newErrorAuditor(errAuditLog)
+	}
+
 	hs.handlerTree = iradix.New()
 	if hs.handlerTree == nil {
 		logrus.Fatalf("Unable to allocate handler radix-tree")
@@ -140,6 +165,79 @@ func (hs *handlerService) Setup(
 	}
 }
 
+// CheckHandlers verifies that every handler in the given list has been properly
+// registered within the handler service, and that its emulated resources are
+// served by it. All the problems found are reported.
+func CheckHandlers(
+	hs domain.HandlerServiceIface,
+	hdlrs []domain.HandlerIface) []error {
+
+	var errs []error
+
+	for i, h := range hdlrs {
+		if h == nil {
+			errs = append(errs, fmt.Errorf("handler #%d is undefined", i))
+			continue
+		}
+
+		name := h.GetName()
+		path := h.GetPath()
+
+		if name == "" {
+			errs = append(errs, fmt.Errorf("handler %s has no name", path))
+		}
+
+		// The passthrough handler's path is symbolic.
+		symbolic := h == domain.HandlerIface(implementations.PassThrough_Handler)
+
+		if !symbolic && (!filepath.IsAbs(path) || filepath.Clean(path) != path) {
+			errs = append(errs, fmt.Errorf("handler %s has invalid path %q", name, path))
+			continue
+		}
+
+		rh, ok := hs.FindHandler(path)
+		if !ok {
+			errs = append(errs, fmt.Errorf("handler %s not registered at %s", name, path))
+			continue
+		}
+		if rh != h {
+			errs = append(errs, fmt.Errorf("handler %s path %s already claimed by handler %s",
+				name, path, rh.GetName()))
+			continue
+		}
+
+		if h.GetService() != hs {
+			errs = append(errs, fmt.Errorf("handler %s not bound to handler service", name))
+		}
+
+		if symbolic || !h.GetEnabled() {
+			continue
+		}
+
+		prefix := path
+		if prefix != "/" {
+			prefix += "/"
+		}
+
+		for _, res := range h.GetResourcesList() {
+			if res != path && !strings.HasPrefix(res, prefix) {
+				errs = append(errs, fmt.Errorf("handler %s resource %s outside of %s",
+					name, res, path))
+				continue
+			}
+
+			// Resources can also be the base directory of a nested handler.
+			lh, ok := hs.LookupHandler(hs.IOService().NewIOnode("", res, 0))
+			if !ok || (lh != h && lh.GetPath() != res) {
+				errs = append(errs, fmt.Errorf("handler %s resource %s not served by it",
+					name, res))
+			}
+		}
+	}
+
+	return errs
+}
+
 func (hs *handlerService) RegisterHandler(h domain.HandlerIface) error {
 	hs.Lock()
 
@@ -306,6 +404,22 @@ func (hs *handlerService) IgnoreErrors() bool {
 	return hs.ignoreErrors
 }
 
+// AuditError records the given handler error in the handler-error audit log,
+// if one is configured. Errors are recorded whether or not they are ignored
+// (see IgnoreErrors()), and the outcome of the operation is left untouched.
+func (hs *handlerService) AuditError(
+	op string,
+	node domain.IOnodeIface,
+	req *domain.HandlerRequest,
+	err error) {
+
+	if hs.auditor == nil {
+		return
+	}
+
+	hs.auditor.record(op, node, req, err)
+}
+
 //
 // Auxiliary methods
 //
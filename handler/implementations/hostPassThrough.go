@@ -0,0 +1,179 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations
+
+import (
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+)
+
+//
+// Host passthrough handler
+//
+// Serves the host's view of the nodes that bypass the emulation (e.g., extra
+// /proc paths under the passthrough policy; see /proc extra paths handler).
+// Unlike the (regular) passthrough handler, no container namespace is entered:
+// nodes are accessed as seen by sysbox-fs (i.e., within the host's
+// namespaces).
+//
+// Accesses are read-only, as the container is granted visibility of the
+// host's state, not control over it. Values are never cached, as they are
+// not the container's ones.
+//
+
+type HostPassThrough struct {
+	domain.HandlerBase
+}
+
+var HostPassThrough_Handler = &HostPassThrough{
+	domain.HandlerBase{
+		Name:    "HostPassThrough",
+		Path:    "*",
+		Enabled: true,
+	},
+}
+
+func (h *HostPassThrough) Lookup(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (os.FileInfo, error) {
+
+	logrus.Debugf("Executing Lookup() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	stat, err := n.Stat()
+	if err != nil {
+		return nil, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	info := &domain.FileInfo{
+		Fname:    n.Name(),
+		Fmode:    stat.Mode(),
+		FmodTime: stat.ModTime(),
+		Fsize:    stat.Size(),
+	}
+
+	// Procfs files carry no size, which would make them look empty (see
+	// PassThrough's Lookup()).
+	if !stat.IsDir() && info.Fsize == 0 {
+		info.Fsize = 32768
+	}
+
+	return info, nil
+}
+
+func (h *HostPassThrough) Open(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (bool, error) {
+
+	logrus.Debugf("Executing Open() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	flags := n.OpenFlags()
+
+	if flags&syscall.O_WRONLY == syscall.O_WRONLY ||
+		flags&syscall.O_RDWR == syscall.O_RDWR {
+		return false, fuse.IOerror{Code: syscall.EACCES}
+	}
+
+	return false, nil
+}
+
+func (h *HostPassThrough) Read(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	req.NoCache = true
+
+	data, err := n.ReadFile()
+	if err != nil {
+		logrus.Debugf("Unable to read %s: %v", n.Path(), err)
+		return 0, fuse.IOerror{Code: syscall.EIO}
+	}
+
+	return readWindow(req, data)
+}
+
+func (h *HostPassThrough) Write(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Write() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return 0, fuse.IOerror{Code: syscall.EACCES}
+}
+
+func (h *HostPassThrough) ReadDirAll(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]os.FileInfo, error) {
+
+	logrus.Debugf("Executing ReadDirAll() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return n.ReadDirAll()
+}
+
+func (h *HostPassThrough) ReadLink(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (string, error) {
+
+	logrus.Debugf("Executing ReadLink() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return n.ReadLink()
+}
+
+func (h *HostPassThrough) GetName() string {
+	return h.Name
+}
+
+func (h *HostPassThrough) GetPath() string {
+	return h.Path
+}
+
+func (h *HostPassThrough) GetService() domain.HandlerServiceIface {
+	return h.Service
+}
+
+func (h *HostPassThrough) GetEnabled() bool {
+	return h.Enabled
+}
+
+func (h *HostPassThrough) SetEnabled(b bool) {
+	h.Enabled = b
+}
+
+func (h *HostPassThrough) GetResourcesList() []string {
+	return nil
+}
+
+func (h *HostPassThrough) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex {
+	return nil
+}
+
+func (h *HostPassThrough) SetService(hs domain.HandlerServiceIface) {
+	h.Service = hs
+}
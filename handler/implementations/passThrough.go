@@ -17,6 +17,7 @@
 package implementations
 
 import (
+	"context"
 	"io"
 	"os"
 	"path/filepath"
@@ -233,9 +234,12 @@ func (h *PassThrough) ReadWithNS(
 		if req.Offset == 0 && sz == 0 && err == io.EOF {
 
 			// Resource is not cached, read it from the filesystem.
-			sz, err = h.fetchFile(process, namespaces, n, req.Offset, &req.Data)
+			sz, err = h.fetchFile(req.Ctx, process, namespaces, n, req.Offset, &req.Data)
 			if err != nil {
 				cntr.Unlock()
+				if isInterruptedError(err) {
+					return 0, err
+				}
 				return 0, fuse.IOerror{Code: syscall.EINVAL}
 			}
 
@@ -256,8 +260,11 @@ func (h *PassThrough) ReadWithNS(
 		cntr.Unlock()
 
 	} else {
-		sz, err = h.fetchFile(process, namespaces, n, req.Offset, &req.Data)
+		sz, err = h.fetchFile(req.Ctx, process, namespaces, n, req.Offset, &req.Data)
 		if err != nil {
+			if isInterruptedError(err) {
+				return 0, err
+			}
 			return 0, fuse.IOerror{Code: syscall.EINVAL}
 		}
 	}
@@ -297,7 +304,7 @@ func (h *PassThrough) WriteWithNS(
 	prs := h.Service.ProcessService()
 	process := prs.ProcessCreate(req.Pid, req.Uid, req.Gid)
 
-	if len, err = h.pushFile(process, namespaces, n, req.Offset, req.Data); err != nil {
+	if len, err = h.pushFile(req.Ctx, process, namespaces, n, req.Offset, req.Data); err != nil {
 		return 0, err
 	}
 
@@ -464,6 +471,7 @@ func (h *PassThrough) Setattr(
 
 // Auxiliary method to fetch the content of any given file within a container.
 func (h *PassThrough) fetchFile(
+	ctx context.Context,
 	process domain.ProcessIface,
 	namespaces []domain.NStype,
 	n domain.IOnodeIface,
@@ -495,7 +503,7 @@ func (h *PassThrough) fetchFile(
 
 	// Launch nsenter-event to obtain file state within container
 	// namespaces.
-	err := nss.SendRequestEvent(event)
+	err := sendNSenterEvent(ctx, nss, event)
 	if err != nil {
 		return 0, err
 	}
@@ -513,6 +521,7 @@ func (h *PassThrough) fetchFile(
 
 // Auxiliary method to inject content into any given file within a container.
 func (h *PassThrough) pushFile(
+	ctx context.Context,
 	process domain.ProcessIface,
 	namespaces []domain.NStype,
 	n domain.IOnodeIface,
@@ -544,7 +553,7 @@ func (h *PassThrough) pushFile(
 
 	// Launch nsenter-event to write file state within container
 	// namespaces.
-	err := nss.SendRequestEvent(event)
+	err := sendNSenterEvent(ctx, nss, event)
 	if err != nil {
 		return 0, err
 	}
@@ -650,3 +659,19 @@ func checkProcAndSysRemount(n domain.IOnodeIface) (bool, bool, uint32) {
 
 	return mountSysfs, mountProcfs, cloneFlags
 }
+
+// sendNSenterEvent launches the given nsenter event, tying it to the context
+// of the FUSE request on whose behalf it's generated (if any). This way,
+// interrupted FUSE requests don't leave behind nsenter agents running to
+// completion for nothing.
+func sendNSenterEvent(
+	ctx context.Context,
+	nss domain.NSenterServiceIface,
+	event domain.NSenterEventIface) error {
+
+	if ctx == nil {
+		return nss.SendRequestEvent(event)
+	}
+
+	return nss.SendRequestEventWithContext(ctx, event)
+}
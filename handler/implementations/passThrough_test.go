@@ -17,6 +17,7 @@
 package implementations_test
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"reflect"
@@ -26,6 +27,7 @@ import (
 	"time"
 
 	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
 	"github.com/nestybox/sysbox-fs/handler/implementations"
 	"github.com/nestybox/sysbox-fs/mocks"
 	"github.com/nestybox/sysbox-fs/mount"
@@ -1120,3 +1122,123 @@ func TestPassThrough_SetService(t *testing.T) {
 		})
 	}
 }
+
+// blockingNSenterService is an nsenter-service fake whose requests only
+// complete upon cancellation of their context (or after a long timeout).
+type blockingNSenterService struct {
+	domain.NSenterServiceIface
+	started chan struct{}
+	aborted chan struct{}
+}
+
+func (s *blockingNSenterService) NewEvent(
+	pid uint32,
+	ns *[]domain.NStype,
+	cloneFlags uint32,
+	req *domain.NSenterMessage,
+	res *domain.NSenterMessage,
+	async bool) domain.NSenterEventIface {
+
+	return &nsenter.NSenterEvent{Pid: pid, ReqMsg: req}
+}
+
+func (s *blockingNSenterService) SendRequestEventWithContext(
+	ctx context.Context,
+	e domain.NSenterEventIface) error {
+
+	close(s.started)
+
+	select {
+	case <-ctx.Done():
+		close(s.aborted)
+		return fuse.IOerror{Code: syscall.EINTR}
+	case <-time.After(10 * time.Second):
+		return nil
+	}
+}
+
+func TestPassThrough_Interrupt(t *testing.T) {
+
+	cntr := css.ContainerCreate(
+		"c-intr",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+	if err := cntr.SetInitProc(1001, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		op   func(h *implementations.PassThrough, req *domain.HandlerRequest) error
+	}{
+		{"read", func(h *implementations.PassThrough, req *domain.HandlerRequest) error {
+			_, err := h.Read(ios.NewIOnode("node_1", "/proc/sys/net/node_1", 0), req)
+			return err
+		}},
+		{"write", func(h *implementations.PassThrough, req *domain.HandlerRequest) error {
+			_, err := h.Write(ios.NewIOnode("node_1", "/proc/sys/net/node_1", 0), req)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bss := &blockingNSenterService{
+				started: make(chan struct{}),
+				aborted: make(chan struct{}),
+			}
+
+			hs := &mocks.HandlerServiceIface{}
+			hs.On("NSenterService").Return(bss)
+			hs.On("ProcessService").Return(prs)
+
+			h := &implementations.PassThrough{
+				domain.HandlerBase{
+					Name:    "PassThrough",
+					Path:    "PassThrough",
+					Service: hs,
+				},
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      make([]byte, 16),
+				Ctx:       ctx,
+			}
+
+			// Interrupt the request once the nsenter event is in-flight.
+			go func() {
+				<-bss.started
+				cancel()
+			}()
+
+			start := time.Now()
+			err := tt.op(h, req)
+
+			if err != (fuse.IOerror{Code: syscall.EINTR}) {
+				t.Errorf("%s error = %v, want EINTR", tt.name, err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("%s took %v to return after interruption", tt.name, elapsed)
+			}
+
+			select {
+			case <-bss.aborted:
+			default:
+				t.Errorf("%s: in-flight nsenter event not aborted", tt.name)
+			}
+		})
+	}
+}
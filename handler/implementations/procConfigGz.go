@@ -0,0 +1,211 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+)
+
+//
+// /proc/config.gz handler
+//
+// Exposes the (gzipped) config of the running kernel, which tools such as
+// kernel-module builders rely on.
+//
+// The file is presented as a whole: if its content can't be obtained the file
+// is reported as absent, rather than exposing a partial view of it. Notice
+// that this handler only kicks in when the host's procfs carries this file, as
+// otherwise there's no mountpoint to place it over (see
+// mount.ProcfsOptionalMounts).
+//
+
+type ProcConfigGz struct {
+	domain.HandlerBase
+}
+
+var ProcConfigGz_Handler = &ProcConfigGz{
+	domain.HandlerBase{
+		Name:    "ProcConfigGz",
+		Path:    "/proc/config.gz",
+		Enabled: true,
+	},
+}
+
+func (h *ProcConfigGz) Lookup(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (os.FileInfo, error) {
+
+	var resource = n.Name()
+
+	logrus.Debugf("Executing Lookup() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, resource)
+
+	data, err := h.readConfig(n, req)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &domain.FileInfo{
+		Fname:    resource,
+		Fmode:    os.FileMode(uint32(0444)),
+		FmodTime: time.Now(),
+		Fsize:    int64(len(data)),
+	}
+
+	return info, nil
+}
+
+func (h *ProcConfigGz) Open(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (bool, error) {
+
+	logrus.Debugf("Executing Open() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if _, err := h.readConfig(n, req); err != nil {
+		return false, err
+	}
+
+	flags := n.OpenFlags()
+
+	if flags&syscall.O_WRONLY == syscall.O_WRONLY ||
+		flags&syscall.O_RDWR == syscall.O_RDWR {
+		return false, fuse.IOerror{Code: syscall.EACCES}
+	}
+
+	return false, nil
+}
+
+func (h *ProcConfigGz) Read(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	data, err := h.readConfig(n, req)
+	if err != nil {
+		return 0, err
+	}
+
+	return readWindow(req, data)
+}
+
+func (h *ProcConfigGz) Write(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Write() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return 0, fuse.IOerror{Code: syscall.EACCES}
+}
+
+func (h *ProcConfigGz) ReadDirAll(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]os.FileInfo, error) {
+
+	logrus.Debugf("Executing ReadDirAll() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return nil, nil
+}
+
+func (h *ProcConfigGz) ReadLink(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (string, error) {
+
+	logrus.Debugf("Executing ReadLink() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return "", nil
+}
+
+func (h *ProcConfigGz) GetName() string {
+	return h.Name
+}
+
+func (h *ProcConfigGz) GetPath() string {
+	return h.Path
+}
+
+func (h *ProcConfigGz) GetService() domain.HandlerServiceIface {
+	return h.Service
+}
+
+func (h *ProcConfigGz) GetEnabled() bool {
+	return h.Enabled
+}
+
+func (h *ProcConfigGz) SetEnabled(b bool) {
+	h.Enabled = b
+}
+
+func (h *ProcConfigGz) GetResourcesList() []string {
+
+	var resources []string
+
+	for resourceKey, resource := range h.EmuResourceMap {
+		resource.Mutex.Lock()
+		if !resource.Enabled {
+			resource.Mutex.Unlock()
+			continue
+		}
+		resource.Mutex.Unlock()
+
+		resources = append(resources, filepath.Join(h.GetPath(), resourceKey))
+	}
+
+	return resources
+}
+
+func (h *ProcConfigGz) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex {
+	resource, ok := h.EmuResourceMap[n.Name()]
+	if !ok {
+		return nil
+	}
+
+	return &resource.Mutex
+}
+
+func (h *ProcConfigGz) SetService(hs domain.HandlerServiceIface) {
+	h.Service = hs
+}
+
+// readConfig returns the host's kernel config, or ENOENT if it can't be
+// obtained.
+func (h *ProcConfigGz) readConfig(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]byte, error) {
+
+	data, err := n.ReadFile()
+	if err != nil {
+		logrus.Debugf("Unable to read kernel config for %s: %v", n.Path(), err)
+		return nil, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	return data, nil
+}
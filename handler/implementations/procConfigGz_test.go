@@ -0,0 +1,119 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+func TestProcConfigGz(t *testing.T) {
+
+	// Gzip magic followed by some payload; the handler doesn't care about the
+	// content itself.
+	hostConfig := "\x1f\x8b\x08\x00host-config"
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("IOService").Return(ios)
+
+	h := implementations.ProcConfigGz_Handler
+	h.SetService(hs)
+
+	cntr := css.ContainerCreate(
+		"c-config",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	tests := []struct {
+		name    string
+		host    string // "" if the host's config can't be read
+		want    string
+		wantErr error
+	}{
+		// Test-case 1: Host's config.
+		{"host", hostConfig, hostConfig, nil},
+		// Test-case 2: Host's config not found; absent rather than partial.
+		{"missing", "", "", fuse.IOerror{Code: syscall.ENOENT}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer ios.RemoveAllIOnodes()
+
+			if tt.host != "" {
+				if err := ios.NewIOnode("", "/proc/config.gz", 0444).WriteFile([]byte(tt.host)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			n := ios.NewIOnode("config.gz", "/proc/config.gz", 0)
+			n.SetOpenFlags(syscall.O_RDONLY)
+
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      make([]byte, 64),
+			}
+
+			info, err := h.Lookup(n, req)
+			if err != tt.wantErr {
+				t.Fatalf("Lookup() error = %v, want %v", err, tt.wantErr)
+			}
+			if _, err := h.Open(n, req); err != tt.wantErr {
+				t.Errorf("Open() error = %v, want %v", err, tt.wantErr)
+			}
+			sz, err := h.Read(n, req)
+			if err != tt.wantErr {
+				t.Errorf("Read() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if info.Mode() != os.FileMode(0444) {
+				t.Errorf("Lookup() mode = %v, want %v", info.Mode(), os.FileMode(0444))
+			}
+			if info.Size() != int64(len(tt.want)) {
+				t.Errorf("Lookup() size = %d, want %d", info.Size(), len(tt.want))
+			}
+			if got := string(req.Data[:sz]); got != tt.want {
+				t.Errorf("Read() = %q, want %q", got, tt.want)
+			}
+
+			// Writes are never allowed.
+			n.SetOpenFlags(syscall.O_WRONLY)
+			if _, err := h.Open(n, req); err != (fuse.IOerror{Code: syscall.EACCES}) {
+				t.Errorf("Open(O_WRONLY) error = %v, want EACCES", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+)
+
+//
+// Extra /proc paths handler
+//
+// Third-party kernel modules register /proc entries of their own (e.g., ZFS's
+// /proc/spl/kstat), which carry host-wide state that sysbox-fs knows nothing
+// about. Rather than emulating each of them, operators can pick one of the
+// following policies for any such path (see the "proc-extra-path" option of
+// sysbox-fs), which applies to the nodes beneath it too:
+//
+// * hide: the path is presented as absent (ENOENT).
+//
+// * passthrough: the host's view of the path is displayed, read-only (as per
+//   the host passthrough handler).
+//
+// * emulate:<file>: the path is presented as a read-only file holding the
+//   content of the given host file, as read during sysbox-fs initialization.
+//
+// One handler instance is registered per configured path. Notice that these
+// paths are only tracked as sysbox-fs submounts when present in the host's
+// procfs (see mount.ProcfsOptionalMounts).
+//
+
+// Per-process paths, which can't be configured as extra paths.
+var procExtraPidRegexp = regexp.MustCompile(`^/proc/([0-9]+|self|thread-self)(/|$)`)
+
+// Policies of the extra /proc paths.
+const (
+	ProcExtraHide        = "hide"
+	ProcExtraPassthrough = "passthrough"
+	ProcExtraEmulate     = "emulate"
+)
+
+type ProcExtra struct {
+	domain.HandlerBase
+	Policy string // one of the ProcExtra* policies
+	Blob   []byte // content presented by the emulate policy
+}
+
+// NewProcExtraHandler returns the handler of the extra /proc path described by
+// the given spec, in "<path>=<policy>" format (e.g., "/proc/spl/kstat=hide",
+// "/proc/spl/kstat=emulate:/etc/sysbox/kstat").
+func NewProcExtraHandler(spec string) (*ProcExtra, error) {
+
+	fields := strings.SplitN(spec, "=", 2)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid extra proc path %q: missing policy", spec)
+	}
+
+	path, policy := strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1])
+
+	if !strings.HasPrefix(path, "/proc/") || filepath.Clean(path) != path {
+		return nil, fmt.Errorf("invalid extra proc path %q: not a /proc path", path)
+	}
+	if procExtraPidRegexp.MatchString(path) {
+		return nil, fmt.Errorf("invalid extra proc path %q: per-process path", path)
+	}
+
+	h := &ProcExtra{
+		HandlerBase: domain.HandlerBase{
+			Name:    "ProcExtra" + path,
+			Path:    path,
+			Enabled: true,
+		},
+		Policy: policy,
+	}
+
+	switch {
+	case policy == ProcExtraHide, policy == ProcExtraPassthrough:
+
+	case strings.HasPrefix(policy, ProcExtraEmulate+":"):
+		src := strings.TrimPrefix(policy, ProcExtraEmulate+":")
+		blob, err := os.ReadFile(src)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extra proc path %q: %v", path, err)
+		}
+		h.Policy = ProcExtraEmulate
+		h.Blob = blob
+
+	default:
+		return nil, fmt.Errorf("invalid extra proc path %q: unknown policy %q",
+			path, policy)
+	}
+
+	return h, nil
+}
+
+func (h *ProcExtra) Lookup(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (os.FileInfo, error) {
+
+	var resource = n.Name()
+
+	logrus.Debugf("Executing Lookup() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, resource)
+
+	switch h.Policy {
+	case ProcExtraPassthrough:
+		return HostPassThrough_Handler.Lookup(n, req)
+
+	case ProcExtraEmulate:
+		if n.Path() != h.Path {
+			return nil, fuse.IOerror{Code: syscall.ENOENT}
+		}
+
+		info := &domain.FileInfo{
+			Fname:    resource,
+			Fmode:    os.FileMode(uint32(0444)),
+			FmodTime: time.Now(),
+			Fsize:    int64(len(h.Blob)),
+		}
+
+		return info, nil
+	}
+
+	return nil, fuse.IOerror{Code: syscall.ENOENT}
+}
+
+func (h *ProcExtra) Open(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (bool, error) {
+
+	logrus.Debugf("Executing Open() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	switch h.Policy {
+	case ProcExtraPassthrough:
+		return HostPassThrough_Handler.Open(n, req)
+
+	case ProcExtraEmulate:
+		if n.Path() != h.Path {
+			return false, fuse.IOerror{Code: syscall.ENOENT}
+		}
+
+		flags := n.OpenFlags()
+
+		if flags&syscall.O_WRONLY == syscall.O_WRONLY ||
+			flags&syscall.O_RDWR == syscall.O_RDWR {
+			return false, fuse.IOerror{Code: syscall.EACCES}
+		}
+
+		return false, nil
+	}
+
+	return false, fuse.IOerror{Code: syscall.ENOENT}
+}
+
+func (h *ProcExtra) Read(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	switch h.Policy {
+	case ProcExtraPassthrough:
+		return HostPassThrough_Handler.Read(n, req)
+
+	case ProcExtraEmulate:
+		if n.Path() != h.Path {
+			return 0, fuse.IOerror{Code: syscall.ENOENT}
+		}
+
+		return readWindow(req, h.Blob)
+	}
+
+	return 0, fuse.IOerror{Code: syscall.ENOENT}
+}
+
+func (h *ProcExtra) Write(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Write() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if h.Policy == ProcExtraHide {
+		return 0, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	return 0, fuse.IOerror{Code: syscall.EACCES}
+}
+
+func (h *ProcExtra) ReadDirAll(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]os.FileInfo, error) {
+
+	logrus.Debugf("Executing ReadDirAll() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	switch h.Policy {
+	case ProcExtraPassthrough:
+		return HostPassThrough_Handler.ReadDirAll(n, req)
+
+	case ProcExtraEmulate:
+		return nil, fuse.IOerror{Code: syscall.ENOTDIR}
+	}
+
+	return nil, fuse.IOerror{Code: syscall.ENOENT}
+}
+
+func (h *ProcExtra) ReadLink(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (string, error) {
+
+	logrus.Debugf("Executing ReadLink() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	switch h.Policy {
+	case ProcExtraPassthrough:
+		return HostPassThrough_Handler.ReadLink(n, req)
+
+	case ProcExtraEmulate:
+		return "", fuse.IOerror{Code: syscall.EINVAL}
+	}
+
+	return "", fuse.IOerror{Code: syscall.ENOENT}
+}
+
+func (h *ProcExtra) GetName() string {
+	return h.Name
+}
+
+func (h *ProcExtra) GetPath() string {
+	return h.Path
+}
+
+func (h *ProcExtra) GetService() domain.HandlerServiceIface {
+	return h.Service
+}
+
+func (h *ProcExtra) GetEnabled() bool {
+	return h.Enabled
+}
+
+func (h *ProcExtra) SetEnabled(b bool) {
+	h.Enabled = b
+}
+
+func (h *ProcExtra) GetResourcesList() []string {
+	return []string{h.GetPath()}
+}
+
+func (h *ProcExtra) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex {
+	return nil
+}
+
+func (h *ProcExtra) SetService(hs domain.HandlerServiceIface) {
+	h.Service = hs
+}
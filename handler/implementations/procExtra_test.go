@@ -0,0 +1,159 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+)
+
+func TestNewProcExtraHandler(t *testing.T) {
+
+	blob := filepath.Join(t.TempDir(), "kstat")
+	if err := os.WriteFile(blob, []byte("emulated\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		spec       string
+		wantPolicy string
+		wantBlob   string
+		wantErr    bool
+	}{
+		{"hide", "/proc/spl/kstat=hide", implementations.ProcExtraHide, "", false},
+		{"passthrough", " /proc/spl/kstat = passthrough", implementations.ProcExtraPassthrough, "", false},
+		{"emulate", "/proc/spl/kstat=emulate:" + blob, implementations.ProcExtraEmulate, "emulated\n", false},
+		{"emulate-missing", "/proc/spl/kstat=emulate:" + blob + ".missing", "", "", true},
+		{"no-policy", "/proc/spl/kstat", "", "", true},
+		{"bad-policy", "/proc/spl/kstat=mask", "", "", true},
+		{"not-proc", "/sys/module/zfs=hide", "", "", true},
+		{"unclean", "/proc/spl/../kstat=hide", "", "", true},
+		{"per-process", "/proc/self/kstat=hide", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := implementations.NewProcExtraHandler(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewProcExtraHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if h.GetPath() != "/proc/spl/kstat" {
+				t.Errorf("NewProcExtraHandler() path = %s", h.GetPath())
+			}
+			if h.Policy != tt.wantPolicy || string(h.Blob) != tt.wantBlob {
+				t.Errorf("NewProcExtraHandler() = (%s, %q), want (%s, %q)",
+					h.Policy, h.Blob, tt.wantPolicy, tt.wantBlob)
+			}
+		})
+	}
+}
+
+func TestProcExtra(t *testing.T) {
+
+	hostArcstats := "hits 4 12345\nmisses 4 678\n"
+
+	if err := ios.NewIOnode("", "/proc/spl/kstat/zfs/arcstats", 0444).WriteFile([]byte(hostArcstats)); err != nil {
+		t.Fatal(err)
+	}
+	defer ios.RemoveAllIOnodes()
+
+	newHandler := func(policy string, blob string) *implementations.ProcExtra {
+		return &implementations.ProcExtra{
+			HandlerBase: domain.HandlerBase{
+				Name:    "ProcExtra/proc/spl/kstat",
+				Path:    "/proc/spl/kstat",
+				Enabled: true,
+			},
+			Policy: policy,
+			Blob:   []byte(blob),
+		}
+	}
+
+	enoent := fuse.IOerror{Code: syscall.ENOENT}
+	eacces := fuse.IOerror{Code: syscall.EACCES}
+
+	tests := []struct {
+		name     string
+		h        *implementations.ProcExtra
+		path     string
+		want     string // content read (if no error)
+		wantErr  error  // on lookup, open and read
+		wantWErr error  // on write
+	}{
+		// Test-case 1: Hidden path and the nodes beneath it.
+		{"hide", newHandler(implementations.ProcExtraHide, ""),
+			"/proc/spl/kstat", "", enoent, enoent},
+		{"hide-nested", newHandler(implementations.ProcExtraHide, ""),
+			"/proc/spl/kstat/zfs/arcstats", "", enoent, enoent},
+		// Test-case 2: Host's view, read-only.
+		{"passthrough", newHandler(implementations.ProcExtraPassthrough, ""),
+			"/proc/spl/kstat/zfs/arcstats", hostArcstats, nil, eacces},
+		// Test-case 3: Fixed blob, with nothing beneath it.
+		{"emulate", newHandler(implementations.ProcExtraEmulate, "arc disabled\n"),
+			"/proc/spl/kstat", "arc disabled\n", nil, eacces},
+		{"emulate-nested", newHandler(implementations.ProcExtraEmulate, "arc disabled\n"),
+			"/proc/spl/kstat/zfs/arcstats", "", enoent, eacces},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := ios.NewIOnode(filepath.Base(tt.path), tt.path, 0)
+			req := &domain.HandlerRequest{Pid: 1001}
+
+			info, err := tt.h.Lookup(n, req)
+			if err != tt.wantErr {
+				t.Fatalf("Lookup() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && info.IsDir() {
+				t.Errorf("Lookup() unexpected dir")
+			}
+
+			n.SetOpenFlags(syscall.O_RDONLY)
+			if _, err := tt.h.Open(n, req); err != tt.wantErr {
+				t.Errorf("Open() error = %v, want %v", err, tt.wantErr)
+			}
+
+			req.Data = make([]byte, 64)
+			sz, err := tt.h.Read(n, req)
+			if err != tt.wantErr {
+				t.Errorf("Read() error = %v, want %v", err, tt.wantErr)
+			} else if err == nil && string(req.Data[:sz]) != tt.want {
+				t.Errorf("Read() = %q, want %q", req.Data[:sz], tt.want)
+			}
+
+			n.SetOpenFlags(syscall.O_WRONLY)
+			if _, err := tt.h.Open(n, req); err == nil {
+				t.Errorf("Open() for writing unexpectedly succeeded")
+			}
+
+			req.Data = []byte("0\n")
+			if _, err := tt.h.Write(n, req); err != tt.wantWErr {
+				t.Errorf("Write() error = %v, want %v", err, tt.wantWErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+)
+
+//
+// /proc/keys and /proc/key-users handlers
+//
+// These files display the keys (and the per-user key accounting) held by the
+// kernel's keyrings. Keyrings are not namespaced, so the host's view exposes
+// the keys of every user in the system, including the host's ones.
+//
+// The container's view is obtained out of the host's one by only keeping the
+// entries owned by users mapped into the container's user-ns (i.e., the ones
+// of the container's session and user keyrings), and by translating their
+// uid and gid columns through the container's uid/gid maps (gids not mapped
+// into the container are displayed as the overflow gid, as the kernel would
+// do). The kernel's formats are preserved, as these files carry no header and
+// are parsed positionally (e.g., by keyctl).
+//
+// An empty view is displayed when the container's keys can't be told apart
+// from the host's ones (e.g., the container has no user-ns mappings, or the
+// host's kernel doesn't expose these files).
+//
+
+// Gid displayed for keys owned by groups not mapped into the container (see
+// /proc/sys/kernel/overflowgid).
+const overflowGid = 65534
+
+// Index of the 'uid' and 'gid' columns in /proc/keys entries.
+const (
+	procKeysUidField = 5
+	procKeysGidField = 6
+)
+
+type ProcKeys struct {
+	domain.HandlerBase
+}
+
+var ProcKeys_Handler = &ProcKeys{
+	domain.HandlerBase{
+		Name:    "ProcKeys",
+		Path:    "/proc/keys",
+		Enabled: true,
+	},
+}
+
+var ProcKeyUsers_Handler = &ProcKeys{
+	domain.HandlerBase{
+		Name:    "ProcKeyUsers",
+		Path:    "/proc/key-users",
+		Enabled: true,
+	},
+}
+
+func (h *ProcKeys) Lookup(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (os.FileInfo, error) {
+
+	var resource = n.Name()
+
+	logrus.Debugf("Executing Lookup() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, resource)
+
+	info := &domain.FileInfo{
+		Fname:    resource,
+		Fmode:    os.FileMode(uint32(0444)),
+		FmodTime: time.Now(),
+		Fsize:    0,
+	}
+
+	return info, nil
+}
+
+func (h *ProcKeys) Open(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (bool, error) {
+
+	logrus.Debugf("Executing Open() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	flags := n.OpenFlags()
+
+	if flags&syscall.O_WRONLY == syscall.O_WRONLY ||
+		flags&syscall.O_RDWR == syscall.O_RDWR {
+		return false, fuse.IOerror{Code: syscall.EACCES}
+	}
+
+	return false, nil
+}
+
+func (h *ProcKeys) Read(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	// Keys come and go (and expire) behind our back, so they must not be
+	// cached.
+	req.NoCache = true
+
+	cntr := req.Container
+
+	if cntr.UidSize() == 0 {
+		return readWindow(req, nil)
+	}
+
+	data, err := n.ReadFile()
+	if err != nil {
+		logrus.Debugf("Unable to read %s: %v", n.Path(), err)
+		return readWindow(req, nil)
+	}
+
+	var content string
+
+	switch n.Name() {
+	case "keys":
+		content, err = procKeysRewrite(
+			string(data),
+			cntr.UID(),
+			cntr.UidSize(),
+			cntr.GID(),
+			cntr.GidSize(),
+		)
+	case "key-users":
+		content, err = procKeyUsersRewrite(
+			string(data),
+			cntr.UID(),
+			cntr.UidSize(),
+		)
+	}
+	if err != nil {
+		logrus.Errorf("Unable to rewrite %s: %v", n.Path(), err)
+		return 0, fuse.IOerror{Code: syscall.EIO}
+	}
+
+	return readWindow(req, []byte(content))
+}
+
+func (h *ProcKeys) Write(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Write() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return 0, fuse.IOerror{Code: syscall.EACCES}
+}
+
+func (h *ProcKeys) ReadDirAll(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]os.FileInfo, error) {
+
+	logrus.Debugf("Executing ReadDirAll() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return nil, nil
+}
+
+func (h *ProcKeys) ReadLink(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (string, error) {
+
+	logrus.Debugf("Executing ReadLink() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return "", nil
+}
+
+func (h *ProcKeys) GetName() string {
+	return h.Name
+}
+
+func (h *ProcKeys) GetPath() string {
+	return h.Path
+}
+
+func (h *ProcKeys) GetService() domain.HandlerServiceIface {
+	return h.Service
+}
+
+func (h *ProcKeys) GetEnabled() bool {
+	return h.Enabled
+}
+
+func (h *ProcKeys) SetEnabled(b bool) {
+	h.Enabled = b
+}
+
+func (h *ProcKeys) GetResourcesList() []string {
+
+	var resources []string
+
+	for resourceKey, resource := range h.EmuResourceMap {
+		resource.Mutex.Lock()
+		if !resource.Enabled {
+			resource.Mutex.Unlock()
+			continue
+		}
+		resource.Mutex.Unlock()
+
+		resources = append(resources, filepath.Join(h.GetPath(), resourceKey))
+	}
+
+	return resources
+}
+
+func (h *ProcKeys) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex {
+	resource, ok := h.EmuResourceMap[n.Name()]
+	if !ok {
+		return nil
+	}
+
+	return &resource.Mutex
+}
+
+func (h *ProcKeys) SetService(hs domain.HandlerServiceIface) {
+	h.Service = hs
+}
+
+// procKeysRewrite takes the host's view of /proc/keys and returns the
+// container's one. Entries are printed by the kernel as "%08x %c%c%c%c%c%c%c
+// %5d %4s %08x %5d %5d %-9.9s <description>", so the translated uid and gid
+// are re-rendered with the same format.
+func procKeysRewrite(
+	keys string,
+	uidFirst, uidSize, gidFirst, gidSize uint32) (string, error) {
+
+	var sb strings.Builder
+
+	for _, line := range strings.SplitAfter(keys, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		uidStart, uidEnd, err := fieldBounds(line, procKeysUidField)
+		if err != nil {
+			return "", err
+		}
+		uid, err := strconv.ParseUint(line[uidStart:uidEnd], 10, 32)
+		if err != nil {
+			return "", err
+		}
+
+		// Keys of users not mapped into the container are not displayed.
+		nsUid, ok := idMap(uid, uidFirst, uidSize)
+		if !ok {
+			continue
+		}
+
+		gidStart, gidEnd, err := fieldBounds(line, procKeysGidField)
+		if err != nil {
+			return "", err
+		}
+		gid, err := strconv.ParseUint(line[gidStart:gidEnd], 10, 32)
+		if err != nil {
+			return "", err
+		}
+
+		nsGid, ok := idMap(gid, gidFirst, gidSize)
+		if !ok {
+			nsGid = overflowGid
+		}
+
+		sb.WriteString(line[:fieldPadStart(line, uidStart)])
+		sb.WriteString(fmt.Sprintf(" %5d %5d", nsUid, nsGid))
+		sb.WriteString(line[gidEnd:])
+	}
+
+	return sb.String(), nil
+}
+
+// procKeyUsersRewrite takes the host's view of /proc/key-users and returns the
+// container's one. Entries are printed by the kernel as "%5u: %5d %d/%d %d/%d
+// %d/%d", so the translated uid is re-rendered with the same format.
+func procKeyUsersRewrite(
+	keyUsers string,
+	uidFirst, uidSize uint32) (string, error) {
+
+	var sb strings.Builder
+
+	for _, line := range strings.SplitAfter(keyUsers, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		sep := strings.Index(line, ":")
+		if sep == -1 {
+			return "", fmt.Errorf("invalid format: no uid found in %q", line)
+		}
+
+		uid, err := strconv.ParseUint(strings.TrimSpace(line[:sep]), 10, 32)
+		if err != nil {
+			return "", err
+		}
+
+		nsUid, ok := idMap(uid, uidFirst, uidSize)
+		if !ok {
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("%5d", nsUid))
+		sb.WriteString(line[sep:])
+	}
+
+	return sb.String(), nil
+}
+
+// idMap translates the given host id through the given id-map range, and
+// reports whether the id is mapped by it.
+func idMap(id uint64, first, size uint32) (uint64, bool) {
+
+	if id < uint64(first) || id >= uint64(first)+uint64(size) {
+		return 0, false
+	}
+
+	return id - uint64(first), true
+}
+
+// fieldPadStart returns the offset of the padding preceding the field that
+// starts at the given offset of the given line.
+func fieldPadStart(line string, fieldStart int) int {
+	return strings.LastIndexFunc(line[:fieldStart], func(r rune) bool {
+		return r != ' '
+	}) + 1
+}
+
+// fieldBounds returns the start and end offsets of the idx-th (zero-based)
+// whitespace-separated field of the given line.
+func fieldBounds(line string, idx int) (int, int, error) {
+
+	var (
+		field = -1
+		start = -1
+	)
+
+	for i := 0; i <= len(line); i++ {
+		isSpace := i == len(line) || line[i] == ' ' || line[i] == '\t' || line[i] == '\n'
+
+		if !isSpace && start == -1 {
+			start = i
+			field++
+			continue
+		}
+
+		if isSpace && start != -1 {
+			if field == idx {
+				return start, i, nil
+			}
+			start = -1
+		}
+	}
+
+	return 0, 0, fmt.Errorf("invalid format: field %d not found in %q", idx, line)
+}
@@ -0,0 +1,151 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+)
+
+func TestProcKeys(t *testing.T) {
+
+	// Host's keyrings: host's root and user 1000 keys, along with the ones of
+	// the container's root (231072) and user 1000 (232072).
+	hostKeys := "" +
+		"0260a4e2 I--Q---     1 perm 3f030000     0     0 keyring   _ses: 1\n" +
+		"0e3b2f2a I--Q---     4 perm 3f010000  1000  1000 user      krb_ccache:primary: 12\n" +
+		"1c1d8fbb I--Q---     2 perm 3f030000 231072 231072 keyring   _ses: 1\n" +
+		"2a7c09d4 I--Q---     1 perm 1f3f0000 231072 231072 keyring   _uid.0: empty\n" +
+		"33a1e6f5 I--Q---     1  59m 3f010000 232072     0 user      token: 32\n"
+	hostKeyUsers := "" +
+		"    0:    10 9/9 2/1000000 39/25000000\n" +
+		" 1000:     4 4/4 4/200 42/20000\n" +
+		"231072:     3 3/3 3/200 28/20000\n" +
+		"232072:     1 1/1 1/200 32/20000\n"
+
+	if err := ios.NewIOnode("", "/proc/keys", 0444).WriteFile([]byte(hostKeys)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ios.NewIOnode("", "/proc/key-users", 0444).WriteFile([]byte(hostKeyUsers)); err != nil {
+		t.Fatal(err)
+	}
+	defer ios.RemoveAllIOnodes()
+
+	newCntr := func(id string, idFirst, idSize uint32) domain.ContainerIface {
+		return css.ContainerCreate(
+			id,
+			uint32(1001),
+			time.Time{},
+			idFirst,
+			idSize,
+			idFirst,
+			idSize,
+			nil,
+			nil,
+			nil,
+		)
+	}
+
+	tests := []struct {
+		name    string
+		handler domain.HandlerIface
+		cntr    domain.ContainerIface
+		want    string
+	}{
+		// Test-case 1: Container's keys, with their uids and gids translated.
+		{
+			"keys",
+			implementations.ProcKeys_Handler,
+			newCntr("c-keys", 231072, 65536),
+			"" +
+				"1c1d8fbb I--Q---     2 perm 3f030000     0     0 keyring   _ses: 1\n" +
+				"2a7c09d4 I--Q---     1 perm 1f3f0000     0     0 keyring   _uid.0: empty\n" +
+				"33a1e6f5 I--Q---     1  59m 3f010000  1000 65534 user      token: 32\n",
+		},
+		// Test-case 2: Container's key users, with their uids translated.
+		{
+			"key-users",
+			implementations.ProcKeyUsers_Handler,
+			newCntr("c-key-users", 231072, 65536),
+			"" +
+				"    0:     3 3/3 3/200 28/20000\n" +
+				" 1000:     1 1/1 1/200 32/20000\n",
+		},
+		// Test-case 3: No user-ns mappings; empty views.
+		{
+			"keys-unmapped",
+			implementations.ProcKeys_Handler,
+			newCntr("c-keys-unmapped", 231072, 0),
+			"",
+		},
+		{
+			"key-users-unmapped",
+			implementations.ProcKeyUsers_Handler,
+			newCntr("c-key-users-unmapped", 231072, 0),
+			"",
+		},
+		// Test-case 4: Single id mapped; only root's keys are displayed.
+		{
+			"keys-root",
+			implementations.ProcKeys_Handler,
+			newCntr("c-keys-root", 231072, 1),
+			"" +
+				"1c1d8fbb I--Q---     2 perm 3f030000     0     0 keyring   _ses: 1\n" +
+				"2a7c09d4 I--Q---     1 perm 1f3f0000     0     0 keyring   _uid.0: empty\n",
+		},
+		// Test-case 5: Container owning no keys; empty views.
+		{
+			"keys-none",
+			implementations.ProcKeys_Handler,
+			newCntr("c-keys-none", 300000, 65536),
+			"",
+		},
+		{
+			"key-users-none",
+			implementations.ProcKeyUsers_Handler,
+			newCntr("c-key-users-none", 300000, 65536),
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.handler.GetPath()
+			n := ios.NewIOnode(path[len("/proc/"):], path, 0)
+
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: tt.cntr,
+				Data:      make([]byte, 4096),
+			}
+			sz, err := tt.handler.Read(n, req)
+			if err != nil && err != io.EOF {
+				t.Fatalf("Read(%s) unexpected error: %v", path, err)
+			}
+			if got := string(req.Data[:sz]); got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", path, got, tt.want)
+			}
+			if !req.NoCache {
+				t.Errorf("Read(%s) must not be cached", path)
+			}
+		})
+	}
+}
@@ -17,7 +17,6 @@
 package implementations
 
 import (
-	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -184,15 +183,9 @@ func (h *ProcSwaps) readSwaps(
 
 	logrus.Debugf("Executing %v Read() method", h.Name)
 
-	if req.Offset > 0 {
-		return 0, io.EOF
-	}
-
 	// Pretend swapping is off
 	//
 	// TODO: fix this once Sysbox intercepts the swapon() and swapoff() syscalls.
 
-	req.Data = []byte(swapsHeader + "\n")
-
-	return len(req.Data), nil
+	return readWindow(req, []byte(swapsHeader+"\n"))
 }
@@ -0,0 +1,190 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+)
+
+//
+// /proc/sys/abi handler
+//
+// Handles all accesses to the /proc/sys/abi subtree, whose nodes vary across
+// architectures (e.g., vsyscall32 in x86, swp / setend / sve_default_vector_length
+// in arm64). None of these tunables is namespaced, so rather than emulating
+// each one of them, accesses are routed into the container's namespaces and,
+// if the kernel rejects a write, the new value is kept at container level
+// (i.e., it's never pushed to the host).
+//
+// Nodes in the deny-list below alter the ABI exposed to every process in the
+// system, so writes to them are rejected with EACCES.
+//
+
+var procSysAbiDenyList = map[string]bool{
+	"vsyscall32":           true,
+	"tagged_addr_disabled": true,
+}
+
+type ProcSysAbi struct {
+	domain.HandlerBase
+}
+
+var ProcSysAbi_Handler = &ProcSysAbi{
+	domain.HandlerBase{
+		Name:           "ProcSysAbi",
+		Path:           "/proc/sys/abi",
+		Enabled:        true,
+		EmuResourceMap: map[string]*domain.EmuResource{},
+	},
+}
+
+func (h *ProcSysAbi) Lookup(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (os.FileInfo, error) {
+
+	logrus.Debugf("Executing Lookup() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return h.Service.GetPassThroughHandler().Lookup(n, req)
+}
+
+func (h *ProcSysAbi) Open(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (bool, error) {
+
+	var resource = n.Name()
+
+	logrus.Debugf("Executing Open() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, resource)
+
+	if procSysAbiDenyList[resource] {
+		flags := n.OpenFlags()
+		if flags&syscall.O_WRONLY == syscall.O_WRONLY ||
+			flags&syscall.O_RDWR == syscall.O_RDWR {
+			return false, fuse.IOerror{Code: syscall.EACCES}
+		}
+	}
+
+	return openNsSysctl(h, n, req)
+}
+
+func (h *ProcSysAbi) Read(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return h.Service.GetPassThroughHandler().Read(n, req)
+}
+
+func (h *ProcSysAbi) Write(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	var resource = n.Name()
+
+	logrus.Debugf("Executing Write() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, resource)
+
+	if procSysAbiDenyList[resource] {
+		logrus.Infof("Rejected write to %s: not allowed within containers",
+			n.Path())
+		return 0, fuse.IOerror{Code: syscall.EACCES}
+	}
+
+	return writeNsSysctl(h, n, req)
+}
+
+func (h *ProcSysAbi) ReadDirAll(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]os.FileInfo, error) {
+
+	logrus.Debugf("Executing ReadDirAll() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return h.Service.GetPassThroughHandler().ReadDirAll(n, req)
+}
+
+func (h *ProcSysAbi) ReadLink(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (string, error) {
+
+	logrus.Debugf("Executing ReadLink() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return h.Service.GetPassThroughHandler().ReadLink(n, req)
+}
+
+func (h *ProcSysAbi) GetName() string {
+	return h.Name
+}
+
+func (h *ProcSysAbi) GetPath() string {
+	return h.Path
+}
+
+func (h *ProcSysAbi) GetService() domain.HandlerServiceIface {
+	return h.Service
+}
+
+func (h *ProcSysAbi) GetEnabled() bool {
+	return h.Enabled
+}
+
+func (h *ProcSysAbi) SetEnabled(b bool) {
+	h.Enabled = b
+}
+
+func (h *ProcSysAbi) GetResourcesList() []string {
+
+	var resources []string
+
+	for resourceKey, resource := range h.EmuResourceMap {
+		resource.Mutex.Lock()
+		if !resource.Enabled {
+			resource.Mutex.Unlock()
+			continue
+		}
+		resource.Mutex.Unlock()
+
+		resources = append(resources, filepath.Join(h.GetPath(), resourceKey))
+	}
+
+	return resources
+}
+
+func (h *ProcSysAbi) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex {
+	resource, ok := h.EmuResourceMap[n.Name()]
+	if !ok {
+		return nil
+	}
+
+	return &resource.Mutex
+}
+
+func (h *ProcSysAbi) SetService(hs domain.HandlerServiceIface) {
+	h.Service = hs
+}
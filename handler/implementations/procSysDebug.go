@@ -0,0 +1,187 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+)
+
+//
+// /proc/sys/debug handler
+//
+// Handles all accesses to the /proc/sys/debug subtree (e.g., exception-trace,
+// kprobes-optimization). Same as with /proc/sys/abi, these are system-wide
+// tunables, so accesses go through the container's namespaces, and writes
+// rejected by the kernel are kept at container level.
+//
+// Writes to kprobes-optimization are rejected with EACCES, as this one
+// affects the kernel's tracing infrastructure as a whole.
+//
+
+var procSysDebugDenyList = map[string]bool{
+	"kprobes-optimization": true,
+}
+
+type ProcSysDebug struct {
+	domain.HandlerBase
+}
+
+var ProcSysDebug_Handler = &ProcSysDebug{
+	domain.HandlerBase{
+		Name:           "ProcSysDebug",
+		Path:           "/proc/sys/debug",
+		Enabled:        true,
+		EmuResourceMap: map[string]*domain.EmuResource{},
+	},
+}
+
+func (h *ProcSysDebug) Lookup(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (os.FileInfo, error) {
+
+	logrus.Debugf("Executing Lookup() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return h.Service.GetPassThroughHandler().Lookup(n, req)
+}
+
+func (h *ProcSysDebug) Open(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (bool, error) {
+
+	var resource = n.Name()
+
+	logrus.Debugf("Executing Open() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, resource)
+
+	if procSysDebugDenyList[resource] {
+		flags := n.OpenFlags()
+		if flags&syscall.O_WRONLY == syscall.O_WRONLY ||
+			flags&syscall.O_RDWR == syscall.O_RDWR {
+			return false, fuse.IOerror{Code: syscall.EACCES}
+		}
+	}
+
+	return openNsSysctl(h, n, req)
+}
+
+func (h *ProcSysDebug) Read(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return h.Service.GetPassThroughHandler().Read(n, req)
+}
+
+func (h *ProcSysDebug) Write(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	var resource = n.Name()
+
+	logrus.Debugf("Executing Write() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, resource)
+
+	if procSysDebugDenyList[resource] {
+		logrus.Infof("Rejected write to %s: not allowed within containers",
+			n.Path())
+		return 0, fuse.IOerror{Code: syscall.EACCES}
+	}
+
+	return writeNsSysctl(h, n, req)
+}
+
+func (h *ProcSysDebug) ReadDirAll(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]os.FileInfo, error) {
+
+	logrus.Debugf("Executing ReadDirAll() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return h.Service.GetPassThroughHandler().ReadDirAll(n, req)
+}
+
+func (h *ProcSysDebug) ReadLink(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (string, error) {
+
+	logrus.Debugf("Executing ReadLink() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return h.Service.GetPassThroughHandler().ReadLink(n, req)
+}
+
+func (h *ProcSysDebug) GetName() string {
+	return h.Name
+}
+
+func (h *ProcSysDebug) GetPath() string {
+	return h.Path
+}
+
+func (h *ProcSysDebug) GetService() domain.HandlerServiceIface {
+	return h.Service
+}
+
+func (h *ProcSysDebug) GetEnabled() bool {
+	return h.Enabled
+}
+
+func (h *ProcSysDebug) SetEnabled(b bool) {
+	h.Enabled = b
+}
+
+func (h *ProcSysDebug) GetResourcesList() []string {
+
+	var resources []string
+
+	for resourceKey, resource := range h.EmuResourceMap {
+		resource.Mutex.Lock()
+		if !resource.Enabled {
+			resource.Mutex.Unlock()
+			continue
+		}
+		resource.Mutex.Unlock()
+
+		resources = append(resources, filepath.Join(h.GetPath(), resourceKey))
+	}
+
+	return resources
+}
+
+func (h *ProcSysDebug) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex {
+	resource, ok := h.EmuResourceMap[n.Name()]
+	if !ok {
+		return nil
+	}
+
+	return &resource.Mutex
+}
+
+func (h *ProcSysDebug) SetService(hs domain.HandlerServiceIface) {
+	h.Service = hs
+}
@@ -0,0 +1,217 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+// sysctlPassThrough is a passthrough-handler fake that stands for the
+// container's namespaces. Nodes not present in 'namespaced' can't be written
+// from within the container (i.e., system-wide sysctls). Just as the real
+// passthrough handler does, reads are served out of the container's data
+// cache when available.
+type sysctlPassThrough struct {
+	domain.PassthroughHandlerIface
+	nodes      map[string]string
+	namespaced map[string]bool
+	dirs       map[string][]os.FileInfo
+	writes     int
+}
+
+func (p *sysctlPassThrough) Open(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (bool, error) {
+
+	if _, ok := p.nodes[n.Path()]; !ok {
+		return false, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	flags := n.OpenFlags()
+	if (flags&syscall.O_WRONLY == syscall.O_WRONLY ||
+		flags&syscall.O_RDWR == syscall.O_RDWR) && !p.namespaced[n.Path()] {
+		return false, fuse.IOerror{Code: syscall.EACCES}
+	}
+
+	return false, nil
+}
+
+func (p *sysctlPassThrough) Read(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	cntr := req.Container
+	cntr.Lock()
+	defer cntr.Unlock()
+
+	sz, err := cntr.Data(n.Path(), req.Offset, &req.Data)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if sz > 0 {
+		return sz, nil
+	}
+
+	val, ok := p.nodes[n.Path()]
+	if !ok {
+		return 0, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	return copy(req.Data, val), nil
+}
+
+func (p *sysctlPassThrough) Write(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	p.writes++
+
+	if _, ok := p.nodes[n.Path()]; !ok {
+		return 0, fuse.IOerror{Code: syscall.ENOENT}
+	}
+	if !p.namespaced[n.Path()] {
+		return 0, fuse.IOerror{Code: syscall.EPERM}
+	}
+	p.nodes[n.Path()] = string(req.Data)
+
+	return len(req.Data), nil
+}
+
+func (p *sysctlPassThrough) ReadDirAll(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]os.FileInfo, error) {
+
+	entries, ok := p.dirs[n.Path()]
+	if !ok {
+		return nil, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	return entries, nil
+}
+
+func TestProcSysAbiDebug(t *testing.T) {
+
+	hostVals := map[string]string{
+		"/proc/sys/debug/exception-trace":      "1\n",
+		"/proc/sys/debug/kprobes-optimization": "1\n",
+		"/proc/sys/abi/vsyscall32":             "1\n",
+	}
+
+	tests := []struct {
+		name       string
+		handler    domain.HandlerIface
+		path       string
+		namespaced bool
+		val        string
+		wantErr    error
+		wantWrites int    // writes routed into the container's namespaces
+		want       string // value read back within the container
+	}{
+		// Test-case 1: Benign key, accepted within the container's namespaces.
+		{"1", implementations.ProcSysDebug_Handler, "/proc/sys/debug/exception-trace",
+			true, "0\n", nil, 1, "0\n"},
+		// Test-case 2: Benign key, rejected by the kernel; kept at container
+		// level.
+		{"2", implementations.ProcSysDebug_Handler, "/proc/sys/debug/exception-trace",
+			false, "0\n", nil, 1, "0\n"},
+		// Test-case 3: Deny-listed debug key.
+		{"3", implementations.ProcSysDebug_Handler, "/proc/sys/debug/kprobes-optimization",
+			false, "0\n", fuse.IOerror{Code: syscall.EACCES}, 0, "1\n"},
+		// Test-case 4: Deny-listed abi key.
+		{"4", implementations.ProcSysAbi_Handler, "/proc/sys/abi/vsyscall32",
+			false, "0\n", fuse.IOerror{Code: syscall.EACCES}, 0, "1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := map[string]string{}
+			for path, val := range hostVals {
+				nodes[path] = val
+			}
+			pt := &sysctlPassThrough{
+				nodes:      nodes,
+				namespaced: map[string]bool{tt.path: tt.namespaced},
+			}
+
+			hs := &mocks.HandlerServiceIface{}
+			hs.On("GetPassThroughHandler").Return(pt)
+			hs.On("IgnoreErrors").Return(false)
+
+			h := tt.handler
+			h.SetService(hs)
+
+			cntr := css.ContainerCreate(
+				"c-sysctl-"+tt.name,
+				uint32(1001),
+				time.Time{},
+				231072,
+				65535,
+				231072,
+				65535,
+				nil,
+				nil,
+				nil,
+			)
+
+			n := ios.NewIOnode(filepath.Base(tt.path), tt.path, 0)
+			n.SetOpenFlags(int(os.O_WRONLY))
+
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      []byte(tt.val),
+			}
+			if _, err := h.Open(n, req); err != tt.wantErr {
+				t.Fatalf("Open(%s) error = %v, want %v", tt.path, err, tt.wantErr)
+			}
+			if _, err := h.Write(n, req); err != tt.wantErr {
+				t.Fatalf("Write(%s) error = %v, want %v", tt.path, err, tt.wantErr)
+			}
+			if pt.writes != tt.wantWrites {
+				t.Errorf("Write(%s) routed %d times, want %d", tt.path, pt.writes, tt.wantWrites)
+			}
+
+			req = &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      make([]byte, 16),
+			}
+			sz, err := h.Read(n, req)
+			if err != nil {
+				t.Fatalf("Read(%s) unexpected error: %v", tt.path, err)
+			}
+			if got := string(req.Data[:sz]); got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", tt.path, got, tt.want)
+			}
+
+			// Host's value must be left untouched unless the node is namespaced.
+			if !tt.namespaced && pt.nodes[tt.path] != hostVals[tt.path] {
+				t.Errorf("host %s = %q, want %q", tt.path, pt.nodes[tt.path], hostVals[tt.path])
+			}
+		})
+	}
+}
@@ -17,8 +17,12 @@
 package implementations
 
 import (
+	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -38,6 +42,36 @@ import (
 // * /proc/sys/fs/nr_open
 // * /proc/sys/fs/protected_hardlinks
 // * /proc/sys/fs/protected_symlinks
+// * /proc/sys/fs/pipe-max-size
+// * /proc/sys/fs/pipe-user-pages-hard
+// * /proc/sys/fs/pipe-user-pages-soft
+//
+// The pipe-* tunables are host-global, so container writes are only stored
+// as container-local values and never pushed to the host. As the kernel does,
+// pipe-max-size values are rounded up to a power-of-two number of pages.
+//
+// * /proc/sys/fs/file-nr
+//
+// Read-only node displaying the number of allocated file handles, the number
+// of unused ones (always zero since Linux 2.6), and the system-wide limit. The
+// container's view reports the files opened by the container's processes (as
+// per their /proc/<pid>/fd entries), along with the container's file-max value
+// (as emulated above).
+//
+// * /proc/sys/fs/aio-max-nr
+//
+// System-wide limit of asynchronous IO requests. Container writes are stored
+// as container-local values, and only pushed to the host when raising the
+// host's limit (as is done for file-max).
+//
+// * /proc/sys/fs/aio-nr
+//
+// Read-only node displaying the number of asynchronous IO requests allocated
+// (through io_setup()). The kernel doesn't account these per process, so the
+// container's view is estimated out of the capacity of the aio rings mapped by
+// the container's processes (as per their /proc/<pid>/maps "/[aio]" entries),
+// which is never below the number of requests allocated for them. The host's
+// value caps the estimate.
 //
 
 const (
@@ -50,6 +84,28 @@ const (
 	maxProtectedHardlinksVal = 1
 )
 
+const (
+	minPipeMaxSizeVal = 1
+	maxPipeMaxSizeVal = 1 << 31
+)
+
+const (
+	minPipeUserPagesVal = 0
+	maxPipeUserPagesVal = math.MaxInt64
+)
+
+const (
+	minAioMaxNrVal = 1
+	maxAioMaxNrVal = math.MaxInt64
+)
+
+// Size of the aio ring's header (struct aio_ring) and of each of its entries
+// (struct io_event).
+const (
+	aioRingHeaderSize = 32
+	aioRingEventSize  = 32
+)
+
 type ProcSysFs struct {
 	domain.HandlerBase
 }
@@ -66,6 +122,12 @@ var ProcSysFs_Handler = &ProcSysFs{
 				Enabled: true,
 				Size:    1024,
 			},
+			"file-nr": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0444)),
+				Enabled: true,
+				Size:    1024,
+			},
 			"nr_open": {
 				Kind:    domain.FileEmuResource,
 				Mode:    os.FileMode(uint32(0644)),
@@ -84,6 +146,36 @@ var ProcSysFs_Handler = &ProcSysFs{
 				Enabled: true,
 				Size:    1024,
 			},
+			"pipe-max-size": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"pipe-user-pages-hard": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"pipe-user-pages-soft": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"aio-max-nr": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"aio-nr": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0444)),
+				Enabled: true,
+				Size:    1024,
+			},
 		},
 	},
 }
@@ -128,6 +220,14 @@ func (h *ProcSysFs) Open(
 	case "file-max":
 		return false, nil
 
+	case "file-nr", "aio-nr":
+		flags := n.OpenFlags()
+		if flags&syscall.O_WRONLY == syscall.O_WRONLY ||
+			flags&syscall.O_RDWR == syscall.O_RDWR {
+			return false, fuse.IOerror{Code: syscall.EACCES}
+		}
+		return false, nil
+
 	case "nr_open":
 		return false, nil
 
@@ -136,6 +236,12 @@ func (h *ProcSysFs) Open(
 
 	case "protected_symlinks":
 		return false, nil
+
+	case "pipe-max-size", "pipe-user-pages-hard", "pipe-user-pages-soft":
+		return false, nil
+
+	case "aio-max-nr":
+		return false, nil
 	}
 
 	return h.Service.GetPassThroughHandler().Open(n, req)
@@ -154,6 +260,9 @@ func (h *ProcSysFs) Read(
 	case "file-max":
 		return readCntrData(h, n, req)
 
+	case "file-nr":
+		return h.readFileNr(n, req)
+
 	case "nr_open":
 		return readCntrData(h, n, req)
 
@@ -162,6 +271,15 @@ func (h *ProcSysFs) Read(
 
 	case "protected_symlinks":
 		return readCntrData(h, n, req)
+
+	case "pipe-max-size", "pipe-user-pages-hard", "pipe-user-pages-soft":
+		return readCntrData(h, n, req)
+
+	case "aio-max-nr":
+		return readCntrData(h, n, req)
+
+	case "aio-nr":
+		return h.readAioNr(n, req)
 	}
 
 	// Refer to generic handler if no node match is found above.
@@ -181,6 +299,9 @@ func (h *ProcSysFs) Write(
 	case "file-max":
 		return writeCntrData(h, n, req, writeMaxIntToFs)
 
+	case "file-nr", "aio-nr":
+		return 0, fuse.IOerror{Code: syscall.EACCES}
+
 	case "nr_open":
 		return writeCntrData(h, n, req, writeMaxIntToFs)
 
@@ -195,6 +316,21 @@ func (h *ProcSysFs) Write(
 			return 0, fuse.IOerror{Code: syscall.EINVAL}
 		}
 		return writeCntrData(h, n, req, nil)
+
+	case "pipe-max-size":
+		return h.writePipeMaxSize(n, req)
+
+	case "pipe-user-pages-hard", "pipe-user-pages-soft":
+		if !checkIntRange(req.Data, minPipeUserPagesVal, maxPipeUserPagesVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		return writeCntrData(h, n, req, nil)
+
+	case "aio-max-nr":
+		if !checkIntRange(req.Data, minAioMaxNrVal, maxAioMaxNrVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		return writeCntrData(h, n, req, writeMaxIntToFs)
 	}
 
 	// Refer to generic handler if no node match is found above.
@@ -272,3 +408,187 @@ func (h *ProcSysFs) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex {
 func (h *ProcSysFs) SetService(hs domain.HandlerServiceIface) {
 	h.Service = hs
 }
+
+// writePipeMaxSize stores the container's pipe-max-size value, once rounded
+// the same way the kernel does.
+func (h *ProcSysFs) writePipeMaxSize(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	if !checkIntRange(req.Data, minPipeMaxSizeVal, maxPipeMaxSizeVal) {
+		return 0, fuse.IOerror{Code: syscall.EINVAL}
+	}
+
+	val, err := strconv.ParseUint(strings.TrimSpace(string(req.Data)), 10, 64)
+	if err != nil {
+		return 0, fuse.IOerror{Code: syscall.EINVAL}
+	}
+
+	// The stored value differs from the written one, but the caller must
+	// still see its whole buffer as consumed.
+	sz := len(req.Data)
+
+	req.Data = []byte(strconv.FormatUint(roundPipeSize(val, uint64(os.Getpagesize())), 10) + "\n")
+
+	if _, err := writeCntrData(h, n, req, nil); err != nil {
+		return 0, err
+	}
+
+	return sz, nil
+}
+
+// readFileNr renders the container's view of file-nr (see above).
+func (h *ProcSysFs) readFileNr(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	// Open files come and go behind our back.
+	req.NoCache = true
+
+	// The limit is the one displayed through the container's file-max node.
+	maxReq := &domain.HandlerRequest{
+		ID:        req.ID,
+		Pid:       req.Pid,
+		Container: req.Container,
+		Data:      make([]byte, 32),
+	}
+	maxNode := h.Service.IOService().NewIOnode(
+		"file-max",
+		filepath.Join(h.Path, "file-max"),
+		0,
+	)
+
+	sz, err := readCntrData(h, maxNode, maxReq)
+	if err != nil {
+		return 0, err
+	}
+	fileMax := strings.TrimSpace(string(maxReq.Data[:sz]))
+
+	content := fmt.Sprintf("%d\t0\t%s\n", h.cntrOpenFiles(req.Container), fileMax)
+
+	return readWindow(req, []byte(content))
+}
+
+// readAioNr renders the container's view of aio-nr (see above).
+func (h *ProcSysFs) readAioNr(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	// Requests are allocated and released behind our back.
+	req.NoCache = true
+
+	nr := h.cntrAioRequests(req.Container)
+
+	data, err := n.ReadFile()
+	if err == nil {
+		hostNr, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err == nil && hostNr < nr {
+			nr = hostNr
+		}
+	}
+
+	return readWindow(req, []byte(strconv.FormatUint(nr, 10)+"\n"))
+}
+
+// cntrAioRequests returns the number of asynchronous IO requests that fit in
+// the aio rings mapped by the processes of the given container.
+func (h *ProcSysFs) cntrAioRequests(cntr domain.ContainerIface) uint64 {
+
+	ios := h.Service.IOService()
+
+	var nr uint64
+
+	// Processes may be gone by now, so their rings are counted on a
+	// best-effort basis.
+	for _, pid := range h.cntrPids(cntr) {
+		maps, err := ios.NewIOnode("", filepath.Join("/proc", pid, "maps"), 0).ReadFile()
+		if err != nil {
+			continue
+		}
+
+		for _, line := range strings.Split(string(maps), "\n") {
+			// Format: start-end perms offset dev inode pathname
+			fields := strings.Fields(line)
+			if len(fields) < 6 || fields[5] != "/[aio]" {
+				continue
+			}
+
+			bounds := strings.SplitN(fields[0], "-", 2)
+			if len(bounds) != 2 {
+				continue
+			}
+			start, err1 := strconv.ParseUint(bounds[0], 16, 64)
+			end, err2 := strconv.ParseUint(bounds[1], 16, 64)
+			if err1 != nil || err2 != nil || end-start < aioRingHeaderSize {
+				continue
+			}
+
+			nr += (end - start - aioRingHeaderSize) / aioRingEventSize
+		}
+	}
+
+	return nr
+}
+
+// cntrOpenFiles returns the number of files opened by the processes of the
+// given container.
+func (h *ProcSysFs) cntrOpenFiles(cntr domain.ContainerIface) int {
+
+	ios := h.Service.IOService()
+
+	var files int
+
+	// Processes may be gone by now, so their fds are counted on a best-effort
+	// basis.
+	for _, pid := range h.cntrPids(cntr) {
+		fds, err := ios.NewIOnode("", filepath.Join("/proc", pid, "fd"), 0).ReadDirAll()
+		if err != nil {
+			continue
+		}
+		files += len(fds)
+	}
+
+	return files
+}
+
+// cntrPids returns the (host) pids of the processes of the given container,
+// these being the ones of its pids cgroup, or just its init process if the
+// cgroup can't be determined.
+func (h *ProcSysFs) cntrPids(cntr domain.ContainerIface) []string {
+
+	ios := h.Service.IOService()
+
+	pids := []string{strconv.FormatUint(uint64(cntr.InitPid()), 10)}
+
+	procsPath, err := cgroupFilePath(ios, cntr.InitPid(), "pids", "cgroup.procs", "cgroup.procs")
+	if err == nil {
+		var data []byte
+		if data, err = ios.NewIOnode("", procsPath, 0).ReadFile(); err == nil {
+			pids = strings.Fields(string(data))
+		}
+	}
+	if err != nil {
+		logrus.Debugf("Unable to obtain processes of container %s: %v",
+			cntr.ID(), err)
+	}
+
+	return pids
+}
+
+// roundPipeSize mirrors the kernel's round_pipe_size(): the given size is
+// rounded up to a power-of-two number of pages, with a minimum of one page.
+func roundPipeSize(size, pageSize uint64) uint64 {
+
+	if size < pageSize {
+		return pageSize
+	}
+
+	pages := (size + pageSize - 1) / pageSize
+
+	rounded := uint64(1)
+	for rounded < pages {
+		rounded <<= 1
+	}
+
+	return rounded * pageSize
+}
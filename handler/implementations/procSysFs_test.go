@@ -0,0 +1,402 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+func TestProcSysFs_Pipe(t *testing.T) {
+
+	hostVals := map[string]string{
+		"/proc/sys/fs/pipe-max-size":        "1048576\n",
+		"/proc/sys/fs/pipe-user-pages-hard": "0\n",
+		"/proc/sys/fs/pipe-user-pages-soft": "16384\n",
+	}
+	for path, val := range hostVals {
+		if err := ios.NewIOnode("", path, 0644).WriteFile([]byte(val)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer ios.RemoveAllIOnodes()
+
+	defer hds.On("IgnoreErrors").Return(false).Unset()
+
+	h := implementations.ProcSysFs_Handler
+	h.SetService(hds)
+
+	cntr := css.ContainerCreate(
+		"c1",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	read := func(resource string) (string, error) {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 64),
+		}
+		n := ios.NewIOnode(resource, "/proc/sys/fs/"+resource, 0)
+		sz, err := h.Read(n, req)
+		if err != nil {
+			return "", err
+		}
+		return string(req.Data[:sz]), nil
+	}
+
+	write := func(resource, val string) (int, error) {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      []byte(val),
+		}
+		n := ios.NewIOnode(resource, "/proc/sys/fs/"+resource, 0)
+		return h.Write(n, req)
+	}
+
+	page := os.Getpagesize()
+	pages := func(n int) string { return strconv.Itoa(n*page) + "\n" }
+
+	// Reads default to the host values.
+	for path, want := range hostVals {
+		resource := path[len("/proc/sys/fs/"):]
+		got, err := read(resource)
+		if err != nil {
+			t.Fatalf("Read(%s) unexpected error: %v", resource, err)
+		}
+		if got != want {
+			t.Errorf("Read(%s) = %q, want %q", resource, got, want)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		resource string
+		val      string
+		wantErr  error
+		want     string
+	}{
+		// Test-case 1: Exact power-of-two number of pages.
+		{"1", "pipe-max-size", pages(16), nil, pages(16)},
+		// Test-case 2: Below one page; rounded up to a page.
+		{"2", "pipe-max-size", "1\n", nil, pages(1)},
+		// Test-case 3: Non page-aligned; rounded up to the next page.
+		{"3", "pipe-max-size", strconv.Itoa(page+1) + "\n", nil, pages(2)},
+		// Test-case 4: Non power-of-two number of pages; rounded up.
+		{"4", "pipe-max-size", pages(5), nil, pages(8)},
+		// Test-case 5: Zero is not allowed; previous value is kept.
+		{"5", "pipe-max-size", "0\n", fuse.IOerror{Code: syscall.EINVAL}, pages(8)},
+		// Test-case 6: Negative value.
+		{"6", "pipe-max-size", "-4096\n", fuse.IOerror{Code: syscall.EINVAL}, pages(8)},
+		// Test-case 7: Non-numeric value.
+		{"7", "pipe-max-size", "big\n", fuse.IOerror{Code: syscall.EINVAL}, pages(8)},
+		// Test-case 8: Above the kernel's limit (2GB).
+		{"8", "pipe-max-size", "2147483649\n", fuse.IOerror{Code: syscall.EINVAL}, pages(8)},
+		// Test-case 9: Valid hard limit.
+		{"9", "pipe-user-pages-hard", "65536\n", nil, "65536\n"},
+		// Test-case 10: Zero (no limit) is allowed.
+		{"10", "pipe-user-pages-hard", "0\n", nil, "0\n"},
+		// Test-case 11: Negative soft limit.
+		{"11", "pipe-user-pages-soft", "-1\n", fuse.IOerror{Code: syscall.EINVAL}, "16384\n"},
+		// Test-case 12: Non-numeric soft limit.
+		{"12", "pipe-user-pages-soft", "lots\n", fuse.IOerror{Code: syscall.EINVAL}, "16384\n"},
+		// Test-case 13: Valid soft limit.
+		{"13", "pipe-user-pages-soft", "32768\n", nil, "32768\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sz, err := write(tt.resource, tt.val)
+			if err != tt.wantErr {
+				t.Fatalf("Write(%s, %q) error = %v, want %v",
+					tt.resource, tt.val, err, tt.wantErr)
+			}
+			if err == nil && sz != len(tt.val) {
+				t.Errorf("Write(%s, %q) = %d, want %d",
+					tt.resource, tt.val, sz, len(tt.val))
+			}
+
+			got, err := read(tt.resource)
+			if err != nil {
+				t.Fatalf("Read(%s) unexpected error: %v", tt.resource, err)
+			}
+			if got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", tt.resource, got, tt.want)
+			}
+		})
+	}
+
+	// Container writes must not be pushed to the host.
+	for path, want := range hostVals {
+		got, err := ios.NewIOnode("", path, 0).ReadFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("host %s = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestProcSysFs_FileNr(t *testing.T) {
+
+	// Host's state: container's pids cgroup (v2) holding two processes, with
+	// 3 and 2 open files respectively.
+	hostFiles := map[string]string{
+		"/proc/sys/fs/file-max":                 "9223372036854775807\n",
+		"/proc/1001/cgroup":                     "0::/sysbox/c1\n",
+		"/sys/fs/cgroup/sysbox/c1/cgroup.procs": "1001\n1002\n",
+		"/proc/1001/fd/0":                       "",
+		"/proc/1001/fd/1":                       "",
+		"/proc/1001/fd/2":                       "",
+		"/proc/1002/fd/0":                       "",
+		"/proc/1002/fd/1":                       "",
+	}
+	for path, val := range hostFiles {
+		if err := ios.NewIOnode("", path, 0644).WriteFile([]byte(val)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer ios.RemoveAllIOnodes()
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("IOService").Return(ios)
+	hs.On("IgnoreErrors").Return(false)
+
+	h := &implementations.ProcSysFs{
+		HandlerBase: domain.HandlerBase{
+			Name:           "ProcSysFs",
+			Path:           "/proc/sys/fs",
+			Service:        hs,
+			EmuResourceMap: implementations.ProcSysFs_Handler.EmuResourceMap,
+		},
+	}
+
+	cntr := css.ContainerCreate(
+		"c-file-nr",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	read := func(resource string) string {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 64),
+		}
+		n := ios.NewIOnode(resource, "/proc/sys/fs/"+resource, 0)
+		sz, err := h.Read(n, req)
+		if err != nil {
+			t.Fatalf("Read(%s) unexpected error: %v", resource, err)
+		}
+		return string(req.Data[:sz])
+	}
+
+	checkFileNr := func(wantMax string) {
+		got := read("file-nr")
+		if want := "5\t0\t" + wantMax + "\n"; got != want {
+			t.Errorf("Read(file-nr) = %q, want %q", got, want)
+		}
+
+		// The limit must match the container's file-max.
+		fields := strings.Split(strings.TrimSuffix(got, "\n"), "\t")
+		if fileMax := read("file-max"); len(fields) != 3 || fields[2]+"\n" != fileMax {
+			t.Errorf("Read(file-nr) limit %v doesn't match file-max %q", fields, fileMax)
+		}
+	}
+
+	// The host's file-max is displayed until a value is written.
+	checkFileNr("9223372036854775807")
+
+	req := &domain.HandlerRequest{
+		Pid:       1001,
+		Container: cntr,
+		Data:      []byte("1048576\n"),
+	}
+	n := ios.NewIOnode("file-max", "/proc/sys/fs/file-max", 0)
+	if _, err := h.Write(n, req); err != nil {
+		t.Fatalf("Write(file-max) unexpected error: %v", err)
+	}
+
+	checkFileNr("1048576")
+
+	// file-nr is read-only.
+	n = ios.NewIOnode("file-nr", "/proc/sys/fs/file-nr", 0)
+	n.SetOpenFlags(syscall.O_WRONLY)
+	if _, err := h.Open(n, &domain.HandlerRequest{Pid: 1001, Container: cntr}); err != (fuse.IOerror{Code: syscall.EACCES}) {
+		t.Errorf("Open(file-nr, O_WRONLY) error = %v, want EACCES", err)
+	}
+}
+
+func TestProcSysFs_Aio(t *testing.T) {
+
+	// Host's state: container's pids cgroup (v2) holding two processes, each
+	// with an aio ring mapped (of 255 and 127 requests respectively).
+	hostFiles := map[string]string{
+		"/proc/sys/fs/aio-max-nr":               "65536\n",
+		"/proc/sys/fs/aio-nr":                   "1024\n",
+		"/proc/1001/cgroup":                     "0::/sysbox/c1\n",
+		"/sys/fs/cgroup/sysbox/c1/cgroup.procs": "1001\n1002\n",
+		"/proc/1001/maps": "55d0a0000000-55d0a0021000 r-xp 00000000 08:01 1234 /usr/sbin/mysqld\n" +
+			"7f0000000000-7f0000002000 rw-s 00000000 00:0f 5678 /[aio] (deleted)\n" +
+			"7ffc00000000-7ffc00021000 rw-p 00000000 00:00 0 [stack]\n",
+		"/proc/1002/maps": "7f1000000000-7f1000001000 rw-s 00000000 00:0f 5679 /[aio] (deleted)\n",
+	}
+	for path, val := range hostFiles {
+		if err := ios.NewIOnode("", path, 0644).WriteFile([]byte(val)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer ios.RemoveAllIOnodes()
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("IOService").Return(ios)
+	hs.On("IgnoreErrors").Return(false)
+
+	h := &implementations.ProcSysFs{
+		HandlerBase: domain.HandlerBase{
+			Name:           "ProcSysFs",
+			Path:           "/proc/sys/fs",
+			Service:        hs,
+			EmuResourceMap: implementations.ProcSysFs_Handler.EmuResourceMap,
+		},
+	}
+
+	cntr := css.ContainerCreate(
+		"c-aio",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	read := func(resource string) string {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 64),
+		}
+		n := ios.NewIOnode(resource, "/proc/sys/fs/"+resource, 0)
+		sz, err := h.Read(n, req)
+		if err != nil {
+			t.Fatalf("Read(%s) unexpected error: %v", resource, err)
+		}
+		return string(req.Data[:sz])
+	}
+
+	readHost := func(resource string) string {
+		data, err := ios.NewIOnode("", "/proc/sys/fs/"+resource, 0).ReadFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// aio-max-nr: the host's value is displayed until the container sets its
+	// own, which is only pushed to the host when raising it.
+	if got := read("aio-max-nr"); got != "65536\n" {
+		t.Errorf("Read(aio-max-nr) = %q, want %q", got, "65536\n")
+	}
+
+	tests := []struct {
+		name     string
+		val      string
+		wantErr  error
+		want     string
+		wantHost string
+	}{
+		// Test-case 1: Raised limit (e.g., as per InnoDB's requirements).
+		{"1", "1048576\n", nil, "1048576\n", "1048576\n"},
+		// Test-case 2: Lowered limit; host is left untouched.
+		{"2", "4096\n", nil, "4096\n", "1048576\n"},
+		// Test-case 3: Zero limit.
+		{"3", "0\n", fuse.IOerror{Code: syscall.EINVAL}, "4096\n", "1048576\n"},
+		// Test-case 4: Negative limit.
+		{"4", "-1\n", fuse.IOerror{Code: syscall.EINVAL}, "4096\n", "1048576\n"},
+		// Test-case 5: Non-numeric limit.
+		{"5", "max\n", fuse.IOerror{Code: syscall.EINVAL}, "4096\n", "1048576\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      []byte(tt.val),
+			}
+			n := ios.NewIOnode("aio-max-nr", "/proc/sys/fs/aio-max-nr", 0)
+			if _, err := h.Write(n, req); err != tt.wantErr {
+				t.Fatalf("Write(aio-max-nr, %q) error = %v, want %v", tt.val, err, tt.wantErr)
+			}
+			if got := read("aio-max-nr"); got != tt.want {
+				t.Errorf("Read(aio-max-nr) = %q, want %q", got, tt.want)
+			}
+			if got := readHost("aio-max-nr"); got != tt.wantHost {
+				t.Errorf("host aio-max-nr = %q, want %q", got, tt.wantHost)
+			}
+		})
+	}
+
+	// aio-nr: requests fitting in the container's rings, capped by the host's
+	// value.
+	if got := read("aio-nr"); got != "382\n" {
+		t.Errorf("Read(aio-nr) = %q, want %q", got, "382\n")
+	}
+	if err := ios.NewIOnode("", "/proc/sys/fs/aio-nr", 0).WriteFile([]byte("100\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := read("aio-nr"); got != "100\n" {
+		t.Errorf("Read(aio-nr) = %q, want %q", got, "100\n")
+	}
+
+	// aio-nr is read-only.
+	n := ios.NewIOnode("aio-nr", "/proc/sys/fs/aio-nr", 0)
+	n.SetOpenFlags(syscall.O_WRONLY)
+	if _, err := h.Open(n, &domain.HandlerRequest{Pid: 1001, Container: cntr}); err != (fuse.IOerror{Code: syscall.EACCES}) {
+		t.Errorf("Open(aio-nr, O_WRONLY) error = %v, want EACCES", err)
+	}
+}
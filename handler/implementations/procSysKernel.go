@@ -17,8 +17,11 @@
 package implementations
 
 import (
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -45,6 +48,21 @@ import (
 // /proc/sys/kernel/cap_last_cap which is the most commonly accessed sysctl.
 //
 //
+// * /proc/sys/kernel/hostname
+// * /proc/sys/kernel/domainname
+//
+// Documentation: These files expose the node and domain names of the UTS
+// namespace of the process accessing them; that is, the values returned by
+// gethostname(2), getdomainname(2) and uname(2).
+//
+// These names can be modified through different paths (e.g., sethostname(2)
+// or writes to these files), so the container's UTS namespace is taken as the
+// only source of truth: accesses are always carried out within the container's
+// namespaces, and their data is never cached by sysbox-fs, as otherwise the
+// values displayed through these files could diverge from the ones obtained
+// through the syscalls above.
+//
+//
 // * /proc/sys/kernel/sysrq
 //
 // Documentation: It is a ‘magical’ key combo you can hit which the kernel will
@@ -74,6 +92,25 @@ import (
 // untouched.
 //
 //
+// * /proc/sys/kernel/randomize_va_space
+//
+// Documentation: Selects the type of process address space randomization
+// (ASLR) used in the system:
+//
+// 0 - no randomization
+//
+// 1 - randomize the positions of the stack, VDSO page and shared memory
+// regions (mmap base)
+//
+// 2 - same as 1, plus the heap (brk) randomization
+//
+// Reproducible-build and debugging workflows commonly disable it. As this is
+// a system-wide attribute, changes will be only made superficially (at
+// sys-container level): the host FS value will be left untouched, and keeps
+// being the one honored by the kernel (processes within the container can
+// still opt out through personality(ADDR_NO_RANDOMIZE), e.g., "setarch -R").
+//
+//
 // * /proc/sys/kernel/panic handler
 //
 // Documentation: The value in this file represents the number of seconds the
@@ -204,22 +241,187 @@ import (
 // pid_max.  On 64-bit systems, pid_max can be set to any value up to 2^22
 // (PID_MAX_LIMIT, approximately 4 million).
 //
+//
+// * /proc/sys/kernel/ns_last_pid
+//
+// Documentation: The last pid allocated within the pid namespace of the process
+// accessing this file. Writing to it (e.g., CRIU restoring a process tree)
+// makes the pid namespace allocate the pid following the written value next.
+//
+// This is namespaced via the pid namespace, so accesses are carried out within
+// the container's namespaces, and are never cached, as the value changes with
+// every process creation. Written values are checked to be positive and not
+// above the container's pid_max. Notice that the last field of /proc/loadavg
+// is already reported out of the reader's pid namespace by the kernel, so it
+// stays consistent with this node.
+//
+//
+// * /proc/sys/kernel/sched_rt_period_us
+// * /proc/sys/kernel/sched_rt_runtime_us
+// * /proc/sys/kernel/sched_rr_timeslice_ms
+// * /proc/sys/kernel/sched_latency_ns
+// * /proc/sys/kernel/sched_min_granularity_ns
+// * /proc/sys/kernel/sched_wakeup_granularity_ns
+// * /proc/sys/kernel/sched_migration_cost_ns
+// * /proc/sys/kernel/sched_autogroup_enabled
+//
+// Documentation: These are the scheduler tunables typically adjusted by
+// latency-sensitive workloads (e.g., RT-tuning scripts adjusting the
+// real-time bandwidth, or the CFS latency targets).
+//
+// Note: These are system-wide attributes that control the host's scheduler,
+// so changes will be only made superficially (at sys-container level). IOW,
+// the host FS value will be left untouched. Reads default to the host's values
+// until a value is written within the sys container. Written values are
+// checked against the ranges accepted by the kernel.
+//
+// Notice that some of these sysctls are not present in all kernels (e.g., CFS
+// tunables were moved to debugfs in 5.13), so they are only exposed within the
+// sys container when present in the host.
+//
+//
+// * /proc/sys/kernel/numa_balancing
+// * /proc/sys/kernel/numa_balancing_scan_delay_ms
+// * /proc/sys/kernel/numa_balancing_scan_period_min_ms
+// * /proc/sys/kernel/numa_balancing_scan_period_max_ms
+// * /proc/sys/kernel/numa_balancing_scan_size_mb
+//
+// Documentation: Automatic NUMA balancing toggle (0 or 1), and the pace at
+// which task's memory is scanned to detect misplaced pages (delay before the
+// first scan, min and max periods between scans, and the amount of memory
+// scanned each time).
+//
+// Same as the scheduler tunables above: these control the host's memory
+// placement, so writes are only honored at sys-container level, reads default
+// to the host's values, and the nodes are only exposed when present in the
+// host (i.e., kernels built with CONFIG_NUMA_BALANCING).
+//
+//
+// * /proc/sys/kernel/overflowuid
+// * /proc/sys/kernel/overflowgid
+//
+// Documentation: The uid and gid displayed for ids that can't be represented
+// in the caller's context, such as the ids of files owned by users/groups not
+// mapped into the caller's user-ns (65534, "nobody" and "nogroup", by
+// default).
+//
+// These are system-wide, and it's the host's values the kernel displays
+// within the sys container for ids out of its uid/gid maps, so reads default
+// to them: unmapped files then show up as owned by the ids these nodes report
+// (i.e., the container's "nobody:nogroup" under the usual 64K-id maps), rather
+// than by ids that can't be told apart from the container's ones. Writes are
+// only honored at sys-container level, as the host's values apply to every
+// user-ns, and are checked against the range accepted by the kernel.
+//
+//
+// * /proc/sys/kernel/shmall
+// * /proc/sys/kernel/shmmax
+// * /proc/sys/kernel/shmmni
+//
+// Documentation: System-wide limits of the SysV shared-memory segments (total
+// pages, max segment size in bytes, and max number of segments respectively).
+//
+// These are namespaced via the IPC namespace, so accesses are carried out
+// within the container's namespaces (all but the user-ns, as the kernel only
+// allows true root to modify them).
+//
+// The shmmax value exposed within the sys container is bounded by the memory
+// limit of the container's cgroup, as segments larger than that could never
+// be honored: reads of larger values display the memory limit, and writes of
+// larger values succeed but are clamped to the memory limit.
+//
+// Written shmmni values are checked against the kernel's range before reaching
+// the container's IPC namespace (the kernel further bounds them to 32768,
+// unless booted with ipcmni_extend).
+//
+//
+// * /proc/sys/kernel/shm_rmid_forced
+//
+// Documentation: When set to 1, all SysV shared-memory segments are marked for
+// destruction as soon as their attach count drops to zero (i.e., segments are
+// not left behind by exiting processes). Only 0 and 1 are accepted.
+//
+// Same as shmmni above, this is namespaced via the IPC namespace, so the
+// value is local to the container and is applied within its IPC namespace.
+//
+//
+// * /proc/sys/kernel/msgmax
+// * /proc/sys/kernel/msgmnb
+// * /proc/sys/kernel/msgmni
+//
+// Documentation: System-wide limits of the SysV message queues (max message
+// size in bytes, default max queue size in bytes, and max number of queues
+// respectively).
+//
+// As with the shm* nodes above, these are namespaced via the IPC namespace, so
+// reads and writes are carried out within the container's namespaces (all but
+// the user-ns). Written values are checked against the range accepted by the
+// kernel (non-negative ints) before reaching the container's IPC namespace.
+//
 
 const (
 	minSysrqVal = 0
 	maxSysrqVal = 511
 
+	minRandomizeVaSpaceVal = 0
+	maxRandomizeVaSpaceVal = 2
+
 	minKptrRestrictVal = 0
 	maxKptrRestrictVal = 3
 
 	minDmesgRestrictVal = 0
 	maxDmesgRestrictVal = 1
 
+	minPanicVal = math.MinInt32
+	maxPanicVal = math.MaxInt32
+
 	minPanicOopsVal = 0
 	maxPanicOopsVal = 1
 
 	minPidMaxVal = 1
 	maxPidMaxVal = 4194304
+
+	minSchedRtPeriodVal = 1
+	maxSchedRtPeriodVal = math.MaxInt32
+
+	minSchedRtRuntimeVal = -1
+	maxSchedRtRuntimeVal = math.MaxInt32
+
+	minSchedRrTimesliceVal = math.MinInt32
+	maxSchedRrTimesliceVal = math.MaxInt32
+
+	minSchedGranularityVal = 100000     // 100us
+	maxSchedGranularityVal = 1000000000 // 1s
+
+	minSchedWakeupGranularityVal = 0
+	maxSchedWakeupGranularityVal = 1000000000 // 1s
+
+	minSchedMigrationCostVal = 0
+	maxSchedMigrationCostVal = math.MaxUint32
+
+	minSchedAutogroupVal = 0
+	maxSchedAutogroupVal = 1
+
+	minShmmniVal = 0
+	maxShmmniVal = 1 << 24 // IPCMNI_EXTEND
+
+	minShmRmidForcedVal = 0
+	maxShmRmidForcedVal = 1
+
+	minMsgVal = 0
+	maxMsgVal = math.MaxInt32
+
+	minNumaBalancingVal = 0
+	maxNumaBalancingVal = 1
+
+	minNumaScanVal = 0
+	maxNumaScanVal = math.MaxInt32
+
+	minNumaScanSizeVal = 1
+	maxNumaScanSizeVal = math.MaxInt32
+
+	minOverflowIdVal = 0
+	maxOverflowIdVal = 65535
 )
 
 type ProcSysKernel struct {
@@ -292,12 +494,114 @@ var ProcSysKernel_Handler = &ProcSysKernel{
 				Enabled: true,
 				Size:    1024,
 			},
+			"randomize_va_space": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    2,
+			},
 			"pid_max": {
 				Kind:    domain.FileEmuResource,
 				Mode:    os.FileMode(uint32(0644)),
 				Enabled: true,
 				Size:    1024,
 			},
+			"ns_last_pid": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0666)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"sched_rt_period_us": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"sched_rt_runtime_us": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"sched_rr_timeslice_ms": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"sched_latency_ns": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"sched_min_granularity_ns": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"sched_wakeup_granularity_ns": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"sched_migration_cost_ns": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"sched_autogroup_enabled": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"numa_balancing": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"numa_balancing_scan_delay_ms": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"numa_balancing_scan_period_min_ms": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"numa_balancing_scan_period_max_ms": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"numa_balancing_scan_size_mb": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"overflowuid": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"overflowgid": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
 		},
 	},
 }
@@ -314,6 +618,14 @@ func (h *ProcSysKernel) Lookup(
 	// Return an artificial fileInfo if looked-up element matches any of the
 	// emulated nodes.
 	if v, ok := h.EmuResourceMap[resource]; ok {
+		// Scheduler and NUMA tunables are only exposed if present in the
+		// host's kernel.
+		if isSchedSysctl(resource) || isNumaSysctl(resource) {
+			if _, err := n.Stat(); err != nil {
+				return nil, fuse.IOerror{Code: syscall.ENOENT}
+			}
+		}
+
 		info := &domain.FileInfo{
 			Fname:    resource,
 			Fmode:    v.Mode,
@@ -351,6 +663,9 @@ func (h *ProcSysKernel) Open(
 	case "pid_max":
 		return false, nil
 
+	case "ns_last_pid":
+		return h.Service.GetPassThroughHandler().Open(n, req)
+
 	case "ngroups_max":
 		if flags&syscall.O_WRONLY == syscall.O_WRONLY ||
 			flags&syscall.O_RDWR == syscall.O_RDWR {
@@ -379,14 +694,42 @@ func (h *ProcSysKernel) Open(
 	case "sysrq":
 		return false, nil
 
+	case "randomize_va_space":
+		return false, nil
+
 	case "printk":
 		return false, nil
 
+	case "sched_rt_period_us",
+		"sched_rt_runtime_us",
+		"sched_rr_timeslice_ms",
+		"sched_latency_ns",
+		"sched_min_granularity_ns",
+		"sched_wakeup_granularity_ns",
+		"sched_migration_cost_ns",
+		"sched_autogroup_enabled":
+		return false, nil
+
+	case "numa_balancing",
+		"numa_balancing_scan_delay_ms",
+		"numa_balancing_scan_period_min_ms",
+		"numa_balancing_scan_period_max_ms",
+		"numa_balancing_scan_size_mb":
+		return false, nil
+
+	case "overflowuid", "overflowgid":
+		return false, nil
+
 	case "shmall":
 		fallthrough
 	case "shmmax":
 		fallthrough
 	case "shmmni":
+		fallthrough
+	case "shm_rmid_forced":
+		return h.Service.GetPassThroughHandler().OpenWithNS(n, req, domain.AllNSsButUser)
+
+	case "msgmax", "msgmnb", "msgmni":
 		return h.Service.GetPassThroughHandler().OpenWithNS(n, req, domain.AllNSsButUser)
 	}
 
@@ -410,14 +753,16 @@ func (h *ProcSysKernel) Read(
 	case "pid_max":
 		return readCntrData(h, n, req)
 
+	case "ns_last_pid":
+		req.NoCache = true
+		return h.Service.GetPassThroughHandler().Read(n, req)
+
 	case "ngroups_max":
 		return readCntrData(h, n, req)
 
-	case "domainname":
-		return readCntrData(h, n, req)
-
-	case "hostname":
-		return readCntrData(h, n, req)
+	case "domainname", "hostname":
+		req.NoCache = true
+		return h.Service.GetPassThroughHandler().Read(n, req)
 
 	case "kptr_restrict":
 		return readCntrData(h, n, req)
@@ -434,14 +779,43 @@ func (h *ProcSysKernel) Read(
 	case "sysrq":
 		return readCntrData(h, n, req)
 
+	case "randomize_va_space":
+		return readCntrData(h, n, req)
+
 	case "printk":
 		return readCntrData(h, n, req)
 
-	case "shmall":
-		fallthrough
+	case "sched_rt_period_us",
+		"sched_rt_runtime_us",
+		"sched_rr_timeslice_ms",
+		"sched_latency_ns",
+		"sched_min_granularity_ns",
+		"sched_wakeup_granularity_ns",
+		"sched_migration_cost_ns",
+		"sched_autogroup_enabled":
+		return readCntrData(h, n, req)
+
+	case "numa_balancing",
+		"numa_balancing_scan_delay_ms",
+		"numa_balancing_scan_period_min_ms",
+		"numa_balancing_scan_period_max_ms",
+		"numa_balancing_scan_size_mb":
+		return readCntrData(h, n, req)
+
+	case "overflowuid", "overflowgid":
+		return readCntrData(h, n, req)
+
 	case "shmmax":
+		return h.readShmmax(n, req)
+
+	case "shmall":
 		fallthrough
 	case "shmmni":
+		fallthrough
+	case "shm_rmid_forced":
+		return h.Service.GetPassThroughHandler().ReadWithNS(n, req, domain.AllNSsButUser)
+
+	case "msgmax", "msgmnb", "msgmni":
 		return h.Service.GetPassThroughHandler().ReadWithNS(n, req, domain.AllNSsButUser)
 	}
 
@@ -471,7 +845,22 @@ func (h *ProcSysKernel) Write(
 		}
 		return writeCntrData(h, n, req, nil)
 
+	case "ns_last_pid":
+		pidMax, err := h.cntrPidMax(req)
+		if err != nil {
+			return 0, err
+		}
+		if !checkIntRange(req.Data, 1, pidMax) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		req.NoCache = true
+		return h.Service.GetPassThroughHandler().Write(n, req)
+
 	case "panic":
+		// Negative values (i.e., immediate reboot) are valid too.
+		if !checkIntRange(req.Data, minPanicVal, maxPanicVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
 		return writeCntrData(h, n, req, nil)
 
 	case "printk":
@@ -479,8 +868,11 @@ func (h *ProcSysKernel) Write(
 
 	case "panic_on_oops":
 		// Even though only values 0 and 1 are defined for panic_on_oops, the
-		// kernel allows other values to be written; thus no range check is
-		// performed here.
+		// kernel allows any other integer to be written; thus no range check
+		// is performed here.
+		if !checkIntRange(req.Data, math.MinInt32, math.MaxInt32) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
 		return writeCntrData(h, n, req, nil)
 
 	case "kptr_restrict":
@@ -501,29 +893,236 @@ func (h *ProcSysKernel) Write(
 		}
 		return writeCntrData(h, n, req, nil)
 
-	case "domainname":
+	case "randomize_va_space":
+		if !checkIntRange(req.Data, minRandomizeVaSpaceVal, maxRandomizeVaSpaceVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
 		return writeCntrData(h, n, req, nil)
 
-	case "hostname":
+	case "sched_rt_period_us":
+		if !checkIntRange(req.Data, minSchedRtPeriodVal, maxSchedRtPeriodVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
 		return writeCntrData(h, n, req, nil)
 
-	case "shmall":
-		fallthrough
+	case "sched_rt_runtime_us":
+		if !checkIntRange(req.Data, minSchedRtRuntimeVal, maxSchedRtRuntimeVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		return writeCntrData(h, n, req, nil)
+
+	case "sched_rr_timeslice_ms":
+		if !checkIntRange(req.Data, minSchedRrTimesliceVal, maxSchedRrTimesliceVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		return writeCntrData(h, n, req, nil)
+
+	case "sched_latency_ns", "sched_min_granularity_ns":
+		if !checkIntRange(req.Data, minSchedGranularityVal, maxSchedGranularityVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		return writeCntrData(h, n, req, nil)
+
+	case "sched_wakeup_granularity_ns":
+		if !checkIntRange(req.Data, minSchedWakeupGranularityVal, maxSchedWakeupGranularityVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		return writeCntrData(h, n, req, nil)
+
+	case "sched_migration_cost_ns":
+		if !checkIntRange(req.Data, minSchedMigrationCostVal, maxSchedMigrationCostVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		return writeCntrData(h, n, req, nil)
+
+	case "sched_autogroup_enabled":
+		if !checkIntRange(req.Data, minSchedAutogroupVal, maxSchedAutogroupVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		return writeCntrData(h, n, req, nil)
+
+	case "numa_balancing":
+		if !checkIntRange(req.Data, minNumaBalancingVal, maxNumaBalancingVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		return writeCntrData(h, n, req, nil)
+
+	case "numa_balancing_scan_delay_ms",
+		"numa_balancing_scan_period_min_ms",
+		"numa_balancing_scan_period_max_ms":
+		if !checkIntRange(req.Data, minNumaScanVal, maxNumaScanVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		return writeCntrData(h, n, req, nil)
+
+	case "numa_balancing_scan_size_mb":
+		if !checkIntRange(req.Data, minNumaScanSizeVal, maxNumaScanSizeVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		return writeCntrData(h, n, req, nil)
+
+	case "overflowuid", "overflowgid":
+		if !checkIntRange(req.Data, minOverflowIdVal, maxOverflowIdVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		return writeCntrData(h, n, req, nil)
+
+	case "domainname", "hostname":
+		req.NoCache = true
+		return h.Service.GetPassThroughHandler().Write(n, req)
+
 	case "shmmax":
-		fallthrough
-	case "shmmni":
+		return h.writeShmmax(n, req)
+
+	case "shmall":
 		// The kernel only allows true root to write to /proc/sys/kernel/shm*.
 		// Root in the container's user-namespaces is not allowed to modify these
 		// values, even though they are namespaced via the IPC namespace.
 		// Therefore ask the passhthrough handler to enter all namespaces except
 		// the user-ns, as otherwise we get permission denied.
 		return h.Service.GetPassThroughHandler().WriteWithNS(n, req, domain.AllNSsButUser)
+
+	case "shmmni":
+		if !checkIntRange(req.Data, minShmmniVal, maxShmmniVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		return h.Service.GetPassThroughHandler().WriteWithNS(n, req, domain.AllNSsButUser)
+
+	case "shm_rmid_forced":
+		if !checkIntRange(req.Data, minShmRmidForcedVal, maxShmRmidForcedVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		return h.Service.GetPassThroughHandler().WriteWithNS(n, req, domain.AllNSsButUser)
+
+	case "msgmax", "msgmnb", "msgmni":
+		if !checkIntRange(req.Data, minMsgVal, maxMsgVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		// Same as shm* above; only true root can write to these.
+		return h.Service.GetPassThroughHandler().WriteWithNS(n, req, domain.AllNSsButUser)
 	}
 
 	// Refer to generic handler if no node match is found above.
 	return h.Service.GetPassThroughHandler().Write(n, req)
 }
 
+func (h *ProcSysKernel) readShmmax(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	// Fetch the whole value, regardless of the offset being requested, as it
+	// may need to be rendered differently.
+	shmReq := *req
+	shmReq.Offset = 0
+	shmReq.Data = make([]byte, 32)
+
+	sz, err := h.Service.GetPassThroughHandler().ReadWithNS(n, &shmReq, domain.AllNSsButUser)
+	if err != nil {
+		return 0, err
+	}
+	data := shmReq.Data[:sz]
+
+	val, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return readWindow(req, data)
+	}
+
+	if limit := h.cntrMemLimit(req.Container); limit != 0 && val > limit {
+		data = []byte(strconv.FormatUint(limit, 10) + "\n")
+	}
+
+	return readWindow(req, data)
+}
+
+func (h *ProcSysKernel) writeShmmax(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	passThrough := h.Service.GetPassThroughHandler()
+
+	// Non-numeric values are left for the kernel to reject.
+	val, err := strconv.ParseUint(strings.TrimSpace(string(req.Data)), 10, 64)
+	if err != nil {
+		return passThrough.WriteWithNS(n, req, domain.AllNSsButUser)
+	}
+
+	limit := h.cntrMemLimit(req.Container)
+	if limit == 0 || val <= limit {
+		return passThrough.WriteWithNS(n, req, domain.AllNSsButUser)
+	}
+
+	logrus.Infof("Clamping shmmax of container %s to its memory limit (%d -> %d)",
+		req.Container.ID(), val, limit)
+
+	// The write is reported as fully carried out to the caller.
+	sz := len(req.Data)
+	req.Data = []byte(strconv.FormatUint(limit, 10) + "\n")
+
+	if _, err := passThrough.WriteWithNS(n, req, domain.AllNSsButUser); err != nil {
+		return 0, err
+	}
+
+	return sz, nil
+}
+
+// cntrPidMax returns the pid_max value displayed within the container.
+func (h *ProcSysKernel) cntrPidMax(req *domain.HandlerRequest) (int, error) {
+
+	n := h.Service.IOService().NewIOnode("pid_max", filepath.Join(h.Path, "pid_max"), 0)
+
+	pidReq := *req
+	pidReq.Offset = 0
+	pidReq.Data = make([]byte, 32)
+
+	sz, err := readCntrData(h, n, &pidReq)
+	if err != nil {
+		return 0, err
+	}
+
+	pidMax, err := strconv.Atoi(strings.TrimSpace(string(pidReq.Data[:sz])))
+	if err != nil {
+		return 0, fuse.IOerror{Code: syscall.EINVAL}
+	}
+
+	return pidMax, nil
+}
+
+// cntrMemLimit returns the memory limit (in bytes) of the container's memory
+// cgroup, or zero if the container's memory is not limited (or its limit can't
+// be determined).
+func (h *ProcSysKernel) cntrMemLimit(cntr domain.ContainerIface) uint64 {
+
+	ios := h.Service.IOService()
+
+	cgPath, err := cgroupFilePath(ios, cntr.InitPid(), "memory", "memory.max", "memory.limit_in_bytes")
+	if err != nil {
+		logrus.Debugf("Unable to find memory cgroup of container %s: %v",
+			cntr.ID(), err)
+		return 0
+	}
+
+	data, err := ios.NewIOnode("", cgPath, 0).ReadFile()
+	if err != nil {
+		logrus.Debugf("Unable to read memory limit of container %s: %v",
+			cntr.ID(), err)
+		return 0
+	}
+
+	// Unlimited memory is reported as "max" in cgroup v2.
+	limit, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	// Unlimited memory is reported as the page-aligned max int64 in cgroup v1.
+	pageSize := uint64(os.Getpagesize())
+	if limit >= math.MaxInt64/pageSize*pageSize {
+		return 0
+	}
+
+	return limit
+}
+
 func (h *ProcSysKernel) ReadDirAll(
 	n domain.IOnodeIface,
 	req *domain.HandlerRequest) ([]os.FileInfo, error) {
@@ -595,3 +1194,15 @@ func (h *ProcSysKernel) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex {
 func (h *ProcSysKernel) SetService(hs domain.HandlerServiceIface) {
 	h.Service = hs
 }
+
+// isSchedSysctl returns true if the given resource is one of the emulated
+// scheduler tunables.
+func isSchedSysctl(resource string) bool {
+	return strings.HasPrefix(resource, "sched_")
+}
+
+// isNumaSysctl returns true if the given resource is one of the emulated NUMA
+// balancing tunables.
+func isNumaSysctl(resource string) bool {
+	return strings.HasPrefix(resource, "numa_balancing")
+}
@@ -0,0 +1,198 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+)
+
+//
+// /proc/sys/kernel/keys handler
+//
+// Handled resources:
+//
+// * /proc/sys/kernel/keys/maxkeys
+// * /proc/sys/kernel/keys/maxbytes
+// * /proc/sys/kernel/keys/root_maxkeys
+// * /proc/sys/kernel/keys/root_maxbytes
+//
+// Documentation: Quotas on the number of keys, and on the bytes of payload,
+// each user can own in the kernel's keyrings (the root_* ones apply to root
+// within the initial user-ns). Software relying on the keyring (e.g., sssd,
+// kerberos) raises them to avoid EDQUOT failures.
+//
+// Accesses are routed into the container's namespaces, so kernels scoping
+// these quotas per user-ns honor the container's writes. Otherwise, the kernel
+// rejects the writes, and the new values are kept at container level (i.e.,
+// they're never pushed to the host); reads default to the host's values until
+// then. Written values must be positive integers, as the kernel requires.
+//
+// The remaining nodes in this subtree (e.g., gc_delay) are handled the same
+// way, with no validation on sysbox-fs' side.
+//
+
+var procSysKernelKeysLimits = map[string]bool{
+	"maxkeys":       true,
+	"maxbytes":      true,
+	"root_maxkeys":  true,
+	"root_maxbytes": true,
+}
+
+const (
+	minKeysLimitVal = 1
+	maxKeysLimitVal = math.MaxInt32
+)
+
+type ProcSysKernelKeys struct {
+	domain.HandlerBase
+}
+
+var ProcSysKernelKeys_Handler = &ProcSysKernelKeys{
+	domain.HandlerBase{
+		Name:           "ProcSysKernelKeys",
+		Path:           "/proc/sys/kernel/keys",
+		Enabled:        true,
+		EmuResourceMap: map[string]*domain.EmuResource{},
+	},
+}
+
+func (h *ProcSysKernelKeys) Lookup(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (os.FileInfo, error) {
+
+	logrus.Debugf("Executing Lookup() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return h.Service.GetPassThroughHandler().Lookup(n, req)
+}
+
+func (h *ProcSysKernelKeys) Open(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (bool, error) {
+
+	logrus.Debugf("Executing Open() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return openNsSysctl(h, n, req)
+}
+
+func (h *ProcSysKernelKeys) Read(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return h.Service.GetPassThroughHandler().Read(n, req)
+}
+
+func (h *ProcSysKernelKeys) Write(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	var resource = n.Name()
+
+	logrus.Debugf("Executing Write() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, resource)
+
+	if procSysKernelKeysLimits[resource] &&
+		!checkIntRange(req.Data, minKeysLimitVal, maxKeysLimitVal) {
+		return 0, fuse.IOerror{Code: syscall.EINVAL}
+	}
+
+	return writeNsSysctl(h, n, req)
+}
+
+func (h *ProcSysKernelKeys) ReadDirAll(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]os.FileInfo, error) {
+
+	logrus.Debugf("Executing ReadDirAll() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return h.Service.GetPassThroughHandler().ReadDirAll(n, req)
+}
+
+func (h *ProcSysKernelKeys) ReadLink(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (string, error) {
+
+	logrus.Debugf("Executing ReadLink() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return h.Service.GetPassThroughHandler().ReadLink(n, req)
+}
+
+func (h *ProcSysKernelKeys) GetName() string {
+	return h.Name
+}
+
+func (h *ProcSysKernelKeys) GetPath() string {
+	return h.Path
+}
+
+func (h *ProcSysKernelKeys) GetService() domain.HandlerServiceIface {
+	return h.Service
+}
+
+func (h *ProcSysKernelKeys) GetEnabled() bool {
+	return h.Enabled
+}
+
+func (h *ProcSysKernelKeys) SetEnabled(b bool) {
+	h.Enabled = b
+}
+
+func (h *ProcSysKernelKeys) GetResourcesList() []string {
+
+	var resources []string
+
+	for resourceKey, resource := range h.EmuResourceMap {
+		resource.Mutex.Lock()
+		if !resource.Enabled {
+			resource.Mutex.Unlock()
+			continue
+		}
+		resource.Mutex.Unlock()
+
+		resources = append(resources, filepath.Join(h.GetPath(), resourceKey))
+	}
+
+	return resources
+}
+
+func (h *ProcSysKernelKeys) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex {
+	resource, ok := h.EmuResourceMap[n.Name()]
+	if !ok {
+		return nil
+	}
+
+	return &resource.Mutex
+}
+
+func (h *ProcSysKernelKeys) SetService(hs domain.HandlerServiceIface) {
+	h.Service = hs
+}
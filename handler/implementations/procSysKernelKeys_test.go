@@ -0,0 +1,144 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+func TestProcSysKernelKeys(t *testing.T) {
+
+	const (
+		maxkeys  = "/proc/sys/kernel/keys/maxkeys"
+		maxbytes = "/proc/sys/kernel/keys/maxbytes"
+	)
+
+	hostVals := map[string]string{
+		maxkeys:  "200\n",
+		maxbytes: "20000\n",
+	}
+
+	einval := fuse.IOerror{Code: syscall.EINVAL}
+
+	tests := []struct {
+		name       string
+		path       string
+		namespaced bool     // whether the kernel scopes the quota per user-ns
+		vals       []string // values written in sequence
+		wantErr    error    // on the last write
+		want       string   // value read back within the container
+	}{
+		// Test-case 1: No writes; host's values are displayed.
+		{"1", maxkeys, false, nil, nil, "200\n"},
+		{"2", maxbytes, false, nil, nil, "20000\n"},
+		// Test-case 3: Global quotas; kept at container level.
+		{"3", maxkeys, false, []string{"2000\n"}, nil, "2000\n"},
+		{"4", maxbytes, false, []string{"25000000\n", "50000000\n"}, nil, "50000000\n"},
+		// Test-case 5: Per user-ns quotas; applied within the container's
+		// namespaces.
+		{"5", maxkeys, true, []string{"2000\n"}, nil, "2000\n"},
+		{"6", maxbytes, true, []string{"25000000\n"}, nil, "25000000\n"},
+		// Test-case 7: Invalid values; previous value is kept.
+		{"7", maxkeys, false, []string{"2000\n", "0\n"}, einval, "2000\n"},
+		{"8", maxkeys, true, []string{"-1\n"}, einval, "200\n"},
+		{"9", maxbytes, false, []string{"2147483648\n"}, einval, "20000\n"},
+		{"10", maxbytes, false, []string{"20M\n"}, einval, "20000\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := map[string]string{}
+			for path, val := range hostVals {
+				nodes[path] = val
+			}
+			pt := &sysctlPassThrough{
+				nodes:      nodes,
+				namespaced: map[string]bool{tt.path: tt.namespaced},
+			}
+
+			hs := &mocks.HandlerServiceIface{}
+			hs.On("GetPassThroughHandler").Return(pt)
+			hs.On("IgnoreErrors").Return(false)
+
+			h := implementations.ProcSysKernelKeys_Handler
+			h.SetService(hs)
+
+			cntr := css.ContainerCreate(
+				"c-keys-"+tt.name,
+				uint32(1001),
+				time.Time{},
+				231072,
+				65535,
+				231072,
+				65535,
+				nil,
+				nil,
+				nil,
+			)
+
+			n := ios.NewIOnode(filepath.Base(tt.path), tt.path, 0)
+			n.SetOpenFlags(int(os.O_WRONLY))
+
+			for i, val := range tt.vals {
+				req := &domain.HandlerRequest{
+					Pid:       1001,
+					Container: cntr,
+					Data:      []byte(val),
+				}
+				if _, err := h.Open(n, req); err != nil {
+					t.Fatalf("Open(%s) unexpected error: %v", tt.path, err)
+				}
+				_, err := h.Write(n, req)
+				if i == len(tt.vals)-1 {
+					if err != tt.wantErr {
+						t.Fatalf("Write(%s, %q) error = %v, want %v", tt.path, val, err, tt.wantErr)
+					}
+				} else if err != nil {
+					t.Fatalf("Write(%s, %q) unexpected error: %v", tt.path, val, err)
+				}
+			}
+
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      make([]byte, 16),
+			}
+			sz, err := h.Read(n, req)
+			if err != nil {
+				t.Fatalf("Read(%s) unexpected error: %v", tt.path, err)
+			}
+			if got := string(req.Data[:sz]); got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", tt.path, got, tt.want)
+			}
+
+			// Host's value must be left untouched unless the quota is scoped
+			// per user-ns.
+			if !tt.namespaced && pt.nodes[tt.path] != hostVals[tt.path] {
+				t.Errorf("host %s = %q, want %q", tt.path, pt.nodes[tt.path], hostVals[tt.path])
+			}
+		})
+	}
+}
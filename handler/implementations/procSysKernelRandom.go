@@ -17,6 +17,8 @@
 package implementations
 
 import (
+	"crypto/rand"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -42,6 +44,20 @@ import (
 // unknown reason the kernel returns the same value when this is read from
 // inside a Sysbox container.
 //
+//
+// * /proc/sys/kernel/random/boot_id
+//
+// Documentation: a UUID identifying the current boot, generated once per boot
+// by the kernel. systemd (among others) ties its per-boot state (e.g., the
+// journal) to this value, and cross-checks it against the machine-id.
+//
+// The host's boot id is of no use within the sys container: it doesn't change
+// across container restarts, and it's shared by all the containers. Instead,
+// every container is presented with its own boot id, randomly generated on
+// first access. The value is held in the container's state, so it remains the same for the container's lifetime
+// regardless of the procfs mounts it's accessed through (e.g., /proc remounts
+// carried out by inner containers' init) or of the process accessing it.
+//
 
 type ProcSysKernelRandom struct {
 	domain.HandlerBase
@@ -59,6 +75,12 @@ var ProcSysKernelRandom_Handler = &ProcSysKernelRandom{
 				Enabled: true,
 				Size:    1024,
 			},
+			"boot_id": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0444)),
+				Enabled: true,
+				Size:    1024,
+			},
 		},
 	},
 }
@@ -117,6 +139,9 @@ func (h *ProcSysKernelRandom) Read(
 			return 0, nil
 		}
 		return sz, nil
+
+	case "boot_id":
+		return h.readBootId(n, req)
 	}
 
 	// Refer to generic handler if no node match is found above.
@@ -133,8 +158,8 @@ func (h *ProcSysKernelRandom) Write(
 		req.ID, h.Name, resource)
 
 	switch resource {
-	case "uuid":
-		// uuid is read-only
+	case "uuid", "boot_id":
+		// uuid and boot_id are read-only
 		return 0, fuse.IOerror{Code: syscall.EPERM}
 	}
 
@@ -213,3 +238,53 @@ func (h *ProcSysKernelRandom) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex
 func (h *ProcSysKernelRandom) SetService(hs domain.HandlerServiceIface) {
 	h.Service = hs
 }
+
+func (h *ProcSysKernelRandom) readBootId(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	path := n.Path()
+	cntr := req.Container
+
+	cntr.Lock()
+	defer cntr.Unlock()
+
+	// Check if the boot id has been already set for this container (the buffer
+	// fits the uuid string along with its trailing newline).
+	data := make([]byte, 64)
+	sz, err := cntr.Data(path, 0, &data)
+	if err != nil && err != io.EOF {
+		return 0, fuse.IOerror{Code: syscall.EINVAL}
+	}
+
+	if sz == 0 {
+		bootId, err := newBootId()
+		if err != nil {
+			logrus.Errorf("Unable to generate boot id for container %s: %v",
+				cntr.ID(), err)
+			return 0, fuse.IOerror{Code: syscall.EIO}
+		}
+
+		data = []byte(bootId + "\n")
+		err = cntr.SetData(path, 0, data)
+		if err != nil {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+	}
+
+	return readWindow(req, data)
+}
+
+// newBootId returns a random (version 4, variant 1) UUID, as generated by the
+// kernel.
+func newBootId() (string, error) {
+
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,105 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"regexp"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+func TestProcSysKernelRandom_BootId(t *testing.T) {
+
+	const bootIdPath = "/proc/sys/kernel/random/boot_id"
+
+	uuidV4 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}\n$`)
+
+	hs := &mocks.HandlerServiceIface{}
+
+	h := &implementations.ProcSysKernelRandom{
+		domain.HandlerBase{
+			Name:    "ProcSysKernelRandom",
+			Path:    "/proc/sys/kernel/random",
+			Service: hs,
+		},
+	}
+
+	newCntr := func(id string) domain.ContainerIface {
+		return css.ContainerCreate(
+			id,
+			uint32(1001),
+			time.Time{},
+			231072,
+			65535,
+			231072,
+			65535,
+			nil,
+			nil,
+			nil,
+		)
+	}
+
+	// Every read goes through a freshly created node, just as after a /proc
+	// remount within the container (the new procfs' sysbox-fs submounts are
+	// backed by the container's same sysbox-fs instance).
+	read := func(cntr domain.ContainerIface, pid uint32) string {
+		req := &domain.HandlerRequest{
+			Pid:       pid,
+			Container: cntr,
+			Data:      make([]byte, 4096),
+		}
+		n := ios.NewIOnode("boot_id", bootIdPath, 0)
+		sz, err := h.Read(n, req)
+		if err != nil {
+			t.Fatalf("Read(%s) unexpected error: %v", bootIdPath, err)
+		}
+		return string(req.Data[:sz])
+	}
+
+	// Generated boot ids are stable across remounts and exec sessions.
+	c1 := newCntr("c1")
+	bootId := read(c1, 1001)
+	if !uuidV4.MatchString(bootId) {
+		t.Fatalf("boot_id = %q, want a random uuid", bootId)
+	}
+	for _, pid := range []uint32{1001, 1002, 2001} {
+		if got := read(c1, pid); got != bootId {
+			t.Errorf("boot_id read by pid %d = %q, want %q", pid, got, bootId)
+		}
+	}
+
+	// Every container boots on its own.
+	if got := read(newCntr("c2"), 1001); got == bootId || !uuidV4.MatchString(got) {
+		t.Errorf("boot_id of c2 = %q, want a random uuid other than %q", got, bootId)
+	}
+
+	// boot_id is read-only.
+	req := &domain.HandlerRequest{Pid: 1001, Container: c1, Data: []byte("x\n")}
+	n := ios.NewIOnode("boot_id", bootIdPath, 0)
+	if _, err := h.Write(n, req); err != (fuse.IOerror{Code: syscall.EPERM}) {
+		t.Errorf("Write(%s) error = %v, want EPERM", bootIdPath, err)
+	}
+	if got := read(c1, 1001); got != bootId {
+		t.Errorf("boot_id after write = %q, want %q", got, bootId)
+	}
+}
@@ -0,0 +1,1403 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+func TestProcSysKernel_Sched(t *testing.T) {
+
+	// Host's scheduler tunables; sched_latency_ns is purposely missing (i.e.,
+	// 5.13+ kernel).
+	hostVals := map[string]string{
+		"/proc/sys/kernel/sched_rt_runtime_us":     "950000\n",
+		"/proc/sys/kernel/sched_rt_period_us":      "1000000\n",
+		"/proc/sys/kernel/sched_autogroup_enabled": "1\n",
+	}
+	for path, val := range hostVals {
+		if err := ios.NewIOnode("", path, 0644).WriteFile([]byte(val)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer ios.RemoveAllIOnodes()
+
+	defer hds.On("IgnoreErrors").Return(false).Unset()
+
+	h := implementations.ProcSysKernel_Handler
+	h.SetService(hds)
+
+	cntr := css.ContainerCreate(
+		"c1",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	read := func(resource string) (string, error) {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 64),
+		}
+		n := ios.NewIOnode(resource, "/proc/sys/kernel/"+resource, 0)
+		sz, err := h.Read(n, req)
+		if err != nil {
+			return "", err
+		}
+		return string(req.Data[:sz]), nil
+	}
+
+	write := func(resource, val string) error {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      []byte(val),
+		}
+		n := ios.NewIOnode(resource, "/proc/sys/kernel/"+resource, 0)
+		_, err := h.Write(n, req)
+		return err
+	}
+
+	tests := []struct {
+		name     string
+		resource string
+		val      string
+		wantErr  error
+		want     string
+	}{
+		// Test-case 1: Valid rt-runtime value.
+		{"1", "sched_rt_runtime_us", "500000\n", nil, "500000\n"},
+		// Test-case 2: Unlimited rt-runtime.
+		{"2", "sched_rt_runtime_us", "-1\n", nil, "-1\n"},
+		// Test-case 3: Out of range rt-runtime; previous value is kept.
+		{"3", "sched_rt_runtime_us", "-2\n", fuse.IOerror{Code: syscall.EINVAL}, "-1\n"},
+		// Test-case 4: Non-numeric rt-period; host value is kept.
+		{"4", "sched_rt_period_us", "fast\n", fuse.IOerror{Code: syscall.EINVAL}, "1000000\n"},
+		// Test-case 5: Zero rt-period is not allowed.
+		{"5", "sched_rt_period_us", "0\n", fuse.IOerror{Code: syscall.EINVAL}, "1000000\n"},
+		// Test-case 6: Boolean-like tunable.
+		{"6", "sched_autogroup_enabled", "0\n", nil, "0\n"},
+		// Test-case 7: Boolean-like tunable out of range.
+		{"7", "sched_autogroup_enabled", "2\n", fuse.IOerror{Code: syscall.EINVAL}, "0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := write(tt.resource, tt.val); err != tt.wantErr {
+				t.Fatalf("Write(%s, %q) error = %v, want %v",
+					tt.resource, tt.val, err, tt.wantErr)
+			}
+
+			got, err := read(tt.resource)
+			if err != nil {
+				t.Fatalf("Read(%s) unexpected error: %v", tt.resource, err)
+			}
+			if got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", tt.resource, got, tt.want)
+			}
+		})
+	}
+
+	// Container writes must not be pushed to the host.
+	for path, want := range hostVals {
+		got, err := ios.NewIOnode("", path, 0).ReadFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("host %s = %q, want %q", path, got, want)
+		}
+	}
+
+	// Tunables missing in the host kernel must not be exposed.
+	req := &domain.HandlerRequest{Pid: 1001, Container: cntr}
+	n := ios.NewIOnode("sched_latency_ns", "/proc/sys/kernel/sched_latency_ns", 0)
+	if _, err := h.Lookup(n, req); err != (fuse.IOerror{Code: syscall.ENOENT}) {
+		t.Errorf("Lookup(sched_latency_ns) error = %v, want ENOENT", err)
+	}
+}
+
+func TestProcSysKernel_Numa(t *testing.T) {
+
+	// Host's NUMA balancing tunables.
+	hostVals := map[string]string{
+		"/proc/sys/kernel/numa_balancing":                    "1\n",
+		"/proc/sys/kernel/numa_balancing_scan_delay_ms":      "1000\n",
+		"/proc/sys/kernel/numa_balancing_scan_period_min_ms": "1000\n",
+		"/proc/sys/kernel/numa_balancing_scan_period_max_ms": "60000\n",
+		"/proc/sys/kernel/numa_balancing_scan_size_mb":       "256\n",
+	}
+	for path, val := range hostVals {
+		if err := ios.NewIOnode("", path, 0644).WriteFile([]byte(val)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer ios.RemoveAllIOnodes()
+
+	defer hds.On("IgnoreErrors").Return(false).Unset()
+
+	h := implementations.ProcSysKernel_Handler
+	h.SetService(hds)
+
+	cntr := css.ContainerCreate(
+		"c1",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	read := func(resource string) (string, error) {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 64),
+		}
+		n := ios.NewIOnode(resource, "/proc/sys/kernel/"+resource, 0)
+		sz, err := h.Read(n, req)
+		if err != nil {
+			return "", err
+		}
+		return string(req.Data[:sz]), nil
+	}
+
+	write := func(resource, val string) error {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      []byte(val),
+		}
+		n := ios.NewIOnode(resource, "/proc/sys/kernel/"+resource, 0)
+		_, err := h.Write(n, req)
+		return err
+	}
+
+	// Reads default to the host's values.
+	if got, err := read("numa_balancing_scan_size_mb"); err != nil || got != "256\n" {
+		t.Errorf("Read(numa_balancing_scan_size_mb) = %q, %v; want %q", got, err, "256\n")
+	}
+
+	tests := []struct {
+		name     string
+		resource string
+		val      string
+		wantErr  error
+		want     string
+	}{
+		// Test-case 1: Disable NUMA balancing.
+		{"1", "numa_balancing", "0\n", nil, "0\n"},
+		// Test-case 2: Re-enable it.
+		{"2", "numa_balancing", "1\n", nil, "1\n"},
+		// Test-case 3: Out of range toggle; previous value is kept.
+		{"3", "numa_balancing", "2\n", fuse.IOerror{Code: syscall.EINVAL}, "1\n"},
+		// Test-case 4: Non-numeric toggle.
+		{"4", "numa_balancing", "on\n", fuse.IOerror{Code: syscall.EINVAL}, "1\n"},
+		// Test-case 5: Scan pace tunables.
+		{"5", "numa_balancing_scan_delay_ms", "0\n", nil, "0\n"},
+		{"6", "numa_balancing_scan_period_min_ms", "500\n", nil, "500\n"},
+		{"7", "numa_balancing_scan_period_max_ms", "120000\n", nil, "120000\n"},
+		// Test-case 8: Negative periods are not allowed.
+		{"8", "numa_balancing_scan_period_max_ms", "-1\n", fuse.IOerror{Code: syscall.EINVAL}, "120000\n"},
+		// Test-case 9: Scan size.
+		{"9", "numa_balancing_scan_size_mb", "64\n", nil, "64\n"},
+		// Test-case 10: Empty scans are not allowed.
+		{"10", "numa_balancing_scan_size_mb", "0\n", fuse.IOerror{Code: syscall.EINVAL}, "64\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := write(tt.resource, tt.val); err != tt.wantErr {
+				t.Fatalf("Write(%s, %q) error = %v, want %v",
+					tt.resource, tt.val, err, tt.wantErr)
+			}
+
+			got, err := read(tt.resource)
+			if err != nil {
+				t.Fatalf("Read(%s) unexpected error: %v", tt.resource, err)
+			}
+			if got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", tt.resource, got, tt.want)
+			}
+		})
+	}
+
+	// Container writes must not be pushed to the host.
+	for path, want := range hostVals {
+		got, err := ios.NewIOnode("", path, 0).ReadFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("host %s = %q, want %q", path, got, want)
+		}
+	}
+
+	// Tunables are not exposed when the host's kernel lacks NUMA balancing.
+	ios.RemoveAllIOnodes()
+	req := &domain.HandlerRequest{Pid: 1001, Container: cntr}
+	n := ios.NewIOnode("numa_balancing", "/proc/sys/kernel/numa_balancing", 0)
+	if _, err := h.Lookup(n, req); err != (fuse.IOerror{Code: syscall.ENOENT}) {
+		t.Errorf("Lookup(numa_balancing) error = %v, want ENOENT", err)
+	}
+}
+
+func TestProcSysKernel_OverflowIds(t *testing.T) {
+
+	// Host's overflow ids.
+	hostVals := map[string]string{
+		"/proc/sys/kernel/overflowuid": "65534\n",
+		"/proc/sys/kernel/overflowgid": "65534\n",
+	}
+	for path, val := range hostVals {
+		if err := ios.NewIOnode("", path, 0644).WriteFile([]byte(val)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer ios.RemoveAllIOnodes()
+
+	defer hds.On("IgnoreErrors").Return(false).Unset()
+
+	h := implementations.ProcSysKernel_Handler
+	h.SetService(hds)
+
+	// Container's uid/gid maps: 231072 -> 0, 65536 ids long.
+	cntr := css.ContainerCreate(
+		"c-overflow",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65536,
+		231072,
+		65536,
+		nil,
+		nil,
+		nil,
+	)
+
+	read := func(resource string) (string, error) {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 64),
+		}
+		n := ios.NewIOnode(resource, "/proc/sys/kernel/"+resource, 0)
+		sz, err := h.Read(n, req)
+		if err != nil {
+			return "", err
+		}
+		return string(req.Data[:sz]), nil
+	}
+
+	write := func(resource, val string) error {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      []byte(val),
+		}
+		n := ios.NewIOnode(resource, "/proc/sys/kernel/"+resource, 0)
+		_, err := h.Write(n, req)
+		return err
+	}
+
+	// Overflow ids match the ones displayed for unmapped files, which are
+	// the container's nobody:nogroup (i.e., ids within the container's maps).
+	for _, tt := range []struct {
+		resource string
+		size     uint32
+	}{
+		{"overflowuid", cntr.UidSize()},
+		{"overflowgid", cntr.GidSize()},
+	} {
+		got, err := read(tt.resource)
+		if err != nil || got != "65534\n" {
+			t.Fatalf("Read(%s) = %q, %v; want %q", tt.resource, got, err, "65534\n")
+		}
+		id, err := strconv.ParseUint(strings.TrimSpace(got), 10, 32)
+		if err != nil || id >= uint64(tt.size) {
+			t.Errorf("Read(%s) = %q, not within the container's %d-id map",
+				tt.resource, got, tt.size)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		resource string
+		val      string
+		wantErr  error
+		want     string
+	}{
+		// Test-case 1: Container-local overflow uid.
+		{"1", "overflowuid", "65533\n", nil, "65533\n"},
+		// Test-case 2: Container-local overflow gid.
+		{"2", "overflowgid", "0\n", nil, "0\n"},
+		// Test-case 3: Out of range uid; previous value is kept.
+		{"3", "overflowuid", "65536\n", fuse.IOerror{Code: syscall.EINVAL}, "65533\n"},
+		// Test-case 4: Negative gid.
+		{"4", "overflowgid", "-1\n", fuse.IOerror{Code: syscall.EINVAL}, "0\n"},
+		// Test-case 5: Non-numeric uid.
+		{"5", "overflowuid", "nobody\n", fuse.IOerror{Code: syscall.EINVAL}, "65533\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := write(tt.resource, tt.val); err != tt.wantErr {
+				t.Fatalf("Write(%s, %q) error = %v, want %v",
+					tt.resource, tt.val, err, tt.wantErr)
+			}
+
+			got, err := read(tt.resource)
+			if err != nil {
+				t.Fatalf("Read(%s) unexpected error: %v", tt.resource, err)
+			}
+			if got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", tt.resource, got, tt.want)
+			}
+		})
+	}
+
+	// Container writes must not be pushed to the host.
+	for path, want := range hostVals {
+		got, err := ios.NewIOnode("", path, 0).ReadFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("host %s = %q, want %q", path, got, want)
+		}
+	}
+}
+
+// utsNSenterService is an nsenter-service fake that carries out file requests
+// from within a private UTS namespace, which stands for the container's one.
+// All the requests are served by the same OS thread, as namespaces are a
+// per-thread attribute.
+type utsNSenterService struct {
+	domain.NSenterServiceIface
+	reqs chan func()
+}
+
+type utsNSenterEvent struct {
+	domain.NSenterEventIface
+	req *domain.NSenterMessage
+	res *domain.NSenterMessage
+}
+
+func newUtsNSenterService() (*utsNSenterService, error) {
+
+	s := &utsNSenterService{reqs: make(chan func())}
+	errCh := make(chan error)
+
+	go func() {
+		// The thread is purposely never unlocked, so that it's terminated
+		// (rather than reused) once this goroutine completes.
+		runtime.LockOSThread()
+
+		if err := unix.Unshare(unix.CLONE_NEWUTS); err != nil {
+			errCh <- err
+			return
+		}
+		errCh <- nil
+
+		for f := range s.reqs {
+			f()
+		}
+	}()
+
+	if err := <-errCh; err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// run executes the given function within the private UTS namespace.
+func (s *utsNSenterService) run(f func()) {
+	done := make(chan struct{})
+	s.reqs <- func() {
+		f()
+		close(done)
+	}
+	<-done
+}
+
+func (s *utsNSenterService) close() {
+	close(s.reqs)
+}
+
+func (s *utsNSenterService) NewEvent(
+	pid uint32,
+	ns *[]domain.NStype,
+	cloneFlags uint32,
+	req *domain.NSenterMessage,
+	res *domain.NSenterMessage,
+	async bool) domain.NSenterEventIface {
+
+	return &utsNSenterEvent{req: req, res: res}
+}
+
+func (s *utsNSenterService) SendRequestEvent(e domain.NSenterEventIface) error {
+
+	event := e.(*utsNSenterEvent)
+
+	s.run(func() {
+		switch payload := event.req.Payload.(type) {
+		case *domain.ReadFilePayload:
+			data, err := ioutil.ReadFile(payload.File)
+			if err != nil {
+				event.res = &domain.NSenterMessage{Type: domain.ErrorResponse, Payload: err}
+				return
+			}
+			event.res = &domain.NSenterMessage{Type: domain.ReadFileResponse, Payload: data}
+
+		case *domain.WriteFilePayload:
+			err := ioutil.WriteFile(payload.File, payload.Data, 0644)
+			if err != nil {
+				event.res = &domain.NSenterMessage{Type: domain.ErrorResponse, Payload: err}
+				return
+			}
+			event.res = &domain.NSenterMessage{Type: domain.WriteFileResponse}
+
+		default:
+			event.res = &domain.NSenterMessage{
+				Type:    domain.ErrorResponse,
+				Payload: fuse.IOerror{Code: syscall.ENOTSUP},
+			}
+		}
+	})
+
+	return nil
+}
+
+func (s *utsNSenterService) ReceiveResponseEvent(e domain.NSenterEventIface) *domain.NSenterMessage {
+	return e.(*utsNSenterEvent).res
+}
+
+func TestProcSysKernel_HostnameUtsConsistency(t *testing.T) {
+
+	if os.Geteuid() != 0 {
+		t.Skip("test requires root privileges")
+	}
+
+	uts, err := newUtsNSenterService()
+	if err != nil {
+		t.Skipf("unable to create UTS namespace: %v", err)
+	}
+	defer uts.close()
+
+	// Handler-service wired to the UTS-namespace nsenter fake.
+	hs := &mocks.HandlerServiceIface{}
+	pt := &implementations.PassThrough{
+		HandlerBase: domain.HandlerBase{
+			Name:    "PassThrough",
+			Path:    "*",
+			Service: hs,
+		},
+	}
+	hs.On("NSenterService").Return(uts)
+	hs.On("ProcessService").Return(prs)
+	hs.On("GetPassThroughHandler").Return(pt)
+
+	h := &implementations.ProcSysKernel{
+		HandlerBase: domain.HandlerBase{
+			Name:    "ProcSysKernel",
+			Path:    "/proc/sys/kernel",
+			Service: hs,
+		},
+	}
+
+	cntr := css.ContainerCreate(
+		"c-uts",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+	if err := cntr.SetInitProc(1001, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	n := ios.NewIOnode("hostname", "/proc/sys/kernel/hostname", 0)
+
+	read := func() string {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 65),
+		}
+		sz, err := h.Read(n, req)
+		if err != nil {
+			t.Fatalf("Read(hostname) unexpected error: %v", err)
+		}
+		return strings.TrimSpace(string(req.Data[:sz]))
+	}
+
+	uname := func() string {
+		var u unix.Utsname
+		uts.run(func() {
+			if err := unix.Uname(&u); err != nil {
+				t.Errorf("uname() failed: %v", err)
+			}
+		})
+		return unix.ByteSliceToString(u.Nodename[:])
+	}
+
+	// Hostname set through the proc file must be reflected by uname(2).
+	req := &domain.HandlerRequest{
+		Pid:       1001,
+		Container: cntr,
+		Data:      []byte("cntr-host-1\n"),
+	}
+	if _, err := h.Write(n, req); err != nil {
+		t.Fatalf("Write(hostname) unexpected error: %v", err)
+	}
+	if got := uname(); got != "cntr-host-1" {
+		t.Errorf("uname() nodename = %q, want %q", got, "cntr-host-1")
+	}
+	if got := read(); got != "cntr-host-1" {
+		t.Errorf("Read(hostname) = %q, want %q", got, "cntr-host-1")
+	}
+
+	// Hostname set through sethostname(2) must be reflected by the proc file
+	// (i.e., no stale value must be served).
+	uts.run(func() {
+		if err := unix.Sethostname([]byte("cntr-host-2")); err != nil {
+			t.Errorf("sethostname() failed: %v", err)
+		}
+	})
+	if got := read(); got != "cntr-host-2" {
+		t.Errorf("Read(hostname) = %q, want %q", got, "cntr-host-2")
+	}
+
+	// Host's hostname must be left untouched.
+	host, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host == "cntr-host-1" || host == "cntr-host-2" {
+		t.Errorf("host's hostname modified: %q", host)
+	}
+}
+
+// ipcPassThrough is a passthrough-handler fake standing for the container's
+// IPC namespace, which holds a single sysctl value.
+type ipcPassThrough struct {
+	domain.PassthroughHandlerIface
+	val string
+}
+
+func (p *ipcPassThrough) ReadWithNS(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest,
+	namespaces []domain.NStype) (int, error) {
+
+	if req.Offset >= int64(len(p.val)) {
+		return 0, nil
+	}
+
+	return copy(req.Data, p.val[req.Offset:]), nil
+}
+
+func (p *ipcPassThrough) WriteWithNS(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest,
+	namespaces []domain.NStype) (int, error) {
+
+	p.val = string(req.Data)
+
+	return len(req.Data), nil
+}
+
+func TestProcSysKernel_ShmmaxClamp(t *testing.T) {
+
+	// Container's memory cgroup (v2).
+	const memMax = "/sys/fs/cgroup/sysbox/c1/memory.max"
+	if err := ios.NewIOnode("", "/proc/1001/cgroup", 0644).WriteFile([]byte("0::/sysbox/c1\n")); err != nil {
+		t.Fatal(err)
+	}
+	defer ios.RemoveAllIOnodes()
+
+	// Kernel's default shmmax.
+	pt := &ipcPassThrough{val: "18446744073692774399\n"}
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("IOService").Return(ios)
+	hs.On("GetPassThroughHandler").Return(pt)
+
+	h := &implementations.ProcSysKernel{
+		HandlerBase: domain.HandlerBase{
+			Name:    "ProcSysKernel",
+			Path:    "/proc/sys/kernel",
+			Service: hs,
+		},
+	}
+
+	cntr := css.ContainerCreate(
+		"c-shm",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	n := ios.NewIOnode("shmmax", "/proc/sys/kernel/shmmax", 0)
+
+	tests := []struct {
+		name     string
+		memLimit string
+		val      string // value written; none if empty
+		want     string // value read
+		wantNs   string // value held by the container's ipc-ns
+	}{
+		// Test-case 1: Unlimited memory; kernel's default is displayed as is.
+		{"1", "max\n", "", "18446744073692774399\n", "18446744073692774399\n"},
+		// Test-case 2: Kernel's default above the memory limit.
+		{"2", "1073741824\n", "", "1073741824\n", "18446744073692774399\n"},
+		// Test-case 3: Value above the memory limit is clamped.
+		{"3", "1073741824\n", "4294967296\n", "1073741824\n", "1073741824\n"},
+		// Test-case 4: Value below the memory limit is kept.
+		{"4", "1073741824\n", "536870912\n", "536870912\n", "536870912\n"},
+		// Test-case 5: Value above a lowered memory limit.
+		{"5", "268435456\n", "", "268435456\n", "536870912\n"},
+		// Test-case 6: Unlimited memory; value is kept.
+		{"6", "max\n", "4294967296\n", "4294967296\n", "4294967296\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ios.NewIOnode("", memMax, 0644).WriteFile([]byte(tt.memLimit)); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.val != "" {
+				req := &domain.HandlerRequest{
+					Pid:       1001,
+					Container: cntr,
+					Data:      []byte(tt.val),
+				}
+				sz, err := h.Write(n, req)
+				if err != nil {
+					t.Fatalf("Write(shmmax, %q) unexpected error: %v", tt.val, err)
+				}
+				if sz != len(tt.val) {
+					t.Errorf("Write(shmmax, %q) = %d, want %d", tt.val, sz, len(tt.val))
+				}
+			}
+
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      make([]byte, 64),
+			}
+			sz, err := h.Read(n, req)
+			if err != nil {
+				t.Fatalf("Read(shmmax) unexpected error: %v", err)
+			}
+			if got := string(req.Data[:sz]); got != tt.want {
+				t.Errorf("Read(shmmax) = %q, want %q", got, tt.want)
+			}
+			if pt.val != tt.wantNs {
+				t.Errorf("ipc-ns shmmax = %q, want %q", pt.val, tt.wantNs)
+			}
+		})
+	}
+}
+
+func TestProcSysKernel_MsgLimits(t *testing.T) {
+
+	cntr := css.ContainerCreate(
+		"c-msg",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	// Kernel's defaults.
+	defaults := map[string]string{
+		"msgmax": "8192\n",
+		"msgmnb": "16384\n",
+		"msgmni": "32000\n",
+	}
+
+	tests := []struct {
+		name    string
+		val     string
+		wantErr error
+		want    string // value read after the write
+	}{
+		// Test-case 1: Valid value.
+		{"1", "65536\n", nil, "65536\n"},
+		// Test-case 2: Upper bound.
+		{"2", "2147483647\n", nil, "2147483647\n"},
+		// Test-case 3: Lower bound.
+		{"3", "0\n", nil, "0\n"},
+		// Test-case 4: Negative value; previous value is kept.
+		{"4", "-1\n", fuse.IOerror{Code: syscall.EINVAL}, "0\n"},
+		// Test-case 5: Out of range value.
+		{"5", "2147483648\n", fuse.IOerror{Code: syscall.EINVAL}, "0\n"},
+		// Test-case 6: Non-numeric value.
+		{"6", "unlimited\n", fuse.IOerror{Code: syscall.EINVAL}, "0\n"},
+	}
+
+	for _, node := range []string{"msgmax", "msgmnb", "msgmni"} {
+		pt := &ipcPassThrough{val: defaults[node]}
+
+		hs := &mocks.HandlerServiceIface{}
+		hs.On("GetPassThroughHandler").Return(pt)
+
+		h := &implementations.ProcSysKernel{
+			HandlerBase: domain.HandlerBase{
+				Name:    "ProcSysKernel",
+				Path:    "/proc/sys/kernel",
+				Service: hs,
+			},
+		}
+
+		n := ios.NewIOnode(node, "/proc/sys/kernel/"+node, 0)
+
+		read := func(t *testing.T) string {
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      make([]byte, 64),
+			}
+			sz, err := h.Read(n, req)
+			if err != nil {
+				t.Fatalf("Read(%s) unexpected error: %v", node, err)
+			}
+			return string(req.Data[:sz])
+		}
+
+		// The value held by the container's ipc-ns is displayed until written.
+		if got := read(t); got != defaults[node] {
+			t.Errorf("Read(%s) = %q, want %q", node, got, defaults[node])
+		}
+
+		for _, tt := range tests {
+			t.Run(node+"-"+tt.name, func(t *testing.T) {
+				req := &domain.HandlerRequest{
+					Pid:       1001,
+					Container: cntr,
+					Data:      []byte(tt.val),
+				}
+				if _, err := h.Write(n, req); err != tt.wantErr {
+					t.Fatalf("Write(%s, %q) error = %v, want %v", node, tt.val, err, tt.wantErr)
+				}
+				if got := read(t); got != tt.want {
+					t.Errorf("Read(%s) = %q, want %q", node, got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestProcSysKernel_ShmLimits(t *testing.T) {
+
+	cntr := css.ContainerCreate(
+		"c-shm-limits",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	einval := fuse.IOerror{Code: syscall.EINVAL}
+
+	tests := []struct {
+		node    string
+		def     string // kernel's default
+		val     string
+		wantErr error
+		want    string // value read after the write
+	}{
+		{"shmmni", "4096\n", "32768\n", nil, "32768\n"},
+		{"shmmni", "4096\n", "0\n", nil, "0\n"},
+		{"shmmni", "4096\n", "16777216\n", nil, "16777216\n"},
+		{"shmmni", "4096\n", "16777217\n", einval, "4096\n"},
+		{"shmmni", "4096\n", "-1\n", einval, "4096\n"},
+		{"shmmni", "4096\n", "many\n", einval, "4096\n"},
+		{"shm_rmid_forced", "0\n", "1\n", nil, "1\n"},
+		{"shm_rmid_forced", "1\n", "0\n", nil, "0\n"},
+		{"shm_rmid_forced", "0\n", "2\n", einval, "0\n"},
+		{"shm_rmid_forced", "0\n", "-1\n", einval, "0\n"},
+		{"shm_rmid_forced", "0\n", "yes\n", einval, "0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.node+"-"+strings.TrimSpace(tt.val), func(t *testing.T) {
+			pt := &ipcPassThrough{val: tt.def}
+
+			hs := &mocks.HandlerServiceIface{}
+			hs.On("GetPassThroughHandler").Return(pt)
+
+			h := &implementations.ProcSysKernel{
+				HandlerBase: domain.HandlerBase{
+					Name:    "ProcSysKernel",
+					Path:    "/proc/sys/kernel",
+					Service: hs,
+				},
+			}
+
+			n := ios.NewIOnode(tt.node, "/proc/sys/kernel/"+tt.node, 0)
+
+			read := func() string {
+				req := &domain.HandlerRequest{
+					Pid:       1001,
+					Container: cntr,
+					Data:      make([]byte, 64),
+				}
+				sz, err := h.Read(n, req)
+				if err != nil {
+					t.Fatalf("Read(%s) unexpected error: %v", tt.node, err)
+				}
+				return string(req.Data[:sz])
+			}
+
+			// The value held by the container's ipc-ns is displayed until written.
+			if got := read(); got != tt.def {
+				t.Errorf("Read(%s) = %q, want %q", tt.node, got, tt.def)
+			}
+
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      []byte(tt.val),
+			}
+			if _, err := h.Write(n, req); err != tt.wantErr {
+				t.Fatalf("Write(%s, %q) error = %v, want %v", tt.node, tt.val, err, tt.wantErr)
+			}
+			if got := read(); got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", tt.node, got, tt.want)
+			}
+		})
+	}
+}
+
+// pidnsPassThrough is a passthrough-handler fake standing for the container's
+// pid namespace, which holds the last pid allocated within it.
+type pidnsPassThrough struct {
+	domain.PassthroughHandlerIface
+	lastPid string
+	cached  bool // whether any access could have been cached
+}
+
+func (p *pidnsPassThrough) Read(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	p.cached = p.cached || !req.NoCache
+
+	if req.Offset >= int64(len(p.lastPid)) {
+		return 0, nil
+	}
+
+	return copy(req.Data, p.lastPid[req.Offset:]), nil
+}
+
+func (p *pidnsPassThrough) Write(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	p.cached = p.cached || !req.NoCache
+	p.lastPid = string(req.Data)
+
+	return len(req.Data), nil
+}
+
+// allocPid stands for a process creation within the pid namespace.
+func (p *pidnsPassThrough) allocPid() {
+	pid, _ := strconv.Atoi(strings.TrimSpace(p.lastPid))
+	p.lastPid = strconv.Itoa(pid+1) + "\n"
+}
+
+func TestProcSysKernel_NsLastPid(t *testing.T) {
+
+	// Host's pid_max.
+	if err := ios.NewIOnode("", "/proc/sys/kernel/pid_max", 0644).WriteFile([]byte("32768\n")); err != nil {
+		t.Fatal(err)
+	}
+	defer ios.RemoveAllIOnodes()
+
+	pt := &pidnsPassThrough{lastPid: "1\n"}
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("IOService").Return(ios)
+	hs.On("GetPassThroughHandler").Return(pt)
+	hs.On("IgnoreErrors").Return(false)
+
+	h := &implementations.ProcSysKernel{
+		HandlerBase: domain.HandlerBase{
+			Name:           "ProcSysKernel",
+			Path:           "/proc/sys/kernel",
+			Service:        hs,
+			EmuResourceMap: implementations.ProcSysKernel_Handler.EmuResourceMap,
+		},
+	}
+
+	cntr := css.ContainerCreate(
+		"c-ns-last-pid",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	read := func(resource string) string {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 64),
+		}
+		n := ios.NewIOnode(resource, "/proc/sys/kernel/"+resource, 0)
+		sz, err := h.Read(n, req)
+		if err != nil {
+			t.Fatalf("Read(%s) unexpected error: %v", resource, err)
+		}
+		return string(req.Data[:sz])
+	}
+
+	write := func(resource, val string) error {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      []byte(val),
+		}
+		n := ios.NewIOnode(resource, "/proc/sys/kernel/"+resource, 0)
+		_, err := h.Write(n, req)
+		return err
+	}
+
+	// Pids allocated within the pid namespace are reflected right away.
+	if got := read("ns_last_pid"); got != "1\n" {
+		t.Errorf("Read(ns_last_pid) = %q, want %q", got, "1\n")
+	}
+	pt.allocPid()
+	if got := read("ns_last_pid"); got != "2\n" {
+		t.Errorf("Read(ns_last_pid) = %q, want %q", got, "2\n")
+	}
+
+	tests := []struct {
+		name    string
+		val     string
+		wantErr error
+		want    string // value held by the pid namespace after the write
+	}{
+		// Test-case 1: Valid value.
+		{"1", "1000\n", nil, "1000\n"},
+		// Test-case 2: Upper bound (container's pid_max).
+		{"2", "32768\n", nil, "32768\n"},
+		// Test-case 3: Above the container's pid_max.
+		{"3", "32769\n", fuse.IOerror{Code: syscall.EINVAL}, "32768\n"},
+		// Test-case 4: Non-positive values.
+		{"4", "0\n", fuse.IOerror{Code: syscall.EINVAL}, "32768\n"},
+		{"5", "-1\n", fuse.IOerror{Code: syscall.EINVAL}, "32768\n"},
+		// Test-case 6: Non-numeric value.
+		{"6", "last\n", fuse.IOerror{Code: syscall.EINVAL}, "32768\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := write("ns_last_pid", tt.val); err != tt.wantErr {
+				t.Fatalf("Write(ns_last_pid, %q) error = %v, want %v", tt.val, err, tt.wantErr)
+			}
+			if pt.lastPid != tt.want {
+				t.Errorf("pid-ns last pid = %q, want %q", pt.lastPid, tt.want)
+			}
+			if got := read("ns_last_pid"); got != tt.want {
+				t.Errorf("Read(ns_last_pid) = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// The container's pid_max is honored, rather than the host's one.
+	if err := write("pid_max", "65536\n"); err != nil {
+		t.Fatalf("Write(pid_max) unexpected error: %v", err)
+	}
+	if err := write("ns_last_pid", "40000\n"); err != nil {
+		t.Errorf("Write(ns_last_pid, %q) unexpected error: %v", "40000\n", err)
+	}
+
+	if pt.cached {
+		t.Errorf("ns_last_pid accesses must not be cached")
+	}
+}
+
+func TestProcSysKernel_Sysrq(t *testing.T) {
+
+	const path = "/proc/sys/kernel/sysrq"
+	const hostVal = "176\n"
+
+	if err := ios.NewIOnode("", path, 0644).WriteFile([]byte(hostVal)); err != nil {
+		t.Fatal(err)
+	}
+	defer ios.RemoveAllIOnodes()
+
+	defer hds.On("IgnoreErrors").Return(false).Unset()
+
+	h := implementations.ProcSysKernel_Handler
+	h.SetService(hds)
+
+	newCntr := func(id string) domain.ContainerIface {
+		return css.ContainerCreate(
+			id,
+			uint32(1001),
+			time.Time{},
+			231072,
+			65535,
+			231072,
+			65535,
+			nil,
+			nil,
+			nil,
+		)
+	}
+	cntr := newCntr("c-sysrq")
+
+	read := func(cntr domain.ContainerIface) string {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 64),
+		}
+		sz, err := h.Read(ios.NewIOnode("sysrq", path, 0), req)
+		if err != nil {
+			t.Fatalf("Read(%s) unexpected error: %v", path, err)
+		}
+		return string(req.Data[:sz])
+	}
+
+	// Host value is displayed until the container sets its own.
+	if got := read(cntr); got != hostVal {
+		t.Errorf("Read(%s) = %q, want %q", path, got, hostVal)
+	}
+
+	tests := []struct {
+		name    string
+		val     string
+		wantErr error
+		want    string
+	}{
+		// Test-case 1: Disable sysrq.
+		{"1", "0\n", nil, "0\n"},
+		// Test-case 2: Enable all functions.
+		{"2", "1\n", nil, "1\n"},
+		// Test-case 3: Bitmask of functions (sync, remount-ro, reboot).
+		{"3", "176\n", nil, "176\n"},
+		// Test-case 4: Full bitmask.
+		{"4", "511\n", nil, "511\n"},
+		// Test-case 5: Unknown function bit; previous value is kept.
+		{"5", "512\n", fuse.IOerror{Code: syscall.EINVAL}, "511\n"},
+		// Test-case 6: Negative value.
+		{"6", "-1\n", fuse.IOerror{Code: syscall.EINVAL}, "511\n"},
+		// Test-case 7: Non-numeric value.
+		{"7", "0x10\n", fuse.IOerror{Code: syscall.EINVAL}, "511\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      []byte(tt.val),
+			}
+			if _, err := h.Write(ios.NewIOnode("sysrq", path, 0), req); err != tt.wantErr {
+				t.Fatalf("Write(%s, %q) error = %v, want %v", path, tt.val, err, tt.wantErr)
+			}
+
+			if got := read(cntr); got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", path, got, tt.want)
+			}
+		})
+	}
+
+	// Neither the host nor other containers are affected.
+	got, err := ios.NewIOnode("", path, 0).ReadFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != hostVal {
+		t.Errorf("host %s = %q, want %q", path, got, hostVal)
+	}
+	if got := read(newCntr("c-sysrq-other")); got != hostVal {
+		t.Errorf("Read(%s) from another container = %q, want %q", path, got, hostVal)
+	}
+}
+
+func TestProcSysKernel_RandomizeVaSpace(t *testing.T) {
+
+	const path = "/proc/sys/kernel/randomize_va_space"
+	const hostVal = "2\n"
+
+	if err := ios.NewIOnode("", path, 0644).WriteFile([]byte(hostVal)); err != nil {
+		t.Fatal(err)
+	}
+	defer ios.RemoveAllIOnodes()
+
+	defer hds.On("IgnoreErrors").Return(false).Unset()
+
+	h := implementations.ProcSysKernel_Handler
+	h.SetService(hds)
+
+	newCntr := func(id string) domain.ContainerIface {
+		return css.ContainerCreate(
+			id,
+			uint32(1001),
+			time.Time{},
+			231072,
+			65535,
+			231072,
+			65535,
+			nil,
+			nil,
+			nil,
+		)
+	}
+	cntr := newCntr("c-aslr")
+
+	n := ios.NewIOnode("randomize_va_space", path, 0)
+
+	read := func(cntr domain.ContainerIface) string {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 64),
+		}
+		sz, err := h.Read(n, req)
+		if err != nil {
+			t.Fatalf("Read(%s) unexpected error: %v", path, err)
+		}
+		return string(req.Data[:sz])
+	}
+
+	// Host value is displayed until the container sets its own.
+	if got := read(cntr); got != hostVal {
+		t.Errorf("Read(%s) = %q, want %q", path, got, hostVal)
+	}
+
+	tests := []struct {
+		name    string
+		val     string
+		wantErr error
+		want    string
+	}{
+		// Test-case 1: ASLR disabled (e.g., reproducible builds).
+		{"1", "0\n", nil, "0\n"},
+		// Test-case 2: Conservative randomization.
+		{"2", "1\n", nil, "1\n"},
+		// Test-case 3: Full randomization.
+		{"3", "2\n", nil, "2\n"},
+		// Test-case 4: Out of range value; previous value is kept.
+		{"4", "3\n", fuse.IOerror{Code: syscall.EINVAL}, "2\n"},
+		// Test-case 5: Negative value.
+		{"5", "-1\n", fuse.IOerror{Code: syscall.EINVAL}, "2\n"},
+		// Test-case 6: Non-numeric value.
+		{"6", "off\n", fuse.IOerror{Code: syscall.EINVAL}, "2\n"},
+		// Test-case 7: ASLR disabled again.
+		{"7", "0\n", nil, "0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      []byte(tt.val),
+			}
+			if _, err := h.Write(n, req); err != tt.wantErr {
+				t.Fatalf("Write(%s, %q) error = %v, want %v", path, tt.val, err, tt.wantErr)
+			}
+
+			if got := read(cntr); got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", path, got, tt.want)
+			}
+		})
+	}
+
+	// Neither the host nor other containers are affected.
+	got, err := ios.NewIOnode("", path, 0).ReadFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != hostVal {
+		t.Errorf("host %s = %q, want %q", path, got, hostVal)
+	}
+	if got := read(newCntr("c-aslr-other")); got != hostVal {
+		t.Errorf("Read(%s) from another container = %q, want %q", path, got, hostVal)
+	}
+}
+
+func TestProcSysKernel_Panic(t *testing.T) {
+
+	hostVals := map[string]string{
+		"/proc/sys/kernel/panic":         "10\n",
+		"/proc/sys/kernel/panic_on_oops": "1\n",
+	}
+	for path, val := range hostVals {
+		if err := ios.NewIOnode("", path, 0644).WriteFile([]byte(val)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer ios.RemoveAllIOnodes()
+
+	defer hds.On("IgnoreErrors").Return(false).Unset()
+
+	h := implementations.ProcSysKernel_Handler
+	h.SetService(hds)
+
+	newCntr := func(id string) domain.ContainerIface {
+		return css.ContainerCreate(
+			id,
+			uint32(1001),
+			time.Time{},
+			231072,
+			65535,
+			231072,
+			65535,
+			nil,
+			nil,
+			nil,
+		)
+	}
+	cntr := newCntr("c-panic")
+
+	read := func(cntr domain.ContainerIface, path string) string {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 64),
+		}
+		sz, err := h.Read(ios.NewIOnode(filepath.Base(path), path, 0), req)
+		if err != nil {
+			t.Fatalf("Read(%s) unexpected error: %v", path, err)
+		}
+		return string(req.Data[:sz])
+	}
+
+	// Host values are displayed until the container sets its own.
+	for path, hostVal := range hostVals {
+		if got := read(cntr, path); got != hostVal {
+			t.Errorf("Read(%s) = %q, want %q", path, got, hostVal)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		val     string
+		wantErr error
+		want    string
+	}{
+		// Test-case 1: Reboot disabled on panic.
+		{"1", "/proc/sys/kernel/panic", "0\n", nil, "0\n"},
+		// Test-case 2: Immediate reboot on panic.
+		{"2", "/proc/sys/kernel/panic", "-1\n", nil, "-1\n"},
+		// Test-case 3: Non-numeric value; previous value is kept.
+		{"3", "/proc/sys/kernel/panic", "never\n", fuse.IOerror{Code: syscall.EINVAL}, "-1\n"},
+		// Test-case 4: Out of int range.
+		{"4", "/proc/sys/kernel/panic", "2147483648\n", fuse.IOerror{Code: syscall.EINVAL}, "-1\n"},
+		// Test-case 5: Panic on oops disabled.
+		{"5", "/proc/sys/kernel/panic_on_oops", "0\n", nil, "0\n"},
+		// Test-case 6: Undefined value, though accepted by the kernel.
+		{"6", "/proc/sys/kernel/panic_on_oops", "2\n", nil, "2\n"},
+		// Test-case 7: Non-numeric value.
+		{"7", "/proc/sys/kernel/panic_on_oops", "yes\n", fuse.IOerror{Code: syscall.EINVAL}, "2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      []byte(tt.val),
+			}
+			n := ios.NewIOnode(filepath.Base(tt.path), tt.path, 0)
+			if _, err := h.Write(n, req); err != tt.wantErr {
+				t.Fatalf("Write(%s, %q) error = %v, want %v", tt.path, tt.val, err, tt.wantErr)
+			}
+
+			if got := read(cntr, tt.path); got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	// Neither the host nor other containers are affected.
+	for path, hostVal := range hostVals {
+		got, err := ios.NewIOnode("", path, 0).ReadFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != hostVal {
+			t.Errorf("host %s = %q, want %q", path, got, hostVal)
+		}
+		if got := read(newCntr("c-panic-other"), path); got != hostVal {
+			t.Errorf("Read(%s) from another container = %q, want %q", path, got, hostVal)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+)
+
+//
+// /proc/sys/net/ipv4/conf and /proc/sys/net/ipv6/conf handlers
+//
+// Per-interface sysctls (e.g., /proc/sys/net/ipv4/conf/<iface>/rp_filter) are
+// scoped per net-ns, so all accesses are carried out within the namespaces of
+// the requesting process. As a result, the <iface> directories displayed
+// match the interfaces of the container (along with the "all" and "default"
+// pseudo-interfaces), and writes apply to the container's net-ns only.
+//
+// Values are never cached at container level: they change behind our back
+// (e.g., writes to "all" propagate to every interface, and interfaces come and
+// go along with their settings), so every read is served by the kernel.
+//
+// Writes to well-known keys are validated prior to reaching the kernel, as
+// many of these are accepted by the kernel regardless of their value (e.g.,
+// rp_filter). Writes to any other key are left for the kernel to validate.
+//
+
+// Range of values (inclusive) accepted by a sysctl.
+type sysctlRange struct {
+	min int
+	max int
+}
+
+var procSysNetIpv4ConfKeys = map[string]sysctlRange{
+	"forwarding":                   {0, 1},
+	"accept_redirects":             {0, 1},
+	"secure_redirects":             {0, 1},
+	"send_redirects":               {0, 1},
+	"shared_media":                 {0, 1},
+	"accept_source_route":          {0, 1},
+	"accept_local":                 {0, 1},
+	"route_localnet":               {0, 1},
+	"rp_filter":                    {0, 2},
+	"proxy_arp":                    {0, 1},
+	"proxy_arp_pvlan":              {0, 1},
+	"arp_filter":                   {0, 1},
+	"arp_announce":                 {0, 2},
+	"arp_ignore":                   {0, 8},
+	"arp_notify":                   {0, 1},
+	"arp_accept":                   {0, 2},
+	"bootp_relay":                  {0, 1},
+	"log_martians":                 {0, 1},
+	"disable_policy":               {0, 1},
+	"disable_xfrm":                 {0, 1},
+	"promote_secondaries":          {0, 1},
+	"ignore_routes_with_linkdown":  {0, 1},
+	"drop_gratuitous_arp":          {0, 1},
+	"drop_unicast_in_l2_multicast": {0, 1},
+}
+
+var procSysNetIpv6ConfKeys = map[string]sysctlRange{
+	"forwarding":           {0, 1},
+	"disable_ipv6":         {0, 1},
+	"accept_ra":            {0, 2},
+	"accept_redirects":     {0, 1},
+	"autoconf":             {0, 1},
+	"accept_dad":           {0, 2},
+	"use_tempaddr":         {-1, 2},
+	"hop_limit":            {1, 255},
+	"mtu":                  {1280, math.MaxInt32},
+	"proxy_ndp":            {0, 1},
+	"keep_addr_on_down":    {-1, 1},
+	"addr_gen_mode":        {0, 3},
+	"router_solicitations": {-1, math.MaxInt32},
+}
+
+type ProcSysNetConf struct {
+	domain.HandlerBase
+	keys map[string]sysctlRange
+}
+
+var ProcSysNetIpv4Conf_Handler = &ProcSysNetConf{
+	HandlerBase: domain.HandlerBase{
+		Name:           "ProcSysNetIpv4Conf",
+		Path:           "/proc/sys/net/ipv4/conf",
+		Enabled:        true,
+		EmuResourceMap: map[string]*domain.EmuResource{},
+	},
+	keys: procSysNetIpv4ConfKeys,
+}
+
+var ProcSysNetIpv6Conf_Handler = &ProcSysNetConf{
+	HandlerBase: domain.HandlerBase{
+		Name:           "ProcSysNetIpv6Conf",
+		Path:           "/proc/sys/net/ipv6/conf",
+		Enabled:        true,
+		EmuResourceMap: map[string]*domain.EmuResource{},
+	},
+	keys: procSysNetIpv6ConfKeys,
+}
+
+func (h *ProcSysNetConf) Lookup(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (os.FileInfo, error) {
+
+	logrus.Debugf("Executing Lookup() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return h.Service.GetPassThroughHandler().Lookup(n, req)
+}
+
+func (h *ProcSysNetConf) Open(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (bool, error) {
+
+	logrus.Debugf("Executing Open() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return h.Service.GetPassThroughHandler().Open(n, req)
+}
+
+func (h *ProcSysNetConf) Read(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	req.NoCache = true
+
+	return h.Service.GetPassThroughHandler().Read(n, req)
+}
+
+func (h *ProcSysNetConf) Write(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	var resource = n.Name()
+
+	logrus.Debugf("Executing Write() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, resource)
+
+	// Keys are placed right under the <iface> directories.
+	relPath, err := filepath.Rel(h.Path, n.Path())
+	if err != nil {
+		return 0, err
+	}
+
+	if strings.Count(relPath, "/") == 1 {
+		if r, ok := h.keys[resource]; ok && !checkIntRange(req.Data, r.min, r.max) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+	}
+
+	req.NoCache = true
+
+	return h.Service.GetPassThroughHandler().Write(n, req)
+}
+
+func (h *ProcSysNetConf) ReadDirAll(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]os.FileInfo, error) {
+
+	logrus.Debugf("Executing ReadDirAll() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	entries, err := h.Service.GetPassThroughHandler().ReadDirAll(n, req)
+	if err != nil || n.Path() != h.Path {
+		return entries, err
+	}
+
+	// Only interface directories are expected at the top of the tree.
+	var fileEntries []os.FileInfo
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		fileEntries = append(fileEntries, e)
+	}
+
+	return fileEntries, nil
+}
+
+func (h *ProcSysNetConf) ReadLink(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (string, error) {
+
+	logrus.Debugf("Executing ReadLink() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return h.Service.GetPassThroughHandler().ReadLink(n, req)
+}
+
+func (h *ProcSysNetConf) GetName() string {
+	return h.Name
+}
+
+func (h *ProcSysNetConf) GetPath() string {
+	return h.Path
+}
+
+func (h *ProcSysNetConf) GetService() domain.HandlerServiceIface {
+	return h.Service
+}
+
+func (h *ProcSysNetConf) GetEnabled() bool {
+	return h.Enabled
+}
+
+func (h *ProcSysNetConf) SetEnabled(b bool) {
+	h.Enabled = b
+}
+
+func (h *ProcSysNetConf) GetResourcesList() []string {
+
+	var resources []string
+
+	for resourceKey, resource := range h.EmuResourceMap {
+		resource.Mutex.Lock()
+		if !resource.Enabled {
+			resource.Mutex.Unlock()
+			continue
+		}
+		resource.Mutex.Unlock()
+
+		resources = append(resources, filepath.Join(h.GetPath(), resourceKey))
+	}
+
+	return resources
+}
+
+func (h *ProcSysNetConf) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex {
+	resource, ok := h.EmuResourceMap[n.Name()]
+	if !ok {
+		return nil
+	}
+
+	return &resource.Mutex
+}
+
+func (h *ProcSysNetConf) SetService(hs domain.HandlerServiceIface) {
+	h.Service = hs
+}
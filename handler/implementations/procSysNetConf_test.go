@@ -0,0 +1,195 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+func TestProcSysNetConf(t *testing.T) {
+
+	// Container's net-ns, holding the "lo" and "eth0" interfaces.
+	ifaceDirs := []os.FileInfo{
+		&domain.FileInfo{Fname: "all", Fmode: os.ModeDir | 0555, FisDir: true},
+		&domain.FileInfo{Fname: "default", Fmode: os.ModeDir | 0555, FisDir: true},
+		&domain.FileInfo{Fname: "eth0", Fmode: os.ModeDir | 0555, FisDir: true},
+		&domain.FileInfo{Fname: "lo", Fmode: os.ModeDir | 0555, FisDir: true},
+	}
+	pt := &sysctlPassThrough{
+		nodes: map[string]string{
+			"/proc/sys/net/ipv4/conf/eth0/rp_filter":       "0\n",
+			"/proc/sys/net/ipv4/conf/eth0/tag":             "0\n",
+			"/proc/sys/net/ipv4/conf/all/route_localnet":   "0\n",
+			"/proc/sys/net/ipv4/conf/eth0/route_localnet":  "0\n",
+			"/proc/sys/net/ipv6/conf/eth0/hop_limit":       "64\n",
+			"/proc/sys/net/ipv6/conf/eth0/disable_ipv6":    "0\n",
+			"/proc/sys/net/ipv6/conf/eth0/accept_ra":       "1\n",
+			"/proc/sys/net/ipv6/conf/default/disable_ipv6": "0\n",
+			"/proc/sys/net/ipv6/conf/all/autoconf":         "1\n",
+		},
+		namespaced: map[string]bool{
+			"/proc/sys/net/ipv4/conf/eth0/rp_filter":       true,
+			"/proc/sys/net/ipv4/conf/eth0/tag":             true,
+			"/proc/sys/net/ipv4/conf/all/route_localnet":   true,
+			"/proc/sys/net/ipv4/conf/eth0/route_localnet":  true,
+			"/proc/sys/net/ipv6/conf/eth0/hop_limit":       true,
+			"/proc/sys/net/ipv6/conf/eth0/disable_ipv6":    true,
+			"/proc/sys/net/ipv6/conf/eth0/accept_ra":       true,
+			"/proc/sys/net/ipv6/conf/default/disable_ipv6": true,
+			"/proc/sys/net/ipv6/conf/all/autoconf":         true,
+		},
+		dirs: map[string][]os.FileInfo{
+			"/proc/sys/net/ipv4/conf": ifaceDirs,
+			"/proc/sys/net/ipv6/conf": ifaceDirs,
+		},
+	}
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("GetPassThroughHandler").Return(pt)
+
+	ipv4 := implementations.ProcSysNetIpv4Conf_Handler
+	ipv4.SetService(hs)
+	ipv6 := implementations.ProcSysNetIpv6Conf_Handler
+	ipv6.SetService(hs)
+
+	cntr := css.ContainerCreate(
+		"c-netconf",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	// Interface enumeration.
+	for _, h := range []domain.HandlerIface{ipv4, ipv6} {
+		req := &domain.HandlerRequest{Pid: 1001, Container: cntr}
+		n := ios.NewIOnode("conf", h.GetPath(), 0)
+		infos, err := h.ReadDirAll(n, req)
+		if err != nil {
+			t.Fatalf("ReadDirAll(%s) unexpected error: %v", h.GetPath(), err)
+		}
+		var entries []string
+		for _, info := range infos {
+			entries = append(entries, info.Name())
+		}
+		sort.Strings(entries)
+		want := []string{"all", "default", "eth0", "lo"}
+		if !reflect.DeepEqual(entries, want) {
+			t.Errorf("ReadDirAll(%s) = %v, want %v", h.GetPath(), entries, want)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		handler domain.HandlerIface
+		path    string
+		val     string
+		wantErr error
+		want    string
+	}{
+		// Test-case 1: Valid rp_filter value (loose mode).
+		{"1", ipv4, "/proc/sys/net/ipv4/conf/eth0/rp_filter", "2\n", nil, "2\n"},
+		// Test-case 2: Out of range rp_filter; previous value is kept.
+		{"2", ipv4, "/proc/sys/net/ipv4/conf/eth0/rp_filter", "3\n", fuse.IOerror{Code: syscall.EINVAL}, "2\n"},
+		// Test-case 3: Non-numeric rp_filter.
+		{"3", ipv4, "/proc/sys/net/ipv4/conf/eth0/rp_filter", "strict\n", fuse.IOerror{Code: syscall.EINVAL}, "2\n"},
+		// Test-case 4: Strict rp_filter.
+		{"4", ipv4, "/proc/sys/net/ipv4/conf/eth0/rp_filter", "1\n", nil, "1\n"},
+		// Test-case 5: Unknown key; left for the kernel to validate.
+		{"5", ipv4, "/proc/sys/net/ipv4/conf/eth0/tag", "99\n", nil, "99\n"},
+		// Test-case 6: Out of range ipv6 hop_limit.
+		{"6", ipv6, "/proc/sys/net/ipv6/conf/eth0/hop_limit", "0\n", fuse.IOerror{Code: syscall.EINVAL}, "64\n"},
+		// Test-case 7: Valid ipv6 hop_limit.
+		{"7", ipv6, "/proc/sys/net/ipv6/conf/eth0/hop_limit", "255\n", nil, "255\n"},
+		// Test-case 8: IPv6 disabled for interfaces created from now on.
+		{"8", ipv6, "/proc/sys/net/ipv6/conf/default/disable_ipv6", "1\n", nil, "1\n"},
+		// Test-case 9: Out of range default disable_ipv6.
+		{"9", ipv6, "/proc/sys/net/ipv6/conf/default/disable_ipv6", "2\n", fuse.IOerror{Code: syscall.EINVAL}, "1\n"},
+		// Test-case 10: IPv6 disabled on a given interface.
+		{"10", ipv6, "/proc/sys/net/ipv6/conf/eth0/disable_ipv6", "1\n", nil, "1\n"},
+		// Test-case 11: Negative per-interface disable_ipv6.
+		{"11", ipv6, "/proc/sys/net/ipv6/conf/eth0/disable_ipv6", "-1\n", fuse.IOerror{Code: syscall.EINVAL}, "1\n"},
+		// Test-case 12: Router advertisements accepted even with forwarding on.
+		{"12", ipv6, "/proc/sys/net/ipv6/conf/eth0/accept_ra", "2\n", nil, "2\n"},
+		// Test-case 13: Out of range accept_ra.
+		{"13", ipv6, "/proc/sys/net/ipv6/conf/eth0/accept_ra", "3\n", fuse.IOerror{Code: syscall.EINVAL}, "2\n"},
+		// Test-case 14: Autoconf disabled on all interfaces.
+		{"14", ipv6, "/proc/sys/net/ipv6/conf/all/autoconf", "0\n", nil, "0\n"},
+		// Test-case 15: Local addresses routed on all interfaces (e.g., for
+		// hairpin NAT to 127.0.0.0/8).
+		{"15", ipv4, "/proc/sys/net/ipv4/conf/all/route_localnet", "1\n", nil, "1\n"},
+		// Test-case 16: Out of range route_localnet.
+		{"16", ipv4, "/proc/sys/net/ipv4/conf/all/route_localnet", "2\n", fuse.IOerror{Code: syscall.EINVAL}, "1\n"},
+		// Test-case 17: Non-numeric route_localnet.
+		{"17", ipv4, "/proc/sys/net/ipv4/conf/all/route_localnet", "on\n", fuse.IOerror{Code: syscall.EINVAL}, "1\n"},
+		// Test-case 18: Per-interface route_localnet.
+		{"18", ipv4, "/proc/sys/net/ipv4/conf/eth0/route_localnet", "1\n", nil, "1\n"},
+		// Test-case 19: Per-interface route_localnet reset.
+		{"19", ipv4, "/proc/sys/net/ipv4/conf/eth0/route_localnet", "0\n", nil, "0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := tt.handler
+			n := ios.NewIOnode(filepath.Base(tt.path), tt.path, 0)
+
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      []byte(tt.val),
+			}
+			if _, err := h.Write(n, req); err != tt.wantErr {
+				t.Fatalf("Write(%s, %q) error = %v, want %v", tt.path, tt.val, err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !req.NoCache {
+				t.Errorf("Write(%s) must not be cached", tt.path)
+			}
+
+			req = &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      make([]byte, 16),
+			}
+			sz, err := h.Read(n, req)
+			if err != nil {
+				t.Fatalf("Read(%s) unexpected error: %v", tt.path, err)
+			}
+			if got := string(req.Data[:sz]); got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", tt.path, got, tt.want)
+			}
+			if !req.NoCache {
+				t.Errorf("Read(%s) must not be cached", tt.path)
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@
 package implementations
 
 import (
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
@@ -65,6 +66,26 @@ import (
 // Description: Limit of socket listen() backlog, known in userspace as SOMAXCONN.
 // Somaxconn refers to the maximum number of clients that the server can accept
 // to process data, that is, to complete the connection limit. Defaults to 128.
+//
+// * /proc/sys/net/core/rmem_default
+// * /proc/sys/net/core/rmem_max
+// * /proc/sys/net/core/wmem_default
+// * /proc/sys/net/core/wmem_max
+//
+// Description: Default and maximum sizes (in bytes) of the socket receive and
+// send buffers.
+//
+// These nodes are accessed within the container's net-ns, so writes apply to
+// that net-ns only (and are cached per container by the passthrough handler).
+// However, not all kernels scope these sysctls per net-ns, in which case the
+// nodes are only present in the host's (init) net-ns. For those, the values
+// are emulated at sys-container level (seeded from the host's values), and
+// the host FS values are left untouched.
+const (
+	minSockBufSizeVal = 1
+	maxSockBufSizeVal = math.MaxInt32
+)
+
 type ProcSysNetCore struct {
 	domain.HandlerBase
 }
@@ -87,6 +108,30 @@ var ProcSysNetCore_Handler = &ProcSysNetCore{
 				Enabled: true,
 				Size:    1024,
 			},
+			"rmem_default": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"rmem_max": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"wmem_default": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"wmem_max": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
 		},
 	},
 }
@@ -143,6 +188,9 @@ func (h *ProcSysNetCore) Read(
 
 	case "somaxconn":
 		return readCntrData(h, n, req)
+
+	case "rmem_default", "rmem_max", "wmem_default", "wmem_max":
+		return h.readSockBufSize(n, req)
 	}
 
 	// Refer to generic handler if no node match is found above.
@@ -164,6 +212,9 @@ func (h *ProcSysNetCore) Write(
 
 	case "somaxconn":
 		return writeCntrData(h, n, req, writeMaxIntToFs)
+
+	case "rmem_default", "rmem_max", "wmem_default", "wmem_max":
+		return h.writeSockBufSize(n, req)
 	}
 
 	// Refer to generic handler if no node match is found above.
@@ -281,3 +332,37 @@ func (h *ProcSysNetCore) writeDefaultQdisc(
 
 	return writeCntrData(h, n, req, writeToFs)
 }
+
+// readSockBufSize reads the given socket-buffer sysctl from the container's
+// net-ns, falling back to the container-level emulated value if the node is
+// not scoped per net-ns.
+func (h *ProcSysNetCore) readSockBufSize(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	sz, err := h.Service.GetPassThroughHandler().Read(n, req)
+	if !isNotExistError(err) {
+		return sz, err
+	}
+
+	return readCntrData(h, n, req)
+}
+
+// writeSockBufSize writes the given socket-buffer sysctl into the container's
+// net-ns, falling back to the container-level emulated value if the node is
+// not scoped per net-ns.
+func (h *ProcSysNetCore) writeSockBufSize(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	if !checkIntRange(req.Data, minSockBufSizeVal, maxSockBufSizeVal) {
+		return 0, fuse.IOerror{Code: syscall.EINVAL}
+	}
+
+	sz, err := h.Service.GetPassThroughHandler().Write(n, req)
+	if !isNotExistError(err) {
+		return sz, err
+	}
+
+	return writeCntrData(h, n, req, nil)
+}
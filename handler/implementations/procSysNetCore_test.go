@@ -0,0 +1,253 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+// netnsPassThrough is a passthrough-handler fake that stands for the
+// container's net-ns. If 'nodes' is nil, the net-ns does not expose any node
+// (i.e., sysctls not scoped per net-ns).
+type netnsPassThrough struct {
+	domain.PassthroughHandlerIface
+	nodes  map[string]string
+	writes int
+}
+
+func (p *netnsPassThrough) Read(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	val, ok := p.nodes[n.Path()]
+	if !ok {
+		return 0, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	return copy(req.Data, val), nil
+}
+
+func (p *netnsPassThrough) Write(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	p.writes++
+
+	if _, ok := p.nodes[n.Path()]; !ok {
+		return 0, fuse.IOerror{Code: syscall.ENOENT}
+	}
+	p.nodes[n.Path()] = string(req.Data)
+
+	return len(req.Data), nil
+}
+
+func TestProcSysNetCore_SockBufSize(t *testing.T) {
+
+	resources := []string{"rmem_default", "rmem_max", "wmem_default", "wmem_max"}
+
+	hostVals := map[string]string{
+		"/proc/sys/net/core/rmem_default": "212992\n",
+		"/proc/sys/net/core/rmem_max":     "212992\n",
+		"/proc/sys/net/core/wmem_default": "212992\n",
+		"/proc/sys/net/core/wmem_max":     "212992\n",
+	}
+	for path, val := range hostVals {
+		if err := ios.NewIOnode("", path, 0644).WriteFile([]byte(val)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer ios.RemoveAllIOnodes()
+
+	tests := []struct {
+		name  string
+		nodes map[string]string
+		want  string // value prior to any write
+	}{
+		// Sysctls scoped per net-ns.
+		{
+			name: "netns",
+			nodes: map[string]string{
+				"/proc/sys/net/core/rmem_default": "131072\n",
+				"/proc/sys/net/core/rmem_max":     "131072\n",
+				"/proc/sys/net/core/wmem_default": "131072\n",
+				"/proc/sys/net/core/wmem_max":     "131072\n",
+			},
+			want: "131072\n",
+		},
+		// Sysctls only present in the init net-ns; emulated values are
+		// seeded from the host.
+		{
+			name:  "emulated",
+			nodes: nil,
+			want:  "212992\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt := &netnsPassThrough{nodes: tt.nodes}
+
+			hs := &mocks.HandlerServiceIface{}
+			hs.On("GetPassThroughHandler").Return(pt)
+			hs.On("IgnoreErrors").Return(false)
+
+			h := &implementations.ProcSysNetCore{
+				HandlerBase: domain.HandlerBase{
+					Name:           "ProcSysNetCore",
+					Path:           "/proc/sys/net/core",
+					EmuResourceMap: implementations.ProcSysNetCore_Handler.EmuResourceMap,
+					Service:        hs,
+				},
+			}
+
+			cntr := css.ContainerCreate(
+				"c-"+tt.name,
+				uint32(1001),
+				time.Time{},
+				231072,
+				65535,
+				231072,
+				65535,
+				nil,
+				nil,
+				nil,
+			)
+
+			read := func(resource string) string {
+				req := &domain.HandlerRequest{
+					Pid:       1001,
+					Container: cntr,
+					Data:      make([]byte, 64),
+				}
+				n := ios.NewIOnode(resource, "/proc/sys/net/core/"+resource, 0)
+				sz, err := h.Read(n, req)
+				if err != nil {
+					t.Fatalf("Read(%s) unexpected error: %v", resource, err)
+				}
+				return string(req.Data[:sz])
+			}
+
+			write := func(resource, val string) error {
+				req := &domain.HandlerRequest{
+					Pid:       1001,
+					Container: cntr,
+					Data:      []byte(val),
+				}
+				n := ios.NewIOnode(resource, "/proc/sys/net/core/"+resource, 0)
+				_, err := h.Write(n, req)
+				return err
+			}
+
+			for _, resource := range resources {
+				// Nodes must be displayed as writable.
+				n := ios.NewIOnode(resource, "/proc/sys/net/core/"+resource, 0)
+				req := &domain.HandlerRequest{Pid: 1001, Container: cntr}
+				info, err := h.Lookup(n, req)
+				if err != nil {
+					t.Fatalf("Lookup(%s) unexpected error: %v", resource, err)
+				}
+				if info.Mode() != os.FileMode(0644) {
+					t.Errorf("Lookup(%s) mode = %v, want %v",
+						resource, info.Mode(), os.FileMode(0644))
+				}
+
+				if got := read(resource); got != tt.want {
+					t.Errorf("Read(%s) = %q, want %q", resource, got, tt.want)
+				}
+
+				if err := write(resource, "4194304\n"); err != nil {
+					t.Fatalf("Write(%s) unexpected error: %v", resource, err)
+				}
+				if got := read(resource); got != "4194304\n" {
+					t.Errorf("Read(%s) = %q, want %q", resource, got, "4194304\n")
+				}
+			}
+
+			// Container writes must not be pushed to the host.
+			for path, want := range hostVals {
+				got, err := ios.NewIOnode("", path, 0).ReadFile()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != want {
+					t.Errorf("host %s = %q, want %q", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProcSysNetCore_SockBufSizeInvalid(t *testing.T) {
+
+	pt := &netnsPassThrough{
+		nodes: map[string]string{"/proc/sys/net/core/rmem_max": "131072\n"},
+	}
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("GetPassThroughHandler").Return(pt)
+	hs.On("IgnoreErrors").Return(false)
+
+	h := &implementations.ProcSysNetCore{
+		HandlerBase: domain.HandlerBase{
+			Name:           "ProcSysNetCore",
+			Path:           "/proc/sys/net/core",
+			EmuResourceMap: implementations.ProcSysNetCore_Handler.EmuResourceMap,
+			Service:        hs,
+		},
+	}
+
+	cntr := css.ContainerCreate(
+		"c-invalid",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	for _, val := range []string{"big\n", "4M\n", "0\n", "-1\n", "\n"} {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      []byte(val),
+		}
+		n := ios.NewIOnode("rmem_max", "/proc/sys/net/core/rmem_max", 0)
+		if _, err := h.Write(n, req); err != (fuse.IOerror{Code: syscall.EINVAL}) {
+			t.Errorf("Write(rmem_max, %q) error = %v, want EINVAL", val, err)
+		}
+	}
+
+	// Invalid values must never reach the container's net-ns.
+	if pt.writes != 0 {
+		t.Errorf("unexpected net-ns writes: %d", pt.writes)
+	}
+	if got := pt.nodes["/proc/sys/net/core/rmem_max"]; got != "131072\n" {
+		t.Errorf("net-ns rmem_max = %q, want %q", got, "131072\n")
+	}
+}
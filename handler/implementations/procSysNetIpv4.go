@@ -40,6 +40,64 @@ import (
 // Emulated resources:
 //
 // * /proc/sys/net/ipv4/ping_group_range
+//
+// * /proc/sys/net/ipv4/tcp_syncookies
+// * /proc/sys/net/ipv4/tcp_max_syn_backlog
+//
+// * /proc/sys/net/ipv4/ip_unprivileged_port_start
+//
+// * /proc/sys/net/ipv4/tcp_keepalive_time
+// * /proc/sys/net/ipv4/tcp_keepalive_intvl
+// * /proc/sys/net/ipv4/tcp_keepalive_probes
+//
+// These are namespaced via the net namespace, so accesses are carried out
+// within the container's namespaces, and are only exposed where the kernel
+// does so (i.e., lookups are served by the passthrough handler). Written
+// values are checked against the accepted ranges before reaching the kernel,
+// which would otherwise take any integer as the syn backlog. As different
+// processes of the container may sit in different net namespaces, the tcp_*
+// ones are never cached.
+//
+// ip_unprivileged_port_start is commonly lowered to let unprivileged services
+// bind to ports such as 80 or 443. Its value is cached per container (for
+// processes at the sys container level), as done by the passthrough handler,
+// since it's rarely changed after the container's initialization.
+//
+// The tcp_keepalive_* triplet is tuned by services holding long-lived
+// connections, and is cached alike. Written values must be positive and
+// within the bounds the kernel enforces on their per-socket counterparts
+// (TCP_KEEPIDLE, TCP_KEEPINTVL and TCP_KEEPCNT).
+//
+// * /proc/sys/net/ipv4/tcp_rmem
+// * /proc/sys/net/ipv4/tcp_wmem
+//
+// The "min default max" sizes of the TCP receive / send buffers, as set by
+// network-tuning software. They're cached per container alike. Written values
+// must hold three positive integers, in ascending order (the kernel would take
+// any ordering, leaving sockets with buffers larger than the intended max).
+
+const (
+	minTcpSyncookiesVal = 0
+	maxTcpSyncookiesVal = 2
+
+	minTcpMaxSynBacklogVal = 1
+	maxTcpMaxSynBacklogVal = math.MaxInt32
+
+	minIpUnprivPortStartVal = 0
+	maxIpUnprivPortStartVal = 65535
+
+	minTcpKeepaliveTimeVal = 1
+	maxTcpKeepaliveTimeVal = 32767
+
+	minTcpKeepaliveIntvlVal = 1
+	maxTcpKeepaliveIntvlVal = 32767
+
+	minTcpKeepaliveProbesVal = 1
+	maxTcpKeepaliveProbesVal = 127
+
+	minTcpMemVal = 1
+	maxTcpMemVal = math.MaxInt32
+)
 
 type ProcSysNetIpv4 struct {
 	domain.HandlerBase
@@ -102,6 +160,11 @@ func (h *ProcSysNetIpv4) Read(
 	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
 		req.ID, h.Name, resource)
 
+	switch resource {
+	case "tcp_syncookies", "tcp_max_syn_backlog":
+		req.NoCache = true
+	}
+
 	return h.Service.GetPassThroughHandler().Read(n, req)
 }
 
@@ -117,6 +180,43 @@ func (h *ProcSysNetIpv4) Write(
 	switch resource {
 	case "ping_group_range":
 		return h.writePingGroupRange(n, req)
+
+	case "tcp_syncookies":
+		if !checkIntRange(req.Data, minTcpSyncookiesVal, maxTcpSyncookiesVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		req.NoCache = true
+
+	case "tcp_max_syn_backlog":
+		if !checkIntRange(req.Data, minTcpMaxSynBacklogVal, maxTcpMaxSynBacklogVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		req.NoCache = true
+
+	case "ip_unprivileged_port_start":
+		if !checkIntRange(req.Data, minIpUnprivPortStartVal, maxIpUnprivPortStartVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+
+	case "tcp_keepalive_time":
+		if !checkIntRange(req.Data, minTcpKeepaliveTimeVal, maxTcpKeepaliveTimeVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+
+	case "tcp_keepalive_intvl":
+		if !checkIntRange(req.Data, minTcpKeepaliveIntvlVal, maxTcpKeepaliveIntvlVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+
+	case "tcp_keepalive_probes":
+		if !checkIntRange(req.Data, minTcpKeepaliveProbesVal, maxTcpKeepaliveProbesVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+
+	case "tcp_rmem", "tcp_wmem":
+		if !checkTcpMemTriplet(req.Data) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
 	}
 
 	// Refer to generic handler if no node match is found above.
@@ -279,3 +379,24 @@ func (h *ProcSysNetIpv4) writePingGroupRange(
 
 	return origDataLength, nil
 }
+
+// checkTcpMemTriplet returns true if the given data holds a valid "min default
+// max" triplet of tcp_rmem / tcp_wmem.
+func checkTcpMemTriplet(data []byte) bool {
+
+	fields := strings.Fields(string(data))
+	if len(fields) != 3 {
+		return false
+	}
+
+	prev := minTcpMemVal
+	for _, field := range fields {
+		val, err := strconv.Atoi(field)
+		if err != nil || val < prev || val > maxTcpMemVal {
+			return false
+		}
+		prev = val
+	}
+
+	return true
+}
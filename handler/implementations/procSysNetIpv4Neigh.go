@@ -17,7 +17,6 @@
 package implementations
 
 import (
-	"io"
 	"math"
 	"os"
 	"path/filepath"
@@ -137,13 +136,7 @@ func (h *ProcSysNetIpv4Neigh) Read(
 	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
 		req.ID, h.Name, n.Name())
 
-	// We are dealing with a single boolean element being read, so we can save
-	// some cycles by returning right away if offset is any higher than zero.
-	if req.Offset > 0 {
-		return 0, io.EOF
-	}
-
-	// Obtain relative path to the element being written.
+	// Obtain relative path to the element being read.
 	relPath, err := filepath.Rel(h.Path, n.Path())
 	if err != nil {
 		return 0, err
@@ -225,26 +218,23 @@ func (h *ProcSysNetIpv4Neigh) ReadDirAll(
 	}
 
 	// Iterate through map of virtual components.
-	for k, _ := range h.EmuResourceMap {
-
-		if relpath == filepath.Dir(k) {
-			info = &domain.FileInfo{
-				Fname:    filepath.Base(k),
-				Fmode:    os.ModeDir,
-				FmodTime: time.Now(),
-				FisDir:   true,
-			}
+	for k, v := range h.EmuResourceMap {
 
-			fileEntries = append(fileEntries, info)
+		if relpath != filepath.Dir(k) {
+			continue
+		}
 
-		} else if relpath != "." && relpath == filepath.Dir(k) {
-			info = &domain.FileInfo{
-				Fname:    filepath.Base(k),
-				FmodTime: time.Now(),
-			}
+		info = &domain.FileInfo{
+			Fname:    filepath.Base(k),
+			Fmode:    v.Mode,
+			FmodTime: time.Now(),
+		}
 
-			fileEntries = append(fileEntries, info)
+		if v.Kind == domain.DirEmuResource {
+			info.FisDir = true
 		}
+
+		fileEntries = append(fileEntries, info)
 	}
 
 	// Obtain the usual entries seen within container's namespaces and add them
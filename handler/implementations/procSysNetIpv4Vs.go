@@ -201,9 +201,10 @@ func (h *ProcSysNetIpv4Vs) ReadDirAll(
 	var fileEntries []os.FileInfo
 
 	// Iterate through map of virtual components.
-	for k, _ := range h.EmuResourceMap {
+	for k, v := range h.EmuResourceMap {
 		info := &domain.FileInfo{
 			Fname:    k,
+			Fmode:    v.Mode,
 			FmodTime: time.Now(),
 		}
 
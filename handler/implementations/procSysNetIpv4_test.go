@@ -0,0 +1,453 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+func TestProcSysNetIpv4_TcpSyn(t *testing.T) {
+
+	// Container's net-ns values.
+	pt := &sysctlPassThrough{
+		nodes: map[string]string{
+			"/proc/sys/net/ipv4/tcp_syncookies":      "1\n",
+			"/proc/sys/net/ipv4/tcp_max_syn_backlog": "512\n",
+		},
+		namespaced: map[string]bool{
+			"/proc/sys/net/ipv4/tcp_syncookies":      true,
+			"/proc/sys/net/ipv4/tcp_max_syn_backlog": true,
+		},
+	}
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("GetPassThroughHandler").Return(pt)
+
+	h := implementations.ProcSysNetIpv4_Handler
+	h.SetService(hs)
+
+	cntr := css.ContainerCreate(
+		"c-tcp-syn",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	read := func(t *testing.T, path string) string {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 32),
+		}
+		sz, err := h.Read(ios.NewIOnode(filepath.Base(path), path, 0), req)
+		if err != nil {
+			t.Fatalf("Read(%s) unexpected error: %v", path, err)
+		}
+		if !req.NoCache {
+			t.Errorf("Read(%s) must not be cached", path)
+		}
+		return string(req.Data[:sz])
+	}
+
+	// Reads reflect the net-ns values.
+	for path, want := range pt.nodes {
+		if got := read(t, path); got != want {
+			t.Errorf("Read(%s) = %q, want %q", path, got, want)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		val     string
+		wantErr error
+		want    string
+	}{
+		// Test-case 1: Syncookies always on.
+		{"1", "/proc/sys/net/ipv4/tcp_syncookies", "2\n", nil, "2\n"},
+		// Test-case 2: Syncookies off.
+		{"2", "/proc/sys/net/ipv4/tcp_syncookies", "0\n", nil, "0\n"},
+		// Test-case 3: Out of range syncookies; previous value is kept.
+		{"3", "/proc/sys/net/ipv4/tcp_syncookies", "3\n", fuse.IOerror{Code: syscall.EINVAL}, "0\n"},
+		// Test-case 4: Negative syncookies.
+		{"4", "/proc/sys/net/ipv4/tcp_syncookies", "-1\n", fuse.IOerror{Code: syscall.EINVAL}, "0\n"},
+		// Test-case 5: Valid backlog.
+		{"5", "/proc/sys/net/ipv4/tcp_max_syn_backlog", "4096\n", nil, "4096\n"},
+		// Test-case 6: Zero backlog.
+		{"6", "/proc/sys/net/ipv4/tcp_max_syn_backlog", "0\n", fuse.IOerror{Code: syscall.EINVAL}, "4096\n"},
+		// Test-case 7: Out of range backlog.
+		{"7", "/proc/sys/net/ipv4/tcp_max_syn_backlog", "2147483648\n", fuse.IOerror{Code: syscall.EINVAL}, "4096\n"},
+		// Test-case 8: Non-numeric backlog.
+		{"8", "/proc/sys/net/ipv4/tcp_max_syn_backlog", "max\n", fuse.IOerror{Code: syscall.EINVAL}, "4096\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := ios.NewIOnode(filepath.Base(tt.path), tt.path, 0)
+
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      []byte(tt.val),
+			}
+			if _, err := h.Write(n, req); err != tt.wantErr {
+				t.Fatalf("Write(%s, %q) error = %v, want %v", tt.path, tt.val, err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !req.NoCache {
+				t.Errorf("Write(%s) must not be cached", tt.path)
+			}
+
+			if got := read(t, tt.path); got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcSysNetIpv4_UnprivPortStart(t *testing.T) {
+
+	const path = "/proc/sys/net/ipv4/ip_unprivileged_port_start"
+
+	// Container's net-ns value.
+	pt := &sysctlPassThrough{
+		nodes:      map[string]string{path: "1024\n"},
+		namespaced: map[string]bool{path: true},
+	}
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("GetPassThroughHandler").Return(pt)
+
+	h := implementations.ProcSysNetIpv4_Handler
+	h.SetService(hs)
+
+	cntr := css.ContainerCreate(
+		"c-unpriv-port",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	n := ios.NewIOnode(filepath.Base(path), path, 0)
+
+	read := func(t *testing.T) string {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 32),
+		}
+		sz, err := h.Read(n, req)
+		if err != nil {
+			t.Fatalf("Read(%s) unexpected error: %v", path, err)
+		}
+		return string(req.Data[:sz])
+	}
+
+	// Reads reflect the net-ns value.
+	if got := read(t); got != "1024\n" {
+		t.Errorf("Read(%s) = %q, want %q", path, got, "1024\n")
+	}
+
+	tests := []struct {
+		name       string
+		val        string
+		wantErr    error
+		wantWrites int // writes routed into the container's net-ns
+		want       string
+	}{
+		// Test-case 1: Unprivileged http port.
+		{"1", "80\n", nil, 1, "80\n"},
+		// Test-case 2: Lower boundary.
+		{"2", "0\n", nil, 1, "0\n"},
+		// Test-case 3: Upper boundary.
+		{"3", "65535\n", nil, 1, "65535\n"},
+		// Test-case 4: Out of range port; previous value is kept.
+		{"4", "65536\n", fuse.IOerror{Code: syscall.EINVAL}, 0, "65535\n"},
+		// Test-case 5: Negative port.
+		{"5", "-1\n", fuse.IOerror{Code: syscall.EINVAL}, 0, "65535\n"},
+		// Test-case 6: Non-numeric port.
+		{"6", "http\n", fuse.IOerror{Code: syscall.EINVAL}, 0, "65535\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt.writes = 0
+
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      []byte(tt.val),
+			}
+			if _, err := h.Write(n, req); err != tt.wantErr {
+				t.Fatalf("Write(%s, %q) error = %v, want %v", path, tt.val, err, tt.wantErr)
+			}
+			if pt.writes != tt.wantWrites {
+				t.Errorf("Write(%s, %q) reached the net-ns %d times, want %d",
+					path, tt.val, pt.writes, tt.wantWrites)
+			}
+
+			if got := read(t); got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcSysNetIpv4_TcpKeepalive(t *testing.T) {
+
+	// Container's net-ns values.
+	pt := &sysctlPassThrough{
+		nodes: map[string]string{
+			"/proc/sys/net/ipv4/tcp_keepalive_time":   "7200\n",
+			"/proc/sys/net/ipv4/tcp_keepalive_intvl":  "75\n",
+			"/proc/sys/net/ipv4/tcp_keepalive_probes": "9\n",
+		},
+		namespaced: map[string]bool{
+			"/proc/sys/net/ipv4/tcp_keepalive_time":   true,
+			"/proc/sys/net/ipv4/tcp_keepalive_intvl":  true,
+			"/proc/sys/net/ipv4/tcp_keepalive_probes": true,
+		},
+	}
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("GetPassThroughHandler").Return(pt)
+
+	h := implementations.ProcSysNetIpv4_Handler
+	h.SetService(hs)
+
+	cntr := css.ContainerCreate(
+		"c-tcp-keepalive",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	read := func(t *testing.T, path string) string {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 32),
+		}
+		sz, err := h.Read(ios.NewIOnode(filepath.Base(path), path, 0), req)
+		if err != nil {
+			t.Fatalf("Read(%s) unexpected error: %v", path, err)
+		}
+		return string(req.Data[:sz])
+	}
+
+	// Reads reflect the net-ns values.
+	for path, want := range pt.nodes {
+		if got := read(t, path); got != want {
+			t.Errorf("Read(%s) = %q, want %q", path, got, want)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		val        string
+		wantErr    error
+		wantWrites int // writes routed into the container's net-ns
+		want       string
+	}{
+		// Test-case 1: Valid keepalive time.
+		{"1", "/proc/sys/net/ipv4/tcp_keepalive_time", "600\n", nil, 1, "600\n"},
+		// Test-case 2: Zero keepalive time; previous value is kept.
+		{"2", "/proc/sys/net/ipv4/tcp_keepalive_time", "0\n", fuse.IOerror{Code: syscall.EINVAL}, 0, "600\n"},
+		// Test-case 3: Out of range keepalive time.
+		{"3", "/proc/sys/net/ipv4/tcp_keepalive_time", "32768\n", fuse.IOerror{Code: syscall.EINVAL}, 0, "600\n"},
+		// Test-case 4: Valid keepalive interval.
+		{"4", "/proc/sys/net/ipv4/tcp_keepalive_intvl", "30\n", nil, 1, "30\n"},
+		// Test-case 5: Negative keepalive interval.
+		{"5", "/proc/sys/net/ipv4/tcp_keepalive_intvl", "-30\n", fuse.IOerror{Code: syscall.EINVAL}, 0, "30\n"},
+		// Test-case 6: Non-numeric keepalive interval.
+		{"6", "/proc/sys/net/ipv4/tcp_keepalive_intvl", "30s\n", fuse.IOerror{Code: syscall.EINVAL}, 0, "30\n"},
+		// Test-case 7: Upper boundary of keepalive probes.
+		{"7", "/proc/sys/net/ipv4/tcp_keepalive_probes", "127\n", nil, 1, "127\n"},
+		// Test-case 8: Out of range keepalive probes.
+		{"8", "/proc/sys/net/ipv4/tcp_keepalive_probes", "128\n", fuse.IOerror{Code: syscall.EINVAL}, 0, "127\n"},
+		// Test-case 9: Zero keepalive probes.
+		{"9", "/proc/sys/net/ipv4/tcp_keepalive_probes", "0\n", fuse.IOerror{Code: syscall.EINVAL}, 0, "127\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt.writes = 0
+
+			n := ios.NewIOnode(filepath.Base(tt.path), tt.path, 0)
+
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      []byte(tt.val),
+			}
+			if _, err := h.Write(n, req); err != tt.wantErr {
+				t.Fatalf("Write(%s, %q) error = %v, want %v", tt.path, tt.val, err, tt.wantErr)
+			}
+			if pt.writes != tt.wantWrites {
+				t.Errorf("Write(%s, %q) reached the net-ns %d times, want %d",
+					tt.path, tt.val, pt.writes, tt.wantWrites)
+			}
+			if tt.wantErr == nil && req.NoCache {
+				t.Errorf("Write(%s) must be cached", tt.path)
+			}
+
+			if got := read(t, tt.path); got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcSysNetIpv4_TcpMem(t *testing.T) {
+
+	// Container's net-ns values.
+	pt := &sysctlPassThrough{
+		nodes: map[string]string{
+			"/proc/sys/net/ipv4/tcp_rmem": "4096\t131072\t6291456\n",
+			"/proc/sys/net/ipv4/tcp_wmem": "4096\t16384\t4194304\n",
+		},
+		namespaced: map[string]bool{
+			"/proc/sys/net/ipv4/tcp_rmem": true,
+			"/proc/sys/net/ipv4/tcp_wmem": true,
+		},
+	}
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("GetPassThroughHandler").Return(pt)
+
+	h := implementations.ProcSysNetIpv4_Handler
+	h.SetService(hs)
+
+	cntr := css.ContainerCreate(
+		"c-tcp-mem",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	read := func(t *testing.T, path string) string {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 64),
+		}
+		sz, err := h.Read(ios.NewIOnode(filepath.Base(path), path, 0), req)
+		if err != nil {
+			t.Fatalf("Read(%s) unexpected error: %v", path, err)
+		}
+		return string(req.Data[:sz])
+	}
+
+	// Reads reflect the net-ns values.
+	for path, want := range pt.nodes {
+		if got := read(t, path); got != want {
+			t.Errorf("Read(%s) = %q, want %q", path, got, want)
+		}
+	}
+
+	einval := fuse.IOerror{Code: syscall.EINVAL}
+
+	tests := []struct {
+		name       string
+		path       string
+		val        string
+		wantErr    error
+		wantWrites int // writes routed into the container's net-ns
+		want       string
+	}{
+		// Test-case 1: Valid receive buffers.
+		{"1", "/proc/sys/net/ipv4/tcp_rmem", "8192 262144 16777216\n", nil, 1, "8192 262144 16777216\n"},
+		// Test-case 2: Equal sizes.
+		{"2", "/proc/sys/net/ipv4/tcp_rmem", "65536\t65536\t65536\n", nil, 1, "65536\t65536\t65536\n"},
+		// Test-case 3: Default below min; previous value is kept.
+		{"3", "/proc/sys/net/ipv4/tcp_rmem", "8192 4096 16777216\n", einval, 0, "65536\t65536\t65536\n"},
+		// Test-case 4: Max below default.
+		{"4", "/proc/sys/net/ipv4/tcp_rmem", "4096 87380 65536\n", einval, 0, "65536\t65536\t65536\n"},
+		// Test-case 5: Too few fields.
+		{"5", "/proc/sys/net/ipv4/tcp_rmem", "4096 87380\n", einval, 0, "65536\t65536\t65536\n"},
+		// Test-case 6: Too many fields.
+		{"6", "/proc/sys/net/ipv4/tcp_rmem", "4096 87380 6291456 1\n", einval, 0, "65536\t65536\t65536\n"},
+		// Test-case 7: Valid send buffers.
+		{"7", "/proc/sys/net/ipv4/tcp_wmem", "4096 65536 16777216\n", nil, 1, "4096 65536 16777216\n"},
+		// Test-case 8: Zero min.
+		{"8", "/proc/sys/net/ipv4/tcp_wmem", "0 65536 16777216\n", einval, 0, "4096 65536 16777216\n"},
+		// Test-case 9: Out of range max.
+		{"9", "/proc/sys/net/ipv4/tcp_wmem", "4096 65536 2147483648\n", einval, 0, "4096 65536 16777216\n"},
+		// Test-case 10: Non-numeric field.
+		{"10", "/proc/sys/net/ipv4/tcp_wmem", "4096 64k 16777216\n", einval, 0, "4096 65536 16777216\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt.writes = 0
+
+			n := ios.NewIOnode(filepath.Base(tt.path), tt.path, 0)
+
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      []byte(tt.val),
+			}
+			if _, err := h.Write(n, req); err != tt.wantErr {
+				t.Fatalf("Write(%s, %q) error = %v, want %v", tt.path, tt.val, err, tt.wantErr)
+			}
+			if pt.writes != tt.wantWrites {
+				t.Errorf("Write(%s, %q) reached the net-ns %d times, want %d",
+					tt.path, tt.val, pt.writes, tt.wantWrites)
+			}
+			if tt.wantErr == nil && req.NoCache {
+				t.Errorf("Write(%s) must be cached", tt.path)
+			}
+
+			if got := read(t, tt.path); got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
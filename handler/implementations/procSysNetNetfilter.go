@@ -17,16 +17,19 @@
 package implementations
 
 import (
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
 )
 
 //
@@ -36,16 +39,18 @@ import (
 //
 // * /proc/sys/net/netfilter/nf_conntrack_max
 //
-// * /proc/sys/net/netfilter/nf_conntrack_generic_timeout
-//
-// * /proc/sys/net/netfilter/nf_conntrack_tcp_timeout_established
-//
-// * /proc/sys/net/netfilter/nf_conntrack_tcp_timeout_close_wait
+// * /proc/sys/net/netfilter/nf_conntrack_buckets
 //
 // * /proc/sys/net/netfilter/nf_conntrack_tcp_be_liberal
 //
 // Documentation: https://www.kernel.org/doc/Documentation/networking/nf_conntrack-sysctl.txt
 //
+// nf_conntrack_max and nf_conntrack_buckets size the conntrack table, which is
+// shared by all the net namespaces (the kernel only allows them to be changed
+// from the initial one). Values written within the sys container are kept at
+// sys-container level, and only pushed to the host when they exceed the
+// host's ones (i.e., the host's table can only grow).
+//
 // nf_conntrack_tcp_be_liberal - BOOLEAN
 // 	0 - disabled (default)
 // 	not 0 - enabled
@@ -56,10 +61,29 @@ import (
 // Taking into account that kernel's netfilter can either operate in one mode or
 // the other, we opt for letting the liberal mode prevail if set within any sys-container.
 //
+// The remaining nodes (e.g., nf_conntrack_tcp_timeout_established,
+// nf_conntrack_generic_timeout) are scoped to the net namespace, so they are
+// accessed within the namespaces of the sys container (all but the user-ns,
+// as the kernel only allows writes from the user-ns owning the initial net-ns
+// for some of them). Values written to nodes directly under this directory
+// are checked to be non-negative ints, as all of them are.
+//
+// Emulated nodes are only exposed when present in the host (i.e., when the
+// nf_conntrack module is loaded).
+//
 
 const (
 	tcpLiberalOff = 0
 	tcpLiberalOn  = 1
+
+	minNfConntrackMaxVal = 0
+	maxNfConntrackMaxVal = math.MaxInt32
+
+	minNfConntrackBucketsVal = 1
+	maxNfConntrackBucketsVal = math.MaxInt32
+
+	minNetfilterVal = 0
+	maxNetfilterVal = math.MaxInt32
 )
 
 type ProcSysNetNetfilter struct {
@@ -78,7 +102,7 @@ var ProcSysNetNetfilter_Handler = &ProcSysNetNetfilter{
 				Enabled: true,
 				Size:    1024,
 			},
-			"nf_conntrack_generic_timeout": {
+			"nf_conntrack_buckets": {
 				Kind:    domain.FileEmuResource,
 				Mode:    os.FileMode(uint32(0644)),
 				Enabled: true,
@@ -90,18 +114,6 @@ var ProcSysNetNetfilter_Handler = &ProcSysNetNetfilter{
 				Enabled: true,
 				Size:    2,
 			},
-			"nf_conntrack_tcp_timeout_established": {
-				Kind:    domain.FileEmuResource,
-				Mode:    os.FileMode(uint32(0644)),
-				Enabled: true,
-				Size:    1024,
-			},
-			"nf_conntrack_tcp_timeout_close_wait": {
-				Kind:    domain.FileEmuResource,
-				Mode:    os.FileMode(uint32(0644)),
-				Enabled: true,
-				Size:    1024,
-			},
 		},
 	},
 }
@@ -116,8 +128,12 @@ func (h *ProcSysNetNetfilter) Lookup(
 		req.ID, h.Name, resource)
 
 	// Return an artificial fileInfo if looked-up element matches any of the
-	// virtual-components.
+	// virtual-components (and it's present in the host).
 	if v, ok := h.EmuResourceMap[resource]; ok {
+		if _, err := n.Stat(); err != nil {
+			return nil, fuse.IOerror{Code: syscall.ENOENT}
+		}
+
 		info := &domain.FileInfo{
 			Fname:    resource,
 			Fmode:    v.Mode,
@@ -137,9 +153,16 @@ func (h *ProcSysNetNetfilter) Open(
 	n domain.IOnodeIface,
 	req *domain.HandlerRequest) (bool, error) {
 
-	logrus.Debugf("Executing %v Open() method\n", h.Name)
+	var resource = n.Name()
+
+	logrus.Debugf("Executing Open() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, resource)
+
+	if _, ok := h.EmuResourceMap[resource]; ok {
+		return false, nil
+	}
 
-	return false, nil
+	return h.Service.GetPassThroughHandler().OpenWithNS(n, req, domain.AllNSsButUser)
 }
 
 func (h *ProcSysNetNetfilter) Read(
@@ -155,21 +178,15 @@ func (h *ProcSysNetNetfilter) Read(
 	case "nf_conntrack_max":
 		return readCntrData(h, n, req)
 
-	case "nf_conntrack_generic_timeout":
+	case "nf_conntrack_buckets":
 		return readCntrData(h, n, req)
 
 	case "nf_conntrack_tcp_be_liberal":
 		return readCntrData(h, n, req)
-
-	case "nf_conntrack_tcp_timeout_established":
-		return readCntrData(h, n, req)
-
-	case "nf_conntrack_tcp_timeout_close_wait":
-		return readCntrData(h, n, req)
 	}
 
-	// Refer to generic handler if no node match is found above.
-	return h.Service.GetPassThroughHandler().Read(n, req)
+	// Net-ns scoped nodes.
+	return h.Service.GetPassThroughHandler().ReadWithNS(n, req, domain.AllNSsButUser)
 }
 
 func (h *ProcSysNetNetfilter) Write(
@@ -183,23 +200,29 @@ func (h *ProcSysNetNetfilter) Write(
 
 	switch resource {
 	case "nf_conntrack_max":
+		if !checkIntRange(req.Data, minNfConntrackMaxVal, maxNfConntrackMaxVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
 		return writeCntrData(h, n, req, writeMaxIntToFs)
 
-	case "nf_conntrack_generic_timeout":
+	case "nf_conntrack_buckets":
+		if !checkIntRange(req.Data, minNfConntrackBucketsVal, maxNfConntrackBucketsVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
 		return writeCntrData(h, n, req, writeMaxIntToFs)
 
 	case "nf_conntrack_tcp_be_liberal":
 		return writeCntrData(h, n, req, writeTcpLiberal)
+	}
 
-	case "nf_conntrack_tcp_timeout_established":
-		return writeCntrData(h, n, req, writeMaxIntToFs)
-
-	case "nf_conntrack_tcp_timeout_close_wait":
-		return writeCntrData(h, n, req, writeMaxIntToFs)
+	// Net-ns scoped nodes. Subdirectories (i.e., nf_log) hold non-numeric
+	// values.
+	if filepath.Dir(n.Path()) == h.Path &&
+		!checkIntRange(req.Data, minNetfilterVal, maxNetfilterVal) {
+		return 0, fuse.IOerror{Code: syscall.EINVAL}
 	}
 
-	// Refer to generic handler if no node match is found above.
-	return h.Service.GetPassThroughHandler().Write(n, req)
+	return h.Service.GetPassThroughHandler().WriteWithNS(n, req, domain.AllNSsButUser)
 }
 
 func (h *ProcSysNetNetfilter) ReadDirAll(
@@ -219,10 +242,18 @@ func (h *ProcSysNetNetfilter) ReadDirAll(
 		return nil, err
 	}
 
+	ios := h.Service.IOService()
+
 	// Iterate through map of emulated components.
 	for k, _ := range h.EmuResourceMap {
 
 		if relpath == filepath.Dir(k) {
+			// Skip the ones missing in the host.
+			hostNode := ios.NewIOnode(k, filepath.Join(h.Path, k), 0)
+			if _, err := hostNode.Stat(); err != nil {
+				continue
+			}
+
 			info := &domain.FileInfo{
 				Fname:    k,
 				Fmode:    os.FileMode(uint32(0644)),
@@ -0,0 +1,257 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+// netfilterPassThrough is a passthrough-handler fake standing for the
+// container's net-ns view of /proc/sys/net/netfilter, recording the namespaces
+// each access is carried out within.
+type netfilterPassThrough struct {
+	domain.PassthroughHandlerIface
+	vals       map[string]string
+	entries    []os.FileInfo
+	namespaces []domain.NStype
+}
+
+func (p *netfilterPassThrough) ReadWithNS(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest,
+	namespaces []domain.NStype) (int, error) {
+
+	p.namespaces = namespaces
+	return copy(req.Data, p.vals[n.Path()]), nil
+}
+
+func (p *netfilterPassThrough) WriteWithNS(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest,
+	namespaces []domain.NStype) (int, error) {
+
+	p.namespaces = namespaces
+	p.vals[n.Path()] = string(req.Data)
+	return len(req.Data), nil
+}
+
+func (p *netfilterPassThrough) ReadDirAll(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]os.FileInfo, error) {
+
+	return p.entries, nil
+}
+
+func TestProcSysNetNetfilter(t *testing.T) {
+
+	const (
+		bucketsPath = "/proc/sys/net/netfilter/nf_conntrack_buckets"
+		maxPath     = "/proc/sys/net/netfilter/nf_conntrack_max"
+		estabPath   = "/proc/sys/net/netfilter/nf_conntrack_tcp_timeout_established"
+	)
+
+	// Host's conntrack state (nf_conntrack_tcp_be_liberal is purposely absent).
+	hostVals := map[string]string{
+		bucketsPath: "65536\n",
+		maxPath:     "262144\n",
+		estabPath:   "432000\n",
+	}
+	for path, val := range hostVals {
+		if err := ios.NewIOnode("", path, 0644).WriteFile([]byte(val)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer ios.RemoveAllIOnodes()
+
+	pt := &netfilterPassThrough{
+		vals: map[string]string{estabPath: "432000\n"},
+		entries: []os.FileInfo{
+			&domain.FileInfo{Fname: "nf_conntrack_tcp_timeout_established"},
+			&domain.FileInfo{Fname: "nf_log", Fmode: os.ModeDir},
+		},
+	}
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("IOService").Return(ios)
+	hs.On("GetPassThroughHandler").Return(pt)
+	hs.On("IgnoreErrors").Return(false)
+
+	h := &implementations.ProcSysNetNetfilter{
+		HandlerBase: domain.HandlerBase{
+			Name:           "ProcSysNetNetfilter",
+			Path:           "/proc/sys/net/netfilter",
+			Service:        hs,
+			EmuResourceMap: implementations.ProcSysNetNetfilter_Handler.EmuResourceMap,
+		},
+	}
+
+	cntr := css.ContainerCreate(
+		"c1",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	read := func(path string) (string, error) {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 64),
+		}
+		n := ios.NewIOnode(path[len(h.Path)+1:], path, 0)
+		sz, err := h.Read(n, req)
+		if err != nil {
+			return "", err
+		}
+		return string(req.Data[:sz]), nil
+	}
+
+	write := func(path, val string) error {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      []byte(val),
+		}
+		n := ios.NewIOnode(path[len(h.Path)+1:], path, 0)
+		_, err := h.Write(n, req)
+		return err
+	}
+
+	hostVal := func(path string) string {
+		data, err := ios.NewIOnode("", path, 0).ReadFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		// Timeouts are written within the container's net-ns, leaving the
+		// host's value untouched.
+		if err := write(estabPath, "7200\n"); err != nil {
+			t.Fatalf("Write(%s) unexpected error: %v", estabPath, err)
+		}
+		if !reflect.DeepEqual(pt.namespaces, domain.AllNSsButUser) {
+			t.Errorf("Write(%s) namespaces = %v, want %v",
+				estabPath, pt.namespaces, domain.AllNSsButUser)
+		}
+		if got := hostVal(estabPath); got != "432000\n" {
+			t.Errorf("Host's %s = %q, want %q", estabPath, got, "432000\n")
+		}
+
+		pt.namespaces = nil
+		if got, err := read(estabPath); err != nil || got != "7200\n" {
+			t.Errorf("Read(%s) = %q, %v; want %q", estabPath, got, err, "7200\n")
+		}
+		if !reflect.DeepEqual(pt.namespaces, domain.AllNSsButUser) {
+			t.Errorf("Read(%s) namespaces = %v, want %v",
+				estabPath, pt.namespaces, domain.AllNSsButUser)
+		}
+
+		// Invalid values never reach the container's net-ns.
+		for _, val := range []string{"-1\n", "abc\n", "4294967296\n"} {
+			err := write(estabPath, val)
+			if err != (fuse.IOerror{Code: syscall.EINVAL}) {
+				t.Errorf("Write(%s, %q) error = %v, want EINVAL", estabPath, val, err)
+			}
+		}
+		if got := pt.vals[estabPath]; got != "7200\n" {
+			t.Errorf("Container's %s = %q, want %q", estabPath, got, "7200\n")
+		}
+	})
+
+	t.Run("buckets", func(t *testing.T) {
+		// Reads default to the host's value.
+		if got, err := read(bucketsPath); err != nil || got != "65536\n" {
+			t.Errorf("Read(%s) = %q, %v; want %q", bucketsPath, got, err, "65536\n")
+		}
+
+		// Lower values are kept at container level.
+		if err := write(bucketsPath, "16384\n"); err != nil {
+			t.Fatalf("Write(%s) unexpected error: %v", bucketsPath, err)
+		}
+		if got, err := read(bucketsPath); err != nil || got != "16384\n" {
+			t.Errorf("Read(%s) = %q, %v; want %q", bucketsPath, got, err, "16384\n")
+		}
+		if got := hostVal(bucketsPath); got != "65536\n" {
+			t.Errorf("Host's %s = %q, want %q", bucketsPath, got, "65536\n")
+		}
+
+		// Higher values are pushed to the host.
+		if err := write(bucketsPath, "131072\n"); err != nil {
+			t.Fatalf("Write(%s) unexpected error: %v", bucketsPath, err)
+		}
+		if got, err := read(bucketsPath); err != nil || got != "131072\n" {
+			t.Errorf("Read(%s) = %q, %v; want %q", bucketsPath, got, err, "131072\n")
+		}
+		if got := hostVal(bucketsPath); got != "131072\n" {
+			t.Errorf("Host's %s = %q, want %q", bucketsPath, got, "131072\n")
+		}
+
+		// Zero-sized tables are rejected.
+		if err := write(bucketsPath, "0\n"); err != (fuse.IOerror{Code: syscall.EINVAL}) {
+			t.Errorf("Write(%s, 0) error = %v, want EINVAL", bucketsPath, err)
+		}
+	})
+
+	t.Run("readdir", func(t *testing.T) {
+		n := ios.NewIOnode("netfilter", h.Path, 0)
+		infos, err := h.ReadDirAll(n, &domain.HandlerRequest{Container: cntr})
+		if err != nil {
+			t.Fatalf("ReadDirAll() unexpected error: %v", err)
+		}
+
+		var got []string
+		for _, info := range infos {
+			got = append(got, info.Name())
+		}
+		sort.Strings(got)
+
+		want := []string{
+			"nf_conntrack_buckets",
+			"nf_conntrack_max",
+			"nf_conntrack_tcp_timeout_established",
+			"nf_log",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ReadDirAll() = %v, want %v", got, want)
+		}
+
+		// Emulated nodes missing in the host can't be looked up.
+		liberal := ios.NewIOnode("nf_conntrack_tcp_be_liberal",
+			"/proc/sys/net/netfilter/nf_conntrack_tcp_be_liberal", 0)
+		if _, err := h.Lookup(liberal, &domain.HandlerRequest{}); err == nil {
+			t.Errorf("Lookup(%s) unexpectedly succeeded", liberal.Path())
+		}
+	})
+}
@@ -17,14 +17,17 @@
 package implementations
 
 import (
+	"math"
 	"os"
 	"path/filepath"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
 )
 
 // /proc/sys/net/unix handler
@@ -32,6 +35,19 @@ import (
 // Emulated resources:
 //
 // * /proc/sys/net/unix/max_dgram_qlen
+//
+// Description: Max number of datagrams queued on a unix socket (e.g., by
+// messaging systems relying on unix datagram sockets). Defaults to 512.
+//
+// This sysctl is scoped per net-ns, so the node is accessed within the
+// container's net-ns: writes apply to that net-ns only (and are cached per
+// container by the passthrough handler), and reads display its value. Only
+// positive values are accepted.
+
+const (
+	minDgramQlenVal = 1
+	maxDgramQlenVal = math.MaxInt32
+)
 
 type ProcSysNetUnix struct {
 	domain.HandlerBase
@@ -108,7 +124,7 @@ func (h *ProcSysNetUnix) Read(
 
 	switch resource {
 	case "max_dgram_qlen":
-		return readCntrData(h, n, req)
+		return h.Service.GetPassThroughHandler().Read(n, req)
 	}
 
 	// Refer to generic handler if no node match is found above.
@@ -126,7 +142,10 @@ func (h *ProcSysNetUnix) Write(
 
 	switch resource {
 	case "max_dgram_qlen":
-		return writeCntrData(h, n, req, writeMaxIntToFs)
+		if !checkIntRange(req.Data, minDgramQlenVal, maxDgramQlenVal) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		return h.Service.GetPassThroughHandler().Write(n, req)
 	}
 
 	// Refer to generic handler if no node match is found above.
@@ -0,0 +1,132 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+func TestProcSysNetUnix_MaxDgramQlen(t *testing.T) {
+
+	const path = "/proc/sys/net/unix/max_dgram_qlen"
+
+	// Host (init net-ns) value, which container writes must never reach.
+	if err := ios.NewIOnode("", path, 0644).WriteFile([]byte("512\n")); err != nil {
+		t.Fatal(err)
+	}
+	defer ios.RemoveAllIOnodes()
+
+	pt := &netnsPassThrough{
+		nodes: map[string]string{path: "10\n"},
+	}
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("GetPassThroughHandler").Return(pt)
+	hs.On("IgnoreErrors").Return(false)
+
+	h := &implementations.ProcSysNetUnix{
+		HandlerBase: domain.HandlerBase{
+			Name:           "ProcSysNetUnix",
+			Path:           "/proc/sys/net/unix",
+			EmuResourceMap: implementations.ProcSysNetUnix_Handler.EmuResourceMap,
+			Service:        hs,
+		},
+	}
+
+	cntr := css.ContainerCreate(
+		"c-dgram-qlen",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	n := ios.NewIOnode("max_dgram_qlen", path, 0)
+
+	read := func() string {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 64),
+		}
+		sz, err := h.Read(n, req)
+		if err != nil {
+			t.Fatalf("Read() unexpected error: %v", err)
+		}
+		return string(req.Data[:sz])
+	}
+
+	write := func(val string) error {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      []byte(val),
+		}
+		_, err := h.Write(n, req)
+		return err
+	}
+
+	// Reads reflect the container's net-ns value.
+	if got := read(); got != "10\n" {
+		t.Errorf("Read() = %q, want %q", got, "10\n")
+	}
+
+	// Round-trip, lowering and raising the value.
+	for _, val := range []string{"1\n", "4096\n", "2147483647\n"} {
+		if err := write(val); err != nil {
+			t.Fatalf("Write(%q) unexpected error: %v", val, err)
+		}
+		if got := read(); got != val {
+			t.Errorf("Read() = %q, want %q", got, val)
+		}
+	}
+
+	// Invalid values are rejected before reaching the container's net-ns.
+	writes := pt.writes
+	for _, val := range []string{"0\n", "-1\n", "2147483648\n", "big\n", "\n"} {
+		if err := write(val); err != (fuse.IOerror{Code: syscall.EINVAL}) {
+			t.Errorf("Write(%q) error = %v, want EINVAL", val, err)
+		}
+	}
+	if pt.writes != writes {
+		t.Errorf("unexpected net-ns writes: %d", pt.writes-writes)
+	}
+	if got := read(); got != "2147483647\n" {
+		t.Errorf("Read() = %q, want %q", got, "2147483647\n")
+	}
+
+	// Container writes must not be pushed to the host.
+	got, err := ios.NewIOnode("", path, 0).ReadFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "512\n" {
+		t.Errorf("host max_dgram_qlen = %q, want %q", got, "512\n")
+	}
+}
@@ -20,6 +20,8 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -49,16 +51,49 @@ import (
 //
 // Note: As this is a system-wide attribute, changes will be only made
 // superficially (at sys-container level). IOW, the host FS value will be left
-// untouched.
+// untouched, so lowering it within a container doesn't weaken the host's
+// protection. Reads default to the host value until the container writes its
+// own one.
 //
 // * /proc/sys/vm/overcommit_memory
 //
+// * /proc/sys/vm/dirty_ratio, dirty_background_ratio, dirty_bytes,
+//   dirty_background_bytes and dirty_expire_centisecs
+//
+// Documentation: Writeback tunables: the amount of dirty memory (as a
+// percentage of the available memory, or in bytes) at which processes
+// generating writes start writeback themselves (dirty_*) or the background
+// flusher threads kick in (dirty_background_*), and the age at which dirty
+// data becomes eligible for writeback (dirty_expire_centisecs).
+//
+// As with mmap_min_addr, these are system-wide attributes, so values written
+// within the container are only displayed back to it. The ratio and bytes
+// variants of each threshold are mutually exclusive, as in the kernel: writing
+// one of them zeroes its counterpart.
+//
 
 const (
 	minOvercommitMem = 0
 	maxOverCommitMem = 2
+
+	maxDirtyRatio = 100
 )
 
+// Smallest dirty_bytes value accepted by the kernel (two pages), and smallest
+// dirty_background_bytes one.
+var (
+	minDirtyBytes           = uint64(2 * os.Getpagesize())
+	minDirtyBackgroundBytes = uint64(1)
+)
+
+// Ratio / bytes counterparts of each dirty memory threshold.
+var dirtyCounterparts = map[string]string{
+	"dirty_ratio":            "dirty_bytes",
+	"dirty_bytes":            "dirty_ratio",
+	"dirty_background_ratio": "dirty_background_bytes",
+	"dirty_background_bytes": "dirty_background_ratio",
+}
+
 type ProcSysVm struct {
 	domain.HandlerBase
 }
@@ -81,6 +116,36 @@ var ProcSysVm_Handler = &ProcSysVm{
 				Enabled: true,
 				Size:    1024,
 			},
+			"dirty_ratio": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"dirty_background_ratio": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"dirty_bytes": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"dirty_background_bytes": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
+			"dirty_expire_centisecs": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0644)),
+				Enabled: true,
+				Size:    1024,
+			},
 		},
 	},
 }
@@ -127,6 +192,10 @@ func (h *ProcSysVm) Open(
 
 	case "mmap_min_addr":
 		return false, nil
+
+	case "dirty_ratio", "dirty_background_ratio", "dirty_bytes",
+		"dirty_background_bytes", "dirty_expire_centisecs":
+		return false, nil
 	}
 
 	return h.Service.GetPassThroughHandler().Open(n, req)
@@ -147,6 +216,10 @@ func (h *ProcSysVm) Read(
 
 	case "mmap_min_addr":
 		return readCntrData(h, n, req)
+
+	case "dirty_ratio", "dirty_background_ratio", "dirty_bytes",
+		"dirty_background_bytes", "dirty_expire_centisecs":
+		return readCntrData(h, n, req)
 	}
 
 	// Refer to generic handler if no node match is found above.
@@ -179,7 +252,28 @@ func (h *ProcSysVm) Write(
 		return writeCntrData(h, n, req, nil)
 
 	case "mmap_min_addr":
-		if !checkIntRange(req.Data, 0, math.MaxInt64) {
+		return h.writeUlong(n, req, 0)
+
+	case "dirty_ratio", "dirty_background_ratio":
+		if !checkIntRange(req.Data, 0, maxDirtyRatio) {
+			return 0, fuse.IOerror{Code: syscall.EINVAL}
+		}
+		return h.writeDirtyThreshold(n, req, func() (int, error) {
+			return writeCntrData(h, n, req, nil)
+		})
+
+	case "dirty_bytes":
+		return h.writeDirtyThreshold(n, req, func() (int, error) {
+			return h.writeUlong(n, req, minDirtyBytes)
+		})
+
+	case "dirty_background_bytes":
+		return h.writeDirtyThreshold(n, req, func() (int, error) {
+			return h.writeUlong(n, req, minDirtyBackgroundBytes)
+		})
+
+	case "dirty_expire_centisecs":
+		if !checkIntRange(req.Data, 0, math.MaxInt32) {
 			return 0, fuse.IOerror{Code: syscall.EINVAL}
 		}
 		return writeCntrData(h, n, req, nil)
@@ -260,3 +354,54 @@ func (h *ProcSysVm) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex {
 func (h *ProcSysVm) SetService(hs domain.HandlerServiceIface) {
 	h.Service = hs
 }
+
+// writeUlong stores the container's value of an unsigned long resource (e.g.,
+// mmap_min_addr). As the kernel does, any unsigned long not below the given
+// minimum is accepted and the value is displayed back in its canonical form.
+func (h *ProcSysVm) writeUlong(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest,
+	min uint64) (int, error) {
+
+	val, err := strconv.ParseUint(strings.TrimSpace(string(req.Data)), 10, 64)
+	if err != nil || val < min {
+		return 0, fuse.IOerror{Code: syscall.EINVAL}
+	}
+
+	// The caller must see its whole buffer as consumed.
+	sz := len(req.Data)
+
+	req.Data = []byte(strconv.FormatUint(val, 10) + "\n")
+
+	if _, err := writeCntrData(h, n, req, nil); err != nil {
+		return 0, err
+	}
+
+	return sz, nil
+}
+
+// writeDirtyThreshold stores the container's value of a dirty memory threshold
+// through the given function and, if successful, zeroes its ratio / bytes
+// counterpart (the kernel only honors the last one written).
+func (h *ProcSysVm) writeDirtyThreshold(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest,
+	write func() (int, error)) (int, error) {
+
+	sz, err := write()
+	if err != nil {
+		return 0, err
+	}
+
+	cntr := req.Container
+	path := filepath.Join(filepath.Dir(n.Path()), dirtyCounterparts[n.Name()])
+
+	cntr.Lock()
+	defer cntr.Unlock()
+
+	if err := cntr.SetData(path, 0, []byte("0\n")); err != nil {
+		return 0, fuse.IOerror{Code: syscall.EINVAL}
+	}
+
+	return sz, nil
+}
@@ -0,0 +1,280 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+)
+
+func TestProcSysVm_MmapMinAddr(t *testing.T) {
+
+	const (
+		path     = "/proc/sys/vm/mmap_min_addr"
+		hostVal  = "65536\n"
+		resource = "mmap_min_addr"
+	)
+
+	if err := ios.NewIOnode("", path, 0644).WriteFile([]byte(hostVal)); err != nil {
+		t.Fatal(err)
+	}
+	defer ios.RemoveAllIOnodes()
+
+	defer hds.On("IgnoreErrors").Return(false).Unset()
+
+	h := implementations.ProcSysVm_Handler
+	h.SetService(hds)
+
+	newCntr := func(id string) domain.ContainerIface {
+		return css.ContainerCreate(
+			id,
+			uint32(1001),
+			time.Time{},
+			231072,
+			65535,
+			231072,
+			65535,
+			nil,
+			nil,
+			nil,
+		)
+	}
+	cntr := newCntr("c-mmap-1")
+
+	read := func(cntr domain.ContainerIface) string {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 64),
+		}
+		sz, err := h.Read(ios.NewIOnode(resource, path, 0), req)
+		if err != nil {
+			t.Fatalf("Read(%s) unexpected error: %v", path, err)
+		}
+		return string(req.Data[:sz])
+	}
+
+	// Reads default to the host value.
+	if got := read(cntr); got != hostVal {
+		t.Errorf("Read(%s) = %q, want %q", path, got, hostVal)
+	}
+
+	tests := []struct {
+		name    string
+		val     string
+		wantErr error
+		want    string
+	}{
+		// Test-case 1: Lower than the host's value.
+		{"1", "4096\n", nil, "4096\n"},
+		// Test-case 2: Zero disables the protection.
+		{"2", "0\n", nil, "0\n"},
+		// Test-case 3: Canonical form is stored.
+		{"3", " 32768", nil, "32768\n"},
+		// Test-case 4: Largest unsigned long.
+		{"4", "18446744073709551615\n", nil, "18446744073709551615\n"},
+		// Test-case 5: Negative value; previous value is kept.
+		{"5", "-1\n", fuse.IOerror{Code: syscall.EINVAL}, "18446744073709551615\n"},
+		// Test-case 6: Non-numeric value.
+		{"6", "64k\n", fuse.IOerror{Code: syscall.EINVAL}, "18446744073709551615\n"},
+		// Test-case 7: Beyond an unsigned long.
+		{"7", "18446744073709551616\n", fuse.IOerror{Code: syscall.EINVAL}, "18446744073709551615\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      []byte(tt.val),
+			}
+			sz, err := h.Write(ios.NewIOnode(resource, path, 0), req)
+			if err != tt.wantErr {
+				t.Fatalf("Write(%q) error = %v, want %v", tt.val, err, tt.wantErr)
+			}
+			if err == nil && sz != len(tt.val) {
+				t.Errorf("Write(%q) = %d, want %d", tt.val, sz, len(tt.val))
+			}
+
+			if got := read(cntr); got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", path, got, tt.want)
+			}
+		})
+	}
+
+	// The host's value is left untouched, and so is the view of other
+	// containers.
+	got, err := ios.NewIOnode("", path, 0).ReadFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != hostVal {
+		t.Errorf("host %s = %q, want %q", path, got, hostVal)
+	}
+	if got := read(newCntr("c-mmap-2")); got != hostVal {
+		t.Errorf("Read(%s) in another container = %q, want %q", path, got, hostVal)
+	}
+}
+
+func TestProcSysVm_DirtyThresholds(t *testing.T) {
+
+	const dir = "/proc/sys/vm"
+
+	hostVals := map[string]string{
+		"dirty_ratio":            "20\n",
+		"dirty_bytes":            "0\n",
+		"dirty_background_ratio": "10\n",
+		"dirty_background_bytes": "0\n",
+		"dirty_expire_centisecs": "3000\n",
+	}
+
+	for name, val := range hostVals {
+		if err := ios.NewIOnode("", dir+"/"+name, 0644).WriteFile([]byte(val)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer ios.RemoveAllIOnodes()
+
+	defer hds.On("IgnoreErrors").Return(false).Unset()
+
+	h := implementations.ProcSysVm_Handler
+	h.SetService(hds)
+
+	newCntr := func(id string) domain.ContainerIface {
+		return css.ContainerCreate(
+			id,
+			uint32(1001),
+			time.Time{},
+			231072,
+			65535,
+			231072,
+			65535,
+			nil,
+			nil,
+			nil,
+		)
+	}
+	cntr := newCntr("c-dirty-1")
+
+	read := func(cntr domain.ContainerIface, name string) string {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 64),
+		}
+		sz, err := h.Read(ios.NewIOnode(name, dir+"/"+name, 0), req)
+		if err != nil {
+			t.Fatalf("Read(%s) unexpected error: %v", name, err)
+		}
+		return string(req.Data[:sz])
+	}
+
+	// Reads default to the host values.
+	for name, val := range hostVals {
+		if got := read(cntr, name); got != val {
+			t.Errorf("Read(%s) = %q, want %q", name, got, val)
+		}
+	}
+
+	einval := fuse.IOerror{Code: syscall.EINVAL}
+
+	tests := []struct {
+		name    string
+		node    string
+		val     string
+		wantErr error
+		want    map[string]string // expected values after the write
+	}{
+		// Test-case 1: Ratio within range.
+		{"1", "dirty_ratio", "40\n", nil,
+			map[string]string{"dirty_ratio": "40\n", "dirty_bytes": "0\n"}},
+		// Test-case 2: Ratio beyond 100.
+		{"2", "dirty_ratio", "101\n", einval,
+			map[string]string{"dirty_ratio": "40\n", "dirty_bytes": "0\n"}},
+		// Test-case 3: Negative ratio.
+		{"3", "dirty_background_ratio", "-1\n", einval,
+			map[string]string{"dirty_background_ratio": "10\n"}},
+		// Test-case 4: Bytes variant zeroes the ratio one.
+		{"4", "dirty_bytes", "268435456\n", nil,
+			map[string]string{"dirty_ratio": "0\n", "dirty_bytes": "268435456\n"}},
+		// Test-case 5: Bytes below two pages.
+		{"5", "dirty_bytes", "4096\n", einval,
+			map[string]string{"dirty_ratio": "0\n", "dirty_bytes": "268435456\n"}},
+		// Test-case 6: Ratio variant zeroes the bytes one.
+		{"6", "dirty_ratio", "30\n", nil,
+			map[string]string{"dirty_ratio": "30\n", "dirty_bytes": "0\n"}},
+		// Test-case 7: Background bytes variant (canonical form is stored).
+		{"7", "dirty_background_bytes", " 1048576", nil,
+			map[string]string{"dirty_background_ratio": "0\n", "dirty_background_bytes": "1048576\n"}},
+		// Test-case 8: Zero background bytes.
+		{"8", "dirty_background_bytes", "0\n", einval,
+			map[string]string{"dirty_background_ratio": "0\n", "dirty_background_bytes": "1048576\n"}},
+		// Test-case 9: Background ratio variant zeroes the bytes one.
+		{"9", "dirty_background_ratio", "5\n", nil,
+			map[string]string{"dirty_background_ratio": "5\n", "dirty_background_bytes": "0\n"}},
+		// Test-case 10: Expiration age; the thresholds are unaffected.
+		{"10", "dirty_expire_centisecs", "1500\n", nil,
+			map[string]string{"dirty_expire_centisecs": "1500\n", "dirty_ratio": "30\n"}},
+		// Test-case 11: Negative expiration age.
+		{"11", "dirty_expire_centisecs", "-5\n", einval,
+			map[string]string{"dirty_expire_centisecs": "1500\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      []byte(tt.val),
+			}
+			sz, err := h.Write(ios.NewIOnode(tt.node, dir+"/"+tt.node, 0), req)
+			if err != tt.wantErr {
+				t.Fatalf("Write(%s, %q) error = %v, want %v", tt.node, tt.val, err, tt.wantErr)
+			}
+			if err == nil && sz != len(tt.val) {
+				t.Errorf("Write(%s, %q) = %d, want %d", tt.node, tt.val, sz, len(tt.val))
+			}
+
+			for name, want := range tt.want {
+				if got := read(cntr, name); got != want {
+					t.Errorf("Read(%s) = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+
+	// The host's values are left untouched, and so is the view of other
+	// containers.
+	other := newCntr("c-dirty-2")
+	for name, val := range hostVals {
+		got, err := ios.NewIOnode("", dir+"/"+name, 0).ReadFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != val {
+			t.Errorf("host %s = %q, want %q", name, got, val)
+		}
+		if got := read(other, name); got != val {
+			t.Errorf("Read(%s) in another container = %q, want %q", name, got, val)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+)
+
+//
+// /proc/timer_list and /proc/timer_stats handlers
+//
+// /proc/timer_list dumps the pending timers of every cpu in the system (along
+// with the owner and callback of each one of them), and /proc/timer_stats
+// used to account the timers set by every process. Neither is namespaced, so
+// they leak the host's timer internals into the container (these paths are
+// usually masked through the OCI spec too, but not every setup does so).
+//
+// Within the container, /proc/timer_list only displays its header (version,
+// number of clock bases and current time, as reported by the host's kernel),
+// so tools that merely check for its presence and format are kept happy,
+// while no timer can be enumerated. As the kernel does, it's only readable by
+// root.
+//
+// /proc/timer_stats was dropped from the kernel (in 4.11), so it's presented
+// as absent. As for /proc/config.gz, these handlers only kick in when the
+// host's procfs carries the file (see mount.ProcfsOptionalMounts).
+//
+
+// Lines of the /proc/timer_list header (see the kernel's timer_list_header()),
+// the last of which precedes the per-cpu sections.
+const procTimerListHeaderLines = 3
+
+// Size of the host's /proc/timer_list chunk holding its header.
+const procTimerListHeaderSize = 256
+
+type ProcTimerList struct {
+	domain.HandlerBase
+}
+
+var ProcTimerList_Handler = &ProcTimerList{
+	domain.HandlerBase{
+		Name:    "ProcTimerList",
+		Path:    "/proc/timer_list",
+		Enabled: true,
+	},
+}
+
+var ProcTimerStats_Handler = &ProcTimerList{
+	domain.HandlerBase{
+		Name:    "ProcTimerStats",
+		Path:    "/proc/timer_stats",
+		Enabled: true,
+	},
+}
+
+func (h *ProcTimerList) Lookup(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (os.FileInfo, error) {
+
+	var resource = n.Name()
+
+	logrus.Debugf("Executing Lookup() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, resource)
+
+	if resource != "timer_list" {
+		return nil, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	info := &domain.FileInfo{
+		Fname:    resource,
+		Fmode:    os.FileMode(uint32(0400)),
+		FmodTime: time.Now(),
+		Fsize:    0,
+	}
+
+	return info, nil
+}
+
+func (h *ProcTimerList) Open(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (bool, error) {
+
+	logrus.Debugf("Executing Open() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if n.Name() != "timer_list" {
+		return false, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	flags := n.OpenFlags()
+
+	if flags&syscall.O_WRONLY == syscall.O_WRONLY ||
+		flags&syscall.O_RDWR == syscall.O_RDWR {
+		return false, fuse.IOerror{Code: syscall.EACCES}
+	}
+
+	return false, nil
+}
+
+func (h *ProcTimerList) Read(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if n.Name() != "timer_list" {
+		return 0, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	// The header carries the current time, so it must not be cached.
+	req.NoCache = true
+
+	data := make([]byte, procTimerListHeaderSize)
+
+	sz, err := readHostFs(h, n, 0, &data)
+	if err != nil && err != io.EOF {
+		logrus.Debugf("Unable to read %s: %v", n.Path(), err)
+		return readWindow(req, nil)
+	}
+
+	return readWindow(req, procTimerListHeader(data[:sz]))
+}
+
+func (h *ProcTimerList) Write(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Write() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if n.Name() != "timer_list" {
+		return 0, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	return 0, fuse.IOerror{Code: syscall.EACCES}
+}
+
+func (h *ProcTimerList) ReadDirAll(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]os.FileInfo, error) {
+
+	logrus.Debugf("Executing ReadDirAll() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return nil, nil
+}
+
+func (h *ProcTimerList) ReadLink(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (string, error) {
+
+	logrus.Debugf("Executing ReadLink() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return "", nil
+}
+
+func (h *ProcTimerList) GetName() string {
+	return h.Name
+}
+
+func (h *ProcTimerList) GetPath() string {
+	return h.Path
+}
+
+func (h *ProcTimerList) GetService() domain.HandlerServiceIface {
+	return h.Service
+}
+
+func (h *ProcTimerList) GetEnabled() bool {
+	return h.Enabled
+}
+
+func (h *ProcTimerList) SetEnabled(b bool) {
+	h.Enabled = b
+}
+
+func (h *ProcTimerList) GetResourcesList() []string {
+
+	var resources []string
+
+	for resourceKey, resource := range h.EmuResourceMap {
+		resource.Mutex.Lock()
+		if !resource.Enabled {
+			resource.Mutex.Unlock()
+			continue
+		}
+		resource.Mutex.Unlock()
+
+		resources = append(resources, filepath.Join(h.GetPath(), resourceKey))
+	}
+
+	return resources
+}
+
+func (h *ProcTimerList) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex {
+	resource, ok := h.EmuResourceMap[n.Name()]
+	if !ok {
+		return nil
+	}
+
+	return &resource.Mutex
+}
+
+func (h *ProcTimerList) SetService(hs domain.HandlerServiceIface) {
+	h.Service = hs
+}
+
+// procTimerListHeader takes the beginning of the host's /proc/timer_list and
+// returns its header, as the kernel renders it when there are no cpus to dump
+// (i.e., followed by an empty line). Nothing is returned if the header can't
+// be found.
+func procTimerListHeader(data []byte) []byte {
+
+	lines := bytes.SplitAfterN(data, []byte("\n"), procTimerListHeaderLines+1)
+	if len(lines) <= procTimerListHeaderLines ||
+		!bytes.HasPrefix(lines[procTimerListHeaderLines-1], []byte("now at ")) {
+		return nil
+	}
+
+	header := bytes.Join(lines[:procTimerListHeaderLines], nil)
+
+	return append(header, '\n')
+}
@@ -0,0 +1,115 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+)
+
+func TestProcTimerList(t *testing.T) {
+
+	const hostTimerList = "Timer List Version: v0.9\n" +
+		"HRTIMER_MAX_CLOCK_BASES: 8\n" +
+		"now at 123456789 nsecs\n" +
+		"\n" +
+		"cpu: 0\n" +
+		" clock 0:\n" +
+		"  .base:       ffff9a3e3f41f140\n" +
+		"active timers:\n" +
+		" #0: <ffffb1c2c0b3be28>, hrtimer_wakeup, S:01\n" +
+		" # expires at 123457000-123458000 nsecs [in 1211 to 2211 nsecs]\n"
+
+	if err := ios.NewIOnode("", "/proc/timer_list", 0400).WriteFile([]byte(hostTimerList)); err != nil {
+		t.Fatal(err)
+	}
+	defer ios.RemoveAllIOnodes()
+
+	h := implementations.ProcTimerList_Handler
+	n := ios.NewIOnode("timer_list", "/proc/timer_list", 0)
+
+	// Only root can read it, as within the host.
+	info, err := h.Lookup(n, &domain.HandlerRequest{})
+	if err != nil {
+		t.Fatalf("Lookup() unexpected error: %v", err)
+	}
+	if info.Mode() != os.FileMode(0400) {
+		t.Errorf("Lookup() mode = %v, want %v", info.Mode(), os.FileMode(0400))
+	}
+
+	n.SetOpenFlags(syscall.O_RDWR)
+	if _, err := h.Open(n, &domain.HandlerRequest{}); err != (fuse.IOerror{Code: syscall.EACCES}) {
+		t.Errorf("Open(O_RDWR) error = %v, want EACCES", err)
+	}
+	n.SetOpenFlags(syscall.O_RDONLY)
+	if _, err := h.Open(n, &domain.HandlerRequest{}); err != nil {
+		t.Errorf("Open() unexpected error: %v", err)
+	}
+
+	// No timer is displayed.
+	req := &domain.HandlerRequest{Data: make([]byte, 4096)}
+	sz, err := h.Read(n, req)
+	if err != nil {
+		t.Fatalf("Read() unexpected error: %v", err)
+	}
+	want := "Timer List Version: v0.9\n" +
+		"HRTIMER_MAX_CLOCK_BASES: 8\n" +
+		"now at 123456789 nsecs\n" +
+		"\n"
+	if got := string(req.Data[:sz]); got != want {
+		t.Errorf("Read() = %q, want %q", got, want)
+	}
+	if !req.NoCache {
+		t.Errorf("Read() must not be cached")
+	}
+
+	// Unexpected formats are not displayed at all.
+	if err := ios.NewIOnode("", "/proc/timer_list", 0400).WriteFile([]byte("cpu: 0\n clock 0:\n")); err != nil {
+		t.Fatal(err)
+	}
+	req = &domain.HandlerRequest{Data: make([]byte, 4096)}
+	if sz, _ := h.Read(n, req); sz != 0 {
+		t.Errorf("Read() = %q, want none", req.Data[:sz])
+	}
+}
+
+func TestProcTimerStats(t *testing.T) {
+
+	h := implementations.ProcTimerStats_Handler
+	n := ios.NewIOnode("timer_stats", "/proc/timer_stats", 0)
+
+	enoent := fuse.IOerror{Code: syscall.ENOENT}
+
+	if _, err := h.Lookup(n, &domain.HandlerRequest{}); err != enoent {
+		t.Errorf("Lookup() error = %v, want ENOENT", err)
+	}
+	n.SetOpenFlags(syscall.O_RDONLY)
+	if _, err := h.Open(n, &domain.HandlerRequest{}); err != enoent {
+		t.Errorf("Open() error = %v, want ENOENT", err)
+	}
+	if _, err := h.Read(n, &domain.HandlerRequest{Data: make([]byte, 64)}); err != enoent {
+		t.Errorf("Read() error = %v, want ENOENT", err)
+	}
+	if _, err := h.Write(n, &domain.HandlerRequest{Data: []byte("1\n")}); err != enoent {
+		t.Errorf("Write() error = %v, want ENOENT", err)
+	}
+}
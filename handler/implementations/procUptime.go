@@ -18,9 +18,10 @@ package implementations
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -34,6 +35,18 @@ import (
 //
 // /proc/uptime handler
 //
+// The container's uptime is measured from the container's creation time. If
+// the container's init process lives in a time namespace, the namespace's
+// boottime offset is added on top, so that the reported value is coherent
+// with the CLOCK_BOOTTIME readings obtained within the container.
+//
+
+// Path of the time-ns offsets file of the container's init process, as seen
+// within the container's procfs.
+const timensOffsetsPath = "/proc/1/timens_offsets"
+
+// Max size of the timens_offsets file (two short lines).
+const timensOffsetsMaxSize = 256
 
 type ProcUptime struct {
 	domain.HandlerBase
@@ -184,12 +197,6 @@ func (h *ProcUptime) readUptime(
 
 	logrus.Debugf("Executing %v Read() method", h.Name)
 
-	// We are dealing with a single integer element being read, so we can save
-	// some cycles by returning right away if offset is any higher than zero.
-	if req.Offset > 0 {
-		return 0, io.EOF
-	}
-
 	cntr := req.Container
 
 	//
@@ -209,10 +216,108 @@ func (h *ProcUptime) readUptime(
 	// an approximation.
 	//
 	uptimeDur := time.Now().Sub(data) / time.Nanosecond
+
+	// Shift the uptime by the container's time-ns boottime offset (if any).
+	offset, err := h.timensBoottimeOffset(req)
+	if err != nil {
+		logrus.Warnf("Unable to obtain time-ns offsets of container %s: %v",
+			cntr.ID(), err)
+	}
+	uptimeDur += offset
+	if uptimeDur < 0 {
+		uptimeDur = 0
+	}
+
 	var uptime float64 = uptimeDur.Seconds()
 	uptimeStr := fmt.Sprintf("%.2f %.2f\n", uptime, uptime)
 
-	req.Data = []byte(uptimeStr)
+	return readWindow(req, []byte(uptimeStr))
+}
+
+// timensBoottimeOffset returns the boottime offset of the time-ns of the
+// container's init process. Time-ns offsets can't be changed once the
+// namespace is populated, so they are fetched (through the container's procfs)
+// just once and then cached in the container's data store. Kernels lacking
+// time-ns support are treated as if no offsets were present.
+func (h *ProcUptime) timensBoottimeOffset(
+	req *domain.HandlerRequest) (time.Duration, error) {
+
+	cntr := req.Container
+
+	data := make([]byte, timensOffsetsMaxSize)
+
+	sz, _ := cntr.Data(timensOffsetsPath, 0, &data)
+	if sz > 0 {
+		return parseTimensBoottimeOffset(string(data[:sz]))
+	}
+
+	nss := h.Service.NSenterService()
+
+	event := nss.NewEvent(
+		cntr.InitPid(),
+		&[]domain.NStype{string(domain.NStypeMount)},
+		0,
+		&domain.NSenterMessage{
+			Type: domain.ReadFileRequest,
+			Payload: &domain.ReadFilePayload{
+				File: timensOffsetsPath,
+				Len:  timensOffsetsMaxSize,
+			},
+		},
+		nil,
+		false,
+	)
+
+	if err := nss.SendRequestEvent(event); err != nil {
+		return 0, err
+	}
+
+	responseMsg := nss.ReceiveResponseEvent(event)
+	if responseMsg.Type == domain.ErrorResponse {
+		if !isNotExistError(responseMsg.Payload.(error)) {
+			return 0, responseMsg.Payload.(error)
+		}
+		data = []byte("boottime 0 0\n")
+	} else {
+		data = responseMsg.Payload.([]byte)
+	}
+
+	if err := cntr.SetData(timensOffsetsPath, 0, data); err != nil {
+		return 0, err
+	}
+
+	return parseTimensBoottimeOffset(string(data))
+}
+
+// parseTimensBoottimeOffset extracts the boottime offset out of the given
+// timens_offsets content. Each line carries a clock, followed by the offset's
+// seconds and nanoseconds. Clocks are displayed by name, though early kernels
+// displayed their ids instead (7 == CLOCK_BOOTTIME).
+func parseTimensBoottimeOffset(offsets string) (time.Duration, error) {
 
-	return len(req.Data), nil
+	for _, line := range strings.Split(offsets, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] != "boottime" && fields[0] != "7" {
+			continue
+		}
+		if len(fields) != 3 {
+			return 0, fmt.Errorf("invalid timens_offsets entry: %q", line)
+		}
+
+		secs, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timens_offsets entry: %q", line)
+		}
+		nsecs, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timens_offsets entry: %q", line)
+		}
+
+		return time.Duration(secs)*time.Second + time.Duration(nsecs), nil
+	}
+
+	return 0, nil
 }
@@ -0,0 +1,171 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"fmt"
+	"math"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+func TestProcUptime_TimeNamespace(t *testing.T) {
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("NSenterService").Return(nss)
+
+	h := &implementations.ProcUptime{
+		HandlerBase: domain.HandlerBase{
+			Name:    "ProcUptime",
+			Path:    "/proc/uptime",
+			Service: hs,
+		},
+	}
+
+	tests := []struct {
+		name    string
+		pid     uint32
+		resp    *domain.NSenterMessage
+		uptime  float64 // container's uptime (secs) without time-ns offsets
+		want    float64
+		wantErr error
+	}{
+		// Test-case 1: Time-ns with a boottime offset.
+		{
+			name: "1",
+			pid:  2001,
+			resp: &domain.NSenterMessage{
+				Type:    domain.ReadFileResponse,
+				Payload: []byte("monotonic          0         0\nboottime        3600 500000000\n"),
+			},
+			uptime: 100,
+			want:   3700.5,
+		},
+		// Test-case 2: Early kernels display clock ids rather than names.
+		{
+			name: "2",
+			pid:  2002,
+			resp: &domain.NSenterMessage{
+				Type:    domain.ReadFileResponse,
+				Payload: []byte("1 0 0\n7 60 0\n"),
+			},
+			uptime: 100,
+			want:   160,
+		},
+		// Test-case 3: No time-ns offsets.
+		{
+			name: "3",
+			pid:  2003,
+			resp: &domain.NSenterMessage{
+				Type:    domain.ReadFileResponse,
+				Payload: []byte("monotonic 0 0\nboottime 0 0\n"),
+			},
+			uptime: 100,
+			want:   100,
+		},
+		// Test-case 4: No time-ns support in the kernel; current behavior.
+		{
+			name: "4",
+			pid:  2004,
+			resp: &domain.NSenterMessage{
+				Type:    domain.ErrorResponse,
+				Payload: fuse.IOerror{Code: syscall.ENOENT},
+			},
+			uptime: 100,
+			want:   100,
+		},
+		// Test-case 5: Negative offset beyond the container's uptime.
+		{
+			name: "5",
+			pid:  2005,
+			resp: &domain.NSenterMessage{
+				Type:    domain.ReadFileResponse,
+				Payload: []byte("monotonic 0 0\nboottime -3600 0\n"),
+			},
+			uptime: 100,
+			want:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nss.Calls = nil
+			defer func() { nss.ExpectedCalls = nil }()
+
+			cntr := css.ContainerCreate(
+				"c"+tt.name,
+				tt.pid,
+				time.Now().Add(-time.Duration(tt.uptime)*time.Second),
+				231072,
+				65535,
+				231072,
+				65535,
+				nil,
+				nil,
+				nil,
+			)
+
+			event := &mocks.NSenterEventIface{}
+			nss.On(
+				"NewEvent",
+				tt.pid,
+				&[]domain.NStype{string(domain.NStypeMount)},
+				uint32(0),
+				&domain.NSenterMessage{
+					Type: domain.ReadFileRequest,
+					Payload: &domain.ReadFilePayload{
+						File: "/proc/1/timens_offsets",
+						Len:  256,
+					},
+				},
+				(*domain.NSenterMessage)(nil),
+				false).Return(event)
+			nss.On("SendRequestEvent", event).Return(nil)
+			nss.On("ReceiveResponseEvent", event).Return(tt.resp)
+
+			// Offsets must be fetched only once, so read twice.
+			for i := 0; i < 2; i++ {
+				req := &domain.HandlerRequest{
+					Pid:       tt.pid,
+					Container: cntr,
+					Data:      make([]byte, 64),
+				}
+				n := ios.NewIOnode("uptime", "/proc/uptime", 0)
+				sz, err := h.Read(n, req)
+				if err != nil {
+					t.Fatalf("Read() unexpected error: %v", err)
+				}
+
+				var up, idle float64
+				if _, err := fmt.Sscanf(string(req.Data[:sz]), "%f %f\n", &up, &idle); err != nil {
+					t.Fatalf("Read() unexpected content %q: %v", req.Data[:sz], err)
+				}
+				if math.Abs(up-tt.want) > 1 {
+					t.Errorf("Read() uptime = %.2f, want %.2f", up, tt.want)
+				}
+			}
+
+			nss.AssertNumberOfCalls(t, "SendRequestEvent", 1)
+		})
+	}
+}
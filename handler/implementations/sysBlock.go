@@ -0,0 +1,316 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+)
+
+//
+// /sys/block handler
+//
+// The host's /sys/block lists every block device in the system, which leaks
+// the host's storage layout into the sys container and leads tools such as
+// lsblk to report disks the container can't make use of.
+//
+// Only the block devices the container has access to are presented, these
+// being:
+//
+// * Those granted read or write access through the container's devices cgroup
+//   (cgroup v1 only, as v2 enforces device access through eBPF programs that
+//   can't be queried).
+// * Those whose device nodes are present in the container's /dev (e.g.,
+//   volumes attached to the container at creation time).
+//
+// Devices are matched by their major:minor numbers, as reported by the host's
+// /sys/block/<dev>/dev file. Entries of the devices that are presented are
+// served as is from the host's sysfs (i.e., they're symlinks into
+// /sys/devices, where their size, ro and removable attributes live); all the
+// others are presented as absent.
+//
+
+type SysBlock struct {
+	domain.HandlerBase
+}
+
+var SysBlock_Handler = &SysBlock{
+	domain.HandlerBase{
+		Name:    "SysBlock",
+		Path:    "/sys/block",
+		Enabled: true,
+	},
+}
+
+func (h *SysBlock) Lookup(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (os.FileInfo, error) {
+
+	logrus.Debugf("Executing Lookup() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if h.isHidden(n, req) {
+		return nil, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	req.SkipIdRemap = true
+
+	return n.Lstat()
+}
+
+func (h *SysBlock) Open(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (bool, error) {
+
+	logrus.Debugf("Executing Open() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if h.isHidden(n, req) {
+		return false, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	return false, n.Open()
+}
+
+func (h *SysBlock) Read(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if h.isHidden(n, req) {
+		return 0, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	return readHostFs(h, n, req.Offset, &req.Data)
+}
+
+func (h *SysBlock) Write(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Write() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if h.isHidden(n, req) {
+		return 0, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	// Host's sysfs nodes must not be modified from within the sys container.
+	return 0, fuse.IOerror{Code: syscall.EACCES}
+}
+
+func (h *SysBlock) ReadDirAll(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]os.FileInfo, error) {
+
+	logrus.Debugf("Executing ReadDirAll() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if h.isHidden(n, req) {
+		return nil, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	entries, err := n.ReadDirAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if n.Path() != h.Path {
+		return entries, nil
+	}
+
+	devs := h.cntrDevs(req.Container)
+
+	var fileEntries []os.FileInfo
+	for _, e := range entries {
+		if h.devAllowed(e.Name(), devs) {
+			fileEntries = append(fileEntries, e)
+		}
+	}
+
+	return fileEntries, nil
+}
+
+func (h *SysBlock) ReadLink(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (string, error) {
+
+	logrus.Debugf("Executing ReadLink() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if h.isHidden(n, req) {
+		return "", fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	return n.ReadLink()
+}
+
+func (h *SysBlock) GetName() string {
+	return h.Name
+}
+
+func (h *SysBlock) GetPath() string {
+	return h.Path
+}
+
+func (h *SysBlock) GetService() domain.HandlerServiceIface {
+	return h.Service
+}
+
+func (h *SysBlock) GetEnabled() bool {
+	return h.Enabled
+}
+
+func (h *SysBlock) SetEnabled(b bool) {
+	h.Enabled = b
+}
+
+func (h *SysBlock) GetResourcesList() []string {
+	return []string{h.GetPath()}
+}
+
+func (h *SysBlock) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex {
+	return nil
+}
+
+func (h *SysBlock) SetService(hs domain.HandlerServiceIface) {
+	h.Service = hs
+}
+
+// isHidden returns true if the given node belongs to a block device the
+// container has no access to.
+func (h *SysBlock) isHidden(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) bool {
+
+	relpath, err := filepath.Rel(h.Path, n.Path())
+	if err != nil || relpath == "." {
+		return false
+	}
+
+	dev := strings.SplitN(relpath, "/", 2)[0]
+
+	return !h.devAllowed(dev, h.cntrDevs(req.Container))
+}
+
+// devAllowed returns true if the given host block device (as named under
+// /sys/block) is within the given set of major:minor numbers.
+func (h *SysBlock) devAllowed(name string, devs map[string]bool) bool {
+
+	if devs["*"] {
+		return true
+	}
+
+	ios := h.Service.IOService()
+	path := filepath.Join(h.Path, name, "dev")
+
+	data, err := ios.NewIOnode("", path, 0).ReadFile()
+	if err != nil {
+		return false
+	}
+
+	return devs[strings.TrimSpace(string(data))]
+}
+
+// cntrDevs returns the major:minor numbers of the block devices the container
+// has access to. The "*" key is set when access to all of them is granted.
+func (h *SysBlock) cntrDevs(cntr domain.ContainerIface) map[string]bool {
+
+	ios := h.Service.IOService()
+	pid := cntr.InitPid()
+	devs := make(map[string]bool)
+
+	// Devices cgroup (v2 carries no devices.list file, so the read fails).
+	path, err := cgroupFilePath(ios, pid, "devices", "devices.list", "devices.list")
+	if err == nil {
+		data, err := ios.NewIOnode("", path, 0).ReadFile()
+		if err == nil {
+			parseDevicesList(data, devs)
+		}
+	}
+
+	// Block device nodes within the container's /dev.
+	devDir := fmt.Sprintf("/proc/%d/root/dev", pid)
+	entries, err := ios.NewIOnode("", devDir, 0).ReadDirAll()
+	if err != nil {
+		logrus.Debugf("Unable to read %s of cntr %s: %v", devDir, cntr.ID(), err)
+		return devs
+	}
+
+	for _, e := range entries {
+		if e.Mode()&os.ModeDevice == 0 || e.Mode()&os.ModeCharDevice != 0 {
+			continue
+		}
+		st, ok := e.Sys().(*syscall.Stat_t)
+		if !ok {
+			continue
+		}
+		rdev := uint64(st.Rdev)
+		devs[fmt.Sprintf("%d:%d", unix.Major(rdev), unix.Minor(rdev))] = true
+	}
+
+	return devs
+}
+
+// parseDevicesList adds to the given set the major:minor numbers of the block
+// devices granted read or write access in the given cgroup v1 devices.list
+// content (e.g., "b 7:0 rwm"). Wildcard grants of all devices, or of all
+// block devices, are recorded under the "*" key; grants of all minors of a
+// major number aren't honored, as they're not expected for sys containers.
+func parseDevicesList(data []byte, devs map[string]bool) {
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		// Format: type major:minor access
+		fields := strings.Fields(s.Text())
+		if len(fields) != 3 {
+			continue
+		}
+
+		devType, devNum, access := fields[0], fields[1], fields[2]
+
+		if devType != "a" && devType != "b" {
+			continue
+		}
+		if !strings.ContainsAny(access, "rw") {
+			continue
+		}
+
+		if devType == "a" || devNum == "*:*" {
+			devs["*"] = true
+			continue
+		}
+
+		if !strings.Contains(devNum, "*") {
+			devs[devNum] = true
+		}
+	}
+}
@@ -0,0 +1,170 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"reflect"
+	"sort"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+func TestSysBlock_AttachedDevices(t *testing.T) {
+
+	// Host with two disks and two loop devices, along with the devices cgroups
+	// (v1) of the containers.
+	hostFiles := map[string]string{
+		"/sys/block/sda/dev":                            "8:0\n",
+		"/sys/block/sda/size":                           "1953525168\n",
+		"/sys/block/nvme0n1/dev":                        "259:0\n",
+		"/sys/block/loop0/dev":                          "7:0\n",
+		"/sys/block/loop0/size":                         "2048\n",
+		"/sys/block/loop0/ro":                           "1\n",
+		"/sys/block/loop0/removable":                    "0\n",
+		"/sys/block/loop1/dev":                          "7:1\n",
+		"/proc/1001/cgroup":                             "5:devices:/sysbox/c1\n",
+		"/proc/1002/cgroup":                             "5:devices:/sysbox/c2\n",
+		"/proc/1003/cgroup":                             "0::/sysbox/c3\n",
+		"/sys/fs/cgroup/devices/sysbox/c1/devices.list": "c 1:3 rwm\nb *:* m\nb 7:0 rw\nb 8:* m\n",
+		"/sys/fs/cgroup/devices/sysbox/c2/devices.list": "a *:* rwm\n",
+	}
+	for path, val := range hostFiles {
+		if err := ios.NewIOnode("", path, 0644).WriteFile([]byte(val)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer ios.RemoveAllIOnodes()
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("IOService").Return(ios)
+
+	h := &implementations.SysBlock{
+		HandlerBase: domain.HandlerBase{
+			Name:    "SysBlock",
+			Path:    "/sys/block",
+			Service: hs,
+		},
+	}
+
+	newCntr := func(id string, initPid uint32) domain.ContainerIface {
+		return css.ContainerCreate(
+			id,
+			initPid,
+			time.Time{},
+			231072,
+			65535,
+			231072,
+			65535,
+			nil,
+			nil,
+			nil,
+		)
+	}
+
+	tests := []struct {
+		name        string
+		cntr        domain.ContainerIface
+		wantEntries []string
+		wantFiles   map[string]string
+		hiddenFiles []string
+	}{
+		// Test-case 1: Single loop device granted access; host disks must be
+		// hidden.
+		{
+			name:        "1",
+			cntr:        newCntr("c1", 1001),
+			wantEntries: []string{"loop0"},
+			wantFiles: map[string]string{
+				"loop0/size":      "2048\n",
+				"loop0/ro":        "1\n",
+				"loop0/removable": "0\n",
+			},
+			hiddenFiles: []string{"sda", "sda/size", "nvme0n1", "loop1"},
+		},
+		// Test-case 2: Access granted to all devices.
+		{
+			name:        "2",
+			cntr:        newCntr("c2", 1002),
+			wantEntries: []string{"loop0", "loop1", "nvme0n1", "sda"},
+			wantFiles: map[string]string{
+				"sda/size": "1953525168\n",
+			},
+		},
+		// Test-case 3: No devices cgroup (v2) nor device nodes.
+		{
+			name:        "3",
+			cntr:        newCntr("c3", 1003),
+			wantEntries: nil,
+			hiddenFiles: []string{"sda", "loop0", "loop0/size"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &domain.HandlerRequest{
+				Pid:       tt.cntr.InitPid(),
+				Container: tt.cntr,
+			}
+
+			infos, err := h.ReadDirAll(ios.NewIOnode("block", "/sys/block", 0), req)
+			if err != nil {
+				t.Fatalf("ReadDirAll() unexpected error: %v", err)
+			}
+			var entries []string
+			for _, info := range infos {
+				entries = append(entries, info.Name())
+			}
+			sort.Strings(entries)
+			if !reflect.DeepEqual(entries, tt.wantEntries) {
+				t.Errorf("ReadDirAll() = %v, want %v", entries, tt.wantEntries)
+			}
+
+			for file, want := range tt.wantFiles {
+				n := ios.NewIOnode("", "/sys/block/"+file, 0)
+				req.Data = make([]byte, 64)
+				sz, err := h.Read(n, req)
+				if err != nil {
+					t.Errorf("Read(%s) unexpected error: %v", file, err)
+					continue
+				}
+				if got := string(req.Data[:sz]); got != want {
+					t.Errorf("Read(%s) = %q, want %q", file, got, want)
+				}
+			}
+
+			// Lookups are served through the host's fs, so they're only
+			// checked for hidden nodes.
+			wantErr := fuse.IOerror{Code: syscall.ENOENT}
+			for _, file := range tt.hiddenFiles {
+				n := ios.NewIOnode("", "/sys/block/"+file, 0)
+				if _, err := h.Lookup(n, req); err != wantErr {
+					t.Errorf("Lookup(%s) error = %v, want %v", file, err, wantErr)
+				}
+				req.Data = make([]byte, 64)
+				if _, err := h.Read(n, req); err != wantErr {
+					t.Errorf("Read(%s) error = %v, want %v", file, err, wantErr)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,345 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+)
+
+//
+// /sys/devices/system/cpu handler
+//
+// Thread-pool sizing libraries inspect the SMT topology of the cpus they can
+// run on through /sys/devices/system/cpu/cpu<N>/topology. Within a sys
+// container restricted to a subset of the host's cpus, the host's topology
+// makes them account for sibling threads (and packages) the container can't
+// use, so the topology of the container's cpus (i.e., its cpuset's effective
+// cpus) is presented as a flat, non-SMT one:
+//
+// * Each cpu is a core of its own (core_id being the cpu's index within the
+//   container's cpus), with no thread siblings.
+// * All cpus belong to a single package, die and cluster (id 0).
+//
+// Files keep the kernel's formats (i.e., cpu-lists and cpu-masks as wide as
+// the host's ones). The topology of any other cpu, as well as the rest of the
+// directory, is served as is from the host's sysfs (refer to /sys/kernel
+// handler for details).
+//
+
+// Topology files of a cpu (e.g., "cpu3/topology/core_id").
+var sysCpuTopologyRegexp = regexp.MustCompile(`^cpu([0-9]+)/topology/([a-z_]+)$`)
+
+type SysDevicesSystemCpu struct {
+	domain.HandlerBase
+}
+
+var SysDevicesSystemCpu_Handler = &SysDevicesSystemCpu{
+	domain.HandlerBase{
+		Name:    "SysDevicesSystemCpu",
+		Path:    "/sys/devices/system/cpu",
+		Enabled: true,
+	},
+}
+
+func (h *SysDevicesSystemCpu) Lookup(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (os.FileInfo, error) {
+
+	logrus.Debugf("Executing Lookup() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	req.SkipIdRemap = true
+
+	return n.Lstat()
+}
+
+func (h *SysDevicesSystemCpu) Open(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (bool, error) {
+
+	logrus.Debugf("Executing Open() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return false, n.Open()
+}
+
+func (h *SysDevicesSystemCpu) Read(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	relpath, err := filepath.Rel(h.Path, n.Path())
+	if err != nil {
+		return 0, err
+	}
+
+	match := sysCpuTopologyRegexp.FindStringSubmatch(relpath)
+	if match == nil {
+		return readHostFs(h, n, req.Offset, &req.Data)
+	}
+
+	cpu, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	return h.readTopology(n, req, cpu, match[2])
+}
+
+func (h *SysDevicesSystemCpu) Write(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Write() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	// Host's sysfs nodes must not be modified from within the sys container.
+	return 0, fuse.IOerror{Code: syscall.EACCES}
+}
+
+func (h *SysDevicesSystemCpu) ReadDirAll(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]os.FileInfo, error) {
+
+	logrus.Debugf("Executing ReadDirAll() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return n.ReadDirAll()
+}
+
+func (h *SysDevicesSystemCpu) ReadLink(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (string, error) {
+
+	logrus.Debugf("Executing ReadLink() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return n.ReadLink()
+}
+
+func (h *SysDevicesSystemCpu) GetName() string {
+	return h.Name
+}
+
+func (h *SysDevicesSystemCpu) GetPath() string {
+	return h.Path
+}
+
+func (h *SysDevicesSystemCpu) GetService() domain.HandlerServiceIface {
+	return h.Service
+}
+
+func (h *SysDevicesSystemCpu) GetEnabled() bool {
+	return h.Enabled
+}
+
+func (h *SysDevicesSystemCpu) SetEnabled(b bool) {
+	h.Enabled = b
+}
+
+func (h *SysDevicesSystemCpu) GetResourcesList() []string {
+	return []string{h.GetPath()}
+}
+
+func (h *SysDevicesSystemCpu) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex {
+	return nil
+}
+
+func (h *SysDevicesSystemCpu) SetService(hs domain.HandlerServiceIface) {
+	h.Service = hs
+}
+
+// readTopology renders the given topology file of the given cpu (see above).
+// Files unknown to the flat topology are served as is.
+func (h *SysDevicesSystemCpu) readTopology(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest,
+	cpu int,
+	file string) (int, error) {
+
+	cpus, err := cntrCpus(h.Service.IOService(), req.Container)
+	if err != nil {
+		logrus.Errorf("Unable to obtain effective cpus of container %s: %v",
+			req.Container.ID(), err)
+		return 0, fuse.IOerror{Code: syscall.EIO}
+	}
+	sort.Ints(cpus)
+
+	idx := sort.SearchInts(cpus, cpu)
+	if idx == len(cpus) || cpus[idx] != cpu {
+		return readHostFs(h, n, req.Offset, &req.Data)
+	}
+
+	var content string
+
+	switch file {
+	case "core_id":
+		content = strconv.Itoa(idx)
+
+	case "physical_package_id", "die_id", "cluster_id":
+		content = "0"
+
+	case "thread_siblings_list", "core_cpus_list":
+		content = formatCpuList([]int{cpu})
+
+	case "core_siblings_list", "package_cpus_list", "die_cpus_list", "cluster_cpus_list":
+		content = formatCpuList(cpus)
+
+	case "thread_siblings", "core_cpus",
+		"core_siblings", "package_cpus", "die_cpus", "cluster_cpus":
+
+		// The mask width depends on the number of cpus supported by the
+		// kernel, so it's taken from the host's mask.
+		data, err := n.ReadFile()
+		if err != nil {
+			return 0, err
+		}
+		words := strings.Count(string(data), ",") + 1
+
+		if file == "thread_siblings" || file == "core_cpus" {
+			content = formatCpuMap([]int{cpu}, words)
+		} else {
+			content = formatCpuMap(cpus, words)
+		}
+
+	default:
+		return readHostFs(h, n, req.Offset, &req.Data)
+	}
+
+	return readWindow(req, []byte(content+"\n"))
+}
+
+// cntrCpus returns the cpus on which the container's processes are allowed to
+// run, as per the effective cpus of the container's cpuset cgroup. The host's
+// online cpus are returned if the cgroup's cpuset can't be determined.
+func cntrCpus(ios domain.IOServiceIface, cntr domain.ContainerIface) ([]int, error) {
+
+	cpusPath := "/sys/devices/system/cpu/online"
+
+	cgPath, err := cgroupCpusetPath(ios, cntr.InitPid())
+	if err != nil {
+		logrus.Debugf("Unable to find cpuset cgroup of container %s: %v",
+			cntr.ID(), err)
+	} else {
+		cpusPath = cgPath
+	}
+
+	data, err := ios.NewIOnode("", cpusPath, 0).ReadFile()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCpuList(strings.TrimSpace(string(data)))
+}
+
+// cgroupCpusetPath returns the path of the file holding the effective cpus of
+// the cpuset cgroup of the given process (cgroup v2 or v1).
+func cgroupCpusetPath(ios domain.IOServiceIface, pid uint32) (string, error) {
+	return cgroupFilePath(ios, pid, "cpuset", "cpuset.cpus.effective", "cpuset.effective_cpus")
+}
+
+// parseCpuList parses a cpu-list (e.g., "0-3,8,10-11") as the one displayed by
+// the kernel.
+func parseCpuList(list string) ([]int, error) {
+
+	var cpus []int
+
+	if list == "" {
+		return cpus, nil
+	}
+
+	for _, r := range strings.Split(list, ",") {
+		bounds := strings.SplitN(r, "-", 2)
+
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu-list %q: %v", list, err)
+		}
+
+		last := first
+		if len(bounds) == 2 {
+			last, err = strconv.Atoi(bounds[1])
+			if err != nil || last < first {
+				return nil, fmt.Errorf("invalid cpu-list %q", list)
+			}
+		}
+
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+
+	return cpus, nil
+}
+
+// formatCpuList renders the given (sorted) cpus as a kernel cpu-list.
+func formatCpuList(cpus []int) string {
+
+	var ranges []string
+
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+
+		if i == j {
+			ranges = append(ranges, strconv.Itoa(cpus[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", cpus[i], cpus[j]))
+		}
+
+		i = j + 1
+	}
+
+	return strings.Join(ranges, ",")
+}
+
+// formatCpuMap renders the given cpus as a kernel cpu-mask made of the given
+// number of comma-separated 32-bit words (most significant word first).
+func formatCpuMap(cpus []int, words int) string {
+
+	mask := make([]uint32, words)
+
+	for _, cpu := range cpus {
+		if cpu/32 < words {
+			mask[words-1-cpu/32] |= 1 << uint(cpu%32)
+		}
+	}
+
+	strs := make([]string, words)
+	for i, w := range mask {
+		strs[i] = fmt.Sprintf("%08x", w)
+	}
+
+	return strings.Join(strs, ",")
+}
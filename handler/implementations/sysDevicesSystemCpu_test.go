@@ -0,0 +1,138 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+func TestSysDevicesSystemCpu_Topology(t *testing.T) {
+
+	// Host with a single package of two SMT cores (cpus 0-1 and 2-3), along
+	// with the container's cgroup (v2) cpuset spanning a thread of each.
+	hostFiles := map[string]string{
+		"/proc/1001/cgroup": "0::/sysbox/c1\n",
+		"/sys/fs/cgroup/sysbox/c1/cpuset.cpus.effective": "1-2\n",
+		"/sys/devices/system/cpu/online":                 "0-3\n",
+	}
+	for cpu := 0; cpu < 4; cpu++ {
+		dir := fmt.Sprintf("/sys/devices/system/cpu/cpu%d/topology/", cpu)
+		siblings := []string{"0-1", "2-3"}[cpu/2]
+		hostFiles[dir+"core_id"] = fmt.Sprintf("%d\n", cpu/2)
+		hostFiles[dir+"physical_package_id"] = "0\n"
+		hostFiles[dir+"thread_siblings_list"] = siblings + "\n"
+		hostFiles[dir+"thread_siblings"] = []string{"00000003\n", "0000000c\n"}[cpu/2]
+		hostFiles[dir+"core_cpus_list"] = siblings + "\n"
+		hostFiles[dir+"core_siblings_list"] = "0-3\n"
+		hostFiles[dir+"package_cpus"] = "0000000f\n"
+		hostFiles[dir+"package_cpus_list"] = "0-3\n"
+	}
+	for path, val := range hostFiles {
+		if err := ios.NewIOnode("", path, 0644).WriteFile([]byte(val)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer ios.RemoveAllIOnodes()
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("IOService").Return(ios)
+
+	h := &implementations.SysDevicesSystemCpu{
+		HandlerBase: domain.HandlerBase{
+			Name:    "SysDevicesSystemCpu",
+			Path:    "/sys/devices/system/cpu",
+			Service: hs,
+		},
+	}
+
+	cntr := css.ContainerCreate(
+		"c-cpu-topology",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	read := func(file string) string {
+		req := &domain.HandlerRequest{
+			Pid:       1001,
+			Container: cntr,
+			Data:      make([]byte, 64),
+		}
+		n := ios.NewIOnode("", "/sys/devices/system/cpu/"+file, 0)
+		sz, err := h.Read(n, req)
+		if err != nil {
+			t.Fatalf("Read(%s) unexpected error: %v", file, err)
+		}
+		return string(req.Data[:sz])
+	}
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		// Container's cpus: one core each, no thread siblings, one package.
+		{"cpu1/topology/core_id", "0\n"},
+		{"cpu2/topology/core_id", "1\n"},
+		{"cpu1/topology/physical_package_id", "0\n"},
+		{"cpu2/topology/physical_package_id", "0\n"},
+		{"cpu1/topology/thread_siblings_list", "1\n"},
+		{"cpu2/topology/thread_siblings_list", "2\n"},
+		{"cpu1/topology/thread_siblings", "00000002\n"},
+		{"cpu2/topology/thread_siblings", "00000004\n"},
+		{"cpu2/topology/core_cpus_list", "2\n"},
+		{"cpu1/topology/core_siblings_list", "1-2\n"},
+		{"cpu2/topology/package_cpus_list", "1-2\n"},
+		{"cpu1/topology/package_cpus", "00000006\n"},
+		// Cpus outside of the container's cpuset are left untouched.
+		{"cpu0/topology/core_id", "0\n"},
+		{"cpu3/topology/thread_siblings_list", "2-3\n"},
+		// As is the rest of the directory.
+		{"online", "0-3\n"},
+	}
+
+	for _, tt := range tests {
+		if got := read(tt.file); got != tt.want {
+			t.Errorf("Read(%s) = %q, want %q", tt.file, got, tt.want)
+		}
+	}
+
+	// The topology must be consistent across the container's cpus: distinct
+	// cores, each one being its own (only) thread.
+	cores := map[string]bool{}
+	for _, cpu := range []int{1, 2} {
+		dir := fmt.Sprintf("cpu%d/topology/", cpu)
+		cores[read(dir+"core_id")] = true
+		if got, want := read(dir+"thread_siblings_list"), fmt.Sprintf("%d\n", cpu); got != want {
+			t.Errorf("cpu%d thread siblings = %q, want %q", cpu, got, want)
+		}
+	}
+	if len(cores) != 2 {
+		t.Errorf("container's cpus span %d cores, want 2", len(cores))
+	}
+}
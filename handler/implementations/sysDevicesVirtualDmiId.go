@@ -20,6 +20,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -78,6 +79,46 @@ import (
 //
 // 00000000-0000-0000-0000-<sys-cntr-id-03> // no 'product_uuid' found
 //
+// As in the host, 'product_uuid' is only readable by the root user. Besides
+// the mode-bits enforced by the kernel, the handler itself rejects (EACCES)
+// requests originated by non-root container processes, as these could
+// otherwise get hold of the uuid value through a file descriptor inherited
+// from a privileged process.
+//
+// * /sys/devices/virtual/dmi/id/bios_{vendor,version,date}
+// * /sys/devices/virtual/dmi/id/{board,chassis,product}_serial
+// * /sys/devices/virtual/dmi/id/{board,chassis}_asset_tag
+// * /sys/devices/virtual/dmi/id/modalias
+//
+// These nodes identify the host's firmware and hardware units, so generic
+// values are displayed in their place. The 'modalias' node carries the bios
+// attributes too, so these are replaced with the generic ones. Nodes describing the platform (e.g., 'sys_vendor',
+// 'product_name') are left as is, as these are commonly relied upon to detect
+// the cloud/hypervisor being used.
+//
+// Notice that /sys/class/dmi/id is a symlink to this directory, so accesses
+// through it are served by this handler too.
+//
+
+// Generic values displayed through the dmi/id nodes identifying the host.
+var dmiGenericValues = map[string]string{
+	"bios_vendor":       "Not Specified",
+	"bios_version":      "Not Specified",
+	"bios_date":         "Not Specified",
+	"board_serial":      "Not Specified",
+	"board_asset_tag":   "Not Specified",
+	"chassis_serial":    "Not Specified",
+	"chassis_asset_tag": "Not Specified",
+	"product_serial":    "Not Specified",
+}
+
+// Fields of the dmi/id 'modalias' node (i.e., "dmi:bvn<bios_vendor>:bvr...")
+// carrying the values of the generic nodes.
+var dmiModaliasFields = map[string]string{
+	"bvn": "bios_vendor",
+	"bvr": "bios_version",
+	"bd":  "bios_date",
+}
 
 // UUID constants as per rfc/4122
 const (
@@ -109,6 +150,60 @@ var SysDevicesVirtualDmiId_Handler = &SysDevicesVirtualDmiId{
 				Size:    4096,
 				Enabled: true,
 			},
+			"bios_vendor": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0444)),
+				Size:    4096,
+				Enabled: true,
+			},
+			"bios_version": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0444)),
+				Size:    4096,
+				Enabled: true,
+			},
+			"bios_date": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0444)),
+				Size:    4096,
+				Enabled: true,
+			},
+			"board_serial": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0400)),
+				Size:    4096,
+				Enabled: true,
+			},
+			"board_asset_tag": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0444)),
+				Size:    4096,
+				Enabled: true,
+			},
+			"chassis_serial": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0400)),
+				Size:    4096,
+				Enabled: true,
+			},
+			"chassis_asset_tag": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0444)),
+				Size:    4096,
+				Enabled: true,
+			},
+			"product_serial": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0400)),
+				Size:    4096,
+				Enabled: true,
+			},
+			"modalias": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0444)),
+				Size:    4096,
+				Enabled: true,
+			},
 		},
 	},
 }
@@ -183,6 +278,21 @@ func (h *SysDevicesVirtualDmiId) Open(
 			flags&syscall.O_RDWR == syscall.O_RDWR {
 			return false, fuse.IOerror{Code: syscall.EACCES}
 		}
+		if !req.IsCntrRoot() {
+			return false, fuse.IOerror{Code: syscall.EACCES}
+		}
+		return false, nil
+	}
+
+	if v, ok := h.EmuResourceMap[resource]; ok {
+		if flags&syscall.O_WRONLY == syscall.O_WRONLY ||
+			flags&syscall.O_RDWR == syscall.O_RDWR {
+			return false, fuse.IOerror{Code: syscall.EACCES}
+		}
+		// Serial numbers are only readable by the root user.
+		if v.Mode&0044 == 0 && !req.IsCntrRoot() {
+			return false, fuse.IOerror{Code: syscall.EACCES}
+		}
 		return false, nil
 	}
 
@@ -198,14 +308,23 @@ func (h *SysDevicesVirtualDmiId) Read(
 	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
 		req.ID, h.Name, n.Name())
 
-	if req.Offset != 0 {
-		return 0, nil
-	}
-
 	switch resource {
 
 	case "product_uuid":
+		if !req.IsCntrRoot() {
+			return 0, fuse.IOerror{Code: syscall.EACCES}
+		}
 		return h.readProductUuid(n, req)
+
+	case "modalias":
+		return h.readModalias(n, req)
+	}
+
+	if val, ok := dmiGenericValues[resource]; ok {
+		if v, ok := h.EmuResourceMap[resource]; ok && v.Mode&0044 == 0 && !req.IsCntrRoot() {
+			return 0, fuse.IOerror{Code: syscall.EACCES}
+		}
+		return readWindow(req, []byte(val+"\n"))
 	}
 
 	return readHostFs(h, n, req.Offset, &req.Data)
@@ -361,25 +480,67 @@ func (h *SysDevicesVirtualDmiId) readProductUuid(
 	cntr.Lock()
 	defer cntr.Unlock()
 
-	// Check if this product_uuid value has been initialized for this container.
-	sz, err := cntr.Data(path, req.Offset, &req.Data)
+	// Check if this product_uuid value has been initialized for this container
+	// (the buffer fits the uuid string along with its trailing newline).
+	data := make([]byte, 64)
+	sz, err := cntr.Data(path, 0, &data)
 	if err != nil && err != io.EOF {
 		return 0, fuse.IOerror{Code: syscall.EINVAL}
 	}
 
-	if req.Offset == 0 && sz == 0 && err == io.EOF {
+	if sz == 0 {
 		// Create an artificial (but consistent) container uuid value and store it
 		// in cache.
 		cntrUuid := h.CreateCntrUuid(cntr)
 
-		req.Data = []byte(cntrUuid + "\n")
-		err = cntr.SetData(path, 0, req.Data)
+		data = []byte(cntrUuid + "\n")
+		err = cntr.SetData(path, 0, data)
 		if err != nil {
 			return 0, fuse.IOerror{Code: syscall.EINVAL}
 		}
 	}
 
-	return len(req.Data), nil
+	return readWindow(req, data)
+}
+
+// readModalias renders the host's modalias with generic bios attributes.
+func (h *SysDevicesVirtualDmiId) readModalias(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	data, err := n.ReadFile()
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Split(strings.TrimSuffix(string(data), "\n"), ":")
+
+	for i, f := range fields {
+		for prefix, node := range dmiModaliasFields {
+			if !strings.HasPrefix(f, prefix) {
+				continue
+			}
+			fields[i] = prefix + dmiModaliasFilter(dmiGenericValues[node])
+			break
+		}
+	}
+
+	return readWindow(req, []byte(strings.Join(fields, ":")+"\n"))
+}
+
+// dmiModaliasFilter filters out the characters that the kernel leaves out of
+// the modalias values (i.e., non-printable ones, spaces and colons).
+func dmiModaliasFilter(val string) string {
+
+	var sb strings.Builder
+
+	for _, c := range val {
+		if c > ' ' && c < 127 && c != ':' {
+			sb.WriteRune(c)
+		}
+	}
+
+	return sb.String()
 }
 
 // Method is public exclusively for unit-testing purposes.
@@ -17,11 +17,14 @@
 package implementations_test
 
 import (
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
 	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mocks"
 )
 
 func TestSysDevicesVirtualDmiId_CreateCntrUuid(t *testing.T) {
@@ -133,3 +136,189 @@ func TestSysDevicesVirtualDmiId_CreateCntrUuid(t *testing.T) {
 		})
 	}
 }
+
+func TestSysDevicesVirtualDmiId_ProductUuidAccess(t *testing.T) {
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("HostUuid").Return("abcdefgh-ijkl-mnop-qrst-uvwxyz123456")
+
+	h := &implementations.SysDevicesVirtualDmiId{
+		HandlerBase: domain.HandlerBase{
+			Name:    "SysDevicesVirtualDmiId",
+			Path:    "/sys/devices/virtual/dmi/id",
+			Service: hs,
+		},
+	}
+
+	cntr := css.ContainerCreate(
+		"012345678901",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	tests := []struct {
+		name    string
+		uid     uint32
+		gid     uint32
+		want    string
+		wantErr error
+	}{
+		// Test-case 1: Container's root user.
+		{"1", 231072, 231072, "abcdefgh-ijkl-mnop-qrst-012345678901\n", nil},
+		// Test-case 2: Container's root uid with non-root gid.
+		{"2", 231072, 232072, "abcdefgh-ijkl-mnop-qrst-012345678901\n", nil},
+		// Test-case 3: Non-root container user.
+		{"3", 232072, 232072, "", fuse.IOerror{Code: syscall.EACCES}},
+		// Test-case 4: Host's root user (not mapped into the container).
+		{"4", 0, 0, "", fuse.IOerror{Code: syscall.EACCES}},
+		// Test-case 5: Host user right past the container's uid range.
+		{"5", 231072 + 65535, 231072, "", fuse.IOerror{Code: syscall.EACCES}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := ios.NewIOnode(
+				"product_uuid",
+				"/sys/devices/virtual/dmi/id/product_uuid",
+				0,
+			)
+
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Uid:       tt.uid,
+				Gid:       tt.gid,
+				Container: cntr,
+				Data:      make([]byte, 64),
+			}
+
+			if _, err := h.Open(n, req); err != tt.wantErr {
+				t.Fatalf("Open() error = %v, want %v", err, tt.wantErr)
+			}
+
+			sz, err := h.Read(n, req)
+			if err != tt.wantErr {
+				t.Fatalf("Read() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := string(req.Data[:sz]); got != tt.want {
+				t.Errorf("Read() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSysDevicesVirtualDmiId_GenericValues(t *testing.T) {
+
+	const modalias = "dmi:bvnAmericanMegatrendsInc.:bvr2.3.1:bd03/15/2021:br5.17:svnDellInc.:pnPowerEdgeR640:pvr:rvnDellInc.:rn0H28RR:rvrA00:cvnDellInc.:ct23:cvr:sku0716:\n"
+
+	hostFiles := map[string]string{
+		"/sys/devices/virtual/dmi/id/modalias":    modalias,
+		"/sys/devices/virtual/dmi/id/sys_vendor":  "Dell Inc.\n",
+		"/sys/devices/virtual/dmi/id/bios_vendor": "American Megatrends Inc.\n",
+	}
+	for path, val := range hostFiles {
+		if err := ios.NewIOnode("", path, 0644).WriteFile([]byte(val)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer ios.RemoveAllIOnodes()
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("IOService").Return(ios)
+
+	h := implementations.SysDevicesVirtualDmiId_Handler
+	h.SetService(hs)
+
+	cntr := css.ContainerCreate(
+		"c-dmi",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	tests := []struct {
+		name    string
+		cntr    domain.ContainerIface
+		node    string
+		uid     uint32
+		want    string
+		wantErr error
+	}{
+		// Test-case 1: Generic bios vendor.
+		{"1", cntr, "bios_vendor", 231072, "Not Specified\n", nil},
+		// Test-case 2: Generic bios date, readable by non-root users.
+		{"2", cntr, "bios_date", 232072, "Not Specified\n", nil},
+		// Test-case 3: Generic serial number.
+		{"3", cntr, "board_serial", 231072, "Not Specified\n", nil},
+		// Test-case 4: Serial numbers are only readable by the root user.
+		{"4", cntr, "chassis_serial", 232072, "", fuse.IOerror{Code: syscall.EACCES}},
+		// Test-case 5: Generic bios attributes within modalias.
+		{"5", cntr, "modalias", 232072,
+			"dmi:bvnNotSpecified:bvrNotSpecified:bdNotSpecified:br5.17:svnDellInc.:pnPowerEdgeR640:pvr:rvnDellInc.:rn0H28RR:rvrA00:cvnDellInc.:ct23:cvr:sku0716:\n", nil},
+		// Test-case 6: Platform nodes are left as is.
+		{"6", cntr, "sys_vendor", 232072, "Dell Inc.\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/sys/devices/virtual/dmi/id/" + tt.node
+			n := ios.NewIOnode(tt.node, path, 0)
+
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Uid:       tt.uid,
+				Gid:       231072,
+				Container: tt.cntr,
+				Data:      make([]byte, 4096),
+			}
+
+			if _, err := h.Open(n, req); err != tt.wantErr {
+				t.Fatalf("Open(%s) error = %v, want %v", path, err, tt.wantErr)
+			}
+
+			sz, err := h.Read(n, req)
+			if err != tt.wantErr {
+				t.Fatalf("Read(%s) error = %v, want %v", path, err, tt.wantErr)
+			}
+			if got := string(req.Data[:sz]); got != tt.want {
+				t.Errorf("Read(%s) = %q, want %q", path, got, tt.want)
+			}
+		})
+	}
+
+	// Emulated nodes are writable by no one.
+	n := ios.NewIOnode("bios_vendor", "/sys/devices/virtual/dmi/id/bios_vendor", 0)
+	n.SetOpenFlags(syscall.O_WRONLY)
+	req := &domain.HandlerRequest{Pid: 1001, Uid: 231072, Gid: 231072, Container: cntr}
+	if _, err := h.Open(n, req); err != (fuse.IOerror{Code: syscall.EACCES}) {
+		t.Errorf("Open(bios_vendor, O_WRONLY) error = %v, want EACCES", err)
+	}
+
+	// Emulated nodes are listed along with the host's ones.
+	dir := ios.NewIOnode("id", "/sys/devices/virtual/dmi/id", 0)
+	infos, err := h.ReadDirAll(dir, &domain.HandlerRequest{Pid: 1001, Container: cntr})
+	if err != nil {
+		t.Fatalf("ReadDirAll() unexpected error: %v", err)
+	}
+	listed := make(map[string]int)
+	for _, info := range infos {
+		listed[info.Name()]++
+	}
+	for _, name := range []string{"bios_vendor", "product_serial", "modalias", "product_uuid", "sys_vendor"} {
+		if listed[name] != 1 {
+			t.Errorf("ReadDirAll() lists %q %d times, want once", name, listed[name])
+		}
+	}
+}
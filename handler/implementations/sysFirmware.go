@@ -0,0 +1,212 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+)
+
+//
+// /sys/firmware handler
+//
+// Emulated resources:
+//
+// * /sys/firmware
+//
+// This hierarchy exposes the host's firmware tables and variables (e.g., raw
+// DMI/SMBIOS and ACPI tables, EFI variables, device-tree), which identify the
+// host's hardware and are of no use to the processes of a sys container. The
+// directory is presented as an empty one, so that tools probing its presence
+// (e.g., to detect EFI systems) behave as they would in a platform with no
+// firmware tables exposed.
+//
+
+type SysFirmware struct {
+	domain.HandlerBase
+}
+
+var SysFirmware_Handler = &SysFirmware{
+	domain.HandlerBase{
+		Name:    "SysFirmware",
+		Path:    "/sys/firmware",
+		Enabled: true,
+		EmuResourceMap: map[string]*domain.EmuResource{
+			".": {
+				Kind:    domain.DirEmuResource,
+				Mode:    os.ModeDir | os.FileMode(uint32(0755)),
+				Enabled: true,
+			},
+		},
+	},
+}
+
+func (h *SysFirmware) Lookup(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (os.FileInfo, error) {
+
+	logrus.Debugf("Executing Lookup() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if !h.isRoot(n) {
+		return nil, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	// Skip uid/gid remaps, as the host's firmware nodes are owned by the host's
+	// root user.
+	req.SkipIdRemap = true
+
+	info := &domain.FileInfo{
+		Fname:    "firmware",
+		Fmode:    h.EmuResourceMap["."].Mode,
+		FmodTime: time.Now(),
+		FisDir:   true,
+	}
+
+	return info, nil
+}
+
+func (h *SysFirmware) Open(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (bool, error) {
+
+	logrus.Debugf("Executing Open() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if !h.isRoot(n) {
+		return false, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	return false, nil
+}
+
+func (h *SysFirmware) Read(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if !h.isRoot(n) {
+		return 0, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	return 0, fuse.IOerror{Code: syscall.EISDIR}
+}
+
+func (h *SysFirmware) Write(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Write() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if !h.isRoot(n) {
+		return 0, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	return 0, fuse.IOerror{Code: syscall.EISDIR}
+}
+
+func (h *SysFirmware) ReadDirAll(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]os.FileInfo, error) {
+
+	logrus.Debugf("Executing ReadDirAll() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if !h.isRoot(n) {
+		return nil, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	return nil, nil
+}
+
+func (h *SysFirmware) ReadLink(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (string, error) {
+
+	logrus.Debugf("Executing ReadLink() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return "", fuse.IOerror{Code: syscall.ENOENT}
+}
+
+func (h *SysFirmware) GetName() string {
+	return h.Name
+}
+
+func (h *SysFirmware) GetPath() string {
+	return h.Path
+}
+
+func (h *SysFirmware) GetService() domain.HandlerServiceIface {
+	return h.Service
+}
+
+func (h *SysFirmware) GetEnabled() bool {
+	return h.Enabled
+}
+
+func (h *SysFirmware) SetEnabled(b bool) {
+	h.Enabled = b
+}
+
+func (h *SysFirmware) GetResourcesList() []string {
+
+	var resources []string
+
+	for resourceKey, resource := range h.EmuResourceMap {
+		resource.Mutex.Lock()
+		if !resource.Enabled {
+			resource.Mutex.Unlock()
+			continue
+		}
+		resource.Mutex.Unlock()
+
+		resources = append(resources, filepath.Join(h.GetPath(), resourceKey))
+	}
+
+	return resources
+}
+
+func (h *SysFirmware) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex {
+	resource, ok := h.EmuResourceMap[n.Name()]
+	if !ok {
+		return nil
+	}
+
+	return &resource.Mutex
+}
+
+func (h *SysFirmware) SetService(hs domain.HandlerServiceIface) {
+	h.Service = hs
+}
+
+// isRoot returns true if the given node is the /sys/firmware directory itself,
+// the only node presented to the sys container.
+func (h *SysFirmware) isRoot(n domain.IOnodeIface) bool {
+	return filepath.Clean(n.Path()) == h.Path
+}
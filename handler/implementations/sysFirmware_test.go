@@ -0,0 +1,114 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+)
+
+func TestSysFirmware(t *testing.T) {
+
+	// Host's firmware tree.
+	hostFiles := []string{
+		"/sys/firmware/dmi/tables/DMI",
+		"/sys/firmware/acpi/tables/DSDT",
+		"/sys/firmware/efi/efivars/Boot0000-8be4df61-93ca-11d2-aa0d-00e098032b8c",
+	}
+	for _, path := range hostFiles {
+		if err := ios.NewIOnode("", path, 0644).WriteFile([]byte("data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer ios.RemoveAllIOnodes()
+
+	h := implementations.SysFirmware_Handler
+
+	cntr := css.ContainerCreate(
+		"c-firmware",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	newReq := func() *domain.HandlerRequest {
+		return &domain.HandlerRequest{
+			Pid:       1001,
+			Uid:       231072,
+			Gid:       231072,
+			Container: cntr,
+			Data:      make([]byte, 64),
+		}
+	}
+
+	// The firmware directory is present, but empty.
+	root := ios.NewIOnode("firmware", "/sys/firmware", 0)
+
+	info, err := h.Lookup(root, newReq())
+	if err != nil {
+		t.Fatalf("Lookup(/sys/firmware) unexpected error: %v", err)
+	}
+	if !info.IsDir() {
+		t.Errorf("Lookup(/sys/firmware) is not a directory")
+	}
+
+	infos, err := h.ReadDirAll(root, newReq())
+	if err != nil {
+		t.Fatalf("ReadDirAll(/sys/firmware) unexpected error: %v", err)
+	}
+	if len(infos) != 0 {
+		var names []string
+		for _, i := range infos {
+			names = append(names, i.Name())
+		}
+		t.Errorf("ReadDirAll(/sys/firmware) = %v, want no entries", names)
+	}
+
+	// The host's firmware nodes are absent.
+	enoent := fuse.IOerror{Code: syscall.ENOENT}
+
+	for _, path := range append(hostFiles,
+		"/sys/firmware/dmi", "/sys/firmware/acpi/tables", "/sys/firmware/efi") {
+
+		n := ios.NewIOnode(filepath.Base(path), path, 0)
+
+		if _, err := h.Lookup(n, newReq()); err != enoent {
+			t.Errorf("Lookup(%s) error = %v, want ENOENT", path, err)
+		}
+		if _, err := h.Open(n, newReq()); err != enoent {
+			t.Errorf("Open(%s) error = %v, want ENOENT", path, err)
+		}
+		if _, err := h.Read(n, newReq()); err != enoent {
+			t.Errorf("Read(%s) error = %v, want ENOENT", path, err)
+		}
+		if _, err := h.ReadDirAll(n, newReq()); err != enoent {
+			t.Errorf("ReadDirAll(%s) error = %v, want ENOENT", path, err)
+		}
+	}
+}
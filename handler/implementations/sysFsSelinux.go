@@ -0,0 +1,170 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+)
+
+//
+// /sys/fs/selinux handler
+//
+// SELinux-aware software (e.g., package managers, container engines) checks
+// for a selinuxfs mounted at /sys/fs/selinux to decide whether to apply SELinux
+// labels to the files it creates. These labeling operations are not allowed
+// within unprivileged sys containers, so we present /sys/fs/selinux as an empty
+// directory: being a sysbox-fs mount, its filesystem type is no longer
+// selinuxfs, and its content (e.g., the "enforce" node) is absent (ENOENT), so
+// this software falls back to its non-SELinux code path.
+//
+// The mountpoint is only set up when present in the host's sysfs (see
+// mount.SysfsOptionalMounts), as there would be nothing to hide otherwise.
+//
+
+type SysFsSelinux struct {
+	domain.HandlerBase
+}
+
+var SysFsSelinux_Handler = &SysFsSelinux{
+	domain.HandlerBase{
+		Name:    "SysFsSelinux",
+		Path:    "/sys/fs/selinux",
+		Enabled: true,
+	},
+}
+
+func (h *SysFsSelinux) Lookup(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (os.FileInfo, error) {
+
+	logrus.Debugf("Executing Lookup() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if h.isSuppressed(n) {
+		return nil, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	// The mountpoint is presented as "nobody:nogroup" within the sys container
+	// (refer to /sys/kernel handler for details).
+	req.SkipIdRemap = true
+
+	return n.Lstat()
+}
+
+func (h *SysFsSelinux) Open(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (bool, error) {
+
+	logrus.Debugf("Executing Open() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if h.isSuppressed(n) {
+		return false, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	return false, n.Open()
+}
+
+func (h *SysFsSelinux) Read(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return 0, fuse.IOerror{Code: syscall.ENOENT}
+}
+
+func (h *SysFsSelinux) Write(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Write() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return 0, fuse.IOerror{Code: syscall.ENOENT}
+}
+
+func (h *SysFsSelinux) ReadDirAll(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]os.FileInfo, error) {
+
+	logrus.Debugf("Executing ReadDirAll() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if h.isSuppressed(n) {
+		return nil, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	return nil, nil
+}
+
+func (h *SysFsSelinux) ReadLink(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (string, error) {
+
+	logrus.Debugf("Executing ReadLink() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return "", fuse.IOerror{Code: syscall.ENOENT}
+}
+
+func (h *SysFsSelinux) GetName() string {
+	return h.Name
+}
+
+func (h *SysFsSelinux) GetPath() string {
+	return h.Path
+}
+
+func (h *SysFsSelinux) GetService() domain.HandlerServiceIface {
+	return h.Service
+}
+
+func (h *SysFsSelinux) GetEnabled() bool {
+	return h.Enabled
+}
+
+func (h *SysFsSelinux) SetEnabled(b bool) {
+	h.Enabled = b
+}
+
+func (h *SysFsSelinux) GetResourcesList() []string {
+	return []string{h.GetPath()}
+}
+
+func (h *SysFsSelinux) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex {
+	return nil
+}
+
+func (h *SysFsSelinux) SetService(hs domain.HandlerServiceIface) {
+	h.Service = hs
+}
+
+// isSuppressed returns true if the given node lies beneath /sys/fs/selinux.
+// The mountpoint itself is always displayed.
+func (h *SysFsSelinux) isSuppressed(n domain.IOnodeIface) bool {
+	return strings.HasPrefix(n.Path(), h.Path+"/")
+}
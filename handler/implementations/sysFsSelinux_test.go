@@ -0,0 +1,92 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+)
+
+func TestSysFsSelinux(t *testing.T) {
+
+	// Host's /sys/fs/selinux layout.
+	if err := ios.NewIOnode("", "/sys/fs/selinux", 0755).MkdirAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ios.NewIOnode("", "/sys/fs/selinux/enforce", 0644).WriteFile([]byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	defer ios.RemoveAllIOnodes()
+
+	cntr := css.ContainerCreate(
+		"c1",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	h := &implementations.SysFsSelinux{
+		HandlerBase: domain.HandlerBase{
+			Name:    "SysFsSelinux",
+			Path:    "/sys/fs/selinux",
+			Service: hds,
+		},
+	}
+
+	req := &domain.HandlerRequest{
+		Pid:       1001,
+		Container: cntr,
+		Data:      make([]byte, 16),
+	}
+
+	// The mountpoint is displayed, but empty.
+	dir := ios.NewIOnode("selinux", "/sys/fs/selinux", 0)
+	if _, err := h.Lookup(dir, req); err != nil {
+		t.Errorf("Lookup(/sys/fs/selinux) unexpected error: %v", err)
+	}
+	infos, err := h.ReadDirAll(dir, req)
+	if err != nil {
+		t.Fatalf("ReadDirAll(/sys/fs/selinux) unexpected error: %v", err)
+	}
+	if len(infos) != 0 {
+		t.Errorf("ReadDirAll(/sys/fs/selinux) = %d entries, want none", len(infos))
+	}
+
+	// Its content is absent.
+	wantErr := fuse.IOerror{Code: syscall.ENOENT}
+	n := ios.NewIOnode("enforce", "/sys/fs/selinux/enforce", 0)
+	if _, err := h.Lookup(n, req); err != wantErr {
+		t.Errorf("Lookup(/sys/fs/selinux/enforce) error = %v, want %v", err, wantErr)
+	}
+	if _, err := h.Open(n, req); err != wantErr {
+		t.Errorf("Open(/sys/fs/selinux/enforce) error = %v, want %v", err, wantErr)
+	}
+	if _, err := h.Read(n, req); err != wantErr {
+		t.Errorf("Read(/sys/fs/selinux/enforce) error = %v, want %v", err, wantErr)
+	}
+}
@@ -41,8 +41,8 @@ import (
 //
 // We are also including "/sys/kernel/security" dir as part of the emulated
 // resources to ensure that system-wide security-related details are not exposed
-// within sysbox containers. In the future, we could expose specific nodes within
-// this hierarchy if we see a need for it.
+// within sysbox containers. The few nodes within this hierarchy that are exposed
+// are served by the /sys/kernel/security handler.
 //
 // Emulated resources:
 //
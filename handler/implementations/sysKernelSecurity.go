@@ -0,0 +1,281 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+)
+
+//
+// /sys/kernel/security handler
+//
+// The securityfs hierarchy holds system-wide security details (e.g., LSM
+// policies), so it's not exposed within sys containers (see /sys/kernel
+// handler), except for the nodes below.
+//
+// Emulated resources:
+//
+// * /sys/kernel/security/lsm
+//
+// Documentation: Comma-separated list of the LSMs active in the system, in
+// the order in which these are invoked (e.g., "lockdown,capability,yama,
+// apparmor,bpf,landlock"). Software probes it to discover the LSMs it has to
+// deal with.
+//
+// LSMs that sysbox-fs presents as absent within the sys container are dropped
+// from the list, so that this detection logic reaches the same conclusion as
+// the rest of the emulation: that's the case of SELinux (see /sys/fs/selinux
+// handler). The node is only exposed when present in the host.
+//
+
+type SysKernelSecurity struct {
+	domain.HandlerBase
+}
+
+var SysKernelSecurity_Handler = &SysKernelSecurity{
+	domain.HandlerBase{
+		Name:    "SysKernelSecurity",
+		Path:    "/sys/kernel/security",
+		Enabled: true,
+		EmuResourceMap: map[string]*domain.EmuResource{
+			"lsm": {
+				Kind:    domain.FileEmuResource,
+				Mode:    os.FileMode(uint32(0444)),
+				Enabled: true,
+				Size:    4096,
+			},
+		},
+	},
+}
+
+func (h *SysKernelSecurity) Lookup(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (os.FileInfo, error) {
+
+	var resource = n.Name()
+
+	logrus.Debugf("Executing Lookup() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, resource)
+
+	if n.Path() == h.Path {
+		return &domain.FileInfo{
+			Fname:    resource,
+			Fmode:    os.ModeDir | os.FileMode(uint32(0755)),
+			FmodTime: time.Now(),
+			FisDir:   true,
+		}, nil
+	}
+
+	v, ok := h.EmuResourceMap[resource]
+	if !ok || !h.isEmulated(n) {
+		return nil, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	if _, err := n.Stat(); err != nil {
+		return nil, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	return &domain.FileInfo{
+		Fname:    resource,
+		Fmode:    v.Mode,
+		FmodTime: time.Now(),
+		Fsize:    v.Size,
+	}, nil
+}
+
+func (h *SysKernelSecurity) Open(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (bool, error) {
+
+	logrus.Debugf("Executing Open() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if n.Path() == h.Path {
+		return false, nil
+	}
+
+	if !h.isEmulated(n) {
+		return false, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	flags := n.OpenFlags()
+	if flags&syscall.O_WRONLY == syscall.O_WRONLY ||
+		flags&syscall.O_RDWR == syscall.O_RDWR {
+		return false, fuse.IOerror{Code: syscall.EACCES}
+	}
+
+	return false, nil
+}
+
+func (h *SysKernelSecurity) Read(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	var resource = n.Name()
+
+	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, resource)
+
+	if !h.isEmulated(n) {
+		return 0, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	switch resource {
+	case "lsm":
+		return h.readLsm(n, req)
+	}
+
+	return 0, nil
+}
+
+func (h *SysKernelSecurity) Write(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	logrus.Debugf("Executing Write() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return 0, fuse.IOerror{Code: syscall.EACCES}
+}
+
+func (h *SysKernelSecurity) ReadDirAll(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) ([]os.FileInfo, error) {
+
+	logrus.Debugf("Executing ReadDirAll() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	if n.Path() != h.Path {
+		return nil, fuse.IOerror{Code: syscall.ENOENT}
+	}
+
+	var fileEntries []os.FileInfo
+
+	ios := h.Service.IOService()
+
+	for k, v := range h.EmuResourceMap {
+		if _, err := ios.NewIOnode(k, h.Path+"/"+k, 0).Stat(); err != nil {
+			continue
+		}
+
+		fileEntries = append(fileEntries, &domain.FileInfo{
+			Fname:    k,
+			Fmode:    v.Mode,
+			FmodTime: time.Now(),
+			Fsize:    v.Size,
+		})
+	}
+
+	return fileEntries, nil
+}
+
+func (h *SysKernelSecurity) ReadLink(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (string, error) {
+
+	logrus.Debugf("Executing ReadLink() for req-id: %#x, handler: %s, resource: %s",
+		req.ID, h.Name, n.Name())
+
+	return "", fuse.IOerror{Code: syscall.EINVAL}
+}
+
+func (h *SysKernelSecurity) GetName() string {
+	return h.Name
+}
+
+func (h *SysKernelSecurity) GetPath() string {
+	return h.Path
+}
+
+func (h *SysKernelSecurity) GetService() domain.HandlerServiceIface {
+	return h.Service
+}
+
+func (h *SysKernelSecurity) GetEnabled() bool {
+	return h.Enabled
+}
+
+func (h *SysKernelSecurity) SetEnabled(b bool) {
+	h.Enabled = b
+}
+
+// The /sys/kernel/security dir is already exposed by the /sys/kernel handler.
+func (h *SysKernelSecurity) GetResourcesList() []string {
+	return nil
+}
+
+func (h *SysKernelSecurity) GetResourceMutex(n domain.IOnodeIface) *sync.Mutex {
+	resource, ok := h.EmuResourceMap[n.Name()]
+	if !ok {
+		return nil
+	}
+
+	return &resource.Mutex
+}
+
+func (h *SysKernelSecurity) SetService(hs domain.HandlerServiceIface) {
+	h.Service = hs
+}
+
+// isEmulated returns true if the given node is one of the emulated nodes right
+// under /sys/kernel/security (the rest of the securityfs hierarchy is hidden).
+func (h *SysKernelSecurity) isEmulated(n domain.IOnodeIface) bool {
+
+	if _, ok := h.EmuResourceMap[n.Name()]; !ok {
+		return false
+	}
+
+	return n.Path() == h.Path+"/"+n.Name()
+}
+
+// readLsm displays the host's LSMs, short of the ones presented as absent
+// within the container.
+func (h *SysKernelSecurity) readLsm(
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	data, err := n.ReadFile()
+	if err != nil {
+		logrus.Debugf("Unable to read %s: %v", n.Path(), err)
+		return 0, fuse.IOerror{Code: syscall.EIO}
+	}
+
+	// LSMs presented as absent within the container.
+	hidden := map[string]bool{
+		"selinux": true,
+	}
+
+	var lsms []string
+	for _, lsm := range strings.Split(strings.TrimSpace(string(data)), ",") {
+		if lsm == "" || hidden[lsm] {
+			continue
+		}
+		lsms = append(lsms, lsm)
+	}
+
+	// As the kernel does, no trailing newline is displayed.
+	return readWindow(req, []byte(strings.Join(lsms, ",")))
+}
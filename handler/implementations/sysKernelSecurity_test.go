@@ -0,0 +1,122 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+func TestSysKernelSecurity_Lsm(t *testing.T) {
+
+	const hostLsm = "lockdown,capability,yama,selinux,bpf,landlock\n"
+
+	// Host's /sys/kernel/security layout.
+	if err := ios.NewIOnode("", "/sys/kernel/security/lsm", 0444).WriteFile([]byte(hostLsm)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ios.NewIOnode("", "/sys/kernel/security/apparmor", 0755).MkdirAll(); err != nil {
+		t.Fatal(err)
+	}
+	defer ios.RemoveAllIOnodes()
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("IOService").Return(ios)
+
+	h := &implementations.SysKernelSecurity{
+		HandlerBase: domain.HandlerBase{
+			Name:           "SysKernelSecurity",
+			Path:           "/sys/kernel/security",
+			EmuResourceMap: implementations.SysKernelSecurity_Handler.EmuResourceMap,
+			Service:        hs,
+		},
+	}
+
+	cntr := css.ContainerCreate(
+		"c1",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	tests := []struct {
+		name string
+		host string
+		want string
+	}{
+		// Test-case 1: Selinux must be hidden.
+		{"selinux", hostLsm, "lockdown,capability,yama,bpf,landlock"},
+		// Test-case 2: No selinux in the host.
+		{"no-selinux", "lockdown,capability,apparmor\n", "lockdown,capability,apparmor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := ios.NewIOnode("lsm", "/sys/kernel/security/lsm", 0)
+			if err := n.WriteFile([]byte(tt.host)); err != nil {
+				t.Fatal(err)
+			}
+			req := &domain.HandlerRequest{
+				Pid:       1001,
+				Container: cntr,
+				Data:      make([]byte, 4096),
+			}
+
+			sz, err := h.Read(n, req)
+			if err != nil {
+				t.Fatalf("Read() unexpected error: %v", err)
+			}
+			if got := string(req.Data[:sz]); got != tt.want {
+				t.Errorf("Read() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Only the lsm node is listed.
+	dir := ios.NewIOnode("security", "/sys/kernel/security", 0)
+	entries, err := h.ReadDirAll(dir, &domain.HandlerRequest{Container: cntr})
+	if err != nil {
+		t.Fatalf("ReadDirAll() unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "lsm" {
+		t.Errorf("ReadDirAll() = %v, want [lsm]", entries)
+	}
+
+	// The rest of the hierarchy is hidden.
+	n := ios.NewIOnode("apparmor", "/sys/kernel/security/apparmor", 0)
+	if _, err := h.Lookup(n, &domain.HandlerRequest{}); err != (fuse.IOerror{Code: syscall.ENOENT}) {
+		t.Errorf("Lookup(apparmor) error = %v, want ENOENT", err)
+	}
+
+	// The lsm node is read-only.
+	n = ios.NewIOnode("lsm", "/sys/kernel/security/lsm", 0)
+	if _, err := h.Write(n, &domain.HandlerRequest{Data: []byte("bpf")}); err != (fuse.IOerror{Code: syscall.EACCES}) {
+		t.Errorf("Write(lsm) error = %v, want EACCES", err)
+	}
+}
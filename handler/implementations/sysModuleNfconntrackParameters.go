@@ -103,10 +103,6 @@ func (h *SysModuleNfconntrackParameters) Read(
 	logrus.Debugf("Executing Read() for req-id: %#x, handler: %s, resource: %s",
 		req.ID, h.Name, resource)
 
-	if req.Offset != 0 {
-		return 0, nil
-	}
-
 	switch resource {
 	case "hashsize":
 		return readCntrData(h, n, req)
@@ -17,10 +17,14 @@
 package implementations
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -86,6 +90,9 @@ func writeCntrData(
 	sz, err := writeFs(h, n, req.Offset, req.Data, pushToFs)
 
 	if ignoreFsErrors {
+		if err != nil {
+			h.GetService().AuditError("write", n, req, err)
+		}
 		err = nil
 		sz = len(req.Data)
 	}
@@ -334,6 +341,55 @@ func writeMinIntToFs(curr, new []byte) (bool, error) {
 	return newInt < currInt, nil
 }
 
+// readWindow serves the given (fully rendered) content of an emulated node at
+// the offset and length of the read request. The kernel may read a node in
+// chunks smaller than its content (e.g., "dd bs=1"), or at an arbitrary offset
+// (i.e., pread()), so content must never be assumed to be served by a single
+// read at offset zero.
+func readWindow(req *domain.HandlerRequest, content []byte) (int, error) {
+
+	if req.Offset >= int64(len(content)) {
+		return 0, io.EOF
+	}
+
+	return copy(req.Data, content[req.Offset:]), nil
+}
+
+// cgroupFilePath returns the path of the given controller's file within the
+// cgroup of the given process, as seen from the host. The v2File is picked for
+// processes in the cgroup v2 unified hierarchy, and v1File otherwise.
+func cgroupFilePath(
+	ios domain.IOServiceIface,
+	pid uint32,
+	ctrl, v2File, v1File string) (string, error) {
+
+	data, err := ios.NewIOnode("", fmt.Sprintf("/proc/%d/cgroup", pid), 0).ReadFile()
+	if err != nil {
+		return "", err
+	}
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		// Format: hierarchy-id:controller-list:cgroup-path
+		fields := strings.SplitN(s.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		if fields[0] == "0" && fields[1] == "" {
+			return filepath.Join("/sys/fs/cgroup", fields[2], v2File), nil
+		}
+
+		for _, c := range strings.Split(fields[1], ",") {
+			if c == ctrl {
+				return filepath.Join("/sys/fs/cgroup", ctrl, fields[2], v1File), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no %s cgroup found for pid %d", ctrl, pid)
+}
+
 // checkIntRange interprets the given data as an integer and checks if it's
 // within the given range (inclusive).
 func checkIntRange(data []byte, min, max int) bool {
@@ -350,6 +406,72 @@ func checkIntRange(data []byte, min, max int) bool {
 	return true
 }
 
+// isNotExistError returns true if the given error (as returned by the
+// passthrough handler) reflects a missing node.
+func isNotExistError(err error) bool {
+	ioErr, ok := err.(fuse.IOerror)
+
+	return ok && ioErr.Code == syscall.ENOENT
+}
+
+// isInterruptedError returns true if the given error reflects a request that
+// was interrupted (see sendNSenterEvent()).
+func isInterruptedError(err error) bool {
+	ioErr, ok := err.(fuse.IOerror)
+
+	return ok && ioErr.Code == syscall.EINTR
+}
+
+// isPermissionError returns true if the given error (as returned by the
+// passthrough handler) reflects an access rejected by the kernel, such as a
+// write to a sysctl that is not scoped per namespace.
+func isPermissionError(err error) bool {
+	ioErr, ok := err.(fuse.IOerror)
+
+	return ok && (ioErr.Code == syscall.EPERM || ioErr.Code == syscall.EACCES)
+}
+
+// openNsSysctl opens the given sysctl node within the container's namespaces.
+// Write-mode opens that the kernel rejects are tolerated, as the subsequent
+// write will be served by writeNsSysctl() at container level.
+func openNsSysctl(
+	h domain.HandlerIface,
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (bool, error) {
+
+	nonBlocking, err := h.GetService().GetPassThroughHandler().Open(n, req)
+
+	flags := n.OpenFlags()
+	if isPermissionError(err) &&
+		(flags&syscall.O_WRONLY == syscall.O_WRONLY ||
+			flags&syscall.O_RDWR == syscall.O_RDWR) {
+		return false, nil
+	}
+
+	return nonBlocking, err
+}
+
+// writeNsSysctl writes the given sysctl node within the container's
+// namespaces. If the kernel rejects the write (i.e., the sysctl is a
+// system-wide one), the value is kept at container level instead, and is
+// never pushed to the host. Subsequent reads through the passthrough handler
+// are served out of this container-level copy.
+func writeNsSysctl(
+	h domain.HandlerIface,
+	n domain.IOnodeIface,
+	req *domain.HandlerRequest) (int, error) {
+
+	sz, err := h.GetService().GetPassThroughHandler().Write(n, req)
+	if !isPermissionError(err) {
+		return sz, err
+	}
+
+	logrus.Debugf("Write to %s rejected within container %s namespaces; keeping it at container level",
+		n.Path(), req.Container.ID())
+
+	return writeCntrData(h, n, req, nil)
+}
+
 func padRight(str, pad string, length int) string {
 	for {
 		str += pad
@@ -0,0 +1,152 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package implementations_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/handler/implementations"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+// readChunked reads the given node by issuing consecutive reads of the given
+// size (as the kernel does when the caller's buffer is smaller than the node's
+// content), and returns the reassembled content.
+func readChunked(
+	t *testing.T,
+	h domain.HandlerIface,
+	n domain.IOnodeIface,
+	newReq func() *domain.HandlerRequest,
+	chunk int) string {
+
+	var sb strings.Builder
+
+	for off := int64(0); off < 1<<20; {
+		req := newReq()
+		req.Offset = off
+		req.Data = make([]byte, chunk)
+
+		sz, err := h.Read(n, req)
+		if err != nil && err != io.EOF {
+			t.Fatalf("Read(%s) at offset %d unexpected error: %v", n.Path(), off, err)
+		}
+		if sz > chunk || sz > len(req.Data) {
+			t.Fatalf("Read(%s) at offset %d returned %d bytes, exceeding the %d-byte buffer",
+				n.Path(), off, sz, chunk)
+		}
+		if sz == 0 {
+			break
+		}
+
+		sb.Write(req.Data[:sz])
+		off += int64(sz)
+	}
+
+	return sb.String()
+}
+
+func TestEmulatedNodes_ChunkedRead(t *testing.T) {
+
+	// Host's state backing the emulated nodes.
+	hostFiles := map[string]string{
+		"/proc/sys/kernel/sched_rt_period_us": "1000000\n",
+	}
+	for path, val := range hostFiles {
+		if err := ios.NewIOnode("", path, 0644).WriteFile([]byte(val)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer ios.RemoveAllIOnodes()
+
+	hs := &mocks.HandlerServiceIface{}
+	hs.On("IOService").Return(ios)
+	hs.On("HostUuid").Return("abcdefgh-ijkl-mnop-qrst-uvwxyz123456")
+
+	cntr := css.ContainerCreate(
+		"012345678901",
+		uint32(1001),
+		time.Time{},
+		231072,
+		65535,
+		231072,
+		65535,
+		nil,
+		nil,
+		nil,
+	)
+
+	procSysKernel := implementations.ProcSysKernel_Handler
+	procSysKernel.SetService(hs)
+
+	tests := []struct {
+		name    string
+		handler domain.HandlerIface
+		path    string
+	}{
+		{"swaps", &implementations.ProcSwaps{
+			HandlerBase: domain.HandlerBase{Name: "ProcSwaps", Path: "/proc/swaps", Service: hs},
+		}, "/proc/swaps"},
+		{"sched-rt-period", procSysKernel, "/proc/sys/kernel/sched_rt_period_us"},
+		{"product-uuid", &implementations.SysDevicesVirtualDmiId{
+			HandlerBase: domain.HandlerBase{Name: "SysDevicesVirtualDmiId", Path: "/sys/devices/virtual/dmi/id", Service: hs},
+		}, "/sys/devices/virtual/dmi/id/product_uuid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newReq := func() *domain.HandlerRequest {
+				return &domain.HandlerRequest{
+					Pid:       1001,
+					Uid:       231072,
+					Gid:       231072,
+					Container: cntr,
+				}
+			}
+			n := ios.NewIOnode(tt.path[strings.LastIndex(tt.path, "/")+1:], tt.path, 0)
+
+			// Single read, large enough to fit the whole content.
+			req := newReq()
+			req.Data = make([]byte, 1<<20)
+			sz, err := tt.handler.Read(n, req)
+			if err != nil && err != io.EOF {
+				t.Fatalf("Read(%s) unexpected error: %v", tt.path, err)
+			}
+			want := string(req.Data[:sz])
+			if want == "" {
+				t.Fatalf("Read(%s) returned no content", tt.path)
+			}
+
+			// Byte-sized reads are limited to small nodes, as every read renders
+			// the node's whole content.
+			chunks := []int{64, 4096}
+			if len(want) < 4096 {
+				chunks = append(chunks, 1, 3, 7)
+			}
+
+			for _, chunk := range chunks {
+				if got := readChunked(t, tt.handler, n, newReq, chunk); got != want {
+					t.Errorf("Read(%s) in %d-byte chunks = %q, want %q",
+						tt.path, chunk, got, want)
+				}
+			}
+		})
+	}
+}
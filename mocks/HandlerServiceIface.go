@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	io "io"
+
 	domain "github.com/nestybox/sysbox-fs/domain"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -12,6 +14,11 @@ type HandlerServiceIface struct {
 	mock.Mock
 }
 
+// AuditError provides a mock function with given fields: op, node, req, err
+func (_m *HandlerServiceIface) AuditError(op string, node domain.IOnodeIface, req *domain.HandlerRequest, err error) {
+	_m.Called(op, node, req, err)
+}
+
 // DisableHandler provides a mock function with given fields: path
 func (_m *HandlerServiceIface) DisableHandler(path string) error {
 	ret := _m.Called(path)
@@ -341,9 +348,9 @@ func (_m *HandlerServiceIface) SetStateService(css domain.ContainerStateServiceI
 	_m.Called(css)
 }
 
-// Setup provides a mock function with given fields: hdlrs, ignoreErrors, css, nss, prs, ios
-func (_m *HandlerServiceIface) Setup(hdlrs []domain.HandlerIface, ignoreErrors bool, css domain.ContainerStateServiceIface, nss domain.NSenterServiceIface, prs domain.ProcessServiceIface, ios domain.IOServiceIface) {
-	_m.Called(hdlrs, ignoreErrors, css, nss, prs, ios)
+// Setup provides a mock function with given fields: hdlrs, ignoreErrors, errAuditLog, css, nss, prs, ios
+func (_m *HandlerServiceIface) Setup(hdlrs []domain.HandlerIface, ignoreErrors bool, errAuditLog io.Writer, css domain.ContainerStateServiceIface, nss domain.NSenterServiceIface, prs domain.ProcessServiceIface, ios domain.IOServiceIface) {
+	_m.Called(hdlrs, ignoreErrors, errAuditLog, css, nss, prs, ios)
 }
 
 // StateService provides a mock function with given fields:
@@ -51,6 +51,20 @@ func (_m *MountHelperIface) IsBind(flags uint64) bool {
 	return r0
 }
 
+// IsBindRemount provides a mock function with given fields: flags
+func (_m *MountHelperIface) IsBindRemount(flags uint64) bool {
+	ret := _m.Called(flags)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(uint64) bool); ok {
+		r0 = rf(flags)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // IsMove provides a mock function with given fields: flags
 func (_m *MountHelperIface) IsMove(flags uint64) bool {
 	ret := _m.Called(flags)
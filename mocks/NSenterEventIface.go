@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	domain "github.com/nestybox/sysbox-fs/domain"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -88,6 +90,20 @@ func (_m *NSenterEventIface) SendRequest() error {
 	return r0
 }
 
+// SendRequestWithContext provides a mock function with given fields: ctx
+func (_m *NSenterEventIface) SendRequestWithContext(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetRequestMsg provides a mock function with given fields: m
 func (_m *NSenterEventIface) SetRequestMsg(m *domain.NSenterMessage) {
 	_m.Called(m)
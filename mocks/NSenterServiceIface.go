@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	domain "github.com/nestybox/sysbox-fs/domain"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -72,6 +74,20 @@ func (_m *NSenterServiceIface) SendRequestEvent(e domain.NSenterEventIface) erro
 	return r0
 }
 
+// SendRequestEventWithContext provides a mock function with given fields: ctx, e
+func (_m *NSenterServiceIface) SendRequestEventWithContext(ctx context.Context, e domain.NSenterEventIface) error {
+	ret := _m.Called(ctx, e)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.NSenterEventIface) error); ok {
+		r0 = rf(ctx, e)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Setup provides a mock function with given fields: prs, mts
 func (_m *NSenterServiceIface) Setup(prs domain.ProcessServiceIface, mts domain.MountServiceIface) {
 	_m.Called(prs, mts)
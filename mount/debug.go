@@ -0,0 +1,139 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mount
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nestybox/sysbox-fs/domain"
+)
+
+// DumpMountInfo parses a captured mountinfo file (e.g., a copy of a sys
+// container's /proc/<pid>/mountinfo) and writes out the classification that
+// sysbox-fs makes of each of the mounts found under the given base path. The
+// passed container provides the procfs read-only and masked paths that would
+// have been configured for the sys container.
+//
+// This is a debugging aid meant to reproduce parser issues offline, so the
+// regular parser code is utilized here. Notice though that offline captures
+// carry no mountpoint inodes, and these ones are required to identify 'clone'
+// mounts. We approximate them by hashing the mountpoint paths, which is
+// accurate for mounts stacked within the same mount namespace.
+func DumpMountInfo(
+	w io.Writer,
+	data []byte,
+	basePath string,
+	cntr domain.ContainerIface) error {
+
+	mts := &MountService{}
+	mts.mh = newMountHelper(mts)
+
+	mi, err := newMountInfoParser(cntr, nil, false, true, false, mts)
+	if err != nil {
+		return err
+	}
+
+	if err := mi.parseData(data); err != nil {
+		return err
+	}
+
+	basePath = strings.TrimSuffix(basePath, "/")
+
+	var mounts []*domain.MountInfo
+	for _, info := range mi.idInfo {
+		info.MpInode = mountpointHash(info.MountPoint)
+
+		if basePath == "" ||
+			info.MountPoint == basePath ||
+			strings.HasPrefix(info.MountPoint, basePath+"/") {
+			mounts = append(mounts, info)
+		}
+	}
+
+	// Display mounts in mount-id order to simplify the correlation with the
+	// original capture.
+	sort.Slice(mounts, func(i, j int) bool {
+		return mounts[i].MountID < mounts[j].MountID
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tPARENT\tMOUNTPOINT\tFSTYPE\tCLASS")
+
+	for _, info := range mounts {
+		class, err := mi.classifyMount(info)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\n",
+			info.MountID, info.ParentID, info.MountPoint, info.FsType, class)
+	}
+
+	return tw.Flush()
+}
+
+// classifyMount returns a comma-separated list of the sysbox-fs categories
+// that fit the given mount, or "-" if none does.
+func (mi *mountInfoParser) classifyMount(info *domain.MountInfo) (string, error) {
+
+	var class []string
+
+	mp := info.MountPoint
+
+	if mi.IsSysboxfsBaseMount(mp) {
+		class = append(class, "base")
+	}
+	if mi.IsSysboxfsSubmount(mp) {
+		class = append(class, "submount")
+	}
+	if mi.IsSysboxfsRoSubmount(mp) {
+		class = append(class, "ro")
+	}
+	if mi.IsSysboxfsMaskedSubmount(mp) {
+		class = append(class, "masked")
+	}
+
+	isClone, err := mi.IsCloneMount(info, false)
+	if err != nil {
+		return "", err
+	}
+	if isClone {
+		class = append(class, "clone")
+	}
+
+	if mi.IsBindMount(info) {
+		class = append(class, "bindmount")
+	}
+
+	if len(class) == 0 {
+		return "-", nil
+	}
+
+	return strings.Join(class, ","), nil
+}
+
+// mountpointHash returns a pseudo-inode for the given mountpoint path.
+func mountpointHash(mp string) domain.Inode {
+	h := fnv.New64a()
+	h.Write([]byte(mp))
+	return h.Sum64()
+}
@@ -0,0 +1,99 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mount
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// Regenerate golden files with: go test ./mount -run Test_DumpMountInfo -update
+var update = flag.Bool("update", false, "update golden files")
+
+func Test_DumpMountInfo(t *testing.T) {
+
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "mountinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Docker's default procfs read-only and masked paths.
+	cntr := &testContainer{
+		roPaths: []string{
+			"/proc/bus",
+			"/proc/fs",
+			"/proc/irq",
+			"/proc/sys/kernel/yama",
+			"/proc/sysrq-trigger",
+		},
+		maskPaths: []string{
+			"/proc/asound",
+			"/proc/acpi",
+			"/proc/keys",
+			"/proc/timer_list",
+			"/proc/sched_debug",
+			"/proc/scsi",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		basePath string
+		golden   string
+	}{
+		{"proc", "/proc", "mountinfo_proc.golden"},
+		{"all", "/", "mountinfo_all.golden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			if err := DumpMountInfo(&out, data, tt.basePath, cntr); err != nil {
+				t.Fatalf("DumpMountInfo() failed: %v", err)
+			}
+
+			golden := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := ioutil.WriteFile(golden, out.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), want) {
+				t.Errorf("DumpMountInfo() output mismatch for %s:\ngot:\n%s\nwant:\n%s",
+					tt.basePath, out.String(), want)
+			}
+		})
+	}
+}
+
+func Test_DumpMountInfo_Malformed(t *testing.T) {
+
+	data := []byte("1590 1526 0:85 / /proc rw,nosuid - proc\n")
+
+	var out bytes.Buffer
+	if err := DumpMountInfo(&out, data, "/", &testContainer{}); err == nil {
+		t.Errorf("DumpMountInfo() expected to fail on malformed input")
+	}
+}
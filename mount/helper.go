@@ -19,6 +19,7 @@ package mount
 import (
 	"strings"
 
+	"github.com/nestybox/sysbox-fs/domain"
 	libutils "github.com/nestybox/sysbox-libs/utils"
 	"golang.org/x/sys/unix"
 )
@@ -46,8 +47,20 @@ func newMountHelper(svc *MountService) *mountHelper {
 	info := &mountHelper{
 		mapMounts:  make(map[string]struct{}),
 		service:    svc,
-		procMounts: ProcfsMounts,
-		sysMounts:  SysfsMounts,
+		procMounts: append([]string{}, ProcfsMounts...),
+		sysMounts:  append([]string{}, SysfsMounts...),
+	}
+
+	for _, mp := range ProcfsOptionalMounts {
+		if domain.FileExists(mp) {
+			info.procMounts = append(info.procMounts, mp)
+		}
+	}
+
+	for _, mp := range SysfsOptionalMounts {
+		if domain.FileExists(mp) {
+			info.sysMounts = append(info.sysMounts, mp)
+		}
 	}
 
 	// Sort proc and sys mounts hierarchically in case later mounts depend on
@@ -103,6 +116,13 @@ func (m *mountHelper) IsBind(flags uint64) bool {
 	return flags&unix.MS_BIND == unix.MS_BIND
 }
 
+// IsBindRemount returns true if the mount flags indicate a bind-remount
+// operation (e.g., "mount -o bind,remount,ro"), which alters the per-mount
+// flags of an existing mountpoint, rather than creating a new bind-mount.
+func (m *mountHelper) IsBindRemount(flags uint64) bool {
+	return m.IsRemount(flags) && m.IsBind(flags)
+}
+
 // IsMove returns true if the mount flags indicate a mount move operation.
 func (m *mountHelper) IsMove(flags uint64) bool {
 	return flags&unix.MS_MOVE == unix.MS_MOVE
@@ -49,6 +49,7 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -484,6 +485,15 @@ func (mi *mountInfoParser) isSysboxfsSubMountOf(info, baseInfo *domain.MountInfo
 
 	relMountpoint := strings.TrimPrefix(info.MountPoint, baseInfo.MountPoint)
 
+	return mi.isSysboxfsManagedMountpoint(relMountpoint, baseInfo)
+}
+
+// isSysboxfsManagedMountpoint checks if the given mountpoint (relative to the
+// passed sysbox-fs base mount) is one of the mountpoints set up by sysbox-fs.
+func (mi *mountInfoParser) isSysboxfsManagedMountpoint(
+	relMountpoint string,
+	baseInfo *domain.MountInfo) bool {
+
 	switch baseInfo.FsType {
 	case "proc":
 		if isMountpointUnder(relMountpoint, mi.service.mh.procMounts) ||
@@ -500,6 +510,19 @@ func (mi *mountInfoParser) isSysboxfsSubMountOf(info, baseInfo *domain.MountInfo
 	return false
 }
 
+// isDescendantMount checks if the given mount is stacked (at any depth) under
+// the passed ancestor mount.
+func (mi *mountInfoParser) isDescendantMount(info, ancestor *domain.MountInfo) bool {
+
+	for p := mi.GetParentMount(info); p != nil; p = mi.GetParentMount(p) {
+		if p.MountID == ancestor.MountID {
+			return true
+		}
+	}
+
+	return false
+}
+
 // isMountpointUnder returns true if the given mountpoint is under of one the
 // mountpoints in the given set.
 func isMountpointUnder(mountpoint string, mpSet []string) bool {
@@ -707,20 +730,70 @@ func (mi *mountInfoParser) GetSysboxfsSubMounts(basemount string) []string {
 	return submounts
 }
 
+// GetSysboxfsNestedSubMounts returns a list of sysbox-fs managed mounts
+// stacked under the given sysbox-fs submount (e.g., if submount is /proc/sys,
+// returns any /proc/sys/* mount managed by sysbox-fs). The list is sorted
+// hierarchically, so parent mounts always precede their children.
+func (mi *mountInfoParser) GetSysboxfsNestedSubMounts(submount string) []string {
+
+	nested := []string{}
+
+	subInfo, found := mi.mpInfo[submount]
+	if !found {
+		return nested
+	}
+
+	baseInfo := mi.GetParentMount(subInfo)
+	if baseInfo == nil || !mi.isSysboxfsBaseMount(baseInfo) {
+		return nested
+	}
+
+	for mp, info := range mi.mpInfo {
+		if !mi.isDescendantMount(info, subInfo) {
+			continue
+		}
+
+		relMountpoint := strings.TrimPrefix(info.MountPoint, baseInfo.MountPoint)
+
+		if mi.isSysboxfsManagedMountpoint(relMountpoint, baseInfo) {
+			nested = append(nested, mp)
+		}
+	}
+
+	sort.Strings(nested)
+
+	return nested
+}
+
 // HasNonSysboxfsSubmount checks if there is at least one non sysbox-fs managed
 // submount under the given base mount (e.g., if basemount is /proc, returns
 // true if there is a mount under /proc that was not setup by sysbox-fs, such as
-// when a user inside the sys container creates a mount under /proc).
+// when a user inside the sys container creates a mount under /proc). Mounts
+// stacked at any depth are considered (e.g., a binfmt_misc mount on top of the
+// /proc/sys submount).
 func (mi *mountInfoParser) HasNonSysboxfsSubmount(basemount string) bool {
 
-	baseInfo := mi.mpInfo[basemount]
-	baseID := baseInfo.MountID
+	baseInfo, found := mi.mpInfo[basemount]
+	if !found {
+		return false
+	}
 
 	for _, info := range mi.mpInfo {
-		if info.ParentID == baseID {
+		if info.ParentID == baseInfo.MountID {
 			if !mi.isSysboxfsSubMountOf(info, baseInfo) {
 				return true
 			}
+			continue
+		}
+
+		if !mi.isDescendantMount(info, baseInfo) {
+			continue
+		}
+
+		relMountpoint := strings.TrimPrefix(info.MountPoint, baseInfo.MountPoint)
+
+		if !mi.isSysboxfsManagedMountpoint(relMountpoint, baseInfo) {
+			return true
 		}
 	}
 
@@ -17,6 +17,7 @@
 package mount
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/nestybox/sysbox-fs/domain"
@@ -102,3 +103,167 @@ func Benchmark_parseData(b *testing.B) {
 		}
 	}
 }
+
+// Minimal container implementation: only the methods utilized by the mountinfo
+// parser are provided.
+type testContainer struct {
+	domain.ContainerIface
+	roPaths   []string
+	maskPaths []string
+}
+
+func (c *testContainer) ProcRoPaths() []string {
+	return c.roPaths
+}
+
+func (c *testContainer) ProcMaskPaths() []string {
+	return c.maskPaths
+}
+
+func Test_GetSysboxfsNestedSubMounts(t *testing.T) {
+
+	// Extend the base mountinfo with a /proc/sys bind-mount (as created by a
+	// non-recursive bind of /proc/sys onto /root/sys), a read-only sysbox-fs
+	// mount stacked on top of /proc/sys, and a user-created mount under
+	// /proc/sys that is not managed by sysbox-fs.
+	data := append([]byte{}, mountInfoData...)
+	data = append(data, []byte(
+		`1800 1638 0:77 /proc/sys/kernel/yama /proc/sys/kernel/yama ro,nosuid,nodev,relatime - fuse sysboxfs rw,user_id=0,group_id=0,default_permissions,allow_other
+1801 1638 0:114 / /proc/sys/fs/binfmt_misc rw,relatime - binfmt_misc binfmt_misc rw
+1802 1801 0:115 / /proc/sys/fs/binfmt_misc/foo rw,relatime - tmpfs tmpfs rw
+1803 1526 0:77 /proc/sys /root/sys rw,nosuid,nodev,relatime - fuse sysboxfs rw,user_id=0,group_id=0,default_permissions,allow_other
+`)...)
+
+	cntr := &testContainer{
+		roPaths:   []string{"/proc/sys/kernel/yama"},
+		maskPaths: []string{},
+	}
+
+	mi := &mountInfoParser{
+		cntr:         cntr,
+		fetchOptions: true,
+		mpInfo:       make(map[string]*domain.MountInfo),
+		idInfo:       make(map[int]*domain.MountInfo),
+		fsIdInfo:     make(map[string][]*domain.MountInfo),
+		service: &MountService{
+			mh: &mountHelper{
+				procMounts: ProcfsMounts,
+				sysMounts:  SysfsMounts,
+			},
+		},
+	}
+
+	if err := mi.parseData(data); err != nil {
+		t.Fatalf("parseData() failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		submount string
+		want     []string
+	}{
+		// Sysbox-fs managed submount with a nested sysbox-fs mount.
+		{"1", "/proc/sys", []string{"/proc/sys/kernel/yama"}},
+
+		// Sysbox-fs managed submount with no nested mounts.
+		{"2", "/proc/uptime", []string{}},
+
+		// Base mounts are not submounts.
+		{"3", "/proc", []string{}},
+
+		// Bind-mount of a submount outside of its base mount.
+		{"4", "/root/sys", []string{}},
+
+		// Unknown mountpoint.
+		{"5", "/proc/none", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mi.GetSysboxfsNestedSubMounts(tt.submount)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetSysboxfsNestedSubMounts(%s) = %v, want %v",
+					tt.submount, got, tt.want)
+			}
+		})
+	}
+
+	// /proc/sys must be identified as a sysbox-fs submount, so that its
+	// bind-mounts are handled by sysbox-fs.
+	if !mi.IsSysboxfsSubmount("/proc/sys") {
+		t.Errorf("/proc/sys not identified as a sysbox-fs submount")
+	}
+	if mi.IsSysboxfsSubmount("/root/sys") {
+		t.Errorf("/root/sys unexpectedly identified as a sysbox-fs submount")
+	}
+}
+
+func Test_HasNonSysboxfsSubmount(t *testing.T) {
+
+	newParser := func(extra string) *mountInfoParser {
+		mi := &mountInfoParser{
+			cntr: &testContainer{
+				roPaths: []string{
+					"/proc/bus",
+					"/proc/fs",
+					"/proc/irq",
+					"/proc/sysrq-trigger",
+					"/proc/sys/kernel/yama",
+				},
+				maskPaths: []string{
+					"/proc/asound",
+					"/proc/acpi",
+					"/proc/keys",
+					"/proc/timer_list",
+					"/proc/sched_debug",
+					"/proc/scsi",
+				},
+			},
+			fetchOptions: true,
+			mpInfo:       make(map[string]*domain.MountInfo),
+			idInfo:       make(map[int]*domain.MountInfo),
+			fsIdInfo:     make(map[string][]*domain.MountInfo),
+			service: &MountService{
+				mh: &mountHelper{
+					procMounts: ProcfsMounts,
+					sysMounts:  SysfsMounts,
+				},
+			},
+		}
+
+		data := append([]byte{}, mountInfoData...)
+		data = append(data, []byte(extra)...)
+
+		if err := mi.parseData(data); err != nil {
+			t.Fatalf("parseData() failed: %v", err)
+		}
+
+		return mi
+	}
+
+	tests := []struct {
+		name  string
+		extra string
+		want  bool
+	}{
+		// Sysbox-fs mount nested under a sysbox-fs submount.
+		{"1", `1800 1638 0:77 /proc/sys/kernel/yama /proc/sys/kernel/yama ro,nosuid,nodev,relatime - fuse sysboxfs rw,user_id=0,group_id=0,default_permissions,allow_other
+`, false},
+
+		// User mount directly under the base mount.
+		{"2", `1800 1590 0:114 / /proc/foo rw,relatime - tmpfs tmpfs rw
+`, true},
+
+		// User mount nested under a sysbox-fs submount.
+		{"3", `1800 1638 0:114 / /proc/sys/fs/binfmt_misc rw,relatime - binfmt_misc binfmt_misc rw
+`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mi := newParser(tt.extra)
+			if got := mi.HasNonSysboxfsSubmount("/proc"); got != tt.want {
+				t.Errorf("HasNonSysboxfsSubmount(/proc) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -37,13 +37,32 @@ import (
 var ProcfsMounts = []string{
 	"/proc/uptime",
 	"/proc/swaps",
+	"/proc/keys",
+	"/proc/key-users",
 	"/proc/sys",
 }
 
+// Procfs counterpart of SysfsOptionalMounts below.
+var ProcfsOptionalMounts = []string{
+	"/proc/config.gz",
+	"/proc/timer_list",
+	"/proc/timer_stats",
+}
+
 var SysfsMounts = []string{
 	"/sys/kernel",
 	"/sys/devices/virtual",
+	"/sys/devices/system/cpu",
+	"/sys/firmware",
 	"/sys/module/nf_conntrack/parameters",
+	"/sys/block",
+}
+
+// Sysfs mountpoints that are only tracked when present in the host's sysfs
+// (i.e., they depend on the host kernel's configuration), as there would be
+// nothing to bind-mount over otherwise.
+var SysfsOptionalMounts = []string{
+	"/sys/fs/selinux",
 }
 
 type MountService struct {
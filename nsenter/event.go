@@ -18,6 +18,7 @@ package nsenter
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -98,8 +99,15 @@ type NSenterEvent struct {
 	// Zombie Reaper (for left-over nsenter child processes)
 	reaper *zombieReaper
 
+	// Rate limiter for nsenter agents' creation
+	limiter *nsenterLimiter
+
 	// Backpointer to Nsenter service
 	service *nsenterService
+
+	// Context of the request (if any); synchronous requests are aborted upon
+	// its cancellation.
+	ctx context.Context
 }
 
 //
@@ -475,13 +483,46 @@ func (e *NSenterEvent) namespacePaths() []string {
 	return paths
 }
 
+// checkRate returns EAGAIN if the event is subject to the nsenter limiter and
+// the container it's issued on behalf of exceeds the allowed agent creation
+// rate.
+func (e *NSenterEvent) checkRate() error {
+
+	if e.limiter == nil || e.ReqMsg == nil || !nsenterLimitedReqs[e.ReqMsg.Type] {
+		return nil
+	}
+
+	key, err := limiterKey(e.Pid)
+	if err != nil {
+		return nil
+	}
+
+	return e.limiter.allow(key)
+}
+
 // Sysbox-fs requests are generated through this method. Handlers seeking to
 // access namespaced resources will call this method to invoke nsexec,
 // which will enter the container namespaces that host these resources.
-func (e *NSenterEvent) SendRequest() error {
+func (e *NSenterEvent) SendRequest() (err error) {
 
 	logrus.Debug("Executing nsenterEvent's SendRequest() method")
 
+	// Requests aborted due to the cancellation of their context are reported
+	// as interrupted, regardless of the step at which they were cut short.
+	defer func() {
+		if err != nil && e.interrupted() {
+			logrus.Debugf("nsenter request on behalf of pid %d interrupted: %v",
+				e.Pid, err)
+			err = fuse.IOerror{Code: syscall.EINTR}
+		}
+	}()
+
+	// Throttle the creation of nsenter agents on behalf of containers that
+	// exceed the allowed rate.
+	if err := e.checkRate(); err != nil {
+		return err
+	}
+
 	// Alert the zombie reaper that nsenter is about to start. Notice that we
 	// skip reaper's services for async requests as, in those cases, the callee
 	// is expected to sigkill its generated nsenter processes.
@@ -508,6 +549,11 @@ func (e *NSenterEvent) SendRequest() error {
 
 	// Set the SO_PASSCRED on the socket (so we can pass process credentials across it)
 	socket := int(parentPipe.Fd())
+
+	if e.ctx != nil && !e.Async {
+		stopWatch := e.watchContext(socket)
+		defer stopWatch()
+	}
 	err = syscall.SetsockoptInt(socket, syscall.SOL_SOCKET, syscall.SO_PASSCRED, 1)
 	if err != nil {
 		return fmt.Errorf("Error setting socket options on nsenter pipe: %v", err)
@@ -662,6 +708,10 @@ func (e *NSenterEvent) SendRequest() error {
 	}
 
 	if ierr != nil {
+		// Don't let the nsenter agent carry on with an interrupted request.
+		if e.interrupted() {
+			e.Process.Kill()
+		}
 		e.reaper.nsenterReapReq()
 		return ierr
 	}
@@ -671,6 +721,49 @@ func (e *NSenterEvent) SendRequest() error {
 	return nil
 }
 
+// SendRequestWithContext is the same as SendRequest(), but the request is
+// aborted (and EINTR returned) as soon as the given context is cancelled. This
+// is utilized to avoid carrying on with nsenter requests on behalf of FUSE
+// requests that have been interrupted (e.g., reader got a signal).
+func (e *NSenterEvent) SendRequestWithContext(ctx context.Context) error {
+
+	if ctx.Err() != nil {
+		return fuse.IOerror{Code: syscall.EINTR}
+	}
+	e.ctx = ctx
+
+	return e.SendRequest()
+}
+
+// watchContext shuts down the given nsenter socket upon cancellation of the
+// event's context, which unblocks any ongoing exchange with the nsenter agent.
+// The returned function stops the watch, and must be invoked before the socket
+// is closed.
+func (e *NSenterEvent) watchContext(socket int) func() {
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		select {
+		case <-e.ctx.Done():
+			unix.Shutdown(socket, unix.SHUT_RDWR)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+func (e *NSenterEvent) interrupted() bool {
+	return e.ctx != nil && e.ctx.Err() != nil
+}
+
 func (e *NSenterEvent) ReceiveResponse() *domain.NSenterMessage {
 
 	return e.ResMsg
@@ -1046,13 +1139,7 @@ func (e *NSenterEvent) processMountSyscallRequest() error {
 
 	// Perform mount instructions.
 	for i = 0; i < len(payload); i++ {
-		err = unix.Mount(
-			payload[i].Source,
-			payload[i].Target,
-			payload[i].FsType,
-			uintptr(payload[i].Flags),
-			payload[i].Data,
-		)
+		err = doMount(&payload[i].Mount)
 		if err != nil {
 			break
 		}
@@ -17,18 +17,22 @@
 package nsenter
 
 import (
+	"context"
+
 	"github.com/nestybox/sysbox-fs/domain"
 )
 
 type nsenterService struct {
-	prs    domain.ProcessServiceIface // for process class interactions (capabilities)
-	mts    domain.MountServiceIface   // for mount class interactions (mountInfoParser)
-	reaper *zombieReaper
+	prs     domain.ProcessServiceIface // for process class interactions (capabilities)
+	mts     domain.MountServiceIface   // for mount class interactions (mountInfoParser)
+	reaper  *zombieReaper
+	limiter *nsenterLimiter
 }
 
 func NewNSenterService() domain.NSenterServiceIface {
 	return &nsenterService{
-		reaper: newZombieReaper(),
+		reaper:  newZombieReaper(),
+		limiter: newNsenterLimiter(nsenterLimiterWindow, nsenterLimiterThreshold),
 	}
 }
 
@@ -56,6 +60,7 @@ func (s *nsenterService) NewEvent(
 		ResMsg:     res,
 		Async:      async,
 		reaper:     s.reaper,
+		limiter:    s.limiter,
 	}
 
 	return event
@@ -66,6 +71,12 @@ func (s *nsenterService) SendRequestEvent(
 	return e.SendRequest()
 }
 
+func (s *nsenterService) SendRequestEventWithContext(
+	ctx context.Context,
+	e domain.NSenterEventIface) error {
+	return e.SendRequestWithContext(ctx)
+}
+
 func (s *nsenterService) TerminateRequestEvent(e domain.NSenterEventIface) error {
 	return e.TerminateRequest()
 }
@@ -0,0 +1,156 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package nsenter
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/nestybox/sysbox-fs/domain"
+)
+
+// Max number of nsenter agents that can be spawned on behalf of a single
+// container within a limiter window. Past this point the container's breaker
+// trips, and new agents are rejected with EAGAIN till the end of the window.
+const (
+	nsenterLimiterWindow    = time.Second
+	nsenterLimiterThreshold = 256
+)
+
+// The nsenter limiter acts as a per-container circuit-breaker to protect
+// sysbox-fs from fork-bombing itself when a container triggers a storm of
+// operations requiring nsenter agents (e.g., mount storms within chroot'ed
+// environments). Containers are identified by their user-ns inode.
+type nsenterLimiter struct {
+	mu        sync.Mutex
+	window    time.Duration
+	threshold int
+	buckets   map[domain.Inode]*nsenterBucket
+	lastGc    time.Time
+	now       func() time.Time
+}
+
+// Per-container agent-creation tracking.
+type nsenterBucket struct {
+	start   time.Time // beginning of the current window
+	count   int       // agents created during the current window
+	tripped bool      // breaker state
+}
+
+func newNsenterLimiter(window time.Duration, threshold int) *nsenterLimiter {
+
+	return &nsenterLimiter{
+		window:    window,
+		threshold: threshold,
+		buckets:   make(map[domain.Inode]*nsenterBucket),
+		now:       time.Now,
+	}
+}
+
+// allow returns nil if a new nsenter agent can be spawned on behalf of the
+// container identified by 'key', or EAGAIN otherwise (retriable condition).
+func (l *nsenterLimiter) allow(key domain.Inode) error {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	if now.Sub(l.lastGc) >= 10*l.window {
+		l.gc(now)
+		l.lastGc = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &nsenterBucket{start: now}
+		l.buckets[key] = b
+	}
+
+	// Start a new window if the current one has expired. A tripped breaker is
+	// reset at this point; should the container keep exceeding the threshold,
+	// the breaker will trip again during the new window.
+	if now.Sub(b.start) >= l.window {
+		if b.tripped {
+			logrus.Warnf("nsenter agent creation rate subsided for userns %d; resetting breaker",
+				key)
+			b.tripped = false
+		}
+		b.start = now
+		b.count = 0
+	}
+
+	b.count++
+
+	if b.count > l.threshold {
+		if !b.tripped {
+			logrus.Errorf("nsenter agent creation rate exceeded for userns %d (more than %d agents in %v); rejecting new agents",
+				key, l.threshold, l.window)
+			b.tripped = true
+		}
+		return syscall.EAGAIN
+	}
+
+	return nil
+}
+
+// gc discards the buckets of idle containers to prevent the limiter state
+// from growing indefinitely. Caller must hold the limiter lock.
+func (l *nsenterLimiter) gc(now time.Time) {
+
+	for k, b := range l.buckets {
+		if now.Sub(b.start) >= l.window && !b.tripped {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// Types of the nsenter requests subject to the limiter: the ones issued while
+// handling the container's mount-related and seccomp-trapped syscalls (along
+// with the mountinfo collection they trigger), which a container can drive at
+// an arbitrary rate. FUSE-originated requests (e.g., lookups and reads of
+// passthrough nodes) are exempt, as throttling them would fail ordinary file
+// accesses within the container with EAGAIN.
+var nsenterLimitedReqs = map[domain.NSenterMsgType]bool{
+	domain.MountSyscallRequest:       true,
+	domain.UmountSyscallRequest:      true,
+	domain.ChownSyscallRequest:       true,
+	domain.SetxattrSyscallRequest:    true,
+	domain.GetxattrSyscallRequest:    true,
+	domain.RemovexattrSyscallRequest: true,
+	domain.ListxattrSyscallRequest:   true,
+	domain.MountInfoRequest:          true,
+	domain.MountInodeRequest:         true,
+	domain.SleepRequest:              true,
+}
+
+// limiterKey returns the key identifying the container on whose behalf an
+// nsenter agent is created (i.e., the user-ns inode of the given process).
+func limiterKey(pid uint32) (domain.Inode, error) {
+	var st unix.Stat_t
+
+	if err := unix.Stat(fmt.Sprintf("/proc/%d/ns/user", pid), &st); err != nil {
+		return 0, err
+	}
+
+	return st.Ino, nil
+}
@@ -0,0 +1,118 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package nsenter
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nestybox/sysbox-fs/domain"
+)
+
+func TestNsenterLimiter(t *testing.T) {
+
+	var (
+		cntr1 uint64 = 4026531837
+		cntr2 uint64 = 4026532400
+	)
+
+	clock := time.Unix(1000, 0)
+
+	l := newNsenterLimiter(time.Second, 10)
+	l.now = func() time.Time { return clock }
+
+	// Agents below the threshold must be allowed.
+	for i := 0; i < 10; i++ {
+		if err := l.allow(cntr1); err != nil {
+			t.Fatalf("agent %d unexpectedly rejected: %v", i, err)
+		}
+	}
+
+	// Past the threshold, new agents must be rejected with a retriable errno.
+	for i := 0; i < 5; i++ {
+		if err := l.allow(cntr1); err != syscall.EAGAIN {
+			t.Fatalf("agent %d unexpectedly allowed: %v", i, err)
+		}
+	}
+	if !l.buckets[cntr1].tripped {
+		t.Fatalf("breaker expected to be tripped")
+	}
+
+	// Other containers must not be affected.
+	if err := l.allow(cntr2); err != nil {
+		t.Fatalf("unrelated container unexpectedly rejected: %v", err)
+	}
+
+	// Still within the same window: breaker must remain tripped.
+	clock = clock.Add(500 * time.Millisecond)
+	if err := l.allow(cntr1); err != syscall.EAGAIN {
+		t.Fatalf("agent unexpectedly allowed within tripped window: %v", err)
+	}
+
+	// Once the rate subsides, the breaker must be reset.
+	clock = clock.Add(time.Second)
+	if err := l.allow(cntr1); err != nil {
+		t.Fatalf("agent unexpectedly rejected after reset: %v", err)
+	}
+	if l.buckets[cntr1].tripped {
+		t.Fatalf("breaker expected to be reset")
+	}
+
+	// Idle containers must be eventually discarded.
+	clock = clock.Add(time.Minute)
+	if err := l.allow(cntr1); err != nil {
+		t.Fatalf("agent unexpectedly rejected: %v", err)
+	}
+	if _, ok := l.buckets[cntr2]; ok {
+		t.Fatalf("idle container state expected to be discarded")
+	}
+}
+
+func TestNSenterEvent_checkRate(t *testing.T) {
+
+	l := newNsenterLimiter(time.Minute, 1)
+
+	newEvent := func(reqType domain.NSenterMsgType) *NSenterEvent {
+		return &NSenterEvent{
+			Pid:     uint32(os.Getpid()),
+			ReqMsg:  &domain.NSenterMessage{Type: reqType},
+			limiter: l,
+		}
+	}
+
+	// FUSE-originated requests are never throttled.
+	for i := 0; i < 5; i++ {
+		if err := newEvent(domain.ReadFileRequest).checkRate(); err != nil {
+			t.Fatalf("read request %d unexpectedly rejected: %v", i, err)
+		}
+	}
+
+	// Syscall-originated requests are, once past the threshold.
+	if err := newEvent(domain.MountSyscallRequest).checkRate(); err != nil {
+		t.Fatalf("mount request unexpectedly rejected: %v", err)
+	}
+	if err := newEvent(domain.MountInfoRequest).checkRate(); err != syscall.EAGAIN {
+		t.Fatalf("mountinfo request unexpectedly allowed: %v", err)
+	}
+
+	// FUSE-originated requests are unaffected by the tripped breaker.
+	if err := newEvent(domain.LookupRequest).checkRate(); err != nil {
+		t.Fatalf("lookup request unexpectedly rejected: %v", err)
+	}
+}
@@ -20,8 +20,11 @@ import (
 	"errors"
 	"os"
 	"strings"
+	"unsafe"
 
 	"golang.org/x/sys/unix"
+
+	"github.com/nestybox/sysbox-fs/domain"
 )
 
 type payloadMountsInfo struct {
@@ -155,3 +158,40 @@ func replaceProcfsAndSysfsPaths(path string, pmi *payloadMountsInfo) string {
 
 	return path
 }
+
+// doMount carries out the given mount instruction. Binary mount data is handed
+// to the kernel as is, as unix.Mount() only takes NUL-terminated strings.
+func doMount(m *domain.Mount) error {
+
+	if len(m.BinData) == 0 {
+		return unix.Mount(m.Source, m.Target, m.FsType, uintptr(m.Flags), m.Data)
+	}
+
+	source, err := unix.BytePtrFromString(m.Source)
+	if err != nil {
+		return err
+	}
+	target, err := unix.BytePtrFromString(m.Target)
+	if err != nil {
+		return err
+	}
+	fstype, err := unix.BytePtrFromString(m.FsType)
+	if err != nil {
+		return err
+	}
+
+	_, _, errno := unix.Syscall6(
+		unix.SYS_MOUNT,
+		uintptr(unsafe.Pointer(source)),
+		uintptr(unsafe.Pointer(target)),
+		uintptr(unsafe.Pointer(fstype)),
+		uintptr(m.Flags),
+		uintptr(unsafe.Pointer(&m.BinData[0])),
+		0,
+	)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
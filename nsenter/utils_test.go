@@ -0,0 +1,85 @@
+//
+// Copyright 2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package nsenter
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/nestybox/sysbox-fs/domain"
+)
+
+func TestDoMount_binData(t *testing.T) {
+
+	// Mount data with embedded NULs (the kernel stops parsing tmpfs' string
+	// options at the first one).
+	binData := make([]byte, os.Getpagesize())
+	copy(binData, "size=1m,mode=0710\x00\xff\x00\x01trailer")
+
+	payload := []domain.MountSyscallPayload{{
+		domain.NSenterMsgHeader{},
+		domain.Mount{
+			Source:  "none",
+			Target:  t.TempDir(),
+			FsType:  "tmpfs",
+			BinData: binData,
+		},
+	}}
+
+	// The data must make it intact through the nsenter request.
+	msg, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var proxied []domain.MountSyscallPayload
+	if err := json.Unmarshal(msg, &proxied); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(proxied[0].BinData, binData) {
+		t.Fatalf("mount data altered when proxied: %q", proxied[0].BinData[:32])
+	}
+
+	m := &proxied[0].Mount
+
+	if err := doMount(m); err != nil {
+		if err == unix.EPERM {
+			t.Skip("mount not permitted")
+		}
+		t.Fatalf("doMount() unexpected error: %v", err)
+	}
+	defer unix.Unmount(m.Target, 0)
+
+	fi, err := os.Stat(m.Target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0710 {
+		t.Errorf("mount data not honored: mode = %#o, want %#o", perm, 0710)
+	}
+
+	// Strings can't carry them.
+	m.Data = strings.TrimRight(string(binData), "\x00")
+	m.BinData = nil
+	if err := doMount(m); err != unix.EINVAL {
+		t.Errorf("doMount() of string data with NULs error = %v, want EINVAL", err)
+	}
+}
@@ -61,6 +61,91 @@ func (ps *processService) ProcessCreate(
 	}
 }
 
+// HostPidToNsPid translates the given pid (as seen in sysbox-fs' pid-ns) into
+// the value that this same process holds within the pid-ns of the 'ref'
+// process. As the kernel does, zero is returned if the process is not visible
+// from within the 'ref' process' pid-ns (e.g., the parent of a container's
+// init process).
+func (ps *processService) HostPidToNsPid(
+	hostPid uint32,
+	ref domain.ProcessIface) (uint32, error) {
+
+	refNsPids, err := ref.NsPids()
+	if err != nil {
+		return 0, err
+	}
+	level := len(refNsPids) - 1
+
+	p := ps.ProcessCreate(hostPid, 0, 0)
+	nsPids, err := p.NsPids()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(nsPids) <= level {
+		return 0, nil
+	}
+
+	return nsPids[level], nil
+}
+
+// NsPidToHostPid does the opposite translation to HostPidToNsPid(): given a
+// pid within the pid-ns of the 'ref' process, it returns the pid that this
+// process holds within sysbox-fs' pid-ns. There's no kernel interface to
+// carry out this lookup, so we must iterate through the host's procfs looking
+// for a process that sits in the same pid-ns as 'ref' and whose pid at this
+// namespace level matches the one being searched.
+func (ps *processService) NsPidToHostPid(
+	nsPid uint32,
+	ref domain.ProcessIface) (uint32, error) {
+
+	refNsPids, err := ref.NsPids()
+	if err != nil {
+		return 0, err
+	}
+	level := len(refNsPids) - 1
+
+	// Shortcut for the most common scenario: the pid being searched is the
+	// 'ref' process itself.
+	if refNsPids[level] == nsPid {
+		return ref.Pid(), nil
+	}
+
+	refPidNs, err := ref.PidNsInode()
+	if err != nil {
+		return 0, err
+	}
+
+	dirs, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range dirs {
+		hostPid, err := strconv.ParseUint(d.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+
+		p := ps.ProcessCreate(uint32(hostPid), 0, 0)
+
+		// Processes may be gone by now, so skip any error.
+		nsPids, err := p.NsPids()
+		if err != nil || len(nsPids) <= level || nsPids[level] != nsPid {
+			continue
+		}
+
+		pidNs, err := p.PidNsInode()
+		if err != nil || pidNs != refPidNs {
+			continue
+		}
+
+		return uint32(hostPid), nil
+	}
+
+	return 0, syscall.ESRCH
+}
+
 type process struct {
 	pid         uint32                  // process id
 	root        string                  // root dir
@@ -375,6 +460,50 @@ func (p *process) UserNsInodeParent() (domain.Inode, error) {
 	return stat.Ino, nil
 }
 
+func (p *process) PidNsInode() (domain.Inode, error) {
+	nsInodes, err := p.NsInodes()
+	if err != nil {
+		return 0, err
+	}
+
+	pidns, found := nsInodes["pid"]
+	if !found {
+		return 0, fmt.Errorf("pidns not found")
+	}
+
+	return pidns, nil
+}
+
+// NsPids returns the pids held by the process in each of the pid namespaces
+// it's a member of, starting with the one of sysbox-fs (i.e., the value of the
+// "NSpid" field in /proc/<pid>/status).
+func (p *process) NsPids() ([]uint32, error) {
+
+	if err := p.getStatus([]string{"NSpid"}); err != nil {
+		return nil, err
+	}
+
+	str, ok := p.status["NSpid"]
+	if !ok {
+		return nil, fmt.Errorf("NSpid status not found")
+	}
+
+	var nsPids []uint32
+	for _, s := range strings.Fields(str) {
+		val, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		nsPids = append(nsPids, uint32(val))
+	}
+
+	if len(nsPids) == 0 {
+		return nil, fmt.Errorf("invalid NSpid status: %s", str)
+	}
+
+	return nsPids, nil
+}
+
 // Collects the namespace inodes of the given process
 func (p *process) GetNsInodes() (map[string]domain.Inode, error) {
 
@@ -0,0 +1,165 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// This file contains Sysbox's bpf syscall trapping & handling code. eBPF
+// programs attached to kernel hooks (e.g., kprobes, tracepoints, XDP) observe
+// or alter system-wide activity, so only socket filters and cgroup programs
+// can be loaded, the latter being required by container managers running
+// within the container (e.g., cgroup v2 device control); loading any other
+// type fails with EPERM. The --allow-all-bpf-programs knob lifts this policy.
+//
+// All other bpf commands (e.g., map operations, attachments of loaded
+// programs) only operate on objects the container has access to, so they're
+// left to the kernel.
+//
+// Notice that the program type is read out of the tracee's memory, which the
+// tracee could alter (through another thread) before the kernel reads it in
+// turn; this policy is not a substitute for the kernel's own checks, which
+// still apply to every program being loaded.
+
+package seccomp
+
+import (
+	"encoding/binary"
+	"strconv"
+	"syscall"
+
+	"github.com/nestybox/sysbox-libs/formatter"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// Size of the prog_type field of union bpf_attr (the first one of the
+// BPF_PROG_LOAD command's attributes).
+const bpfAttrProgTypeSize = 4
+
+// eBPF program types, by their enum bpf_prog_type name (lower-cased and with
+// no prefix).
+var bpfProgTypes = map[string]uint32{
+	"socket_filter":           unix.BPF_PROG_TYPE_SOCKET_FILTER,
+	"kprobe":                  unix.BPF_PROG_TYPE_KPROBE,
+	"sched_cls":               unix.BPF_PROG_TYPE_SCHED_CLS,
+	"sched_act":               unix.BPF_PROG_TYPE_SCHED_ACT,
+	"tracepoint":              unix.BPF_PROG_TYPE_TRACEPOINT,
+	"xdp":                     unix.BPF_PROG_TYPE_XDP,
+	"perf_event":              unix.BPF_PROG_TYPE_PERF_EVENT,
+	"cgroup_skb":              unix.BPF_PROG_TYPE_CGROUP_SKB,
+	"cgroup_sock":             unix.BPF_PROG_TYPE_CGROUP_SOCK,
+	"lwt_in":                  unix.BPF_PROG_TYPE_LWT_IN,
+	"lwt_out":                 unix.BPF_PROG_TYPE_LWT_OUT,
+	"lwt_xmit":                unix.BPF_PROG_TYPE_LWT_XMIT,
+	"sock_ops":                unix.BPF_PROG_TYPE_SOCK_OPS,
+	"sk_skb":                  unix.BPF_PROG_TYPE_SK_SKB,
+	"cgroup_device":           unix.BPF_PROG_TYPE_CGROUP_DEVICE,
+	"sk_msg":                  unix.BPF_PROG_TYPE_SK_MSG,
+	"raw_tracepoint":          unix.BPF_PROG_TYPE_RAW_TRACEPOINT,
+	"cgroup_sock_addr":        unix.BPF_PROG_TYPE_CGROUP_SOCK_ADDR,
+	"lwt_seg6local":           unix.BPF_PROG_TYPE_LWT_SEG6LOCAL,
+	"lirc_mode2":              unix.BPF_PROG_TYPE_LIRC_MODE2,
+	"sk_reuseport":            unix.BPF_PROG_TYPE_SK_REUSEPORT,
+	"flow_dissector":          unix.BPF_PROG_TYPE_FLOW_DISSECTOR,
+	"cgroup_sysctl":           unix.BPF_PROG_TYPE_CGROUP_SYSCTL,
+	"raw_tracepoint_writable": unix.BPF_PROG_TYPE_RAW_TRACEPOINT_WRITABLE,
+	"cgroup_sockopt":          unix.BPF_PROG_TYPE_CGROUP_SOCKOPT,
+	"tracing":                 unix.BPF_PROG_TYPE_TRACING,
+	"struct_ops":              unix.BPF_PROG_TYPE_STRUCT_OPS,
+	"ext":                     unix.BPF_PROG_TYPE_EXT,
+	"lsm":                     unix.BPF_PROG_TYPE_LSM,
+	"sk_lookup":               unix.BPF_PROG_TYPE_SK_LOOKUP,
+	"syscall":                 unix.BPF_PROG_TYPE_SYSCALL,
+	"netfilter":               unix.BPF_PROG_TYPE_NETFILTER,
+}
+
+// Program types allowed to be loaded within the container.
+var bpfAllowedProgTypes = []string{
+	"socket_filter",
+	"cgroup_skb",
+	"cgroup_sock",
+	"cgroup_device",
+	"cgroup_sock_addr",
+	"cgroup_sysctl",
+	"cgroup_sockopt",
+}
+
+type bpfSyscallInfo struct {
+	syscallCtx        // syscall generic info
+	cmd        uint32 // bpf command
+	attr       uint64 // address of the command's attributes (union bpf_attr)
+	size       uint32 // size of the command's attributes
+}
+
+func (si *bpfSyscallInfo) processBpf() (*sysResponse, error) {
+
+	t := si.tracer
+
+	if si.cmd != unix.BPF_PROG_LOAD {
+		return t.createContinueResponse(si.reqId), nil
+	}
+
+	// Attributes too short to carry the program type are rejected by the
+	// kernel (the type would be BPF_PROG_TYPE_UNSPEC).
+	if si.size < bpfAttrProgTypeSize {
+		return t.createContinueResponse(si.reqId), nil
+	}
+
+	// Unreadable attributes are left for the kernel to report.
+	parsedArgs, err := t.memParser.ReadSyscallBytesArgs(
+		si.pid,
+		[]memParserDataElem{{si.attr, bpfAttrProgTypeSize, nil}},
+	)
+	if err != nil || len(parsedArgs[0]) != bpfAttrProgTypeSize {
+		return t.createContinueResponse(si.reqId), nil
+	}
+
+	progType := binary.NativeEndian.Uint32([]byte(parsedArgs[0]))
+
+	if !bpfProgTypeAllowed(progType) {
+		logrus.Infof("Rejected bpf program load from pid %d, cntr %s: program type %s",
+			si.pid, formatter.ContainerID{si.cntr.ID()}, bpfProgTypeName(progType))
+		return t.createErrorResponse(si.reqId, syscall.EPERM), nil
+	}
+
+	logrus.Debugf("bpf program load from pid %d: program type %s",
+		si.pid, bpfProgTypeName(progType))
+
+	return t.createContinueResponse(si.reqId), nil
+}
+
+// bpfProgTypeAllowed returns true if the given program type is in the
+// allowlist.
+func bpfProgTypeAllowed(progType uint32) bool {
+
+	for _, name := range bpfAllowedProgTypes {
+		if bpfProgTypes[name] == progType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bpfProgTypeName returns the name of the given program type, or its numeric
+// value if unknown.
+func bpfProgTypeName(progType uint32) string {
+
+	for name, t := range bpfProgTypes {
+		if t == progType {
+			return name
+		}
+	}
+
+	return strconv.FormatUint(uint64(progType), 10)
+}
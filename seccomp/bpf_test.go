@@ -0,0 +1,106 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package seccomp
+
+import (
+	"encoding/binary"
+	"errors"
+	"syscall"
+	"testing"
+
+	libseccomp "github.com/seccomp/libseccomp-golang"
+	"golang.org/x/sys/unix"
+)
+
+// Tracee-memory fake holding the prog_type field of a union bpf_attr.
+type testBpfMemParser struct {
+	memParser
+	progType uint32
+	fault    bool
+}
+
+func (m *testBpfMemParser) ReadSyscallBytesArgs(
+	pid uint32,
+	elems []memParserDataElem) ([]string, error) {
+
+	if m.fault || len(elems) != 1 || elems[0].size != bpfAttrProgTypeSize {
+		return nil, errors.New("unexpected read")
+	}
+
+	attr := make([]byte, bpfAttrProgTypeSize)
+	binary.NativeEndian.PutUint32(attr, m.progType)
+
+	return []string{string(attr)}, nil
+}
+
+func Test_bpfSyscallInfo_processBpf(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		allowAll  bool
+		cmd       uint32
+		size      uint32
+		progType  uint32
+		fault     bool
+		wantErrno syscall.Errno // zero if the syscall is to be continued
+	}{
+		// Allowlist: socket filters and cgroup programs.
+		{"socket-filter", false, unix.BPF_PROG_LOAD, 128, unix.BPF_PROG_TYPE_SOCKET_FILTER, false, 0},
+		{"cgroup-device", false, unix.BPF_PROG_LOAD, 128, unix.BPF_PROG_TYPE_CGROUP_DEVICE, false, 0},
+		{"kprobe", false, unix.BPF_PROG_LOAD, 128, unix.BPF_PROG_TYPE_KPROBE, false, syscall.EPERM},
+		{"tracepoint", false, unix.BPF_PROG_LOAD, 128, unix.BPF_PROG_TYPE_TRACEPOINT, false, syscall.EPERM},
+		{"xdp", false, unix.BPF_PROG_LOAD, 128, unix.BPF_PROG_TYPE_XDP, false, syscall.EPERM},
+		{"unknown-type", false, unix.BPF_PROG_LOAD, 128, 999, false, syscall.EPERM},
+		// Interception disabled.
+		{"allow-all", true, unix.BPF_PROG_LOAD, 128, unix.BPF_PROG_TYPE_KPROBE, false, 0},
+		// Other commands are left to the kernel.
+		{"map-create", false, unix.BPF_MAP_CREATE, 128, 0, false, 0},
+		{"map-lookup", false, unix.BPF_MAP_LOOKUP_ELEM, 128, 0, true, 0},
+		// Attributes too short to carry the program type.
+		{"short-attr", false, unix.BPF_PROG_LOAD, 2, unix.BPF_PROG_TYPE_KPROBE, false, 0},
+		// Unreadable attributes.
+		{"fault", false, unix.BPF_PROG_LOAD, 128, unix.BPF_PROG_TYPE_KPROBE, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer := &syscallTracer{
+				service: &SyscallMonitorService{allowAllBpfProgs: tt.allowAll},
+				memParser: &testBpfMemParser{
+					progType: tt.progType,
+					fault:    tt.fault,
+				},
+			}
+
+			req := &sysRequest{ID: 1, Pid: 1001}
+			req.Data.Args = []uint64{uint64(tt.cmd), 0x1000, uint64(tt.size)}
+
+			resp, err := tracer.processBpf(req, 0, &testContainer{})
+			if err != nil {
+				t.Fatalf("processBpf() unexpected error: %v", err)
+			}
+
+			if resp.Error != int32(tt.wantErrno) {
+				t.Errorf("processBpf() errno = %d, want %d", resp.Error, tt.wantErrno)
+			}
+			wantCont := tt.wantErrno == 0
+			if gotCont := resp.Flags&libseccomp.NotifRespFlagContinue != 0; gotCont != wantCont {
+				t.Errorf("processBpf() continue = %v, want %v", gotCont, wantCont)
+			}
+		})
+	}
+}
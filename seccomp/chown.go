@@ -29,6 +29,7 @@
 package seccomp
 
 import (
+	"math"
 	"path/filepath"
 	"syscall"
 
@@ -49,6 +50,20 @@ type chownSyscallInfo struct {
 	flags      int
 }
 
+// chownId returns the given uid / gid argument of a chown-family syscall.
+// Legacy (16-bit) ids, as taken by the x86 chown() and fchown() syscalls,
+// stand for -1 (i.e., id left unchanged) when all their bits are set.
+func chownId(arg uint64, legacy bool) int64 {
+	if legacy {
+		if uint16(arg) == math.MaxUint16 {
+			return -1
+		}
+		return int64(uint16(arg))
+	}
+
+	return int64(int32(arg))
+}
+
 func (ci *chownSyscallInfo) ignoreChown(absPath string) bool {
 
 	// Note: we only ignore chown targeting "/sys" directly. We purposely avoid
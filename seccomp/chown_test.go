@@ -0,0 +1,50 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package seccomp
+
+import "testing"
+
+func Test_chownId(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		arg    uint64
+		legacy bool
+		want   int64
+	}{
+		// 32-bit ids (i.e., chown32() from a 32-bit x86 process), as
+		// zero-extended by the kernel.
+		{"32bit", 1000, false, 1000},
+		{"32bit-unchanged", 0xffffffff, false, -1},
+		{"32bit-high", 0x80000, false, 0x80000},
+		// Native ids, sign-extended by the caller.
+		{"native-unchanged", 0xffffffffffffffff, false, -1},
+		// Legacy 16-bit ids (i.e., chown() from a 32-bit x86 process).
+		{"16bit", 1000, true, 1000},
+		{"16bit-unchanged", 0xffff, true, -1},
+		{"16bit-nobody", 0xfffe, true, 65534},
+		{"16bit-truncated", 0x10001, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chownId(tt.arg, tt.legacy); got != tt.want {
+				t.Errorf("chownId(%#x, %v) = %d, want %d", tt.arg, tt.legacy, got, tt.want)
+			}
+		})
+	}
+}
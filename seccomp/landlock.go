@@ -0,0 +1,121 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package seccomp
+
+import (
+	"encoding/binary"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Landlock syscalls are trapped to give operators visibility into the
+// sandboxes created within sys containers.
+//
+// Rulesets are enforced by the kernel on the sandboxed processes, whereas the
+// emulated /proc and /sys resources are served by sysbox-fs from outside of
+// them, so a ruleset can't break the emulation as such. What a ruleset can do
+// is to leave emulated resources out of the sandbox's reach, just as it would
+// happen on a host. Thus, path-beneath rules are only inspected to report the
+// ones covering the /proc and /sys hierarchies, and are otherwise left to the
+// kernel.
+
+const (
+	landlockCreateRulesetVersion = 1 << 0 // LANDLOCK_CREATE_RULESET_VERSION
+	landlockRulePathBeneath      = 1      // LANDLOCK_RULE_PATH_BENEATH
+
+	// sizeof(struct landlock_path_beneath_attr): allowed_access (u64) plus
+	// parent_fd (s32); the struct is packed.
+	landlockPathBeneathAttrSize = 12
+)
+
+type landlockSyscallInfo struct {
+	syscallCtx        // syscall generic info
+	flags      uint32 // syscall flags
+	ruleType   uint32 // landlock_add_rule(): rule type
+	ruleAttr   uint64 // landlock_add_rule(): rule attributes' address
+}
+
+func (li *landlockSyscallInfo) processCreateRuleset() (*sysResponse, error) {
+
+	t := li.tracer
+
+	// ABI version queries don't create any ruleset.
+	if li.flags&landlockCreateRulesetVersion == landlockCreateRulesetVersion {
+		return t.createContinueResponse(li.reqId), nil
+	}
+
+	logrus.Infof("Landlock ruleset created by pid %d in container %s",
+		li.pid, li.cntr.ID())
+
+	return t.createContinueResponse(li.reqId), nil
+}
+
+func (li *landlockSyscallInfo) processAddRule() (*sysResponse, error) {
+
+	t := li.tracer
+
+	if li.ruleType != landlockRulePathBeneath {
+		return t.createContinueResponse(li.reqId), nil
+	}
+
+	// Any problem while inspecting the rule is left for the kernel to report.
+	parsedArgs, err := t.memParser.ReadSyscallBytesArgs(
+		li.pid,
+		[]memParserDataElem{{li.ruleAttr, landlockPathBeneathAttrSize, nil}},
+	)
+	if err != nil || len(parsedArgs[0]) != landlockPathBeneathAttrSize {
+		return t.createContinueResponse(li.reqId), nil
+	}
+	attr := []byte(parsedArgs[0])
+
+	access := binary.NativeEndian.Uint64(attr[0:8])
+	parentFd := int32(binary.NativeEndian.Uint32(attr[8:12]))
+
+	process := t.service.prs.ProcessCreate(li.pid, 0, 0)
+
+	path, err := process.GetFd(parentFd)
+	if err != nil {
+		return t.createContinueResponse(li.reqId), nil
+	}
+
+	if isLandlockEmulatedPath(path) {
+		logrus.Infof("Landlock rule from pid %d in container %s covers sysbox-fs emulated path %s (access = %#x)",
+			li.pid, li.cntr.ID(), path, access)
+	} else {
+		logrus.Debugf("landlock_add_rule(): path = %s, access = %#x", path, access)
+	}
+
+	return t.createContinueResponse(li.reqId), nil
+}
+
+// isLandlockEmulatedPath returns true if the given path is (or contains) a
+// hierarchy where sysbox-fs emulates resources.
+func isLandlockEmulatedPath(path string) bool {
+
+	if path == "/" {
+		return true
+	}
+
+	for _, root := range []string{"/proc", "/sys"} {
+		if path == root || strings.HasPrefix(path, root+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,185 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package seccomp
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	logrusTest "github.com/sirupsen/logrus/hooks/test"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	libseccomp "github.com/seccomp/libseccomp-golang"
+)
+
+// Minimal process fake; only fd resolution is implemented.
+type testFdProcess struct {
+	domain.ProcessIface
+	fds map[int32]string
+}
+
+func (p *testFdProcess) GetFd(fd int32) (string, error) {
+	path, ok := p.fds[fd]
+	if !ok {
+		return "", syscall.EBADF
+	}
+	return path, nil
+}
+
+type testFdProcessService struct {
+	domain.ProcessServiceIface
+	process *testFdProcess
+}
+
+func (s *testFdProcessService) ProcessCreate(pid uint32, uid uint32, gid uint32) domain.ProcessIface {
+	return s.process
+}
+
+// Tracee-memory fake holding a single landlock_path_beneath_attr.
+type testLandlockMemParser struct {
+	memParser
+	access   uint64
+	parentFd int32
+}
+
+func (m *testLandlockMemParser) ReadSyscallBytesArgs(
+	pid uint32,
+	elems []memParserDataElem) ([]string, error) {
+
+	if len(elems) != 1 || elems[0].size != landlockPathBeneathAttrSize {
+		return nil, errors.New("unexpected read")
+	}
+
+	attr := make([]byte, landlockPathBeneathAttrSize)
+	binary.NativeEndian.PutUint64(attr[0:8], m.access)
+	binary.NativeEndian.PutUint32(attr[8:12], uint32(m.parentFd))
+
+	return []string{string(attr)}, nil
+}
+
+func Test_landlockSyscallInfo(t *testing.T) {
+
+	const (
+		accessReadFile = 1 << 2 // LANDLOCK_ACCESS_FS_READ_FILE
+		accessReadDir  = 1 << 3 // LANDLOCK_ACCESS_FS_READ_DIR
+	)
+
+	tests := []struct {
+		name     string
+		syscall  string
+		flags    uint32
+		ruleType uint32
+		parentFd int32
+		wantLog  string // expected info-level log (if any)
+	}{
+		// Ruleset creation; logged.
+		{"create", "landlock_create_ruleset", 0, 0, 0,
+			"Landlock ruleset created by pid 1001 in container test"},
+		// ABI version query; not logged.
+		{"create-version", "landlock_create_ruleset", landlockCreateRulesetVersion, 0, 0, ""},
+		// Rule covering an emulated hierarchy; logged.
+		{"rule-proc", "landlock_add_rule", 0, landlockRulePathBeneath, 3,
+			"Landlock rule from pid 1001 in container test covers sysbox-fs emulated path /proc"},
+		// Rule covering a non-emulated hierarchy.
+		{"rule-usr", "landlock_add_rule", 0, landlockRulePathBeneath, 4, ""},
+		// Unresolvable parent fd; left for the kernel to report.
+		{"rule-bad-fd", "landlock_add_rule", 0, landlockRulePathBeneath, 9, ""},
+		// Unknown rule type; left for the kernel to report.
+		{"rule-unknown-type", "landlock_add_rule", 0, 99, 3, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := logrusTest.NewGlobal()
+			defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+			tracer := &syscallTracer{
+				service: &SyscallMonitorService{
+					prs: &testFdProcessService{
+						process: &testFdProcess{
+							fds: map[int32]string{3: "/proc", 4: "/usr"},
+						},
+					},
+				},
+				memParser: &testLandlockMemParser{
+					access:   accessReadFile | accessReadDir,
+					parentFd: tt.parentFd,
+				},
+			}
+
+			li := &landlockSyscallInfo{
+				syscallCtx: syscallCtx{
+					reqId:  1,
+					pid:    1001,
+					cntr:   &testContainer{},
+					tracer: tracer,
+				},
+				flags:    tt.flags,
+				ruleType: tt.ruleType,
+				ruleAttr: 0x1000,
+			}
+
+			var resp *sysResponse
+			var err error
+			if tt.syscall == "landlock_create_ruleset" {
+				resp, err = li.processCreateRuleset()
+			} else {
+				resp, err = li.processAddRule()
+			}
+			if err != nil {
+				t.Fatalf("%s unexpected error: %v", tt.syscall, err)
+			}
+
+			// Landlock syscalls are always left to the kernel.
+			if resp.Flags&libseccomp.NotifRespFlagContinue == 0 {
+				t.Errorf("%s: expected continue response, got %+v", tt.syscall, resp)
+			}
+
+			var infos []string
+			for _, e := range hook.AllEntries() {
+				if e.Level <= logrus.InfoLevel {
+					infos = append(infos, e.Message)
+				}
+			}
+			got := strings.Join(infos, "\n")
+			if tt.wantLog == "" && got != "" {
+				t.Errorf("%s unexpected logs: %q", tt.syscall, got)
+			}
+			if tt.wantLog != "" && !strings.Contains(got, tt.wantLog) {
+				t.Errorf("%s logs = %q, want %q", tt.syscall, got, tt.wantLog)
+			}
+		})
+	}
+
+	// Sanity check of the path classification.
+	for path, want := range map[string]bool{
+		"/":          true,
+		"/proc":      true,
+		"/proc/sys":  true,
+		"/sys/fs":    true,
+		"/procfs":    false,
+		"/usr/share": false,
+	} {
+		if got := isLandlockEmulatedPath(path); got != want {
+			t.Errorf("isLandlockEmulatedPath(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -18,7 +18,10 @@ package seccomp
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -28,6 +31,17 @@ import (
 	"golang.org/x/sys/unix"
 )
 
+// Filesystems whose mount data is a binary struct rather than a string (i.e.,
+// the ones flagged with FS_BINARY_MOUNTDATA by the kernel).
+var binaryMountDataFsTypes = map[string]bool{
+	"coda": true,
+	"nfs":  true,
+	"nfs4": true,
+}
+
+// Magic-links to the fds of the process (i.e., /proc/self/fd/<N>).
+var procSelfFdRegexp = regexp.MustCompile(`^/proc/(?:self|thread-self)/fd/([0-9]+)$`)
+
 // MountSyscall information structure.
 type mountSyscallInfo struct {
 	syscallCtx                  // syscall generic info
@@ -77,6 +91,8 @@ func (m *mountSyscallInfo) process() (*sysResponse, error) {
 			return m.processOverlayMount(mip)
 		case "nfs":
 			return m.processNfsMount(mip)
+		case "bpf", "tracefs", "debugfs":
+			return m.processObsMount(mip)
 		}
 	}
 
@@ -91,7 +107,10 @@ func (m *mountSyscallInfo) process() (*sysResponse, error) {
 		return m.tracer.createContinueResponse(m.reqId), nil
 	}
 
-	// Handle remount requests on filesystems managed by sysbox-fs
+	// Handle remount requests on filesystems managed by sysbox-fs. Notice that
+	// bind-remounts (MS_REMOUNT|MS_BIND) are handled here too, as these alter
+	// the flags of an existing mountpoint and must go through the same
+	// immutable checks; they must not be mistaken for new bind-mounts below.
 	if mh.IsRemount(m.Flags) {
 
 		mip, err := mts.NewMountInfoParser(m.cntr, m.processInfo, true, true, false)
@@ -130,11 +149,16 @@ func (m *mountSyscallInfo) process() (*sysResponse, error) {
 		}
 
 		// Ignore /dev/null bind mounts on sysbox-fs managed submounts which are
-		// already bind-mounted to /dev/null (i.e., masked).
-		if m.Source == "/dev/null" && mip.IsSysboxfsMaskedSubmount(m.Target) {
-			logrus.Debugf("Ignoring /dev/null bind request over sysbox-fs masked submount at %s",
-				m.Target)
-			return m.tracer.createSuccessResponse(m.reqId), nil
+		// already bind-mounted to /dev/null (i.e., masked). Masking of other
+		// sysbox-fs submounts (e.g., /proc/uptime) is left to the kernel, which
+		// stacks /dev/null on top of the emulated file.
+		if m.Source == "/dev/null" && mip.IsSysboxfsSubmount(m.Target) {
+			if mip.IsSysboxfsMaskedSubmount(m.Target) {
+				logrus.Debugf("Ignoring /dev/null bind request over sysbox-fs masked submount at %s",
+					m.Target)
+				return m.tracer.createSuccessResponse(m.reqId), nil
+			}
+			return m.tracer.createContinueResponse(m.reqId), nil
 		}
 
 		// Process bind-mounts whose source is a sysbox-fs base mount (as we
@@ -143,7 +167,23 @@ func (m *mountSyscallInfo) process() (*sysResponse, error) {
 			return m.processBindMount(mip)
 		}
 
-		// No action by sysbox-fs
+		// Same as above for bind-mounts whose source is a sysbox-fs submount
+		// (e.g., /proc/sys), as there may be other sysbox-fs managed mounts
+		// stacked on top of it. Submounts of emulated files (e.g., /proc/uptime)
+		// carry none, so these are bound on their own.
+		if m.Source != m.Target && mip.IsSysboxfsSubmount(m.Source) {
+			if m.isFile(m.Source) {
+				return m.processFileBindMount()
+			}
+			return m.processBindMount(mip)
+		}
+
+		// No action by sysbox-fs. Notice that this includes bind-mounts over
+		// masked submounts (other than the /dev/null ones above), which the
+		// kernel stacks on top of the mask: the user is explicitly mounting
+		// there, so the mask is shadowed rather than enforced. Unmounting the
+		// bind-mount reveals the mask again, as that unmount targets the user's
+		// mount rather than the masked submount (see umount.go).
 		return m.tracer.createContinueResponse(m.reqId), nil
 	}
 
@@ -164,11 +204,93 @@ func (m *mountSyscallInfo) processProcMount(
 		return nil, fmt.Errorf("Could not construct procMount payload")
 	}
 
+	// Chown the proc mount to the requesting process' uid:gid (typically
+	// root:root) as otherwise it will show up as "nobody:nogroup".
+	//
+	// NOTE: by default we skip the chown if the mount is read-only, as
+	// otherwise the chown will fail. This means that read-only mounts of proc
+	// will still show up as "nobody:nouser" inside the sys container (e.g., in
+	// inner containers). When requested (see chownRoProcMounts), we solve this
+	// by first mounting proc read-write, then chowning it, and then remounting
+	// it read-only. This requires 3 nsenter events, because the namespaces
+	// that we must enter for each are not the same (in particular for the
+	// chown to succeed, we must not enter the user-ns of the container).
+	// Mounts that are read-only at super-block level can't be chowned either
+	// way.
+
+	readOnly := (*payload)[0].Flags&unix.MS_RDONLY == unix.MS_RDONLY
+	roRemount := readOnly && m.tracer.service.chownRoProcMounts && !procSbReadOnly(mip)
+
+	if roRemount {
+		base := *(*payload)[0]
+		base.Flags &^= unix.MS_RDONLY
+		(*payload)[0] = &base
+	}
+
+	if resp, err := m.sendMountRequest(domain.AllNSs, payload); resp != nil || err != nil {
+		return resp, err
+	}
+
+	if readOnly && !roRemount {
+		return m.tracer.createSuccessResponse(m.reqId), nil
+	}
+
+	ci := &chownSyscallInfo{
+		path:     m.Target,
+		ownerUid: int64(m.uid),
+		ownerGid: int64(m.gid),
+	}
+
+	ci.syscallCtx.reqId = m.reqId
+	ci.syscallCtx.pid = m.pid
+	ci.syscallCtx.tracer = m.tracer
+
+	resp, err := ci.processChownNSenter(domain.AllNSsButUser)
+	if !roRemount {
+		return resp, err
+	}
+
+	// The mount must end up read-only regardless of the chown outcome. Only
+	// its per-mount flags are set by the bind-remount (the super-block ones
+	// are shared with the container's procfs).
+	if err != nil {
+		logrus.Warnf("Unable to chown read-only proc mount at %s: %v", m.Target, err)
+	}
+
+	remount := &[]*domain.MountSyscallPayload{
+		{
+			domain.NSenterMsgHeader{},
+			domain.Mount{
+				Target: m.Target,
+				Flags: unix.MS_REMOUNT | unix.MS_BIND | unix.MS_RDONLY |
+					m.Flags&(unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC|submountInheritedFlags),
+			},
+		},
+	}
+
+	if remountResp, remountErr := m.sendMountRequest(domain.AllNSs, remount); remountResp != nil || remountErr != nil {
+		return remountResp, remountErr
+	}
+
+	return resp, err
+}
+
+// sendMountRequest carries out the given mount instructions within the given
+// namespaces of the process generating the syscall. A response is only
+// returned if the instructions fail.
+func (m *mountSyscallInfo) sendMountRequest(
+	namespaces []domain.NStype,
+	payload *[]*domain.MountSyscallPayload) (*sysResponse, error) {
+
+	if m.rootChanged() {
+		return m.tracer.createErrorResponse(m.reqId, syscall.EAGAIN), nil
+	}
+
 	// Create nsenter-event envelope.
 	nss := m.tracer.service.nss
 	event := nss.NewEvent(
 		m.syscallCtx.pid,
-		&domain.AllNSs,
+		&namespaces,
 		0,
 		&domain.NSenterMessage{
 			Type:    domain.MountSyscallRequest,
@@ -193,33 +315,20 @@ func (m *mountSyscallInfo) processProcMount(
 		return resp, nil
 	}
 
-	// Chown the proc mount to the requesting process' uid:gid (typically
-	// root:root) as otherwise it will show up as "nobody:nogroup".
-	//
-	// NOTE: for now we skip the chown if the mount is read-only, as otherwise
-	// the chown will fail. This means that read-only mounts of proc will still
-	// show up as "nobody:nouser" inside the sys container (e.g., in inner
-	// containers). Solving this would require that we first mount proc, then
-	// chown, then remount read-only. This would in turn require 3 nsenter
-	// events, because the namespaces that we must enter for each are not the
-	// same (in particular for the chown to succeed, we must not enter the
-	// user-ns of the container).
+	return nil, nil
+}
 
-	if m.Flags&unix.MS_RDONLY == unix.MS_RDONLY {
-		return m.tracer.createSuccessResponse(m.reqId), nil
-	}
+// procSbReadOnly returns true if the container's procfs is read-only at
+// super-block level (in which case so is any new procfs mount).
+func procSbReadOnly(mip domain.MountInfoParserIface) bool {
 
-	ci := &chownSyscallInfo{
-		path:     m.Target,
-		ownerUid: int64(m.uid),
-		ownerGid: int64(m.gid),
+	procInfo := mip.GetInfo("/proc")
+	if procInfo == nil {
+		return false
 	}
 
-	ci.syscallCtx.reqId = m.reqId
-	ci.syscallCtx.pid = m.pid
-	ci.syscallCtx.tracer = m.tracer
-
-	return ci.processChownNSenter(domain.AllNSsButUser)
+	_, ok := procInfo.VfsOptions["ro"]
+	return ok
 }
 
 // Build instructions payload required to mount "/proc" subtree.
@@ -234,11 +343,8 @@ func (m *mountSyscallInfo) createProcPayload(
 	// If procfs has a read-only attribute at super-block level, we must also
 	// apply this to the new mountpoint (otherwise we will get a permission
 	// denied from the kernel when doing the mount).
-	procInfo := mip.GetInfo("/proc")
-	if procInfo != nil {
-		if _, ok := procInfo.VfsOptions["ro"]; ok {
-			payload[0].Flags |= unix.MS_RDONLY
-		}
+	if procSbReadOnly(mip) {
+		payload[0].Flags |= unix.MS_RDONLY
 	}
 
 	mh := m.tracer.service.mts.MountHelper()
@@ -316,9 +422,8 @@ func (m *mountSyscallInfo) createProcPayload(
 					Source: "",
 					Target: filepath.Join(m.Target, relPath),
 					FsType: "",
-					// TODO: Avoid hard-coding these flags.
-					Flags: unix.MS_RDONLY | unix.MS_BIND | unix.MS_REMOUNT | unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC,
-					Data:  "",
+					Flags:  m.submountRoRemountFlags(),
+					Data:   "",
 				},
 			}
 			payload = append(payload, newelem)
@@ -341,6 +446,10 @@ func (m *mountSyscallInfo) processSysMount(
 		return nil, fmt.Errorf("Could not construct sysfsMount payload")
 	}
 
+	if m.rootChanged() {
+		return m.tracer.createErrorResponse(m.reqId, syscall.EAGAIN), nil
+	}
+
 	// Create nsenter-event envelope.
 	nss := m.tracer.service.nss
 	event := nss.NewEvent(
@@ -373,6 +482,29 @@ func (m *mountSyscallInfo) processSysMount(
 	return m.tracer.createSuccessResponse(m.reqId), nil
 }
 
+// Per-mount flags of a procfs / sysfs base mount that are carried over to its
+// sysbox-fs submounts when these are remounted as read-only.
+const submountInheritedFlags = unix.MS_NOATIME | unix.MS_NODIRATIME |
+	unix.MS_RELATIME | unix.MS_STRICTATIME
+
+// submountRoRemountFlags returns the flags to utilize for the read-only
+// remount of the sysbox-fs submounts of the procfs / sysfs mount being
+// processed. These flags are derived from the ones of the base mount, so that
+// submounts match their parent's attributes (e.g., a "noatime" procfs mount
+// must not expose "relatime" submounts).
+//
+// Notice that sysbox-fs submounts are always nosuid, nodev and noexec, and
+// these flags are locked (we are operating within the container's user-ns), so
+// they must be kept regardless of the base mount ones. Also, in the absence of
+// any atime flag the kernel defaults to "relatime", which is what we want.
+func (m *mountSyscallInfo) submountRoRemountFlags() uint64 {
+
+	flags := uint64(unix.MS_RDONLY | unix.MS_BIND | unix.MS_REMOUNT |
+		unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC)
+
+	return flags | m.Flags&submountInheritedFlags
+}
+
 // Build instructions payload required to mount "/sys" subtree.
 func (m *mountSyscallInfo) createSysPayload(
 	mip domain.MountInfoParserIface) *[]*domain.MountSyscallPayload {
@@ -425,9 +557,8 @@ func (m *mountSyscallInfo) createSysPayload(
 					Source: "",
 					Target: filepath.Join(m.Target, relPath),
 					FsType: "",
-					// TODO: Avoid hard-coding these flags.
-					Flags: unix.MS_RDONLY | unix.MS_BIND | unix.MS_REMOUNT | unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC,
-					Data:  "",
+					Flags:  m.submountRoRemountFlags(),
+					Data:   "",
 				},
 			}
 			payload = append(payload, newelem)
@@ -456,6 +587,10 @@ func (m *mountSyscallInfo) processOverlayMount(
 		return nil, fmt.Errorf("Could not construct overlayMount payload")
 	}
 
+	if m.rootChanged() {
+		return m.tracer.createErrorResponse(m.reqId, syscall.EAGAIN), nil
+	}
+
 	// Create nsenter-event envelope.
 	nss := m.tracer.service.nss
 	event := nss.NewEvent(
@@ -529,6 +664,10 @@ func (m *mountSyscallInfo) processNfsMount(
 		return nil, fmt.Errorf("Could not construct nfsMount payload")
 	}
 
+	if m.rootChanged() {
+		return m.tracer.createErrorResponse(m.reqId, syscall.EAGAIN), nil
+	}
+
 	// Create nsenter-event envelope
 	nss := m.tracer.service.nss
 	event := nss.NewEvent(
@@ -561,6 +700,68 @@ func (m *mountSyscallInfo) processNfsMount(
 	return m.tracer.createSuccessResponse(m.reqId), nil
 }
 
+// Method handles the mount of filesystems exposing kernel observability
+// interfaces (i.e., bpf, tracefs and debugfs), which can't be mounted from
+// within a user-ns. These are only allowed when explicitly requested by the
+// user, in which case the mount is carried out by an nsenter process that
+// retains the (host) capabilities required by the kernel.
+func (m *mountSyscallInfo) processObsMount(
+	mip domain.MountInfoParserIface) (*sysResponse, error) {
+
+	// Let the kernel deal with the request if no action is expected from us.
+	if !m.tracer.service.allowObsMounts {
+		return m.tracer.createContinueResponse(m.reqId), nil
+	}
+
+	logrus.Debugf("Processing new %s mount: %v", m.FsType, m)
+
+	// The mountpoint must be located within the process' root (which, for
+	// processes not chroot'ed, is the container's rootfs).
+	if m.root != "/" && !strings.HasPrefix(filepath.Clean(m.Target), m.root+"/") {
+		logrus.Infof("Rejected %s mount over %s: target outside of process' root %s",
+			m.FsType, m.Target, m.root)
+		return m.tracer.createErrorResponse(m.reqId, syscall.EPERM), nil
+	}
+
+	// Create instruction's payload.
+	payload := []*domain.MountSyscallPayload{m.MountSyscallPayload}
+
+	if m.rootChanged() {
+		return m.tracer.createErrorResponse(m.reqId, syscall.EAGAIN), nil
+	}
+
+	// Create nsenter-event envelope
+	nss := m.tracer.service.nss
+	event := nss.NewEvent(
+		m.syscallCtx.pid,
+		&domain.AllNSsButUser,
+		0,
+		&domain.NSenterMessage{
+			Type:    domain.MountSyscallRequest,
+			Payload: &payload,
+		},
+		nil,
+		false,
+	)
+
+	// Launch nsenter-event.
+	err := nss.SendRequestEvent(event)
+	if err != nil {
+		return nil, err
+	}
+
+	// Obtain nsenter-event response.
+	responseMsg := nss.ReceiveResponseEvent(event)
+	if responseMsg.Type == domain.ErrorResponse {
+		resp := m.tracer.createErrorResponse(
+			m.reqId,
+			responseMsg.Payload.(fuse.IOerror).Code)
+		return resp, nil
+	}
+
+	return m.tracer.createSuccessResponse(m.reqId), nil
+}
+
 // Build instructions payload required for remount operations.
 func (m *mountSyscallInfo) createNfsMountPayload(
 	mip domain.MountInfoParserIface) *[]*domain.MountSyscallPayload {
@@ -590,7 +791,13 @@ func (m *mountSyscallInfo) remountAllowed(
 	}
 
 	// Skip instructions targeting file-systems explicitly handled by sysbox-fs.
-	if m.FsType == "proc" || m.FsType == "sysfs" {
+	// The kernel ignores the fstype argument of bind-remounts (typically left
+	// empty by callers), so these are identified through their target.
+	if mh.IsBindRemount(m.Flags) {
+		if mip.IsSysboxfsBaseMount(m.Target) || mip.IsSysboxfsSubmount(m.Target) {
+			return true, nil
+		}
+	} else if m.FsType == "proc" || m.FsType == "sysfs" {
 		return true, nil
 	}
 
@@ -680,8 +887,8 @@ func (m *mountSyscallInfo) remountAllowed(
 		}
 
 		if logrus.IsLevelEnabled(logrus.DebugLevel) {
-			if m.processInfo.Root() == "/" {
-				processRootInode := m.processInfo.RootInode()
+			if m.root == "/" {
+				processRootInode := m.rootInode
 
 				// Scenario 1): no-unshare(mnt) & no-privot() & no-chroot()
 				if processRootInode == syscntrRootInode {
@@ -694,7 +901,7 @@ func (m *mountSyscallInfo) remountAllowed(
 				}
 			}
 
-			if m.processInfo.Root() != "/" {
+			if m.root != "/" {
 				// We are dealing with a chroot'ed process, so obtain the inode of "/"
 				// as seen within the process' namespaces, and *not* the one associated
 				// to the process' root-path.
@@ -719,8 +926,8 @@ func (m *mountSyscallInfo) remountAllowed(
 
 	} else {
 
-		if m.processInfo.Root() == "/" {
-			processRootInode := m.processInfo.RootInode()
+		if m.root == "/" {
+			processRootInode := m.rootInode
 
 			// Scenario 5): unshare(mnt) & no-pivot() & no-chroot()
 			if processRootInode == syscntrRootInode {
@@ -781,7 +988,7 @@ func (m *mountSyscallInfo) remountAllowed(
 			}
 		}
 
-		if m.processInfo.Root() != "/" {
+		if m.root != "/" {
 			// We are dealing with a chroot'ed process, so obtain the inode of "/"
 			// as seen within the process' namespaces, and *not* the one associated
 			// to the process' root-path.
@@ -858,12 +1065,31 @@ func (m *mountSyscallInfo) processRemount(
 
 	logrus.Debugf("Processing re-mount: %v", m)
 
+	// Submounts exposed as read-only in the container's procfs (e.g.,
+	// /proc/bus) are always kept read-only (see createRemountPayload()), so a
+	// remount that clears MS_RDONLY on them can't succeed. Reject it rather
+	// than reporting success on a submount that is left read-only. Notice that
+	// this doesn't apply to the base mount, as its read-write remounts are
+	// expected to leave the read-only submounts untouched.
+	if !mip.IsSysboxfsBaseMount(m.Target) &&
+		m.Flags&unix.MS_RDONLY == 0 &&
+		mip.IsSysboxfsRoSubmount(m.Target) {
+
+		logrus.Infof("Rejected read-write remount of read-only sysbox-fs submount at %s",
+			m.Target)
+		return m.tracer.createErrorResponse(m.reqId, syscall.EPERM), nil
+	}
+
 	// Create instruction's payload.
 	payload := m.createRemountPayload(mip)
 	if payload == nil {
 		return nil, fmt.Errorf("Could not construct ReMount payload")
 	}
 
+	if m.rootChanged() {
+		return m.tracer.createErrorResponse(m.reqId, syscall.EAGAIN), nil
+	}
+
 	// Create nsenter-event envelope.
 	nss := m.tracer.service.nss
 	event := nss.NewEvent(
@@ -912,7 +1138,21 @@ func (m *mountSyscallInfo) createRemountPayload(
 	submounts := []string{}
 
 	if mip.IsSysboxfsBaseMount(m.Target) {
-		submounts = mip.GetSysboxfsSubMounts(m.Target)
+		for _, subm := range mip.GetSysboxfsSubMounts(m.Target) {
+			submounts = append(submounts, subm)
+
+			// Sysbox-fs mounts stacked on a submount (e.g., on /proc/sys) are
+			// not affected by the submount's remount, so they're remounted
+			// too; otherwise they would remain writable on a read-only /proc.
+			// Read-only and masked paths are left alone when clearing the
+			// read-only flag.
+			for _, nested := range mip.GetSysboxfsNestedSubMounts(subm) {
+				if m.Flags&unix.MS_RDONLY == 0 && m.isProcRoOrMaskedPath(nested) {
+					continue
+				}
+				submounts = append(submounts, nested)
+			}
+		}
 	} else {
 		submounts = append(submounts, m.Target)
 	}
@@ -976,6 +1216,25 @@ func (m *mountSyscallInfo) createRemountPayload(
 	return &payload
 }
 
+// isProcRoOrMaskedPath checks if the given mountpoint, stacked under the base
+// mount being remounted, corresponds to one of the container's read-only or
+// masked procfs paths.
+func (m *mountSyscallInfo) isProcRoOrMaskedPath(mountpoint string) bool {
+
+	// "/some/path/proc/sys/kernel/yama" -> "/proc/sys/kernel/yama"
+	procMp := filepath.Join("/proc", strings.TrimPrefix(mountpoint, m.Target))
+
+	for _, paths := range [][]string{m.cntr.ProcRoPaths(), m.cntr.ProcMaskPaths()} {
+		for _, p := range paths {
+			if p == procMp {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 // Method handles bind-mount requests whose source is a mountpoint managed by
 // sysbox-fs.
 func (m *mountSyscallInfo) processBindMount(
@@ -989,36 +1248,125 @@ func (m *mountSyscallInfo) processBindMount(
 		return nil, fmt.Errorf("Could not construct ReMount payload")
 	}
 
-	// Create nsenter-event envelope.
-	nss := m.tracer.service.nss
-	event := nss.NewEvent(
-		m.syscallCtx.pid,
-		&domain.AllNSs,
-		0,
-		&domain.NSenterMessage{
-			Type:    domain.MountSyscallRequest,
-			Payload: payload,
-		},
-		nil,
-		false,
-	)
+	if resp, err := m.sendMountRequest(domain.AllNSs, payload); resp != nil || err != nil {
+		return resp, err
+	}
 
-	// Launch nsenter-event.
-	err := nss.SendRequestEvent(event)
+	// For recursive bind-mounts the submounts are copied by the kernel; make
+	// sure their read-only and masked attributes made it to the target.
+	if m.Flags&unix.MS_REC == unix.MS_REC {
+		if resp, err := m.reconcileRecBindMount(mip); resp != nil || err != nil {
+			return resp, err
+		}
+	}
+
+	return m.tracer.createSuccessResponse(m.reqId), nil
+}
+
+// Method handles bind-mounts whose source is a file managed by sysbox-fs (e.g.,
+// /proc/uptime). The bind is carried out within the container's namespaces, so
+// that the target is backed by the container's emulated file (i.e., the one
+// served by its FUSE mount). Files hold no submounts, so unlike directories
+// there's nothing to replicate or reconcile at the target. As with any other
+// bind, the target must exist and must not be a directory (ENOTDIR).
+func (m *mountSyscallInfo) processFileBindMount() (*sysResponse, error) {
+
+	logrus.Debugf("Processing file bind mount: %v", m)
+
+	payload := []*domain.MountSyscallPayload{m.MountSyscallPayload}
+
+	if resp, err := m.sendMountRequest(domain.AllNSs, &payload); resp != nil || err != nil {
+		return resp, err
+	}
+
+	return m.tracer.createSuccessResponse(m.reqId), nil
+}
+
+// isFile checks if the given path, as seen by the process, is something other
+// than a directory. Paths that can't be accessed are not considered files.
+func (m *mountSyscallInfo) isFile(path string) bool {
+
+	fi, err := os.Stat(fmt.Sprintf("/proc/%d/root%s", m.pid, path))
+	if err != nil {
+		return false
+	}
+
+	return !fi.IsDir()
+}
+
+// reconcileRecBindMount re-asserts the attributes of the sysbox-fs read-only
+// and masked submounts on their copies at the target of a recursive bind-mount.
+// Depending on the propagation type of the mounts involved, the copies done by
+// the kernel may not carry them (e.g., a masked submount may be missing at the
+// target, thereby exposing the underlying procfs file).
+func (m *mountSyscallInfo) reconcileRecBindMount(
+	mip domain.MountInfoParserIface) (*sysResponse, error) {
+
+	var submounts []string
+	if mip.IsSysboxfsBaseMount(m.Source) {
+		submounts = mip.GetSysboxfsSubMounts(m.Source)
+	} else {
+		submounts = mip.GetSysboxfsNestedSubMounts(m.Source)
+	}
+
+	// Mountinfo as of after the bind-mount.
+	mts := m.tracer.service.mts
+	bindMip, err := mts.NewMountInfoParser(m.cntr, m.processInfo, true, true, false)
 	if err != nil {
 		return nil, err
 	}
 
-	// Obtain nsenter-event response.
-	responseMsg := nss.ReceiveResponseEvent(event)
-	if responseMsg.Type == domain.ErrorResponse {
-		resp := m.tracer.createErrorResponse(
-			m.reqId,
-			responseMsg.Payload.(fuse.IOerror).Code)
-		return resp, nil
+	var payload []*domain.MountSyscallPayload
+
+	for _, subm := range submounts {
+		ro := mip.IsSysboxfsRoSubmount(subm)
+		masked := mip.IsSysboxfsMaskedSubmount(subm)
+		if !ro && !masked {
+			continue
+		}
+
+		subTarget := filepath.Join(m.Target, strings.TrimPrefix(subm, m.Source))
+		submInfo := mip.GetInfo(subm)
+		targetInfo := bindMip.GetInfo(subTarget)
+
+		// The copy is missing, or is not the one of the submount (e.g., the
+		// underlying procfs file is exposed): bind-mount the submount again.
+		if targetInfo == nil ||
+			targetInfo.MajorMinorVer != submInfo.MajorMinorVer ||
+			targetInfo.Root != submInfo.Root {
+
+			logrus.Debugf("Re-binding sysbox-fs submount %s at %s", subm, subTarget)
+
+			payload = append(payload, &domain.MountSyscallPayload{
+				domain.NSenterMsgHeader{},
+				domain.Mount{
+					Source: subm,
+					Target: subTarget,
+					Flags:  unix.MS_BIND,
+				},
+			})
+			targetInfo = nil
+		}
+
+		if ro && !bindMip.IsRoMount(targetInfo) {
+			logrus.Debugf("Re-asserting read-only sysbox-fs submount at %s", subTarget)
+
+			payload = append(payload, &domain.MountSyscallPayload{
+				domain.NSenterMsgHeader{},
+				domain.Mount{
+					Target: subTarget,
+					Flags: unix.MS_REMOUNT | unix.MS_BIND | unix.MS_RDONLY |
+						unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC,
+				},
+			})
+		}
 	}
 
-	return m.tracer.createSuccessResponse(m.reqId), nil
+	if len(payload) == 0 {
+		return nil, nil
+	}
+
+	return m.sendMountRequest(domain.AllNSs, &payload)
 }
 
 // Build instructions payload required for bind-mount operations.
@@ -1034,14 +1382,21 @@ func (m *mountSyscallInfo) createBindMountPayload(
 	payload = append(payload, m.MountSyscallPayload)
 
 	// If the bind-mount is recursive, then the kernel will do the remounting
-	// of the submounts. No need for us to do anything.
+	// of the submounts (see reconcileRecBindMount() for the submounts whose
+	// attributes must survive the copy).
 	if m.Flags&unix.MS_REC == unix.MS_REC {
 		return &payload
 	}
 
 	// If the bind-mount is not recursive, then we do the bind-mount of the
-	// sysbox-fs managed submounts explicitly.
-	submounts := mip.GetSysboxfsSubMounts(m.Source)
+	// sysbox-fs managed submounts explicitly. If the source is a submount
+	// itself, these are the sysbox-fs managed mounts stacked on top of it.
+	var submounts []string
+	if mip.IsSysboxfsBaseMount(m.Source) {
+		submounts = mip.GetSysboxfsSubMounts(m.Source)
+	} else {
+		submounts = mip.GetSysboxfsNestedSubMounts(m.Source)
+	}
 
 	for _, subm := range submounts {
 		relTarget := strings.TrimPrefix(subm, m.Source)
@@ -1063,6 +1418,110 @@ func (m *mountSyscallInfo) createBindMountPayload(
 	return &payload
 }
 
+// targetAccess verifies the process has the proper rights to access the mount
+// target, and returns the target with its path resolved.
+//
+// Targets lying within a sysbox-fs submount (e.g., /proc/sys/fs/foo) are only
+// resolved up to the submount. The path resolution is carried out from
+// sysbox-fs' own context, so the lookups of the remaining components would hit
+// sysbox-fs' FUSE handlers on behalf of a process outside of the container,
+// which would answer them out of the host's namespaces (i.e., the target could
+// be found missing, or present, when it isn't so within the container). These
+// components are left for the kernel to resolve when carrying out the mount,
+// which does so within the process' context.
+func (m *mountSyscallInfo) targetAccess() (string, error) {
+
+	process := m.processInfo
+
+	target := m.Target
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(m.cwd, target)
+	}
+	target = filepath.Clean(target)
+
+	submount, err := m.enclosingSubmount(target)
+	if err != nil {
+		return "", err
+	}
+	if submount == "" {
+		return process.PathAccess(m.Target, 0, true)
+	}
+
+	resolved, err := process.PathAccess(submount, 0, true)
+	if err != nil {
+		return "", err
+	}
+
+	logrus.Debugf("Mount target %s lies within sysbox-fs submount %s; resolving it up to the submount",
+		target, submount)
+
+	return filepath.Join(resolved, strings.TrimPrefix(target, submount)), nil
+}
+
+// enclosingSubmount returns the sysbox-fs (i.e., FUSE-backed) submount the given
+// absolute target lies within, if any. The target itself being the submount
+// doesn't qualify, as the submount's root is served by the kernel.
+func (m *mountSyscallInfo) enclosingSubmount(target string) (string, error) {
+
+	mts := m.tracer.service.mts
+	if mts == nil {
+		return "", fmt.Errorf("unexpected mount-service handler")
+	}
+	mh := mts.MountHelper()
+	if mh == nil {
+		return "", fmt.Errorf("unexpected mount-service-helper handler")
+	}
+
+	var submounts []string
+	submounts = append(submounts, mh.ProcMounts()...)
+	submounts = append(submounts, mh.SysMounts()...)
+
+	// Skip the mountinfo parsing for targets that can't possibly be within a
+	// sysbox-fs submount (i.e., none of their ancestors ends with a submount
+	// path). This is just a shortcut; the enclosing mount is determined out of
+	// the mountinfo below.
+	var maybeSubmount bool
+	for dir := filepath.Dir(target); dir != "/" && !maybeSubmount; dir = filepath.Dir(dir) {
+		for _, mp := range submounts {
+			if strings.HasSuffix(dir, mp) {
+				maybeSubmount = true
+				break
+			}
+		}
+	}
+	if !maybeSubmount {
+		return "", nil
+	}
+
+	if !m.cntr.IsMountInfoInitialized() {
+		if err := m.cntr.InitializeMountInfo(); err != nil {
+			return "", err
+		}
+	}
+
+	mip, err := mts.NewMountInfoParser(m.cntr, m.processInfo, true, true, false)
+	if err != nil {
+		return "", err
+	}
+
+	// The enclosing mount is the deepest mountpoint the target's path is
+	// prefixed by. Mountpoints in the mountinfo are relative to the host's
+	// root, while the target is relative to the process' one.
+	for dir := filepath.Dir(target); dir != "/"; dir = filepath.Dir(dir) {
+		mp := filepath.Join(m.root, dir)
+		info := mip.GetInfo(mp)
+		if info == nil {
+			continue
+		}
+		if mip.IsSysboxfsSubmount(mp) && info.FsType == "fuse" {
+			return dir, nil
+		}
+		break
+	}
+
+	return "", nil
+}
+
 // Method addresses scenarios where the process generating the mount syscall has
 // a 'root' attribute different than default one ("/"). This is typically the
 // case in chroot'ed environments. Method's goal is to make the required target
@@ -1090,6 +1549,38 @@ func (m *mountSyscallInfo) targetUnadjust() {
 	m.Target = strings.TrimPrefix(m.Target, m.root)
 }
 
+// resolveMountSource resolves the given mount source if it refers to the
+// procfs of the process (i.e., /proc/self). Sources referring to one of the
+// process' fds (e.g., fds out of open_tree(), or of memfd-backed images) are
+// resolved through the process' fd table, as the fd's magic-link can only be
+// followed from within the process' context. The fd's path is utilized if it
+// has one; otherwise (e.g., memfds, deleted files or anonymous inodes) there's
+// no path to reach the fd's file through, so the source is left as is. Such
+// sources are never sysbox-fs managed, so their mounts are left to the kernel.
+func resolveMountSource(process domain.ProcessIface, source string) (string, error) {
+
+	match := procSelfFdRegexp.FindStringSubmatch(filepath.Clean(source))
+	if match == nil {
+		return process.ResolveProcSelf(source)
+	}
+
+	fd, err := strconv.ParseInt(match[1], 10, 32)
+	if err != nil {
+		return "", syscall.ENOENT
+	}
+
+	path, err := process.GetFd(int32(fd))
+	if err != nil {
+		return "", syscall.ENOENT
+	}
+
+	if !filepath.IsAbs(path) || strings.HasSuffix(path, " (deleted)") {
+		return source, nil
+	}
+
+	return path, nil
+}
+
 func (m *mountSyscallInfo) String() string {
 	return fmt.Sprintf("source: %s, target: %s, fstype: %s, flags: %#x, data: %s, root: %s, cwd: %s",
 		m.Source, m.Target, m.FsType, m.Flags, m.Data, m.root, m.cwd)
@@ -0,0 +1,243 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// This file contains Sysbox's trapping & handling code for the syscalls of the
+// new mount API that operate on existing mounts: open_tree(2) and
+// move_mount(2). Unlike mount(2), these hand out (or take) mounts as fds, so
+// there's no way for sysbox-fs to emulate them on behalf of the process (the
+// resulting fd would have to be installed in the process). These are policed
+// instead, and left to the kernel when acceptable.
+//
+// Cloning a sysbox-fs base mount (e.g., /proc), or a submount carrying other
+// sysbox-fs submounts (e.g., /proc/sys), via open_tree(OPEN_TREE_CLONE) must be
+// recursive (AT_RECURSIVE): a non-recursive clone would lack the sysbox-fs
+// submounts and thereby expose the host's resources. The kernel rejects these
+// clones with EINVAL whenever the submounts are locked (i.e., inherited from a
+// more privileged mount-ns), so we do the same for the ones that aren't (e.g.,
+// the ones of a procfs mounted within the container).
+//
+// Moves of sysbox-fs managed mounts via move_mount() are handled just like
+// their mount(MS_MOVE) counterparts (see mountSyscallInfo.process()). All other
+// requests are handled by the kernel.
+
+package seccomp
+
+import (
+	"fmt"
+	"path/filepath"
+	"syscall"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+type openTreeSyscallInfo struct {
+	syscallCtx        // syscall generic info
+	path       string // pathname of the mount to open
+	dirFd      int32  // dirfd the pathname is relative to
+	flags      uint64 // open_tree flags
+}
+
+type moveMountSyscallInfo struct {
+	syscallCtx        // syscall generic info
+	fromPath   string // pathname of the mount to move
+	fromDirFd  int32  // dirfd the source pathname is relative to
+	toPath     string // pathname of the target mountpoint
+	toDirFd    int32  // dirfd the target pathname is relative to
+	flags      uint64 // move_mount flags
+}
+
+func (oi *openTreeSyscallInfo) process() (*sysResponse, error) {
+
+	t := oi.tracer
+
+	// Mounts are only cloned when asked for; otherwise open_tree() merely
+	// obtains an O_PATH fd.
+	if oi.flags&unix.OPEN_TREE_CLONE == 0 {
+		return t.createContinueResponse(oi.reqId), nil
+	}
+
+	if ok, resp := oi.collectProcessInfo(); !ok {
+		return resp, nil
+	}
+
+	path, err := resolveAtPath(oi.processInfo, oi.dirFd, oi.path,
+		oi.flags&unix.AT_EMPTY_PATH != 0)
+	if err != nil {
+		return t.createErrorResponse(oi.reqId, err), nil
+	}
+	path = rootAdjust(oi.root, path)
+
+	logrus.Debugf("open_tree(): pid = %d, path = %s, flags = %#x", oi.pid, path, oi.flags)
+
+	if oi.flags&unix.AT_RECURSIVE != 0 {
+		return t.createContinueResponse(oi.reqId), nil
+	}
+
+	mip, err := newMountApiInfoParser(&oi.syscallCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	if mip.IsSysboxfsBaseMount(path) ||
+		(mip.IsSysboxfsSubmount(path) && len(mip.GetSysboxfsNestedSubMounts(path)) > 0) {
+		logrus.Infof("Rejected non-recursive open_tree() clone of sysbox-fs managed mount %s from pid %d",
+			path, oi.pid)
+		return t.createErrorResponse(oi.reqId, syscall.EINVAL), nil
+	}
+
+	// No action by sysbox-fs
+	return t.createContinueResponse(oi.reqId), nil
+}
+
+func (mi *moveMountSyscallInfo) process() (*sysResponse, error) {
+
+	t := mi.tracer
+
+	// Propagation group changes move nothing; let the kernel handle them (as
+	// mount(2) propagation changes).
+	if mi.flags&unix.MOVE_MOUNT_SET_GROUP != 0 {
+		return t.createContinueResponse(mi.reqId), nil
+	}
+
+	if ok, resp := mi.collectProcessInfo(); !ok {
+		return resp, nil
+	}
+
+	from, err := resolveAtPath(mi.processInfo, mi.fromDirFd, mi.fromPath,
+		mi.flags&unix.MOVE_MOUNT_F_EMPTY_PATH != 0)
+	if err != nil {
+		return t.createErrorResponse(mi.reqId, err), nil
+	}
+	to, err := resolveAtPath(mi.processInfo, mi.toDirFd, mi.toPath,
+		mi.flags&unix.MOVE_MOUNT_T_EMPTY_PATH != 0)
+	if err != nil {
+		return t.createErrorResponse(mi.reqId, err), nil
+	}
+
+	logrus.Debugf("move_mount(): pid = %d, from = %s, to = %s, flags = %#x",
+		mi.pid, from, to, mi.flags)
+
+	mip, err := newMountApiInfoParser(&mi.syscallCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	adjFrom := rootAdjust(mi.root, from)
+	if !mip.IsSysboxfsBaseMount(adjFrom) && !mip.IsSysboxfsSubmount(adjFrom) {
+		// No action by sysbox-fs
+		return t.createContinueResponse(mi.reqId), nil
+	}
+
+	mount := &mountSyscallInfo{
+		syscallCtx: mi.syscallCtx,
+		MountSyscallPayload: &domain.MountSyscallPayload{
+			domain.NSenterMsgHeader{},
+			domain.Mount{
+				Source: from,
+				Target: to,
+				Flags:  unix.MS_MOVE,
+			},
+		},
+	}
+
+	return mount.process()
+}
+
+// collectProcessInfo gathers the attributes of the process issuing a mount
+// API syscall. As with mount(2), cap_sys_admin is required; the error response
+// is returned otherwise.
+func (s *syscallCtx) collectProcessInfo() (bool, *sysResponse) {
+
+	process := s.tracer.service.prs.ProcessCreate(s.pid, 0, 0)
+	if !process.IsSysAdminCapabilitySet() {
+		return false, s.tracer.createErrorResponse(s.reqId, syscall.EPERM)
+	}
+
+	s.uid = process.Uid()
+	s.gid = process.Gid()
+	s.cwd = process.Cwd()
+	s.root = process.Root()
+	s.rootInode = process.RootInode()
+	s.processInfo = process
+
+	return true, nil
+}
+
+// newMountApiInfoParser returns a mountinfo parser of the container hosting
+// the process issuing a mount API syscall.
+func newMountApiInfoParser(s *syscallCtx) (domain.MountInfoParserIface, error) {
+
+	mts := s.tracer.service.mts
+	if mts == nil {
+		return nil, fmt.Errorf("unexpected mount-service handler")
+	}
+
+	if !s.cntr.IsMountInfoInitialized() {
+		if err := s.cntr.InitializeMountInfo(); err != nil {
+			return nil, err
+		}
+	}
+
+	return mts.NewMountInfoParser(s.cntr, s.processInfo, true, true, false)
+}
+
+// resolveAtPath returns the absolute path referred to by the given dirfd and
+// pathname, as interpreted by the *at() syscalls. An empty pathname refers to
+// dirfd itself if emptyPath is set (i.e., AT_EMPTY_PATH or alike).
+func resolveAtPath(
+	process domain.ProcessIface,
+	dirFd int32,
+	path string,
+	emptyPath bool) (string, error) {
+
+	if path == "" && !emptyPath {
+		return "", syscall.ENOENT
+	}
+
+	if !filepath.IsAbs(path) {
+		var dir string
+		if dirFd == unix.AT_FDCWD {
+			dir = process.Cwd()
+		} else {
+			var err error
+			dir, err = process.GetFd(dirFd)
+			if err != nil {
+				return "", syscall.EBADF
+			}
+		}
+		path = filepath.Join(dir, path)
+	}
+
+	path, err := process.ResolveProcSelf(filepath.Clean(path))
+	if err != nil {
+		return "", syscall.EACCES
+	}
+
+	return path, nil
+}
+
+// rootAdjust returns the given path as seen from the root of sysbox-fs (see
+// mountSyscallInfo.targetAdjust()).
+func rootAdjust(root string, path string) string {
+
+	if root == "/" {
+		return path
+	}
+
+	return filepath.Join(root, path)
+}
@@ -0,0 +1,269 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package seccomp
+
+import (
+	"syscall"
+	"testing"
+
+	libseccomp "github.com/seccomp/libseccomp-golang"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/sys/unix"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+// Tracee-memory fake holding the pathnames of the syscall, indexed by their
+// address.
+type testMountApiMemParser struct {
+	memParser
+	paths map[uint64]string
+}
+
+func (m *testMountApiMemParser) ReadSyscallStringArgs(
+	pid uint32,
+	elems []memParserDataElem) ([]string, error) {
+
+	var result []string
+	for _, e := range elems {
+		result = append(result, m.paths[e.addr])
+	}
+	return result, nil
+}
+
+// Process fake issuing the mount API syscalls from within /root.
+type testMountApiProcess struct {
+	domain.ProcessIface
+	sysAdmin bool
+	fds      map[int32]string
+}
+
+func (p *testMountApiProcess) IsSysAdminCapabilitySet() bool { return p.sysAdmin }
+func (p *testMountApiProcess) Uid() uint32                   { return 0 }
+func (p *testMountApiProcess) Gid() uint32                   { return 0 }
+func (p *testMountApiProcess) Cwd() string                   { return "/root" }
+func (p *testMountApiProcess) Root() string                  { return "/" }
+func (p *testMountApiProcess) RootInode() uint64             { return 0 }
+
+func (p *testMountApiProcess) ResolveProcSelf(path string) (string, error) {
+	return path, nil
+}
+
+func (p *testMountApiProcess) GetFd(fd int32) (string, error) {
+	path, ok := p.fds[fd]
+	if !ok {
+		return "", syscall.EBADF
+	}
+	return path, nil
+}
+
+type testMountApiProcessService struct {
+	domain.ProcessServiceIface
+	process *testMountApiProcess
+}
+
+func (s *testMountApiProcessService) ProcessCreate(pid uint32, uid uint32, gid uint32) domain.ProcessIface {
+	return s.process
+}
+
+// Addresses of the pathnames within the tracee's memory.
+const (
+	testFromPathAddr = 0x1000
+	testToPathAddr   = 0x2000
+)
+
+func newTestMountApiTracer(
+	sysAdmin bool,
+	paths map[uint64]string) (*syscallTracer, *mocks.MountHelperIface) {
+
+	mh := &mocks.MountHelperIface{}
+	mh.On("IsNewMount", mock.Anything).Return(false)
+	mh.On("IsMove", mock.Anything).Return(true)
+
+	// Container's procfs: /proc/sys carries sysbox-fs submounts of its own,
+	// whereas /proc/uptime is an emulated file.
+	mip := &testUmountInfoParser{
+		base: "/proc",
+		sysboxfs: map[string][]string{
+			"/proc/sys":     {"/proc/sys/net"},
+			"/proc/sys/net": nil,
+			"/proc/uptime":  nil,
+		},
+	}
+
+	mts := &mocks.MountServiceIface{}
+	mts.On("MountHelper").Return(mh)
+	mts.On("NewMountInfoParser", mock.Anything, mock.Anything,
+		true, true, false).Return(mip, nil)
+
+	process := &testMountApiProcess{
+		sysAdmin: sysAdmin,
+		fds: map[int32]string{
+			5: "/proc",
+			6: "/root",
+			7: "/", // detached mount (e.g., out of fsmount())
+		},
+	}
+
+	return &syscallTracer{
+		service: &SyscallMonitorService{
+			mts: mts,
+			prs: &testMountApiProcessService{process: process},
+		},
+		memParser: &testMountApiMemParser{paths: paths},
+	}, mh
+}
+
+func checkMountApiResp(
+	t *testing.T,
+	resp *sysResponse,
+	err error,
+	wantErrno syscall.Errno) {
+
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Error != int32(wantErrno) {
+		t.Errorf("errno = %d, want %d", resp.Error, wantErrno)
+	}
+	wantCont := wantErrno == 0
+	if gotCont := resp.Flags&libseccomp.NotifRespFlagContinue != 0; gotCont != wantCont {
+		t.Errorf("continue = %v, want %v", gotCont, wantCont)
+	}
+}
+
+func Test_syscallTracer_processOpenTree(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		sysAdmin  bool
+		dirFd     int32
+		path      string
+		flags     uint64
+		wantErrno syscall.Errno // zero if the syscall is to be continued
+	}{
+		// Plain opens create no mount.
+		{"open", false, unix.AT_FDCWD, "/proc", 0, 0},
+		// Recursive clones carry the sysbox-fs submounts along.
+		{"clone-rec-proc", true, unix.AT_FDCWD, "/proc",
+			unix.OPEN_TREE_CLONE | unix.AT_RECURSIVE, 0},
+		{"clone-rec-sys", true, 6, "../proc/sys",
+			unix.OPEN_TREE_CLONE | unix.AT_RECURSIVE, 0},
+		// Non-recursive clones of sysbox-fs mounts carrying submounts.
+		{"clone-proc", true, unix.AT_FDCWD, "/proc", unix.OPEN_TREE_CLONE, syscall.EINVAL},
+		{"clone-sys", true, unix.AT_FDCWD, "/proc/sys/", unix.OPEN_TREE_CLONE, syscall.EINVAL},
+		{"clone-sys-dirfd", true, 5, "sys", unix.OPEN_TREE_CLONE, syscall.EINVAL},
+		{"clone-proc-empty-path", true, 5, "",
+			unix.OPEN_TREE_CLONE | unix.AT_EMPTY_PATH, syscall.EINVAL},
+		// Non-recursive clones of mounts carrying no sysbox-fs submounts.
+		{"clone-uptime", true, unix.AT_FDCWD, "/proc/uptime", unix.OPEN_TREE_CLONE, 0},
+		{"clone-net", true, unix.AT_FDCWD, "/proc/sys/net", unix.OPEN_TREE_CLONE, 0},
+		{"clone-other", true, unix.AT_FDCWD, "data", unix.OPEN_TREE_CLONE, 0},
+		// Invalid requests.
+		{"clone-unprivileged", false, unix.AT_FDCWD, "/root", unix.OPEN_TREE_CLONE, syscall.EPERM},
+		{"clone-empty-path", true, 5, "", unix.OPEN_TREE_CLONE, syscall.ENOENT},
+		{"clone-bad-dirfd", true, 9, "sys", unix.OPEN_TREE_CLONE, syscall.EBADF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer, _ := newTestMountApiTracer(tt.sysAdmin,
+				map[uint64]string{testFromPathAddr: tt.path})
+
+			req := &sysRequest{
+				ID: 1,
+				Data: libseccomp.ScmpNotifData{
+					Args: []uint64{uint64(tt.dirFd), testFromPathAddr, tt.flags},
+				},
+			}
+
+			resp, err := tracer.processOpenTree(req, 0, &testContainer{})
+			checkMountApiResp(t, resp, err, tt.wantErrno)
+		})
+	}
+}
+
+func Test_syscallTracer_processMoveMount(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		sysAdmin  bool
+		fromDirFd int32
+		fromPath  string
+		toPath    string
+		flags     uint64
+		wantMove  bool // handled as a mount(MS_MOVE) request
+		wantErrno syscall.Errno
+	}{
+		// Moves of sysbox-fs managed mounts.
+		{"move-proc", true, unix.AT_FDCWD, "/proc", "/mnt/proc", 0, true, 0},
+		{"move-sys", true, 5, "sys", "/mnt/sys", 0, true, 0},
+		{"move-proc-empty-path", true, 5, "", "/mnt/proc",
+			unix.MOVE_MOUNT_F_EMPTY_PATH, true, 0},
+		// Moves of other mounts, detached ones included.
+		{"move-other", true, unix.AT_FDCWD, "data", "/mnt/data", 0, false, 0},
+		{"attach-detached", true, 7, "", "/mnt/proc",
+			unix.MOVE_MOUNT_F_EMPTY_PATH, false, 0},
+		// Propagation group changes.
+		{"set-group", false, unix.AT_FDCWD, "/proc", "/mnt/proc",
+			unix.MOVE_MOUNT_SET_GROUP, false, 0},
+		// Invalid requests.
+		{"move-unprivileged", false, unix.AT_FDCWD, "/proc", "/mnt/proc", 0, false, syscall.EPERM},
+		{"move-empty-path", true, 5, "", "/mnt/proc", 0, false, syscall.ENOENT},
+		{"move-bad-dirfd", true, 9, "sys", "/mnt/sys", 0, false, syscall.EBADF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer, mh := newTestMountApiTracer(tt.sysAdmin, map[uint64]string{
+				testFromPathAddr: tt.fromPath,
+				testToPathAddr:   tt.toPath,
+			})
+
+			toDirFd := int32(unix.AT_FDCWD)
+
+			req := &sysRequest{
+				ID: 1,
+				Data: libseccomp.ScmpNotifData{
+					Args: []uint64{
+						uint64(tt.fromDirFd),
+						testFromPathAddr,
+						uint64(toDirFd),
+						testToPathAddr,
+						tt.flags,
+					},
+				},
+			}
+
+			resp, err := tracer.processMoveMount(req, 0, &testContainer{})
+			checkMountApiResp(t, resp, err, tt.wantErrno)
+
+			gotMove := false
+			for _, call := range mh.Calls {
+				if call.Method == "IsMove" && call.Arguments.Get(0).(uint64) == unix.MS_MOVE {
+					gotMove = true
+				}
+			}
+			if gotMove != tt.wantMove {
+				t.Errorf("handled as mount(MS_MOVE) = %v, want %v", gotMove, tt.wantMove)
+			}
+		})
+	}
+}
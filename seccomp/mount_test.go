@@ -0,0 +1,1624 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package seccomp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+
+	libseccomp "github.com/seccomp/libseccomp-golang"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/sys/unix"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/fuse"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+// Minimal container fake; only the methods utilized by the syscall handlers
+// under test are implemented.
+type testContainer struct {
+	domain.ContainerIface
+	procRoPaths   []string
+	procMaskPaths []string
+}
+
+func (c *testContainer) ID() string              { return "test" }
+func (c *testContainer) ProcRoPaths() []string   { return c.procRoPaths }
+func (c *testContainer) ProcMaskPaths() []string { return c.procMaskPaths }
+
+func (c *testContainer) IsMountInfoInitialized() bool { return true }
+
+// Minimal mountinfo-parser fake; no super-block info is available.
+type testMountInfoParser struct {
+	domain.MountInfoParserIface
+}
+
+func (p *testMountInfoParser) GetInfo(mountpoint string) *domain.MountInfo {
+	return nil
+}
+
+func Test_mountSyscallInfo_submountRoRemountFlags(t *testing.T) {
+
+	const baseRoFlags = unix.MS_RDONLY | unix.MS_BIND | unix.MS_REMOUNT |
+		unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC
+
+	mh := &mocks.MountHelperIface{}
+	mh.On("ProcMounts").Return([]string{"/proc/sys", "/proc/uptime"})
+	mh.On("SysMounts").Return([]string{"/sys/kernel", "/sys/devices/virtual"})
+
+	mts := &mocks.MountServiceIface{}
+	mts.On("MountHelper").Return(mh)
+
+	tracer := &syscallTracer{service: &SyscallMonitorService{mts: mts}}
+
+	tests := []struct {
+		name      string
+		fsType    string
+		flags     uint64
+		wantFlags uint64
+	}{
+		{"proc-default", "proc", unix.MS_RDONLY, baseRoFlags},
+		{"proc-noatime", "proc", unix.MS_RDONLY | unix.MS_NOATIME, baseRoFlags | unix.MS_NOATIME},
+		{"proc-relatime", "proc", unix.MS_RDONLY | unix.MS_RELATIME, baseRoFlags | unix.MS_RELATIME},
+		{"proc-nodiratime", "proc", unix.MS_RDONLY | unix.MS_NODIRATIME, baseRoFlags | unix.MS_NODIRATIME},
+		{"proc-strictatime", "proc", unix.MS_RDONLY | unix.MS_STRICTATIME, baseRoFlags | unix.MS_STRICTATIME},
+		{"proc-ignore-non-atime", "proc", unix.MS_RDONLY | unix.MS_NOATIME | unix.MS_SYNCHRONOUS, baseRoFlags | unix.MS_NOATIME},
+		{"sys-default", "sysfs", unix.MS_RDONLY, baseRoFlags},
+		{"sys-noatime", "sysfs", unix.MS_RDONLY | unix.MS_NOATIME | unix.MS_NODIRATIME, baseRoFlags | unix.MS_NOATIME | unix.MS_NODIRATIME},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &mountSyscallInfo{
+				syscallCtx{tracer: tracer, cntr: &testContainer{}},
+				&domain.MountSyscallPayload{
+					domain.NSenterMsgHeader{},
+					domain.Mount{
+						Source: tt.fsType,
+						Target: "/root/" + tt.fsType,
+						FsType: tt.fsType,
+						Flags:  tt.flags,
+					},
+				},
+			}
+
+			var payload *[]*domain.MountSyscallPayload
+			if tt.fsType == "proc" {
+				payload = m.createProcPayload(&testMountInfoParser{})
+			} else {
+				payload = m.createSysPayload(&testMountInfoParser{})
+			}
+
+			// Payload: base mount + submount binds + submount ro-remounts.
+			if len(*payload) != 5 {
+				t.Fatalf("unexpected payload length: got %d, want 5", len(*payload))
+			}
+
+			for _, p := range (*payload)[3:] {
+				if p.Flags != tt.wantFlags {
+					t.Errorf("%s remount flags = %#x, want %#x",
+						p.Target, p.Flags, tt.wantFlags)
+				}
+			}
+		})
+	}
+}
+
+// Mountinfo-parser fake describing a procfs base mount along with its
+// sysbox-fs submounts (submount -> read-only).
+type testRemountInfoParser struct {
+	domain.MountInfoParserIface
+	base      string
+	submounts map[string]bool
+	nested    map[string][]string // submount -> sysbox-fs mounts stacked on it
+}
+
+func (p *testRemountInfoParser) GetSysboxfsNestedSubMounts(mp string) []string {
+	return p.nested[mp]
+}
+
+func (p *testRemountInfoParser) IsSysboxfsBaseMount(mp string) bool {
+	return mp == p.base
+}
+
+func (p *testRemountInfoParser) IsSysboxfsSubmount(mp string) bool {
+	_, ok := p.submounts[mp]
+	return ok
+}
+
+func (p *testRemountInfoParser) IsSysboxfsRoSubmount(mp string) bool {
+	return p.submounts[mp]
+}
+
+func (p *testRemountInfoParser) GetSysboxfsSubMounts(mp string) []string {
+	var submounts []string
+	for subm := range p.submounts {
+		submounts = append(submounts, subm)
+	}
+	sort.Strings(submounts)
+	return submounts
+}
+
+func (p *testRemountInfoParser) GetInfo(mp string) *domain.MountInfo {
+	opts := map[string]string{"rw": ""}
+	if p.submounts[mp] {
+		opts = map[string]string{"ro": ""}
+	}
+	return &domain.MountInfo{
+		MountPoint: mp,
+		Options:    opts,
+		VfsOptions: map[string]string{},
+	}
+}
+
+func Test_mountSyscallInfo_processRemount_roSubmount(t *testing.T) {
+
+	mh := &mocks.MountHelperIface{}
+	mh.On("StringToFlags", map[string]string{"ro": ""}).Return(uint64(unix.MS_RDONLY))
+	mh.On("StringToFlags", map[string]string{"rw": ""}).Return(uint64(0))
+	mh.On("StringToFlags", map[string]string{}).Return(uint64(0))
+	mh.On("FilterFsFlags", mock.Anything).Return("")
+
+	mts := &mocks.MountServiceIface{}
+	mts.On("MountHelper").Return(mh)
+
+	mip := &testRemountInfoParser{
+		base: "/proc",
+		submounts: map[string]bool{
+			"/proc/bus": true,
+			"/proc/sys": false,
+		},
+	}
+
+	const rwFlags = unix.MS_REMOUNT | unix.MS_BIND
+	const roFlags = unix.MS_REMOUNT | unix.MS_BIND | unix.MS_RDONLY
+
+	tests := []struct {
+		name      string
+		target    string
+		flags     uint64
+		wantErrno syscall.Errno
+		wantFlags map[string]uint64 // remounted targets -> flags
+	}{
+		// Clearing RO on a read-only submount must be rejected.
+		{"ro-submount-rw", "/proc/bus", unix.MS_REMOUNT | unix.MS_BIND, syscall.EPERM, nil},
+		{"ro-submount-rw-nosuid", "/proc/bus", unix.MS_REMOUNT | unix.MS_NOSUID, syscall.EPERM, nil},
+		// Keeping RO on a read-only submount is fine.
+		{"ro-submount-ro", "/proc/bus", roFlags, 0, map[string]uint64{"/proc/bus": roFlags}},
+		// Clearing RO on a read-write submount is fine.
+		{"rw-submount-rw", "/proc/sys", rwFlags, 0, map[string]uint64{"/proc/sys": rwFlags}},
+		// Clearing RO on the base mount leaves read-only submounts untouched.
+		{"base-rw", "/proc", unix.MS_REMOUNT, 0, map[string]uint64{
+			"/proc/bus": roFlags,
+			"/proc/sys": rwFlags,
+			"/proc":     unix.MS_REMOUNT,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &mocks.NSenterEventIface{}
+			nss := &mocks.NSenterServiceIface{}
+			nss.On("NewEvent", mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything, mock.Anything, mock.Anything).Return(event)
+			nss.On("SendRequestEvent", event).Return(nil)
+			nss.On("ReceiveResponseEvent", event).Return(
+				&domain.NSenterMessage{Type: domain.MountSyscallResponse})
+
+			tracer := &syscallTracer{
+				service: &SyscallMonitorService{mts: mts, nss: nss},
+			}
+
+			m := &mountSyscallInfo{
+				syscallCtx{tracer: tracer, cntr: &testContainer{}},
+				&domain.MountSyscallPayload{
+					domain.NSenterMsgHeader{},
+					domain.Mount{
+						Target: tt.target,
+						Flags:  tt.flags,
+					},
+				},
+			}
+
+			resp, err := m.processRemount(mip)
+			if err != nil {
+				t.Fatalf("processRemount() unexpected error: %v", err)
+			}
+			if resp.Error != int32(tt.wantErrno) {
+				t.Errorf("processRemount() errno = %d, want %d", resp.Error, tt.wantErrno)
+			}
+
+			var gotFlags map[string]uint64
+			for _, call := range nss.Calls {
+				if call.Method != "NewEvent" {
+					continue
+				}
+				gotFlags = map[string]uint64{}
+				req := call.Arguments.Get(3).(*domain.NSenterMessage)
+				for _, p := range *req.Payload.(*[]*domain.MountSyscallPayload) {
+					gotFlags[p.Target] = p.Flags
+				}
+			}
+			if !reflect.DeepEqual(gotFlags, tt.wantFlags) {
+				t.Errorf("remounted targets = %v, want %v", gotFlags, tt.wantFlags)
+			}
+		})
+	}
+}
+
+// Remounting the base mount as read-only must make every sysbox-fs mount under
+// it read-only, including the ones stacked on its submounts, while keeping
+// them readable. The remount payload is applied through real mounts.
+func Test_mountSyscallInfo_createRemountPayload_roBaseMount(t *testing.T) {
+
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	base := filepath.Join(dir, "proc")
+
+	// Emulated files, as served through the sysbox-fs submounts.
+	files := map[string]string{
+		"sys/net/netfilter/nf_conntrack_max": "65536\n",
+		"yama/ptrace_scope":                  "1\n",
+	}
+	for f, content := range files {
+		path := filepath.Join(src, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(src, "sys/kernel/yama"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Base mount, submount (/proc/sys) and a mount stacked on the latter
+	// (/proc/sys/kernel/yama).
+	sys := filepath.Join(base, "sys")
+	yama := filepath.Join(sys, "kernel/yama")
+
+	mount := func(source, target, fstype string, flags uintptr) {
+		if err := os.MkdirAll(target, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := unix.Mount(source, target, fstype, flags, ""); err != nil {
+			t.Skipf("unable to mount %s: %v", target, err)
+		}
+		t.Cleanup(func() { unix.Unmount(target, unix.MNT_DETACH) })
+	}
+	mount("proc", base, "tmpfs", 0)
+	mount(filepath.Join(src, "sys"), sys, "", unix.MS_BIND)
+	mount(filepath.Join(src, "yama"), yama, "", unix.MS_BIND)
+
+	mh := &mocks.MountHelperIface{}
+	mh.On("StringToFlags", map[string]string{"rw": ""}).Return(uint64(0))
+	mh.On("StringToFlags", map[string]string{}).Return(uint64(0))
+	mh.On("FilterFsFlags", mock.Anything).Return("")
+
+	mts := &mocks.MountServiceIface{}
+	mts.On("MountHelper").Return(mh)
+
+	mip := &testRemountInfoParser{
+		base:      base,
+		submounts: map[string]bool{sys: false},
+		nested:    map[string][]string{sys: {yama}},
+	}
+
+	m := &mountSyscallInfo{
+		syscallCtx{
+			tracer: &syscallTracer{service: &SyscallMonitorService{mts: mts}},
+			cntr:   &testContainer{},
+		},
+		&domain.MountSyscallPayload{
+			domain.NSenterMsgHeader{},
+			domain.Mount{
+				Target: base,
+				Flags:  unix.MS_REMOUNT | unix.MS_RDONLY,
+			},
+		},
+	}
+
+	// Apply the payload as the nsenter agent would.
+	for _, p := range *m.createRemountPayload(mip) {
+		if err := unix.Mount(p.Source, p.Target, p.FsType, uintptr(p.Flags), p.Data); err != nil {
+			t.Fatalf("remount of %s failed: %v", p.Target, err)
+		}
+	}
+
+	for _, path := range []string{
+		filepath.Join(sys, "net/netfilter/nf_conntrack_max"),
+		filepath.Join(yama, "ptrace_scope"),
+	} {
+		if err := os.WriteFile(path, []byte("0\n"), 0644); !errors.Is(err, syscall.EROFS) {
+			t.Errorf("write of %s error = %v, want EROFS", path, err)
+		}
+		if _, err := os.ReadFile(path); err != nil {
+			t.Errorf("read of %s unexpected error: %v", path, err)
+		}
+	}
+}
+
+func Test_mountSyscallInfo_isProcRoOrMaskedPath(t *testing.T) {
+
+	m := &mountSyscallInfo{
+		syscallCtx{
+			cntr: &testContainer{
+				procRoPaths:   []string{"/proc/sys/kernel/yama"},
+				procMaskPaths: []string{"/proc/keys"},
+			},
+		},
+		&domain.MountSyscallPayload{
+			domain.NSenterMsgHeader{},
+			domain.Mount{Target: "/root/proc"},
+		},
+	}
+
+	tests := []struct {
+		mountpoint string
+		want       bool
+	}{
+		{"/root/proc/sys/kernel/yama", true},
+		{"/root/proc/keys", true},
+		{"/root/proc/sys/kernel", false},
+		{"/root/proc/kernel/yama", false},
+		{"/root/proc/1/sys/kernel/yama", false},
+		{"/root/proc/sys/kernel/yama/ptrace_scope", false},
+	}
+
+	for _, tt := range tests {
+		if got := m.isProcRoOrMaskedPath(tt.mountpoint); got != tt.want {
+			t.Errorf("isProcRoOrMaskedPath(%q) = %v, want %v", tt.mountpoint, got, tt.want)
+		}
+	}
+}
+
+func Test_mountSyscallInfo_processObsMount(t *testing.T) {
+
+	mh := &mocks.MountHelperIface{}
+	mh.On("IsNewMount", mock.Anything).Return(true)
+
+	mts := &mocks.MountServiceIface{}
+	mts.On("MountHelper").Return(mh)
+	mts.On("NewMountInfoParser", mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything).Return(&testMountInfoParser{}, nil)
+
+	tests := []struct {
+		name         string
+		allowed      bool
+		fstype       string
+		root         string
+		target       string
+		wantProxied  bool
+		wantContinue bool
+		wantErrno    syscall.Errno
+		wantTarget   string
+	}{
+		// bpffs mount is proxied when allowed.
+		{"bpf", true, "bpf", "/", "/sys/fs/bpf", true, false, 0, "/sys/fs/bpf"},
+		// Chroot'ed processes get their target adjusted to their root.
+		{"bpf-chroot", true, "bpf", "/var/lib/jail", "/sys/fs/bpf", true, false, 0, "/var/lib/jail/sys/fs/bpf"},
+		// debugfs (and tracefs) mounts are left to the kernel unless allowed.
+		{"debugfs-disallowed", false, "debugfs", "/", "/sys/kernel/debug", false, true, 0, ""},
+		{"debugfs", true, "debugfs", "/", "/sys/kernel/debug", true, false, 0, "/sys/kernel/debug"},
+		{"tracefs-disallowed", false, "tracefs", "/", "/sys/kernel/tracing", false, true, 0, ""},
+		// Targets outside of the process' root are rejected.
+		{"tracefs-escape", true, "tracefs", "/var/lib/jail", "/../../../sys/kernel/tracing", false, false, syscall.EPERM, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &mocks.NSenterEventIface{}
+			nss := &mocks.NSenterServiceIface{}
+			nss.On("NewEvent", mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything, mock.Anything, mock.Anything).Return(event)
+			nss.On("SendRequestEvent", event).Return(nil)
+			nss.On("ReceiveResponseEvent", event).Return(
+				&domain.NSenterMessage{Type: domain.MountSyscallResponse})
+
+			tracer := &syscallTracer{
+				service: &SyscallMonitorService{
+					mts:            mts,
+					nss:            nss,
+					allowObsMounts: tt.allowed,
+				},
+			}
+
+			m := &mountSyscallInfo{
+				syscallCtx{tracer: tracer, cntr: &testContainer{}, root: tt.root},
+				&domain.MountSyscallPayload{
+					domain.NSenterMsgHeader{},
+					domain.Mount{
+						Source: tt.fstype,
+						Target: tt.target,
+						FsType: tt.fstype,
+					},
+				},
+			}
+
+			resp, err := m.process()
+			if err != nil {
+				t.Fatalf("process() unexpected error: %v", err)
+			}
+			if resp.Error != int32(tt.wantErrno) {
+				t.Errorf("process() errno = %d, want %d", resp.Error, tt.wantErrno)
+			}
+			if gotContinue := resp.Flags == libseccomp.NotifRespFlagContinue; gotContinue != tt.wantContinue {
+				t.Errorf("process() continue = %v, want %v", gotContinue, tt.wantContinue)
+			}
+
+			var gotProxied bool
+			for _, call := range nss.Calls {
+				if call.Method != "NewEvent" {
+					continue
+				}
+				gotProxied = true
+				req := call.Arguments.Get(3).(*domain.NSenterMessage)
+				payload := *req.Payload.(*[]*domain.MountSyscallPayload)
+				if len(payload) != 1 ||
+					payload[0].Target != tt.wantTarget ||
+					payload[0].FsType != tt.fstype {
+					t.Errorf("proxied mount payload = %+v, want %s mount over %s",
+						payload, tt.fstype, tt.wantTarget)
+				}
+			}
+			if gotProxied != tt.wantProxied {
+				t.Errorf("mount proxied = %v, want %v", gotProxied, tt.wantProxied)
+			}
+		})
+	}
+}
+
+// Process fake holding the mount-ns and root of the process.
+type testMntProcess struct {
+	domain.ProcessIface
+	mntNs     domain.Inode
+	rootInode uint64
+}
+
+func (p *testMntProcess) Root() string                        { return "/" }
+func (p *testMntProcess) RootInode() uint64                   { return p.rootInode }
+func (p *testMntProcess) MountNsInode() (domain.Inode, error) { return p.mntNs, nil }
+
+// Container fake holding the mount-ids of its read-only immutable mounts.
+type testImmutableContainer struct {
+	testNsContainer
+	roImmutables map[int]bool
+}
+
+func (c *testImmutableContainer) IsImmutableRoMountID(id int) bool {
+	return c.roImmutables[id]
+}
+
+func (c *testImmutableContainer) IsImmutableRoBindMount(info *domain.MountInfo) bool {
+	return false
+}
+
+// Mountinfo-parser fake describing the container's procfs (see
+// testRemountInfoParser) along with mounts not managed by sysbox-fs (mount-id
+// -> read-only).
+type testBindRemountInfoParser struct {
+	testRemountInfoParser
+	mounts map[string]int
+	ro     map[int]bool
+}
+
+func (p *testBindRemountInfoParser) GetInfo(mp string) *domain.MountInfo {
+	id, ok := p.mounts[mp]
+	if !ok {
+		return p.testRemountInfoParser.GetInfo(mp)
+	}
+	opts := map[string]string{"rw": ""}
+	if p.ro[id] {
+		opts = map[string]string{"ro": ""}
+	}
+	return &domain.MountInfo{
+		MountID:    id,
+		MountPoint: mp,
+		Options:    opts,
+		VfsOptions: map[string]string{},
+	}
+}
+
+func (p *testBindRemountInfoParser) IsRoMount(info *domain.MountInfo) bool {
+	_, ok := info.Options["ro"]
+	return ok
+}
+
+func Test_mountSyscallInfo_process_bindRemount(t *testing.T) {
+
+	mh := &mocks.MountHelperIface{}
+	mh.On("IsNewMount", mock.Anything).Return(false)
+	mh.On("IsMove", mock.Anything).Return(false)
+	mh.On("HasPropagationFlag", mock.Anything).Return(false)
+	mh.On("IsRemount", mock.Anything).Return(func(flags uint64) bool {
+		return flags&unix.MS_REMOUNT == unix.MS_REMOUNT
+	})
+	mh.On("IsBind", mock.Anything).Return(func(flags uint64) bool {
+		return flags&unix.MS_BIND == unix.MS_BIND
+	})
+	mh.On("IsBindRemount", mock.Anything).Return(func(flags uint64) bool {
+		return flags&(unix.MS_REMOUNT|unix.MS_BIND) == unix.MS_REMOUNT|unix.MS_BIND
+	})
+	mh.On("IsReadOnlyMount", mock.Anything).Return(func(flags uint64) bool {
+		return flags&unix.MS_RDONLY == unix.MS_RDONLY
+	})
+	mh.On("StringToFlags", map[string]string{"ro": ""}).Return(uint64(unix.MS_RDONLY))
+	mh.On("StringToFlags", map[string]string{"rw": ""}).Return(uint64(0))
+	mh.On("StringToFlags", map[string]string{}).Return(uint64(0))
+	mh.On("FilterFsFlags", mock.Anything).Return("")
+
+	mip := &testBindRemountInfoParser{
+		testRemountInfoParser: testRemountInfoParser{
+			base: "/proc",
+			submounts: map[string]bool{
+				"/proc/bus": true,
+				"/proc/sys": false,
+			},
+		},
+		mounts: map[string]int{
+			"/var/lib/docker": 1721, // read-write
+			"/etc/hostname":   1716, // read-only immutable
+			"/mnt/data":       1800, // read-only, created within the container
+		},
+		ro: map[int]bool{1716: true, 1800: true},
+	}
+
+	mts := &mocks.MountServiceIface{}
+	mts.On("MountHelper").Return(mh)
+	mts.On("NewMountInfoParser", mock.Anything, mock.Anything,
+		true, true, false).Return(mip, nil)
+
+	proc := &testMntProcess{mntNs: 4026532000, rootInode: 2}
+	cntr := &testImmutableContainer{
+		testNsContainer: testNsContainer{initProc: proc},
+		roImmutables:    map[int]bool{1716: true},
+	}
+
+	const bindRemount = unix.MS_REMOUNT | unix.MS_BIND
+
+	tests := []struct {
+		name         string
+		target       string // "." as resolved against the process' cwd
+		fstype       string
+		flags        uint64
+		wantErrno    syscall.Errno
+		wantContinue bool
+		wantFlags    map[string]uint64 // remounted targets -> flags
+	}{
+		// "mount -o bind,remount,ro ." over a read-write mount.
+		{"ro-rw-mount", "/var/lib/docker", "", bindRemount | unix.MS_RDONLY, 0, true, nil},
+		// Same as above over a read-only immutable.
+		{"ro-immutable", "/etc/hostname", "", bindRemount | unix.MS_RDONLY, 0, true, nil},
+		// "mount -o bind,remount,rw ." over a read-only immutable.
+		{"rw-immutable", "/etc/hostname", "", bindRemount, syscall.EPERM, false, nil},
+		// The fstype of a bind-remount is ignored by the kernel, so it must not
+		// exempt the target from the immutable checks.
+		{"rw-immutable-proc-fstype", "/etc/hostname", "proc", bindRemount, syscall.EPERM, false, nil},
+		// "mount -o bind,remount,rw ." over a regular read-only mount.
+		{"rw-ro-mount", "/mnt/data", "", bindRemount, 0, true, nil},
+		// Bind-remounts over sysbox-fs submounts are carried out by sysbox-fs.
+		{"ro-submount", "/proc/sys", "", bindRemount | unix.MS_RDONLY, 0, false,
+			map[string]uint64{"/proc/sys": bindRemount | unix.MS_RDONLY}},
+		{"rw-ro-submount", "/proc/bus", "", bindRemount, syscall.EPERM, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &mocks.NSenterEventIface{}
+			nss := &mocks.NSenterServiceIface{}
+			nss.On("NewEvent", mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything, mock.Anything, mock.Anything).Return(event)
+			nss.On("SendRequestEvent", event).Return(nil)
+			nss.On("ReceiveResponseEvent", event).Return(
+				&domain.NSenterMessage{Type: domain.MountSyscallResponse})
+
+			tracer := &syscallTracer{
+				service: &SyscallMonitorService{mts: mts, nss: nss},
+			}
+
+			m := &mountSyscallInfo{
+				syscallCtx{
+					tracer:      tracer,
+					cntr:        cntr,
+					root:        "/",
+					processInfo: proc,
+				},
+				&domain.MountSyscallPayload{
+					domain.NSenterMsgHeader{},
+					domain.Mount{
+						Source: "none",
+						Target: tt.target,
+						FsType: tt.fstype,
+						Flags:  tt.flags,
+					},
+				},
+			}
+
+			resp, err := m.process()
+			if err != nil {
+				t.Fatalf("process() unexpected error: %v", err)
+			}
+			if resp.Error != int32(tt.wantErrno) {
+				t.Errorf("process() errno = %d, want %d", resp.Error, tt.wantErrno)
+			}
+			if gotContinue := resp.Flags == libseccomp.NotifRespFlagContinue; gotContinue != tt.wantContinue {
+				t.Errorf("process() continue = %v, want %v", gotContinue, tt.wantContinue)
+			}
+
+			var gotFlags map[string]uint64
+			for _, call := range nss.Calls {
+				if call.Method != "NewEvent" {
+					continue
+				}
+				gotFlags = map[string]uint64{}
+				req := call.Arguments.Get(3).(*domain.NSenterMessage)
+				for _, p := range *req.Payload.(*[]*domain.MountSyscallPayload) {
+					gotFlags[p.Target] = p.Flags
+				}
+			}
+			if !reflect.DeepEqual(gotFlags, tt.wantFlags) {
+				t.Errorf("remounted targets = %v, want %v", gotFlags, tt.wantFlags)
+			}
+		})
+	}
+}
+
+// Mountinfo-parser fake describing a container's procfs that is read-only at
+// super-block level.
+type testRoProcInfoParser struct {
+	domain.MountInfoParserIface
+}
+
+func (p *testRoProcInfoParser) GetInfo(mountpoint string) *domain.MountInfo {
+	if mountpoint != "/proc" {
+		return nil
+	}
+	return &domain.MountInfo{
+		MountPoint: mountpoint,
+		VfsOptions: map[string]string{"ro": ""},
+	}
+}
+
+func Test_mountSyscallInfo_processProcMount_roChown(t *testing.T) {
+
+	const (
+		cntrRootUid = 231072
+		cntrRootGid = 231072
+		procFlags   = unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC
+	)
+
+	mh := &mocks.MountHelperIface{}
+	mh.On("ProcMounts").Return([]string{"/proc/sys"})
+
+	mts := &mocks.MountServiceIface{}
+	mts.On("MountHelper").Return(mh)
+
+	// nsenter requests, as "<type> <target> <flags|uid:gid> <namespaces>".
+	type request string
+
+	mountReq := func(target string, flags uint64, ns []domain.NStype) request {
+		return request(fmt.Sprintf("mount %s %#x %v", target, flags, ns))
+	}
+	chownReq := func(target string, uid, gid int, ns []domain.NStype) request {
+		return request(fmt.Sprintf("chown %s %d:%d %v", target, uid, gid, ns))
+	}
+
+	tests := []struct {
+		name     string
+		enabled  bool
+		mip      domain.MountInfoParserIface
+		flags    uint64
+		wantReqs []request
+	}{
+		// Read-write mounts are chowned regardless of the knob.
+		{"rw", false, &testMountInfoParser{}, procFlags, []request{
+			mountReq("/root/proc", procFlags, domain.AllNSs),
+			chownReq("/root/proc", cntrRootUid, cntrRootGid, domain.AllNSsButUser),
+		}},
+		// Read-only mounts are left as nobody:nogroup by default.
+		{"ro-disabled", false, &testMountInfoParser{}, procFlags | unix.MS_RDONLY, []request{
+			mountReq("/root/proc", procFlags|unix.MS_RDONLY, domain.AllNSs),
+		}},
+		// Read-only mounts are mounted read-write, chowned and remounted
+		// read-only.
+		{"ro-enabled", true, &testMountInfoParser{}, procFlags | unix.MS_RDONLY, []request{
+			mountReq("/root/proc", procFlags, domain.AllNSs),
+			chownReq("/root/proc", cntrRootUid, cntrRootGid, domain.AllNSsButUser),
+			mountReq("/root/proc", unix.MS_REMOUNT|unix.MS_BIND|unix.MS_RDONLY|procFlags, domain.AllNSs),
+		}},
+		// Mounts that are read-only at super-block level can't be chowned.
+		{"ro-superblock", true, &testRoProcInfoParser{}, procFlags, []request{
+			mountReq("/root/proc", procFlags|unix.MS_RDONLY, domain.AllNSs),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &mocks.NSenterEventIface{}
+			nss := &mocks.NSenterServiceIface{}
+			nss.On("NewEvent", mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything, mock.Anything, mock.Anything).Return(event)
+			nss.On("SendRequestEvent", event).Return(nil)
+			nss.On("ReceiveResponseEvent", event).Return(
+				&domain.NSenterMessage{Type: domain.MountSyscallResponse})
+
+			tracer := &syscallTracer{
+				service: &SyscallMonitorService{
+					mts:               mts,
+					nss:               nss,
+					chownRoProcMounts: tt.enabled,
+				},
+			}
+
+			m := &mountSyscallInfo{
+				syscallCtx{
+					tracer: tracer,
+					cntr:   &testContainer{},
+					uid:    cntrRootUid,
+					gid:    cntrRootGid,
+				},
+				&domain.MountSyscallPayload{
+					domain.NSenterMsgHeader{},
+					domain.Mount{
+						Source: "proc",
+						Target: "/root/proc",
+						FsType: "proc",
+						Flags:  tt.flags,
+					},
+				},
+			}
+
+			resp, err := m.processProcMount(tt.mip)
+			if err != nil {
+				t.Fatalf("processProcMount() unexpected error: %v", err)
+			}
+			if resp.Error != 0 {
+				t.Errorf("processProcMount() errno = %d, want 0", resp.Error)
+			}
+
+			var gotReqs []request
+			for _, call := range nss.Calls {
+				if call.Method != "NewEvent" {
+					continue
+				}
+				ns := *call.Arguments.Get(1).(*[]domain.NStype)
+				req := call.Arguments.Get(3).(*domain.NSenterMessage)
+
+				switch payload := req.Payload.(type) {
+				case *[]*domain.MountSyscallPayload:
+					// Only the proc mount itself is of interest.
+					p := (*payload)[0]
+					gotReqs = append(gotReqs, mountReq(p.Target, p.Flags, ns))
+				case []*domain.ChownSyscallPayload:
+					p := payload[0]
+					gotReqs = append(gotReqs, chownReq(p.Target, p.TargetUid, p.TargetGid, ns))
+				}
+			}
+			if !reflect.DeepEqual(gotReqs, tt.wantReqs) {
+				t.Errorf("nsenter requests = %q, want %q", gotReqs, tt.wantReqs)
+			}
+		})
+	}
+}
+
+// Mountinfo-parser fake describing the given mounts, where the sysbox-fs
+// submounts are the ones of the given base mount, tagged as read-only or
+// masked.
+type testRecBindInfoParser struct {
+	domain.MountInfoParserIface
+	base   string
+	mounts map[string]*domain.MountInfo
+	ro     map[string]bool
+	masked map[string]bool
+}
+
+func (p *testRecBindInfoParser) IsSysboxfsBaseMount(mp string) bool {
+	return mp == p.base
+}
+
+func (p *testRecBindInfoParser) GetSysboxfsSubMounts(mp string) []string {
+	var submounts []string
+	for subm := range p.mounts {
+		if subm != p.base && strings.HasPrefix(subm, p.base+"/") {
+			submounts = append(submounts, subm)
+		}
+	}
+	sort.Strings(submounts)
+	return submounts
+}
+
+func (p *testRecBindInfoParser) IsSysboxfsRoSubmount(mp string) bool {
+	return p.ro[mp]
+}
+
+func (p *testRecBindInfoParser) IsSysboxfsMaskedSubmount(mp string) bool {
+	return p.masked[mp]
+}
+
+func (p *testRecBindInfoParser) GetInfo(mp string) *domain.MountInfo {
+	return p.mounts[mp]
+}
+
+func (p *testRecBindInfoParser) IsRoMount(info *domain.MountInfo) bool {
+	if info == nil {
+		return false
+	}
+	_, ok := info.Options["ro"]
+	return ok
+}
+
+func Test_mountSyscallInfo_processBindMount_recursive(t *testing.T) {
+
+	const (
+		procFlags = unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC
+		roFlags   = unix.MS_REMOUNT | unix.MS_BIND | unix.MS_RDONLY | procFlags
+	)
+
+	procMount := func(mp, root string, ro bool) *domain.MountInfo {
+		opts := map[string]string{"rw": ""}
+		if ro {
+			opts = map[string]string{"ro": ""}
+		}
+		return &domain.MountInfo{MountPoint: mp, MajorMinorVer: "0:20", Root: root, Options: opts}
+	}
+	nullMount := func(mp string) *domain.MountInfo {
+		return &domain.MountInfo{MountPoint: mp, MajorMinorVer: "0:5", Root: "/null",
+			Options: map[string]string{"rw": ""}}
+	}
+
+	// Container's procfs: /proc/bus is read-only, /proc/kcore is masked.
+	mip := &testRecBindInfoParser{
+		base: "/proc",
+		mounts: map[string]*domain.MountInfo{
+			"/proc":       procMount("/proc", "/", false),
+			"/proc/bus":   procMount("/proc/bus", "/bus", true),
+			"/proc/kcore": nullMount("/proc/kcore"),
+			"/proc/sys":   procMount("/proc/sys", "/sys", false),
+		},
+		ro:     map[string]bool{"/proc/bus": true},
+		masked: map[string]bool{"/proc/kcore": true},
+	}
+
+	// nsenter mount requests, as "<source> -> <target> <flags>".
+	mountReq := func(source, target string, flags uint64) string {
+		return fmt.Sprintf("%s -> %s %#x", source, target, flags)
+	}
+
+	tests := []struct {
+		name     string
+		flags    uint64
+		bindMnts map[string]*domain.MountInfo // mounts at the target after the bind
+		wantReqs []string
+	}{
+		// The kernel's copy kept all the submounts' attributes.
+		{"preserved", unix.MS_BIND | unix.MS_REC, map[string]*domain.MountInfo{
+			"/mnt/proc/bus":   procMount("/mnt/proc/bus", "/bus", true),
+			"/mnt/proc/kcore": nullMount("/mnt/proc/kcore"),
+			"/mnt/proc/sys":   procMount("/mnt/proc/sys", "/sys", false),
+		}, []string{
+			mountReq("/proc", "/mnt/proc", unix.MS_BIND|unix.MS_REC),
+		}},
+		// The masked submount didn't make it to the target.
+		{"masked-missing", unix.MS_BIND | unix.MS_REC, map[string]*domain.MountInfo{
+			"/mnt/proc/bus": procMount("/mnt/proc/bus", "/bus", true),
+			"/mnt/proc/sys": procMount("/mnt/proc/sys", "/sys", false),
+		}, []string{
+			mountReq("/proc", "/mnt/proc", unix.MS_BIND|unix.MS_REC),
+			mountReq("/proc/kcore", "/mnt/proc/kcore", unix.MS_BIND),
+		}},
+		// The target exposes the procfs file underneath the masked submount.
+		{"masked-exposed", unix.MS_BIND | unix.MS_REC, map[string]*domain.MountInfo{
+			"/mnt/proc/bus":   procMount("/mnt/proc/bus", "/bus", true),
+			"/mnt/proc/kcore": procMount("/mnt/proc/kcore", "/kcore", false),
+			"/mnt/proc/sys":   procMount("/mnt/proc/sys", "/sys", false),
+		}, []string{
+			mountReq("/proc", "/mnt/proc", unix.MS_BIND|unix.MS_REC),
+			mountReq("/proc/kcore", "/mnt/proc/kcore", unix.MS_BIND),
+		}},
+		// The read-only submount was copied as read-write.
+		{"ro-lost", unix.MS_BIND | unix.MS_REC, map[string]*domain.MountInfo{
+			"/mnt/proc/bus":   procMount("/mnt/proc/bus", "/bus", false),
+			"/mnt/proc/kcore": nullMount("/mnt/proc/kcore"),
+			"/mnt/proc/sys":   procMount("/mnt/proc/sys", "/sys", false),
+		}, []string{
+			mountReq("/proc", "/mnt/proc", unix.MS_BIND|unix.MS_REC),
+			mountReq("", "/mnt/proc/bus", roFlags),
+		}},
+		// The read-only submount didn't make it to the target.
+		{"ro-missing", unix.MS_BIND | unix.MS_REC, map[string]*domain.MountInfo{
+			"/mnt/proc/kcore": nullMount("/mnt/proc/kcore"),
+			"/mnt/proc/sys":   procMount("/mnt/proc/sys", "/sys", false),
+		}, []string{
+			mountReq("/proc", "/mnt/proc", unix.MS_BIND|unix.MS_REC),
+			mountReq("/proc/bus", "/mnt/proc/bus", unix.MS_BIND),
+			mountReq("", "/mnt/proc/bus", roFlags),
+		}},
+		// Non-recursive binds carry the submounts explicitly.
+		{"non-recursive", unix.MS_BIND, nil, []string{
+			mountReq("/proc", "/mnt/proc", unix.MS_BIND),
+			mountReq("/proc/bus", "/mnt/proc/bus", unix.MS_BIND),
+			mountReq("/proc/kcore", "/mnt/proc/kcore", unix.MS_BIND),
+			mountReq("/proc/sys", "/mnt/proc/sys", unix.MS_BIND),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bindMip := &testRecBindInfoParser{mounts: tt.bindMnts}
+
+			mts := &mocks.MountServiceIface{}
+			mts.On("NewMountInfoParser", mock.Anything, mock.Anything, true, true, false).
+				Return(bindMip, nil)
+
+			event := &mocks.NSenterEventIface{}
+			nss := &mocks.NSenterServiceIface{}
+			nss.On("NewEvent", mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything, mock.Anything, mock.Anything).Return(event)
+			nss.On("SendRequestEvent", event).Return(nil)
+			nss.On("ReceiveResponseEvent", event).Return(
+				&domain.NSenterMessage{Type: domain.MountSyscallResponse})
+
+			tracer := &syscallTracer{
+				service: &SyscallMonitorService{mts: mts, nss: nss},
+			}
+
+			m := &mountSyscallInfo{
+				syscallCtx{tracer: tracer, cntr: &testContainer{}},
+				&domain.MountSyscallPayload{
+					domain.NSenterMsgHeader{},
+					domain.Mount{
+						Source: "/proc",
+						Target: "/mnt/proc",
+						Flags:  tt.flags,
+					},
+				},
+			}
+
+			resp, err := m.processBindMount(mip)
+			if err != nil {
+				t.Fatalf("processBindMount() unexpected error: %v", err)
+			}
+			if resp.Error != 0 {
+				t.Errorf("processBindMount() errno = %d, want 0", resp.Error)
+			}
+
+			var gotReqs []string
+			for _, call := range nss.Calls {
+				if call.Method != "NewEvent" {
+					continue
+				}
+				req := call.Arguments.Get(3).(*domain.NSenterMessage)
+				for _, p := range *req.Payload.(*[]*domain.MountSyscallPayload) {
+					gotReqs = append(gotReqs, mountReq(p.Source, p.Target, p.Flags))
+				}
+			}
+			if !reflect.DeepEqual(gotReqs, tt.wantReqs) {
+				t.Errorf("mount requests = %q, want %q", gotReqs, tt.wantReqs)
+			}
+		})
+	}
+}
+
+// Mountinfo-parser fake holding the sysbox-fs submounts of the container's
+// procfs (mountpoint -> masked), none of which have nested submounts.
+type testFileBindInfoParser struct {
+	domain.MountInfoParserIface
+	submounts map[string]bool
+}
+
+func (p *testFileBindInfoParser) IsSysboxfsBaseMount(mp string) bool {
+	return false
+}
+
+func (p *testFileBindInfoParser) IsSysboxfsSubmount(mp string) bool {
+	_, ok := p.submounts[mp]
+	return ok
+}
+
+func (p *testFileBindInfoParser) IsSysboxfsMaskedSubmount(mp string) bool {
+	return p.submounts[mp]
+}
+
+func (p *testFileBindInfoParser) GetSysboxfsNestedSubMounts(mp string) []string {
+	return nil
+}
+
+func Test_mountSyscallInfo_process_fileBind(t *testing.T) {
+
+	mh := &mocks.MountHelperIface{}
+	mh.On("IsNewMount", mock.Anything).Return(false)
+	mh.On("IsMove", mock.Anything).Return(false)
+	mh.On("HasPropagationFlag", mock.Anything).Return(false)
+	mh.On("IsRemount", mock.Anything).Return(false)
+	mh.On("IsBind", mock.Anything).Return(true)
+
+	// Container's procfs, as seen by the process: uptime is an emulated file,
+	// kcore a masked one, and sys an emulated directory.
+	proc := filepath.Join(t.TempDir(), "proc")
+	if err := os.MkdirAll(filepath.Join(proc, "sys"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"uptime", "kcore"} {
+		if err := os.WriteFile(filepath.Join(proc, f), nil, 0444); err != nil {
+			t.Fatal(err)
+		}
+	}
+	uptime := filepath.Join(proc, "uptime")
+	kcore := filepath.Join(proc, "kcore")
+	sys := filepath.Join(proc, "sys")
+
+	mip := &testFileBindInfoParser{
+		submounts: map[string]bool{uptime: false, kcore: true, sys: false},
+	}
+
+	// nsenter mount requests, as "<source> -> <target> <flags>".
+	mountReq := func(source, target string, flags uint64) string {
+		return fmt.Sprintf("%s -> %s %#x", source, target, flags)
+	}
+
+	tests := []struct {
+		name         string
+		source       string
+		target       string
+		flags        uint64
+		nsErrno      syscall.Errno // error of the nsenter mount request
+		wantErrno    syscall.Errno
+		wantContinue bool
+		wantReqs     []string
+	}{
+		// "mount --bind /proc/uptime /root/uptime"
+		{"file", uptime, "/root/uptime", unix.MS_BIND, 0, 0, false, []string{
+			mountReq(uptime, "/root/uptime", unix.MS_BIND),
+		}},
+		// "mount --rbind /proc/uptime /root/uptime"
+		{"file-rec", uptime, "/root/uptime", unix.MS_BIND | unix.MS_REC, 0, 0, false, []string{
+			mountReq(uptime, "/root/uptime", unix.MS_BIND|unix.MS_REC),
+		}},
+		// "mount --bind /proc/uptime /root" (directory target)
+		{"file-dir-target", uptime, "/root", unix.MS_BIND, syscall.ENOTDIR, syscall.ENOTDIR, false, []string{
+			mountReq(uptime, "/root", unix.MS_BIND),
+		}},
+		// "mount --bind /proc/kcore /root/kcore" (the copy remains masked)
+		{"masked-file", kcore, "/root/kcore", unix.MS_BIND, 0, 0, false, []string{
+			mountReq(kcore, "/root/kcore", unix.MS_BIND),
+		}},
+		// "mount --bind /proc/sys /mnt/sys"
+		{"dir", sys, "/mnt/sys", unix.MS_BIND, 0, 0, false, []string{
+			mountReq(sys, "/mnt/sys", unix.MS_BIND),
+		}},
+		// "mount --bind /dev/null /proc/uptime" masks the emulated file.
+		{"mask-file", "/dev/null", uptime, unix.MS_BIND, 0, 0, true, nil},
+		// "mount --bind /dev/null /proc/kcore" is redundant.
+		{"mask-masked", "/dev/null", kcore, unix.MS_BIND, 0, 0, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mts := &mocks.MountServiceIface{}
+			mts.On("MountHelper").Return(mh)
+			mts.On("NewMountInfoParser", mock.Anything, mock.Anything,
+				true, true, false).Return(mip, nil)
+
+			resMsg := &domain.NSenterMessage{Type: domain.MountSyscallResponse}
+			if tt.nsErrno != 0 {
+				resMsg = &domain.NSenterMessage{
+					Type:    domain.ErrorResponse,
+					Payload: fuse.IOerror{Code: tt.nsErrno},
+				}
+			}
+
+			event := &mocks.NSenterEventIface{}
+			nss := &mocks.NSenterServiceIface{}
+			nss.On("NewEvent", mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything, mock.Anything, mock.Anything).Return(event)
+			nss.On("SendRequestEvent", event).Return(nil)
+			nss.On("ReceiveResponseEvent", event).Return(resMsg)
+
+			tracer := &syscallTracer{
+				service: &SyscallMonitorService{mts: mts, nss: nss},
+			}
+
+			m := &mountSyscallInfo{
+				syscallCtx{
+					tracer: tracer,
+					cntr:   &testContainer{},
+					pid:    uint32(os.Getpid()),
+					root:   "/",
+				},
+				&domain.MountSyscallPayload{
+					domain.NSenterMsgHeader{},
+					domain.Mount{
+						Source: tt.source,
+						Target: tt.target,
+						Flags:  tt.flags,
+					},
+				},
+			}
+
+			resp, err := m.process()
+			if err != nil {
+				t.Fatalf("process() unexpected error: %v", err)
+			}
+			if resp.Error != int32(tt.wantErrno) {
+				t.Errorf("process() errno = %d, want %d", resp.Error, tt.wantErrno)
+			}
+			if gotContinue := resp.Flags == libseccomp.NotifRespFlagContinue; gotContinue != tt.wantContinue {
+				t.Errorf("process() continue = %v, want %v", gotContinue, tt.wantContinue)
+			}
+
+			var gotReqs []string
+			for _, call := range nss.Calls {
+				if call.Method != "NewEvent" {
+					continue
+				}
+				req := call.Arguments.Get(3).(*domain.NSenterMessage)
+				for _, p := range *req.Payload.(*[]*domain.MountSyscallPayload) {
+					gotReqs = append(gotReqs, mountReq(p.Source, p.Target, p.Flags))
+				}
+			}
+			if !reflect.DeepEqual(gotReqs, tt.wantReqs) {
+				t.Errorf("mount requests = %q, want %q", gotReqs, tt.wantReqs)
+			}
+		})
+	}
+}
+
+// A user's bind-mount over a masked submount shadows the mask, and unmounting
+// it brings the mask back (rather than exposing the underlying procfs file).
+func Test_mountSyscallInfo_process_bindOverMasked(t *testing.T) {
+
+	mh := &mocks.MountHelperIface{}
+	mh.On("IsNewMount", mock.Anything).Return(false)
+	mh.On("IsMove", mock.Anything).Return(false)
+	mh.On("HasPropagationFlag", mock.Anything).Return(false)
+	mh.On("IsRemount", mock.Anything).Return(false)
+	mh.On("IsBind", mock.Anything).Return(true)
+
+	// Mountinfo before the bind-mount: kcore is masked by sysbox-fs.
+	bindMip := &testFileBindInfoParser{
+		submounts: map[string]bool{"/proc/kcore": true, "/proc/uptime": false},
+	}
+
+	// Mountinfo after the bind-mount: the top mount at /proc/kcore is the
+	// user's one.
+	stackedMip := &testUmountInfoParser{
+		base: "/proc",
+		sysboxfs: map[string][]string{
+			"/proc/uptime": nil,
+		},
+		user: []string{"/proc/kcore"},
+	}
+
+	// Mountinfo once the user's bind-mount is gone.
+	maskedMip := &testUmountInfoParser{
+		base: "/proc",
+		sysboxfs: map[string][]string{
+			"/proc/uptime": nil,
+			"/proc/kcore":  nil,
+		},
+		masked: map[string]bool{"/proc/kcore": true},
+	}
+
+	newTracer := func(mip domain.MountInfoParserIface) (*syscallTracer, *mocks.NSenterServiceIface) {
+		mts := &mocks.MountServiceIface{}
+		mts.On("MountHelper").Return(mh)
+		mts.On("NewMountInfoParser", mock.Anything, mock.Anything,
+			true, true, false).Return(mip, nil)
+
+		nss := &mocks.NSenterServiceIface{}
+
+		return &syscallTracer{
+			service: &SyscallMonitorService{
+				mts:                    mts,
+				nss:                    nss,
+				allowImmutableUnmounts: true,
+			},
+		}, nss
+	}
+
+	checkResp := func(op string, resp *sysResponse, err error, wantCont bool) {
+		if err != nil {
+			t.Fatalf("%s: process() unexpected error: %v", op, err)
+		}
+		if resp.Error != 0 {
+			t.Errorf("%s: process() errno = %d, want 0", op, resp.Error)
+		}
+		if gotCont := resp.Flags&libseccomp.NotifRespFlagContinue != 0; gotCont != wantCont {
+			t.Errorf("%s: process() continue = %v, want %v", op, gotCont, wantCont)
+		}
+	}
+
+	// "mount --bind /root/kcore /proc/kcore" is left for the kernel to stack
+	// over the mask.
+	tracer, nss := newTracer(bindMip)
+	m := &mountSyscallInfo{
+		syscallCtx{
+			tracer: tracer,
+			cntr:   &testContainer{},
+			pid:    uint32(os.Getpid()),
+			root:   "/",
+		},
+		&domain.MountSyscallPayload{
+			domain.NSenterMsgHeader{},
+			domain.Mount{
+				Source: "/root/kcore",
+				Target: "/proc/kcore",
+				Flags:  unix.MS_BIND,
+			},
+		},
+	}
+	resp, err := m.process()
+	checkResp("bind", resp, err, true)
+	if len(nss.Calls) != 0 {
+		t.Errorf("bind: unexpected nsenter requests: %v", nss.Calls)
+	}
+
+	// "umount /proc/kcore" takes down the user's bind-mount.
+	tracer, _ = newTracer(stackedMip)
+	u := &umountSyscallInfo{
+		syscallCtx{tracer: tracer, cntr: &testContainer{}, root: "/"},
+		&domain.UmountSyscallPayload{
+			domain.NSenterMsgHeader{},
+			domain.Mount{Target: "/proc/kcore"},
+		},
+	}
+	resp, err = u.process()
+	checkResp("umount", resp, err, true)
+
+	// A further "umount /proc/kcore" must leave the mask in place.
+	tracer, _ = newTracer(maskedMip)
+	u.syscallCtx.tracer = tracer
+	resp, err = u.process()
+	checkResp("umount-masked", resp, err, false)
+}
+
+// Process fake resolving paths as sysbox-fs' FUSE handlers would when looked up
+// from outside of the container: nodes within /proc/sys are missing.
+type testPathProcess struct {
+	domain.ProcessIface
+	accessed []string
+}
+
+func (p *testPathProcess) PathAccess(path string, mode domain.AccessMode, follow bool) (string, error) {
+	p.accessed = append(p.accessed, path)
+	if strings.Contains(path, "/proc/sys/") {
+		return "", syscall.ENOENT
+	}
+	return path, nil
+}
+
+// Mountinfo-parser fake holding the sysbox-fs submounts of the container, as
+// well as other mounts stacked on them.
+type testSubmountInfoParser struct {
+	domain.MountInfoParserIface
+	submounts map[string]string // mountpoint -> fstype
+	mounts    map[string]string // mountpoint -> fstype
+}
+
+func (p *testSubmountInfoParser) IsSysboxfsSubmount(mp string) bool {
+	_, ok := p.submounts[mp]
+	return ok
+}
+
+func (p *testSubmountInfoParser) GetInfo(mp string) *domain.MountInfo {
+	fstype, ok := p.submounts[mp]
+	if !ok {
+		if fstype, ok = p.mounts[mp]; !ok {
+			return nil
+		}
+	}
+	return &domain.MountInfo{MountPoint: mp, FsType: fstype}
+}
+
+func Test_mountSyscallInfo_targetAccess_sysboxfsSubmount(t *testing.T) {
+
+	mh := &mocks.MountHelperIface{}
+	mh.On("ProcMounts").Return([]string{"/proc/uptime", "/proc/sys"})
+	mh.On("SysMounts").Return([]string{"/sys/kernel", "/sys/fs"})
+	mh.On("IsNewMount", mock.Anything).Return(true)
+	mh.On("IsMove", mock.Anything).Return(false)
+	mh.On("HasPropagationFlag", mock.Anything).Return(false)
+	mh.On("IsRemount", mock.Anything).Return(false)
+	mh.On("IsBind", mock.Anything).Return(false)
+
+	mip := &testSubmountInfoParser{
+		submounts: map[string]string{
+			"/proc/sys":                "fuse",
+			"/proc/bus":                "proc",
+			"/mnt/proc/sys":            "fuse",
+			"/var/lib/jail/proc/sys":   "fuse",
+			"/var/lib/jail/sys/kernel": "fuse",
+		},
+		mounts: map[string]string{
+			"/proc/sys/fs/binfmt_misc": "binfmt_misc",
+		},
+	}
+
+	tests := []struct {
+		name         string
+		root         string
+		cwd          string
+		target       string
+		wantTarget   string
+		wantAccessed []string
+		wantErr      error
+	}{
+		// tmpfs mount inside /proc/sys: only the submount is resolved.
+		{"proc-sys", "/", "/", "/proc/sys/fs/foo", "/proc/sys/fs/foo", []string{"/proc/sys"}, nil},
+		{"proc-sys-relative", "/", "/proc/sys", "fs/foo", "/proc/sys/fs/foo", []string{"/proc/sys"}, nil},
+		{"proc-sys-dotdot", "/", "/", "/proc/sys/fs/../kernel/foo", "/proc/sys/kernel/foo", []string{"/proc/sys"}, nil},
+		// Copies of the submount (e.g., bind-mounted procfs).
+		{"proc-sys-copy", "/", "/", "/mnt/proc/sys/fs/foo", "/mnt/proc/sys/fs/foo", []string{"/mnt/proc/sys"}, nil},
+		// Chroot'ed processes.
+		{"proc-sys-chroot", "/var/lib/jail", "/", "/proc/sys/fs/foo", "/proc/sys/fs/foo", []string{"/proc/sys"}, nil},
+		{"sys-kernel-chroot", "/var/lib/jail", "/", "/sys/kernel/foo", "/sys/kernel/foo", []string{"/sys/kernel"}, nil},
+		// The submount itself and paths elsewhere are resolved as usual.
+		{"proc-sys-itself", "/", "/", "/proc/sys", "/proc/sys", []string{"/proc/sys"}, nil},
+		{"tmp", "/", "/", "/tmp/foo", "/tmp/foo", []string{"/tmp/foo"}, nil},
+		// Not a sysbox-fs submount in the process' mount-ns.
+		{"not-submount", "/", "/", "/data/proc/sys/foo", "", []string{"/data/proc/sys/foo"}, syscall.ENOENT},
+		// Within a mount stacked on the submount: resolved as usual.
+		{"proc-sys-stacked", "/", "/", "/proc/sys/fs/binfmt_misc/foo", "", []string{"/proc/sys/fs/binfmt_misc/foo"}, syscall.ENOENT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mts := &mocks.MountServiceIface{}
+			mts.On("MountHelper").Return(mh)
+			mts.On("NewMountInfoParser", mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything, mock.Anything).Return(mip, nil)
+
+			tracer := &syscallTracer{
+				service: &SyscallMonitorService{mts: mts},
+			}
+
+			process := &testPathProcess{}
+
+			m := &mountSyscallInfo{
+				syscallCtx{
+					tracer:      tracer,
+					cntr:        &testContainer{},
+					root:        tt.root,
+					cwd:         tt.cwd,
+					processInfo: process,
+				},
+				&domain.MountSyscallPayload{
+					domain.NSenterMsgHeader{},
+					domain.Mount{
+						Source: "tmpfs",
+						Target: tt.target,
+						FsType: "tmpfs",
+					},
+				},
+			}
+
+			target, err := m.targetAccess()
+			if err != tt.wantErr {
+				t.Fatalf("targetAccess() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(process.accessed, tt.wantAccessed) {
+				t.Errorf("targetAccess() resolved %v, want %v", process.accessed, tt.wantAccessed)
+			}
+			if err != nil {
+				return
+			}
+			if target != tt.wantTarget {
+				t.Errorf("targetAccess() = %s, want %s", target, tt.wantTarget)
+			}
+
+			// The tmpfs mount itself is left to the kernel.
+			m.Target = target
+			resp, err := m.process()
+			if err != nil {
+				t.Fatalf("process() unexpected error: %v", err)
+			}
+			if resp.Flags != libseccomp.NotifRespFlagContinue {
+				t.Errorf("process() = %+v, want continue response", resp)
+			}
+		})
+	}
+}
+
+func Test_resolveMountSource(t *testing.T) {
+
+	process := &testMountApiProcess{
+		fds: map[int32]string{
+			3: "/proc/uptime",
+			4: "/memfd:image (deleted)",
+			5: "/root/image (deleted)",
+			6: "anon_inode:[eventfd]",
+		},
+	}
+
+	tests := []struct {
+		name       string
+		source     string
+		wantSource string
+		wantErr    error
+	}{
+		{"path", "/root/image", "/root/image", nil},
+		{"fd", "/proc/self/fd/3", "/proc/uptime", nil},
+		{"fd-thread", "/proc/thread-self/fd/3", "/proc/uptime", nil},
+		{"fd-unclean", "/proc/self/fd//3/", "/proc/uptime", nil},
+		// Fds that can't be reached through a path.
+		{"fd-memfd", "/proc/self/fd/4", "/proc/self/fd/4", nil},
+		{"fd-deleted", "/proc/self/fd/5", "/proc/self/fd/5", nil},
+		{"fd-anon", "/proc/self/fd/6", "/proc/self/fd/6", nil},
+		// Fds not open by the process.
+		{"fd-closed", "/proc/self/fd/9", "", syscall.ENOENT},
+		{"fd-out-of-range", "/proc/self/fd/4294967299", "", syscall.ENOENT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveMountSource(process, tt.source)
+			if err != tt.wantErr {
+				t.Fatalf("resolveMountSource(%s) error = %v, want %v", tt.source, err, tt.wantErr)
+			}
+			if got != tt.wantSource {
+				t.Errorf("resolveMountSource(%s) = %s, want %s", tt.source, got, tt.wantSource)
+			}
+		})
+	}
+}
+
+// Process fake issuing mount syscalls; all paths are accessible.
+type testFdMntProcess struct {
+	*testMountApiProcess
+}
+
+func (p *testFdMntProcess) PathAccess(path string, mode domain.AccessMode, follow bool) (string, error) {
+	return path, nil
+}
+
+type testFdMntProcessService struct {
+	domain.ProcessServiceIface
+	process *testFdMntProcess
+}
+
+func (s *testFdMntProcessService) ProcessCreate(pid uint32, uid uint32, gid uint32) domain.ProcessIface {
+	return s.process
+}
+
+func Test_syscallTracer_processMount_fdSource(t *testing.T) {
+
+	mh := &mocks.MountHelperIface{}
+	mh.On("ProcMounts").Return([]string{"/proc/sys"})
+	mh.On("SysMounts").Return([]string{})
+	mh.On("IsNewMount", mock.Anything).Return(false)
+	mh.On("IsMove", mock.Anything).Return(false)
+	mh.On("HasPropagationFlag", mock.Anything).Return(false)
+	mh.On("IsRemount", mock.Anything).Return(false)
+	mh.On("IsBind", mock.Anything).Return(true)
+
+	// Container's procfs, as seen by the process: uptime is an emulated file.
+	proc := filepath.Join(t.TempDir(), "proc")
+	if err := os.MkdirAll(proc, 0755); err != nil {
+		t.Fatal(err)
+	}
+	uptime := filepath.Join(proc, "uptime")
+	if err := os.WriteFile(uptime, nil, 0444); err != nil {
+		t.Fatal(err)
+	}
+
+	mip := &testFileBindInfoParser{
+		submounts: map[string]bool{uptime: false},
+	}
+
+	process := &testFdMntProcess{
+		&testMountApiProcess{
+			sysAdmin: true,
+			fds: map[int32]string{
+				3: uptime,
+				4: "/memfd:image (deleted)",
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		source       string
+		wantErrno    syscall.Errno
+		wantContinue bool
+		wantSource   string // source of the nsenter mount request, if any
+	}{
+		// "mount --bind /proc/self/fd/3 /root/uptime", fd 3 being an O_PATH
+		// fd of /proc/uptime.
+		{"emulated-file", "/proc/self/fd/3", 0, false, uptime},
+		// Same, out of a memfd-backed image.
+		{"memfd", "/proc/self/fd/4", 0, true, ""},
+		// Fd not open by the process.
+		{"closed", "/proc/self/fd/9", syscall.ENOENT, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mts := &mocks.MountServiceIface{}
+			mts.On("MountHelper").Return(mh)
+			mts.On("NewMountInfoParser", mock.Anything, mock.Anything,
+				true, true, false).Return(mip, nil)
+
+			event := &mocks.NSenterEventIface{}
+			nss := &mocks.NSenterServiceIface{}
+			nss.On("NewEvent", mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything, mock.Anything, mock.Anything).Return(event)
+			nss.On("SendRequestEvent", event).Return(nil)
+			nss.On("ReceiveResponseEvent", event).Return(
+				&domain.NSenterMessage{Type: domain.MountSyscallResponse})
+
+			tracer := &syscallTracer{
+				service: &SyscallMonitorService{
+					mts: mts,
+					nss: nss,
+					prs: &testFdMntProcessService{process: process},
+				},
+				memParser: &testMountApiMemParser{
+					paths: map[uint64]string{
+						testFromPathAddr: tt.source,
+						testToPathAddr:   "/root/uptime",
+					},
+				},
+			}
+
+			req := &sysRequest{
+				ID:  1,
+				Pid: uint32(os.Getpid()),
+				Data: libseccomp.ScmpNotifData{
+					Args: []uint64{testFromPathAddr, testToPathAddr, 0, unix.MS_BIND, 0},
+				},
+			}
+
+			resp, err := tracer.processMount(req, 0, &testContainer{})
+			if err != nil {
+				t.Fatalf("processMount() unexpected error: %v", err)
+			}
+			if resp.Error != int32(tt.wantErrno) {
+				t.Errorf("processMount() errno = %d, want %d", resp.Error, tt.wantErrno)
+			}
+			if gotContinue := resp.Flags == libseccomp.NotifRespFlagContinue; gotContinue != tt.wantContinue {
+				t.Errorf("processMount() continue = %v, want %v", gotContinue, tt.wantContinue)
+			}
+
+			var gotSource string
+			for _, call := range nss.Calls {
+				if call.Method != "NewEvent" {
+					continue
+				}
+				req := call.Arguments.Get(3).(*domain.NSenterMessage)
+				for _, p := range *req.Payload.(*[]*domain.MountSyscallPayload) {
+					gotSource = p.Source
+				}
+			}
+			if gotSource != tt.wantSource {
+				t.Errorf("mount request source = %q, want %q", gotSource, tt.wantSource)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// This file contains Sysbox's openat2 syscall trapping & handling code. We
+// trap openat2 to police the open of namespace files (i.e., /proc/<pid>/ns/*):
+// the fds obtained out of these files can be later handed to setns(2) to join
+// the namespaces of the given process, so processes within a sys container must
+// not be able to obtain them for processes outside of the container.
+//
+// Notice that this is a partial measure: open(2) and openat(2) are not trapped,
+// as that would route every file open within the container through sysbox-fs,
+// so namespace files can still be opened through them. The actual enforcement
+// point is setns(2), which isn't intercepted in this tree yet. openat2, on the
+// other hand, is seldom used (e.g., by container runtimes and systemd for safe
+// path resolution, never by libc's open wrappers), so trapping it costs a
+// seccomp round-trip on a cold path only, and opens that don't target a
+// namespace file are continued right after the path is inspected.
+//
+// A namespace file is deemed to belong to a process outside of the container
+// when the process can't be found in the container's pid-ns, or when the
+// procfs through which the file is reached is not the one of the process'
+// pid-ns (e.g., a host's procfs exposed within the container). All other
+// opens are handled normally by the kernel.
+//
+// Namespace files are magic links, so the path's resolution constraints (see
+// struct open_how) are honored when deciding whether the open is to be
+// policed: the kernel never hands out an fd to the namespace itself when the
+// link isn't followed (O_NOFOLLOW, RESOLVE_NO_SYMLINKS or RESOLVE_NO_MAGICLINKS
+// fail the open, or yield an fd to the link itself). O_PATH opens are policed
+// like any other, as their fd can be re-opened through /proc/<pid>/fd.
+//
+// Emulated nodes (e.g., /proc/sys/*) are left to the kernel too, regardless of
+// the open flags: O_PATH opens yield a path-only fd without ever reaching
+// sysbox-fs' FUSE open/read handlers, and such fd can be utilized as the dirfd
+// of subsequent *at syscalls (which are interpreted through its path).
+//
+// Opens carrying RESOLVE_CACHED are expected to fail fast with EAGAIN when they
+// can't be completed out of the kernel's caches, for the caller to retry them
+// without the flag. Policed opens always require sysbox-fs to inspect the
+// process owning the namespace (verdicts are not cached, as pids and namespace
+// inodes are recycled), so these fail with EAGAIN. All other opens are left
+// to the kernel, which enforces RESOLVE_CACHED itself (emulated nodes
+// included, as their lookups are served out of the kernel's dentry cache
+// while valid, and only reach sysbox-fs otherwise).
+
+package seccomp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"syscall"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// Resolve flag restricting the path's resolution to cached entries (not
+// defined by the unix package).
+const resolveCached = 0x20 // RESOLVE_CACHED
+
+// Namespace files of a process (or of one of its threads).
+var procNsPathRegexp = regexp.MustCompile(`^/proc/([0-9]+|self|thread-self)(?:/task/[0-9]+)?/ns/[a-z_]+$`)
+
+type openat2SyscallInfo struct {
+	syscallCtx // syscall generic info
+	path       string
+	dirFd      int32
+	flags      uint64 // open_how.flags
+	resolve    uint64 // open_how.resolve
+}
+
+// parseOpenHow extracts the flags and resolve fields out of the given (native
+// endian) struct open_how.
+func parseOpenHow(how []byte) (flags uint64, resolve uint64) {
+	flags = binary.NativeEndian.Uint64(how[0:8])
+	resolve = binary.NativeEndian.Uint64(how[16:24])
+	return flags, resolve
+}
+
+func (oi *openat2SyscallInfo) processOpenat2() (*sysResponse, error) {
+
+	t := oi.tracer
+	prs := t.service.prs
+	oi.processInfo = prs.ProcessCreate(oi.pid, 0, 0)
+
+	// Interpret dirFd (if the pathname is not absolute, or if absolute ones
+	// are to be resolved as if dirFd was the root directory).
+	path := oi.path
+	if !filepath.IsAbs(path) || oi.resolve&unix.RESOLVE_IN_ROOT != 0 {
+		if oi.dirFd == unix.AT_FDCWD {
+			path = filepath.Join(oi.processInfo.Cwd(), path)
+		} else {
+			dirPath, err := oi.processInfo.GetFd(oi.dirFd)
+			if err != nil {
+				return t.createContinueResponse(oi.reqId), nil
+			}
+			path = filepath.Join(dirPath, path)
+		}
+	}
+
+	path = filepath.Clean(path)
+
+	match := procNsPathRegexp.FindStringSubmatch(path)
+	if match == nil {
+		return t.createContinueResponse(oi.reqId), nil
+	}
+	pidStr := match[1]
+
+	// The process' own namespaces.
+	if pidStr == "self" || pidStr == "thread-self" {
+		return t.createContinueResponse(oi.reqId), nil
+	}
+
+	// The namespace's magic link won't be followed by the kernel.
+	if oi.flags&unix.O_NOFOLLOW != 0 ||
+		oi.resolve&(unix.RESOLVE_NO_SYMLINKS|unix.RESOLVE_NO_MAGICLINKS) != 0 {
+		return t.createContinueResponse(oi.reqId), nil
+	}
+
+	// Paths not leading to a namespace file are left for the kernel to deal
+	// with.
+	if _, err := t.nsInodeAt(oi.processInfo, path); err != nil {
+		return t.createContinueResponse(oi.reqId), nil
+	}
+
+	if oi.resolve&resolveCached != 0 {
+		return t.createErrorResponse(oi.reqId, syscall.EAGAIN), nil
+	}
+
+	if !oi.nsInCntr(pidStr, filepath.Dir(path)) {
+		logrus.Warnf("Rejected openat2 syscall from pid %d, cntr %s: %s belongs to a process outside of the container",
+			oi.pid, oi.cntr.ID(), path)
+		return t.createErrorResponse(oi.reqId, syscall.EPERM), nil
+	}
+
+	return t.createContinueResponse(oi.reqId), nil
+}
+
+// nsInCntr returns true if the namespace directory at the given path, which
+// belongs to the given pid (as seen within the process' pid-ns), is the one of
+// a process of the container.
+func (oi *openat2SyscallInfo) nsInCntr(pidStr string, nsDir string) bool {
+
+	t := oi.tracer
+	prs := t.service.prs
+
+	nsPid, err := strconv.ParseUint(pidStr, 10, 32)
+	if err != nil {
+		return false
+	}
+
+	hostPid, err := prs.NsPidToHostPid(uint32(nsPid), oi.processInfo)
+	if err != nil {
+		return false
+	}
+
+	// The namespaces reached through the given path must match the ones of the
+	// process found in the process' pid-ns, as otherwise the path leads to a
+	// foreign procfs.
+	nsInodes, err := prs.ProcessCreate(hostPid, 0, 0).NsInodes()
+	if err != nil {
+		return false
+	}
+	for ns, inode := range nsInodes {
+		viewed, err := t.nsInodeAt(oi.processInfo, filepath.Join(nsDir, ns))
+		if err != nil || viewed != inode {
+			return false
+		}
+	}
+
+	// Processes not visible within the container's pid-ns are reported with
+	// pid zero.
+	cntrPid, err := prs.HostPidToNsPid(hostPid, oi.cntr.InitProc())
+	if err != nil || cntrPid == 0 {
+		return false
+	}
+
+	return true
+}
+
+// nsInodeAt returns the inode of the namespace referred to by the given path,
+// as seen by the given process.
+func (t *syscallTracer) nsInodeAt(
+	process domain.ProcessIface,
+	path string) (domain.Inode, error) {
+
+	procPath := fmt.Sprintf("/proc/%d/root%s", process.Pid(), path)
+
+	var fs unix.Statfs_t
+	if err := unix.Statfs(procPath, &fs); err != nil {
+		return 0, err
+	}
+	if fs.Type != unix.NSFS_MAGIC {
+		return 0, fmt.Errorf("%s is not a namespace file", path)
+	}
+
+	var st unix.Stat_t
+	if err := unix.Stat(procPath, &st); err != nil {
+		return 0, err
+	}
+
+	return st.Ino, nil
+}
@@ -0,0 +1,356 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package seccomp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+
+	libseccomp "github.com/seccomp/libseccomp-golang"
+	"golang.org/x/sys/unix"
+
+	"github.com/nestybox/sysbox-fs/domain"
+)
+
+// Process fake holding the namespaces of the process (as seen from the host).
+type testNsProcess struct {
+	domain.ProcessIface
+	pid      uint32
+	nsInodes map[string]domain.Inode
+}
+
+func (p *testNsProcess) Pid() uint32 { return p.pid }
+func (p *testNsProcess) Cwd() string { return "/" }
+
+func (p *testNsProcess) NsInodes() (map[string]domain.Inode, error) {
+	return p.nsInodes, nil
+}
+
+// Process-service fake mapping the pids seen within the container to the
+// host's ones. Host pids outside of cntrPids are not part of the container.
+type testNsProcessService struct {
+	domain.ProcessServiceIface
+	procs    map[uint32]*testNsProcess
+	nsToHost map[uint32]uint32
+	cntrPids map[uint32]bool
+}
+
+func (s *testNsProcessService) ProcessCreate(pid uint32, uid uint32, gid uint32) domain.ProcessIface {
+	return s.procs[pid]
+}
+
+func (s *testNsProcessService) NsPidToHostPid(nsPid uint32, ref domain.ProcessIface) (uint32, error) {
+	hostPid, ok := s.nsToHost[nsPid]
+	if !ok {
+		return 0, fmt.Errorf("pid %d not found", nsPid)
+	}
+	return hostPid, nil
+}
+
+// As the real one, pids not visible within the ref process' pid-ns are
+// reported as zero.
+func (s *testNsProcessService) HostPidToNsPid(hostPid uint32, ref domain.ProcessIface) (uint32, error) {
+	if !s.cntrPids[hostPid] {
+		return 0, nil
+	}
+	return hostPid, nil
+}
+
+// Container fake exposing its init process.
+type testNsContainer struct {
+	testContainer
+	initProc domain.ProcessIface
+}
+
+func (c *testNsContainer) InitProc() domain.ProcessIface { return c.initProc }
+
+func Test_openat2SyscallInfo_processOpenat2_nsFiles(t *testing.T) {
+
+	// The test process stands for the process issuing the openat2 syscall, so
+	// its procfs is the one through which the namespace files are reached.
+	self := uint32(os.Getpid())
+
+	selfNs := map[string]domain.Inode{}
+	for _, ns := range domain.AllNSs {
+		var st unix.Stat_t
+		if err := unix.Stat(fmt.Sprintf("/proc/%d/ns/%s", self, ns), &st); err != nil {
+			t.Skipf("unable to stat namespace files: %v", err)
+		}
+		selfNs[ns] = st.Ino
+	}
+
+	// Namespaces of a process outside of the container.
+	foreignNs := map[string]domain.Inode{}
+	for ns := range selfNs {
+		foreignNs[ns] = 4026531000
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		nsToHost  map[uint32]uint32
+		cntrPids  map[uint32]bool
+		wantErrno syscall.Errno
+	}{
+		// Namespace file of a container's process.
+		{
+			"in-cntr",
+			fmt.Sprintf("/proc/%d/ns/net", self),
+			map[uint32]uint32{self: self},
+			map[uint32]bool{self: true},
+			0,
+		},
+		{
+			"in-cntr-relative",
+			fmt.Sprintf("proc/%d/task/%d/ns/user", self, self),
+			map[uint32]uint32{self: self},
+			map[uint32]bool{self: true},
+			0,
+		},
+		// Namespace file of a process outside of the container's pid-ns.
+		{
+			"out-of-cntr",
+			fmt.Sprintf("/proc/%d/ns/net", self),
+			map[uint32]uint32{self: self},
+			map[uint32]bool{},
+			syscall.EPERM,
+		},
+		// Namespace file reached through a foreign procfs (i.e., the pid maps to
+		// a process whose namespaces differ from the ones found).
+		{
+			"foreign-procfs",
+			fmt.Sprintf("/proc/%d/ns/mnt", self),
+			map[uint32]uint32{self: 4242},
+			map[uint32]bool{4242: true},
+			syscall.EPERM,
+		},
+		// Pid not found in the process' pid-ns.
+		{
+			"unknown-pid",
+			fmt.Sprintf("/proc/%d/ns/ipc", self),
+			map[uint32]uint32{},
+			map[uint32]bool{self: true},
+			syscall.EPERM,
+		},
+		// The process' own namespaces and regular files are left to the kernel.
+		{"self", "/proc/self/ns/net", nil, nil, 0},
+		{"not-ns", fmt.Sprintf("/proc/%d/status", self), nil, nil, 0},
+		{"no-ns-file", fmt.Sprintf("/proc/%d/ns/none", self), nil, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prs := &testNsProcessService{
+				procs: map[uint32]*testNsProcess{
+					self: {pid: self, nsInodes: selfNs},
+					4242: {pid: 4242, nsInodes: foreignNs},
+				},
+				nsToHost: tt.nsToHost,
+				cntrPids: tt.cntrPids,
+			}
+			tracer := &syscallTracer{service: &SyscallMonitorService{prs: prs}}
+
+			oi := &openat2SyscallInfo{
+				syscallCtx: syscallCtx{
+					reqId:  1,
+					pid:    self,
+					cntr:   &testNsContainer{initProc: prs.procs[self]},
+					tracer: tracer,
+				},
+				path:  tt.path,
+				dirFd: unix.AT_FDCWD,
+			}
+
+			resp, err := oi.processOpenat2()
+			if err != nil {
+				t.Fatalf("processOpenat2() unexpected error: %v", err)
+			}
+			if resp.Error != int32(tt.wantErrno) {
+				t.Errorf("processOpenat2(%s) errno = %d, want %d", tt.path, resp.Error, tt.wantErrno)
+			}
+			if tt.wantErrno == 0 && resp.Flags != libseccomp.NotifRespFlagContinue {
+				t.Errorf("processOpenat2(%s) must be left to the kernel", tt.path)
+			}
+		})
+	}
+}
+
+// Process fake holding the paths of the process' fds.
+type testFdNsProcess struct {
+	testNsProcess
+	fds map[int32]string
+}
+
+func (p *testFdNsProcess) GetFd(fd int32) (string, error) {
+	path, ok := p.fds[fd]
+	if !ok {
+		return "", syscall.EBADF
+	}
+	return path, nil
+}
+
+// Memory-parser fake holding the openat2 pathname and struct open_how.
+type testOpenat2MemParser struct {
+	memParser
+	path string
+	how  unix.OpenHow
+}
+
+func (m *testOpenat2MemParser) ReadSyscallStringArgs(
+	pid uint32,
+	elems []memParserDataElem) ([]string, error) {
+
+	return []string{m.path}, nil
+}
+
+func (m *testOpenat2MemParser) ReadSyscallBytesArgs(
+	pid uint32,
+	elems []memParserDataElem) ([]string, error) {
+
+	if len(elems) != 1 || elems[0].size != unix.SizeofOpenHow {
+		return nil, errors.New("unexpected read")
+	}
+
+	how := make([]byte, unix.SizeofOpenHow)
+	binary.NativeEndian.PutUint64(how[0:8], m.how.Flags)
+	binary.NativeEndian.PutUint64(how[8:16], m.how.Mode)
+	binary.NativeEndian.PutUint64(how[16:24], m.how.Resolve)
+
+	return []string{string(how)}, nil
+}
+
+func Test_syscallTracer_processOpenat2_resolve(t *testing.T) {
+
+	self := uint32(os.Getpid())
+
+	var st unix.Stat_t
+	if err := unix.Stat(fmt.Sprintf("/proc/%d/ns/net", self), &st); err != nil {
+		t.Skipf("unable to stat namespace files: %v", err)
+	}
+
+	// The test process stands for a process outside of the container, and
+	// issues the openat2 syscalls too.
+	prs := &testNsProcessService{
+		nsToHost: map[uint32]uint32{self: self},
+		cntrPids: map[uint32]bool{},
+	}
+	proc := &testFdNsProcess{
+		testNsProcess: testNsProcess{pid: self},
+		fds: map[int32]string{
+			5: "/proc/sys",
+			6: fmt.Sprintf("/proc/%d", self),
+		},
+	}
+	cntr := &testNsContainer{initProc: proc}
+
+	nsPath := fmt.Sprintf("/proc/%d/ns/net", self)
+
+	tests := []struct {
+		name      string
+		dirFd     int32
+		path      string
+		how       unix.OpenHow
+		howSize   uint64
+		wantErrno syscall.Errno
+	}{
+		// Emulated nodes are opened by the kernel, path-only fds included.
+		{"emulated-opath", unix.AT_FDCWD, "/proc/sys/kernel/hostname",
+			unix.OpenHow{Flags: unix.O_PATH | unix.O_CLOEXEC, Resolve: unix.RESOLVE_NO_SYMLINKS},
+			unix.SizeofOpenHow, 0},
+		{"emulated-opath-dirfd", 5, "kernel/hostname",
+			unix.OpenHow{Flags: unix.O_PATH, Resolve: unix.RESOLVE_NO_SYMLINKS | unix.RESOLVE_BENEATH},
+			unix.SizeofOpenHow, 0},
+		// O_PATH fds of foreign namespaces are rejected, as they can be
+		// re-opened.
+		{"ns-opath", unix.AT_FDCWD, nsPath,
+			unix.OpenHow{Flags: unix.O_PATH}, unix.SizeofOpenHow, syscall.EPERM},
+		// Extended struct open_how.
+		{"ns-opath-ext", unix.AT_FDCWD, nsPath,
+			unix.OpenHow{Flags: unix.O_PATH}, unix.SizeofOpenHow + 8, syscall.EPERM},
+		// The namespace's link is not followed.
+		{"ns-opath-nosymlinks", unix.AT_FDCWD, nsPath,
+			unix.OpenHow{Flags: unix.O_PATH, Resolve: unix.RESOLVE_NO_SYMLINKS}, unix.SizeofOpenHow, 0},
+		{"ns-nomagiclinks", unix.AT_FDCWD, nsPath,
+			unix.OpenHow{Resolve: unix.RESOLVE_NO_MAGICLINKS}, unix.SizeofOpenHow, 0},
+		{"ns-opath-nofollow", unix.AT_FDCWD, nsPath,
+			unix.OpenHow{Flags: unix.O_PATH | unix.O_NOFOLLOW}, unix.SizeofOpenHow, 0},
+		// Absolute paths resolved within dirFd.
+		{"ns-in-root", 6, "/ns/net",
+			unix.OpenHow{Resolve: unix.RESOLVE_IN_ROOT}, unix.SizeofOpenHow, syscall.EPERM},
+		// Opens restricted to cached resolutions: policed opens are to be
+		// retried without the flag, whereas emulated nodes (whose lookups are
+		// served out of the kernel's caches once warm) and the process' own
+		// namespaces are left to the kernel.
+		{"ns-cached", unix.AT_FDCWD, nsPath,
+			unix.OpenHow{Resolve: resolveCached}, unix.SizeofOpenHow, syscall.EAGAIN},
+		{"ns-cached-nofollow", unix.AT_FDCWD, nsPath,
+			unix.OpenHow{Flags: unix.O_NOFOLLOW | unix.O_PATH, Resolve: resolveCached}, unix.SizeofOpenHow, 0},
+		{"emulated-cached", unix.AT_FDCWD, "/proc/sys/kernel/hostname",
+			unix.OpenHow{Resolve: resolveCached}, unix.SizeofOpenHow, 0},
+		{"self-ns-cached", unix.AT_FDCWD, "/proc/self/ns/net",
+			unix.OpenHow{Resolve: resolveCached}, unix.SizeofOpenHow, 0},
+		// Invalid struct open_how sizes are left for the kernel to reject.
+		{"short-how", unix.AT_FDCWD, nsPath,
+			unix.OpenHow{}, 8, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer := &syscallTracer{
+				service:   &SyscallMonitorService{prs: &testFdNsProcessService{prs, proc}},
+				memParser: &testOpenat2MemParser{path: tt.path, how: tt.how},
+			}
+
+			req := &sysRequest{
+				ID:  1,
+				Pid: self,
+				Data: libseccomp.ScmpNotifData{
+					Args: []uint64{uint64(tt.dirFd), 0, 0, tt.howSize},
+				},
+			}
+
+			resp, err := tracer.processOpenat2(req, 0, cntr)
+			if err != nil {
+				t.Fatalf("processOpenat2() unexpected error: %v", err)
+			}
+			if resp.Error != int32(tt.wantErrno) {
+				t.Errorf("processOpenat2(%s) errno = %d, want %d", tt.path, resp.Error, tt.wantErrno)
+			}
+			if tt.wantErrno == 0 && resp.Flags != libseccomp.NotifRespFlagContinue {
+				t.Errorf("processOpenat2(%s) must be left to the kernel", tt.path)
+			}
+		})
+	}
+}
+
+// Process-service fake handing out the given fd-aware process for the
+// requesting pid.
+type testFdNsProcessService struct {
+	*testNsProcessService
+	proc *testFdNsProcess
+}
+
+func (s *testFdNsProcessService) ProcessCreate(pid uint32, uid uint32, gid uint32) domain.ProcessIface {
+	if pid == s.proc.pid {
+		return s.proc
+	}
+	return s.testNsProcessService.ProcessCreate(pid, uid, gid)
+}
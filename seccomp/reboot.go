@@ -0,0 +1,137 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// This file contains Sysbox's reboot syscall trapping & handling code. Callers
+// lacking CAP_SYS_BOOT are rejected upfront; otherwise the command is decoded
+// out of the syscall arguments:
+//
+// * Restart, halt and power-off commands terminate the init process of the
+//   caller's pid-ns (and thereby the rest of its processes), as the kernel
+//   does for callers within a non-initial pid-ns (see reboot_pid_ns()). For
+//   the container's pid-ns, this means stopping the container. Notice that
+//   init is sent a SIGKILL (as the kernel does), as the pid-ns init ignores
+//   any other signal it hasn't set a handler for.
+//
+// * Ctrl-Alt-Del toggles carry no meaning within a container, so they're
+//   reported as successful without further action (init systems issue them
+//   during their initialization, and the kernel would fail them).
+//
+// * Any other command (e.g., kexec, suspend) is rejected.
+//
+// Requests coming from nested pid namespaces are handed over to the kernel,
+// which scopes them to that pid-ns.
+
+package seccomp
+
+import (
+	"syscall"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	cap "github.com/nestybox/sysbox-libs/capability"
+	"github.com/nestybox/sysbox-libs/formatter"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// Alternative values of reboot's second magic argument (not defined by the
+// unix package).
+const (
+	linuxRebootMagic2A = 0x05121996
+	linuxRebootMagic2B = 0x16041998
+	linuxRebootMagic2C = 0x20112000
+)
+
+type rebootSyscallInfo struct {
+	syscallCtx // syscall generic info
+	magic1     uint32
+	magic2     uint32
+	cmd        uint32
+}
+
+// processReboot handles the reboot request; the passed function is in charge
+// of terminating the container's init process.
+func (si *rebootSyscallInfo) processReboot(
+	killInit func(domain.ContainerIface) error) (*sysResponse, error) {
+
+	t := si.tracer
+
+	// As the kernel does, callers lacking CAP_SYS_BOOT are rejected before
+	// the command is even looked at.
+	si.processInfo = t.service.prs.ProcessCreate(si.pid, 0, 0)
+	if !si.processInfo.IsCapabilitySet(cap.EFFECTIVE, cap.CAP_SYS_BOOT) {
+		return t.createErrorResponse(si.reqId, syscall.EPERM), nil
+	}
+
+	if si.magic1 != unix.LINUX_REBOOT_MAGIC1 ||
+		(si.magic2 != unix.LINUX_REBOOT_MAGIC2 &&
+			si.magic2 != linuxRebootMagic2A &&
+			si.magic2 != linuxRebootMagic2B &&
+			si.magic2 != linuxRebootMagic2C) {
+		return t.createErrorResponse(si.reqId, syscall.EINVAL), nil
+	}
+
+	switch si.cmd {
+	case unix.LINUX_REBOOT_CMD_CAD_ON, unix.LINUX_REBOOT_CMD_CAD_OFF:
+		logrus.Debugf("Ignoring reboot syscall from pid %d: cmd = %#x", si.pid, si.cmd)
+		return t.createSuccessResponse(si.reqId), nil
+
+	case unix.LINUX_REBOOT_CMD_RESTART,
+		unix.LINUX_REBOOT_CMD_RESTART2,
+		unix.LINUX_REBOOT_CMD_HALT,
+		unix.LINUX_REBOOT_CMD_POWER_OFF:
+
+	default:
+		logrus.Warnf("Rejected reboot syscall from pid %d, cntr %s: cmd = %#x",
+			si.pid, formatter.ContainerID{si.cntr.ID()}, si.cmd)
+		return t.createErrorResponse(si.reqId, syscall.EINVAL), nil
+	}
+
+	cntrPidNs, err := t.service.prs.ProcessCreate(si.cntr.InitPid(), 0, 0).PidNsInode()
+	if err != nil {
+		return t.createErrorResponse(si.reqId, syscall.EPERM), nil
+	}
+
+	pidNs, err := si.processInfo.PidNsInode()
+	if err != nil {
+		return t.createErrorResponse(si.reqId, syscall.EPERM), nil
+	}
+
+	if pidNs != cntrPidNs {
+		return t.createContinueResponse(si.reqId), nil
+	}
+
+	logrus.Infof("Stopping cntr %s on reboot syscall from pid %d: cmd = %#x",
+		formatter.ContainerID{si.cntr.ID()}, si.pid, si.cmd)
+
+	if err := killInit(si.cntr); err != nil {
+		logrus.Errorf("Unable to stop cntr %s: %v", formatter.ContainerID{si.cntr.ID()}, err)
+		return t.createErrorResponse(si.reqId, syscall.EPERM), nil
+	}
+
+	return t.createSuccessResponse(si.reqId), nil
+}
+
+// killCntrInit sends a SIGKILL to the container's init process, through its
+// pidfd when available (so that a recycled pid can't be hit).
+func killCntrInit(cntr domain.ContainerIface) error {
+
+	pidfd := cntr.InitPidFd()
+	if pidfd == 0 {
+		return unix.Kill(int(cntr.InitPid()), unix.SIGKILL)
+	}
+
+	return unix.PidfdSendSignal(int(pidfd), unix.SIGKILL, nil, 0)
+}
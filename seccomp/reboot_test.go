@@ -0,0 +1,146 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package seccomp
+
+import (
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	cap "github.com/nestybox/sysbox-libs/capability"
+)
+
+// Container fake whose init process is the given one.
+type testInitContainer struct {
+	testContainer
+	initPid uint32
+}
+
+func (c *testInitContainer) InitPid() uint32 { return c.initPid }
+
+// Process fake sitting in the given pid-ns, with or without CAP_SYS_BOOT.
+type testRebootProcess struct {
+	domain.ProcessIface
+	pidNs   domain.Inode
+	sysBoot bool
+}
+
+func (p *testRebootProcess) PidNsInode() (domain.Inode, error) { return p.pidNs, nil }
+
+func (p *testRebootProcess) IsCapabilitySet(which cap.CapType, what cap.Cap) bool {
+	return what == cap.CAP_SYS_BOOT && p.sysBoot
+}
+
+// Process service fake handing out the container's init process and the
+// caller.
+type testRebootProcessService struct {
+	domain.ProcessServiceIface
+	init   *testRebootProcess
+	caller *testRebootProcess
+}
+
+func (s *testRebootProcessService) ProcessCreate(pid uint32, uid uint32, gid uint32) domain.ProcessIface {
+	if pid == testRebootInitPid {
+		return s.init
+	}
+	return s.caller
+}
+
+const (
+	testRebootInitPid   = 1001
+	testRebootCallerPid = 1002
+)
+
+func Test_rebootSyscallInfo_processReboot(t *testing.T) {
+
+	cntr := &testInitContainer{initPid: testRebootInitPid}
+
+	tests := []struct {
+		name         string
+		magic2       uint32
+		cmd          uint32
+		sysBoot      bool
+		callerPidNs  domain.Inode
+		wantErrno    syscall.Errno
+		wantContinue bool
+		wantKill     bool
+	}{
+		// Power-off stops the container.
+		{"poweroff", unix.LINUX_REBOOT_MAGIC2, unix.LINUX_REBOOT_CMD_POWER_OFF, true, 1, 0, false, true},
+		// Restart, with an alternative magic value.
+		{"restart", linuxRebootMagic2C, unix.LINUX_REBOOT_CMD_RESTART, true, 1, 0, false, true},
+		// Ctrl-Alt-Del toggles are no-ops.
+		{"cad-on", unix.LINUX_REBOOT_MAGIC2, unix.LINUX_REBOOT_CMD_CAD_ON, true, 1, 0, false, false},
+		{"cad-off", unix.LINUX_REBOOT_MAGIC2, unix.LINUX_REBOOT_CMD_CAD_OFF, true, 1, 0, false, false},
+		// Unsupported command.
+		{"sw-suspend", unix.LINUX_REBOOT_MAGIC2, unix.LINUX_REBOOT_CMD_SW_SUSPEND, true, 1, syscall.EINVAL, false, false},
+		// Invalid magic value.
+		{"bad-magic", 0x12345678, unix.LINUX_REBOOT_CMD_POWER_OFF, true, 1, syscall.EINVAL, false, false},
+		// Non-privileged callers can't stop the container, nor toggle
+		// Ctrl-Alt-Del.
+		{"poweroff-unprivileged", unix.LINUX_REBOOT_MAGIC2, unix.LINUX_REBOOT_CMD_POWER_OFF, false, 1, syscall.EPERM, false, false},
+		{"cad-on-unprivileged", unix.LINUX_REBOOT_MAGIC2, unix.LINUX_REBOOT_CMD_CAD_ON, false, 1, syscall.EPERM, false, false},
+		// Requests from nested pid namespaces are handed over to the kernel.
+		{"poweroff-nested", unix.LINUX_REBOOT_MAGIC2, unix.LINUX_REBOOT_CMD_POWER_OFF, true, 2, 0, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var killed bool
+
+			tracer := &syscallTracer{
+				service: &SyscallMonitorService{
+					prs: &testRebootProcessService{
+						init:   &testRebootProcess{pidNs: 1, sysBoot: true},
+						caller: &testRebootProcess{pidNs: tt.callerPidNs, sysBoot: tt.sysBoot},
+					},
+				},
+			}
+
+			si := &rebootSyscallInfo{
+				syscallCtx: syscallCtx{
+					reqId:  1,
+					pid:    testRebootCallerPid,
+					cntr:   cntr,
+					tracer: tracer,
+				},
+				magic1: unix.LINUX_REBOOT_MAGIC1,
+				magic2: tt.magic2,
+				cmd:    tt.cmd,
+			}
+
+			resp, err := si.processReboot(func(c domain.ContainerIface) error {
+				killed = true
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("processReboot() unexpected error: %v", err)
+			}
+			if syscall.Errno(resp.Error) != tt.wantErrno {
+				t.Errorf("processReboot() errno = %v, want %v", syscall.Errno(resp.Error), tt.wantErrno)
+			}
+			if (resp.Flags != 0) != tt.wantContinue {
+				t.Errorf("processReboot() continued in the kernel = %v, want %v", resp.Flags != 0, tt.wantContinue)
+			}
+			if killed != tt.wantKill {
+				t.Errorf("processReboot() stopped the container = %v, want %v", killed, tt.wantKill)
+			}
+		})
+	}
+}
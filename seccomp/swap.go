@@ -0,0 +1,151 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// This file contains Sysbox's swapon syscall trapping & handling code. Swap
+// areas can't be enabled from within a sys container (the kernel requires
+// CAP_SYS_ADMIN in the initial user-ns), so swapon is trapped and reported as
+// successful without enabling anything, as software provisioning a swap area
+// (e.g., installers, cloud-init) would otherwise fail.
+//
+// Before doing so, the target is checked to be a genuine swap area (i.e., a
+// regular file or block device carrying a swap signature, as left by mkswap)
+// reachable within the container, as the kernel would check, so that bogus
+// targets are rejected with EINVAL rather than silently "enabled".
+
+package seccomp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/nestybox/sysbox-libs/formatter"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// Signature of (version 1) swap areas, placed at the end of their first page
+// (see the kernel's union swap_header).
+const swapSignature = "SWAPSPACE2"
+
+// Offsets of the version and last_page fields of the swap header.
+const (
+	swapHeaderVersionOffset  = 1024
+	swapHeaderLastPageOffset = 1028
+)
+
+type swaponSyscallInfo struct {
+	syscallCtx // syscall generic info
+	path       string
+}
+
+func (si *swaponSyscallInfo) processSwapon() (*sysResponse, error) {
+
+	t := si.tracer
+	si.processInfo = t.service.prs.ProcessCreate(si.pid, 0, 0)
+
+	path, err := si.processInfo.PathAccess(si.path, 0, true)
+	if err != nil {
+		errno, ok := err.(syscall.Errno)
+		if !ok {
+			errno = syscall.EINVAL
+		}
+		return t.createErrorResponse(si.reqId, errno), nil
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(si.processInfo.Cwd(), path)
+	}
+
+	size, errno := swapAreaSize(fmt.Sprintf("/proc/%d/root%s", si.pid, path))
+	if errno != 0 {
+		logrus.Warnf("Rejected swapon syscall from pid %d, cntr %s: %s is not a valid swap area",
+			si.pid, formatter.ContainerID{si.cntr.ID()}, path)
+		return t.createErrorResponse(si.reqId, errno), nil
+	}
+
+	logrus.Debugf("Ignoring swapon syscall from pid %d: path = %s, size = %d",
+		si.pid, path, size)
+
+	return t.createSuccessResponse(si.reqId), nil
+}
+
+// swapAreaSize returns the usable size (in bytes) of the swap area at the
+// given path, or the errno the kernel would fail the swapon with if it's not
+// a valid one.
+func swapAreaSize(path string) (uint64, syscall.Errno) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		var errno syscall.Errno
+		if errors.As(err, &errno) {
+			return 0, errno
+		}
+		return 0, syscall.EINVAL
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, syscall.EINVAL
+	}
+
+	var devSize uint64
+
+	switch mode := fi.Mode(); {
+	case mode.IsRegular():
+		devSize = uint64(fi.Size())
+
+	case mode&os.ModeDevice != 0 && mode&os.ModeCharDevice == 0:
+		sz, err := unix.IoctlGetInt(int(f.Fd()), unix.BLKGETSIZE64)
+		if err != nil {
+			return 0, syscall.EINVAL
+		}
+		devSize = uint64(sz)
+
+	default:
+		return 0, syscall.EINVAL
+	}
+
+	pageSize := os.Getpagesize()
+
+	header := make([]byte, pageSize)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		return 0, syscall.EINVAL
+	}
+
+	if !bytes.Equal(header[pageSize-len(swapSignature):], []byte(swapSignature)) {
+		return 0, syscall.EINVAL
+	}
+
+	version := binary.NativeEndian.Uint32(header[swapHeaderVersionOffset:])
+	lastPage := uint64(binary.NativeEndian.Uint32(header[swapHeaderLastPageOffset:]))
+
+	if version != 1 || lastPage == 0 {
+		return 0, syscall.EINVAL
+	}
+
+	// The swap area must not extend beyond the file / device.
+	if (lastPage+1)*uint64(pageSize) > devSize {
+		return 0, syscall.EINVAL
+	}
+
+	return lastPage * uint64(pageSize), 0
+}
@@ -0,0 +1,94 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package seccomp
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// mkswap returns the content of a swap area of the given number of pages, as
+// laid out by mkswap (with the given last_page value).
+func mkswap(pages int, lastPage uint32, signature string) []byte {
+
+	pageSize := os.Getpagesize()
+
+	area := make([]byte, pages*pageSize)
+	binary.NativeEndian.PutUint32(area[swapHeaderVersionOffset:], 1)
+	binary.NativeEndian.PutUint32(area[swapHeaderLastPageOffset:], lastPage)
+	copy(area[pageSize-len(signature):], signature)
+
+	return area
+}
+
+func Test_swapAreaSize(t *testing.T) {
+
+	pageSize := os.Getpagesize()
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		content  []byte // nil for a directory
+		wantSize uint64
+		wantErr  syscall.Errno
+	}{
+		// Test-case 1: Valid swap file.
+		{"valid", mkswap(16, 15, swapSignature), uint64(15 * pageSize), 0},
+		// Test-case 2: Valid swap file, not using all its pages.
+		{"valid-partial", mkswap(16, 7, swapSignature), uint64(7 * pageSize), 0},
+		// Test-case 3: Regular file without a swap signature.
+		{"no-signature", make([]byte, 16*pageSize), 0, syscall.EINVAL},
+		// Test-case 4: Swap signature of an obsolete format.
+		{"old-signature", mkswap(16, 15, "SWAP-SPACE"), 0, syscall.EINVAL},
+		// Test-case 5: Swap area shorter than its header indicates.
+		{"truncated", mkswap(16, 16, swapSignature), 0, syscall.EINVAL},
+		// Test-case 6: Empty swap area.
+		{"empty", mkswap(1, 0, swapSignature), 0, syscall.EINVAL},
+		// Test-case 7: File shorter than the swap header.
+		{"short", []byte("SWAPSPACE2"), 0, syscall.EINVAL},
+		// Test-case 8: Directory.
+		{"dir", nil, 0, syscall.EINVAL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+
+			if tt.content == nil {
+				if err := os.Mkdir(path, 0700); err != nil {
+					t.Fatal(err)
+				}
+			} else if err := os.WriteFile(path, tt.content, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			size, errno := swapAreaSize(path)
+			if size != tt.wantSize || errno != tt.wantErr {
+				t.Errorf("swapAreaSize(%s) = %d, %v; want %d, %v",
+					tt.name, size, errno, tt.wantSize, tt.wantErr)
+			}
+		})
+	}
+
+	// Missing targets.
+	if _, errno := swapAreaSize(filepath.Join(dir, "missing")); errno != syscall.ENOENT {
+		t.Errorf("swapAreaSize(missing) error = %v, want ENOENT", errno)
+	}
+}
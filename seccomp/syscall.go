@@ -17,7 +17,11 @@
 package seccomp
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/sirupsen/logrus"
 )
 
 // Syscall generic information / state.
@@ -30,7 +34,44 @@ type syscallCtx struct {
 	gid         uint32                // Gid of the process generating the syscall
 	cwd         string                // Cwd of process generating the syscall
 	root        string                // Root of process generating the syscall
+	rootInode   uint64                // Inode of the root of process generating the syscall
 	processInfo domain.ProcessIface   // Process details associated to the syscall request
 	cntr        domain.ContainerIface // Container hosting the process generating the syscall
 	tracer      *syscallTracer        // Backpointer to the seccomp-tracer owning the syscall
 }
+
+// rootChanged returns true if the root or cwd of the process generating the
+// syscall no longer match the ones collected when the syscall was trapped.
+// This is the case when another thread of the process chroot()s / chdir()s in
+// the meantime, which would leave the paths adjusted against these attributes
+// (see targetAdjust()) pointing to a different filesystem context. The root's
+// inode is checked too, as a chroot() into a path displayed under the same
+// name can't be told apart otherwise. Nothing is revalidated if the root's
+// inode wasn't collected.
+func (s *syscallCtx) rootChanged() bool {
+
+	if s.rootInode == 0 {
+		return false
+	}
+
+	procRoot := fmt.Sprintf("/proc/%d/root", s.pid)
+	procCwd := fmt.Sprintf("/proc/%d/cwd", s.pid)
+
+	root, err := os.Readlink(procRoot)
+	if err != nil {
+		return true
+	}
+
+	cwd, err := os.Readlink(procCwd)
+	if err != nil {
+		return true
+	}
+
+	if root != s.root || cwd != s.cwd || domain.FileInode(procRoot) != s.rootInode {
+		logrus.Infof("Rejected syscall %d from pid %d: process root / cwd changed (%s / %s -> %s / %s)",
+			s.syscallNum, s.pid, s.root, s.cwd, root, cwd)
+		return true
+	}
+
+	return false
+}
@@ -20,9 +20,11 @@ import (
 	"C"
 	"fmt"
 	"net"
+	"os"
 	"path/filepath"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/nestybox/sysbox-fs/domain"
 	unixIpc "github.com/nestybox/sysbox-ipc/unix"
@@ -42,9 +44,18 @@ type sysRequest = libseccomp.ScmpNotifReq
 type sysResponse = libseccomp.ScmpNotifResp
 
 // Slice of supported syscalls to monitor.
+//
+// Notice that the seccomp-notify filter is not installed by sysbox-fs but by
+// sysbox-runc (which hands its fd over to sysbox-fs), so syscalls only trap
+// into sysbox-fs when listed in sysbox-runc's filter as well; entries here have
+// no effect otherwise. The open_tree, move_mount, landlock, kexec, openat2 and
+// bpf syscalls (as well as the x86 chown flavors below) require a sysbox-runc
+// carrying them in its filter.
 var monitoredSyscalls = []string{
 	"mount",
 	"umount2",
+	"open_tree",
+	"move_mount",
 	"reboot",
 	"swapon",
 	"swapoff",
@@ -63,6 +74,22 @@ var monitoredSyscalls = []string{
 	"listxattr",
 	"llistxattr",
 	"flistxattr",
+	"landlock_create_ruleset",
+	"landlock_add_rule",
+	"kexec_load",
+	"kexec_file_load",
+	"openat2",
+	"bpf",
+}
+
+// Syscalls only monitored for 32-bit x86 processes, as their ABI carries
+// chown() and fchown() in two flavors: the legacy syscalls take 16-bit ids,
+// whereas the ones issued by glibc (chown32() and fchown32()) take 32-bit ones.
+// Notice that none of the monitored syscalls is multiplexed through
+// socketcall() or ipc() on x86, so no demultiplexing is needed.
+var monitoredX86Syscalls = []string{
+	"chown32",
+	"fchown32",
 }
 
 // Seccomp's syscall-monitoring/trapping service struct. External packages
@@ -74,6 +101,9 @@ type SyscallMonitorService struct {
 	mts                    domain.MountServiceIface          // for mount-services purposes
 	allowImmutableRemounts bool                              // allow immutable mounts to be remounted
 	allowImmutableUnmounts bool                              // allow immutable mounts to be unmounted
+	allowObsMounts         bool                              // allow bpf, tracefs and debugfs mounts
+	allowAllBpfProgs       bool                              // let the kernel handle all bpf syscalls
+	chownRoProcMounts      bool                              // chown read-only proc mounts to the container's root
 	closeSeccompOnContExit bool                              // close seccomp fds on container exit, not on process exit
 	tracer                 *syscallTracer                    // pointer to actual syscall-tracer instance
 }
@@ -89,6 +119,9 @@ func (scs *SyscallMonitorService) Setup(
 	mts domain.MountServiceIface,
 	allowImmutableRemounts bool,
 	allowImmutableUnmounts bool,
+	allowObsMounts bool,
+	allowAllBpfProgs bool,
+	chownRoProcMounts bool,
 	seccompFdReleasePolicy string) {
 
 	scs.nss = nss
@@ -97,13 +130,21 @@ func (scs *SyscallMonitorService) Setup(
 	scs.mts = mts
 	scs.allowImmutableRemounts = allowImmutableRemounts
 	scs.allowImmutableUnmounts = allowImmutableUnmounts
+	scs.allowObsMounts = allowObsMounts
+	scs.allowAllBpfProgs = allowAllBpfProgs
+	scs.chownRoProcMounts = chownRoProcMounts
 
 	if seccompFdReleasePolicy == "cont-exit" {
 		scs.closeSeccompOnContExit = true
 	}
 
 	// Allocate a new syscall-tracer.
-	scs.tracer = newSyscallTracer(scs)
+	tracer, err := newSyscallTracer(scs)
+	if err != nil {
+		logrus.Fatalf("syscallMonitorService initialization error (%v). Exiting ...",
+			err)
+	}
+	scs.tracer = tracer
 
 	// Initialize and launch the syscall-tracer.
 	if err := scs.tracer.start(); err != nil {
@@ -112,6 +153,32 @@ func (scs *SyscallMonitorService) Setup(
 	}
 }
 
+// SelfTest verifies that the syscall-tracer can be initialized on the running
+// kernel: every monitored syscall must resolve for all the supported archs, and
+// the seccomp API level must be sufficient. Unlike Setup(), no tracer is
+// launched, and all the problems found are reported.
+func (scs *SyscallMonitorService) SelfTest() []error {
+
+	var errs []error
+
+	nativeArchId, err := libseccomp.GetNativeArch()
+	if err != nil {
+		return []error{fmt.Errorf("unable to obtain native architecture: %v", err)}
+	}
+
+	if _, resolveErrs := resolveSyscalls(nativeArchId); len(resolveErrs) > 0 {
+		errs = append(errs, resolveErrs...)
+	} else if _, err := newSyscallTracer(scs); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := checkSeccompAPI(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
 type seccompArchSyscallPair struct {
 	archId    libseccomp.ScmpArch
 	syscallId libseccomp.ScmpSyscall
@@ -135,6 +202,7 @@ type syscallTracer struct {
 	pidToContMap       map[uint32]string                 // maps pid -> container id
 	seccompSessionMu   sync.RWMutex                      // seccomp session table lock
 	seccompUnusedNotif bool                              // seccomp-fd unused notification feature supported by kernel
+	seccompAddFd       bool                              // seccomp-fd addfd feature supported by kernel
 	seccompNotifPidTrk *seccompNotifPidTracker           // Ensures seccomp notifs for the same pid are processed sequentially (not in parallel).
 	service            *SyscallMonitorService            // backpointer to syscall-monitor service
 }
@@ -144,8 +212,7 @@ func getSupportedCompatibleSyscalls(nativeArchId libseccomp.ScmpArch) map[libsec
 	case libseccomp.ArchAMD64:
 		return map[libseccomp.ScmpArch][]string{
 			libseccomp.ArchAMD64: monitoredSyscalls,
-			// TODO: Add x86 specific syscalls such as chown32
-			libseccomp.ArchX86: monitoredSyscalls,
+			libseccomp.ArchX86:   append(append([]string{}, monitoredSyscalls...), monitoredX86Syscalls...),
 		}
 	default:
 		return map[libseccomp.ScmpArch][]string{
@@ -154,12 +221,72 @@ func getSupportedCompatibleSyscalls(nativeArchId libseccomp.ScmpArch) map[libsec
 	}
 }
 
+// resolveSyscalls obtains the seccomp ids of the monitored syscalls for every
+// architecture supported by the native one. All the syscalls that can't be
+// resolved are reported.
+func resolveSyscalls(
+	nativeArchId libseccomp.ScmpArch) (map[seccompArchSyscallPair]string, []error) {
+
+	var errs []error
+
+	syscalls := make(map[seccompArchSyscallPair]string)
+
+	for archId, archSyscalls := range getSupportedCompatibleSyscalls(nativeArchId) {
+		for _, syscall := range archSyscalls {
+			syscallId, err := libseccomp.GetSyscallFromNameByArch(syscall, archId)
+			if err != nil {
+				errs = append(errs,
+					fmt.Errorf("unknown syscall (%v, %v)", archId, syscall))
+				continue
+			}
+			syscalls[seccompArchSyscallPair{archId, syscallId}] = syscall
+		}
+	}
+
+	return syscalls, errs
+}
+
+// checkSeccompAPI enforces proper support of seccomp-monitoring capabilities
+// by the existing kernel.
+func checkSeccompAPI() error {
+
+	api, err := libseccomp.GetAPI()
+	if err != nil {
+		return fmt.Errorf("unable to obtain seccomp API level: %v", err)
+	}
+	if api < 5 {
+		return fmt.Errorf("need seccomp API level >= 5; it's currently %d", api)
+	}
+
+	return nil
+}
+
+// checkSeccompAddFd returns true if the kernel supports the installation of
+// fds in the tracee (SECCOMP_IOCTL_NOTIF_ADDFD), as provided by kernels
+// starting with v5.9. Syscall emulations that hand out fds to the tracee must
+// be skipped (i.e., the syscall continued) otherwise; none of the currently
+// trapped syscalls requires it (e.g., openat2 is policed, never emulated).
+func checkSeccompAddFd(kernelVersionCmp func(k, m int) (int, error)) (bool, error) {
+
+	cmp, err := kernelVersionCmp(5, 9)
+	if err != nil {
+		return false, fmt.Errorf("unable to parse kernel string: %v", err)
+	}
+
+	if cmp < 0 {
+		logrus.Warn("Seccomp-fd addfd feature not supported by kernel (requires v5.9+); " +
+			"syscall emulations returning fds to container processes are disabled.")
+		return false, nil
+	}
+
+	return true, nil
+}
+
 // syscallTracer constructor.
-func newSyscallTracer(sms *SyscallMonitorService) *syscallTracer {
+func newSyscallTracer(sms *SyscallMonitorService) (*syscallTracer, error) {
 
 	tracer := &syscallTracer{
-		service:  sms,
-		syscalls: make(map[seccompArchSyscallPair]string),
+		service: sms,
 	}
 
 	if sms.closeSeccompOnContExit {
@@ -170,21 +297,17 @@ func newSyscallTracer(sms *SyscallMonitorService) *syscallTracer {
 	// Populate hashmap of supported syscalls to monitor.
 	nativeArchId, err := libseccomp.GetNativeArch()
 	if err != nil {
-		logrus.Warnf("Seccomp-tracer initialization error: Error obtaining native architecture")
-		return nil
+		return nil, fmt.Errorf("unable to obtain native architecture: %v", err)
 	}
 
-	for archId, syscalls := range getSupportedCompatibleSyscalls(nativeArchId) {
-		for _, syscall := range syscalls {
-			syscallId, err := libseccomp.GetSyscallFromNameByArch(syscall, archId)
-			if err != nil {
-				logrus.Warnf("Seccomp-tracer initialization error: unknown syscall (%v, %v).",
-					archId, syscall)
-				return nil
-			}
-			tracer.syscalls[seccompArchSyscallPair{archId, syscallId}] = syscall
+	syscalls, errs := resolveSyscalls(nativeArchId)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			logrus.Warnf("Seccomp-tracer initialization error: %v.", err)
 		}
+		return nil, errs[0]
 	}
+	tracer.syscalls = syscalls
 
 	// Elect the memParser to utilize based on the availability of process_vm_readv()
 	// syscall.
@@ -200,17 +323,20 @@ func newSyscallTracer(sms *SyscallMonitorService) *syscallTracer {
 	// Seccomp-fd's unused notification feature is provided by kernel starting with v5.8.
 	cmp, err := linuxUtils.KernelCurrentVersionCmp(5, 8)
 	if err != nil {
-		logrus.Warnf("Seccomp-tracer initialization error: unable to parse kernel string (%v).",
-			err)
-		return nil
+		return nil, fmt.Errorf("unable to parse kernel string: %v", err)
 	}
 	if cmp >= 0 {
 		tracer.seccompUnusedNotif = true
 	}
 
+	tracer.seccompAddFd, err = checkSeccompAddFd(linuxUtils.KernelCurrentVersionCmp)
+	if err != nil {
+		return nil, err
+	}
+
 	tracer.seccompNotifPidTrk = newSeccompNotifPidTracker()
 
-	return tracer
+	return tracer, nil
 }
 
 // Start syscall tracer.
@@ -218,15 +344,18 @@ func (t *syscallTracer) start() error {
 
 	// Enforce proper support of seccomp-monitoring capabilities by the existing
 	// kernel; bail otherwise.
-	api, err := libseccomp.GetAPI()
-	if err != nil {
-		logrus.Errorf("Error while obtaining seccomp API level (%v).", err)
-		return err
-	} else if api < 5 {
-		logrus.Errorf("Error: need seccomp API level >= 5; it's currently %d", api)
+	if err := checkSeccompAPI(); err != nil {
+		logrus.Errorf("Error: %v", err)
 		return fmt.Errorf("Error: unsupported kernel")
 	}
 
+	// A socket left behind by a prior (crashed) instance would make the
+	// server's bind fail.
+	if err := reclaimTracerSock(seccompTracerSockAddr); err != nil {
+		logrus.Errorf("Unable to initialize seccomp-tracer server: %v", err)
+		return err
+	}
+
 	// Launch a new server to listen to seccomp-tracer's socket. Incoming messages
 	// will be handled through a separated / dedicated goroutine.
 	srv, err := unixIpc.NewServer(seccompTracerSockAddr, t.connHandler)
@@ -239,6 +368,40 @@ func (t *syscallTracer) start() error {
 	return nil
 }
 
+// reclaimTracerSock removes the seccomp-tracer socket at the given path if
+// it's stale (i.e., left behind by an instance that didn't shut down cleanly).
+// Sockets with a live listener are left untouched and an error is returned.
+// Notice that other running sysbox-fs instances are already ruled out by the
+// pid-file check at startup (see main.go).
+func reclaimTracerSock(sockAddr string) error {
+
+	fi, err := os.Lstat(sockAddr)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", sockAddr)
+	}
+
+	conn, err := net.DialTimeout("unix", sockAddr, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("%s in use by a live listener", sockAddr)
+	}
+
+	if err := os.Remove(sockAddr); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove stale socket %s: %v", sockAddr, err)
+	}
+
+	logrus.Infof("Removed stale seccomp-tracer socket %s", sockAddr)
+
+	return nil
+}
+
 func (t *syscallTracer) seccompSessionAdd(s seccompSession) {
 
 	t.seccompSessionMu.Lock()
@@ -504,6 +667,12 @@ func (t *syscallTracer) processSyscall(
 	case "umount2":
 		resp, err = t.processUmount(req, fd, cntr)
 
+	case "open_tree":
+		resp, err = t.processOpenTree(req, fd, cntr)
+
+	case "move_mount":
+		resp, err = t.processMoveMount(req, fd, cntr)
+
 	case "reboot":
 		resp, err = t.processReboot(req, fd, cntr)
 
@@ -513,11 +682,11 @@ func (t *syscallTracer) processSyscall(
 	case "swapoff":
 		resp, err = t.processSwapoff(req, fd, cntr)
 
-	case "chown":
-		resp, err = t.processChown(req, fd, cntr)
+	case "chown", "chown32":
+		resp, err = t.processChown(req, fd, cntr, syscallName)
 
-	case "fchown":
-		resp, err = t.processFchown(req, fd, cntr)
+	case "fchown", "fchown32":
+		resp, err = t.processFchown(req, fd, cntr, syscallName)
 
 	case "fchownat":
 		resp, err = t.processFchownat(req, fd, cntr)
@@ -546,6 +715,21 @@ func (t *syscallTracer) processSyscall(
 	case "flistxattr":
 		resp, err = t.processFlistxattr(req, fd, cntr)
 
+	case "landlock_create_ruleset":
+		resp, err = t.processLandlockCreateRuleset(req, fd, cntr)
+
+	case "landlock_add_rule":
+		resp, err = t.processLandlockAddRule(req, fd, cntr)
+
+	case "kexec_load", "kexec_file_load":
+		resp, err = t.processKexec(req, fd, cntr, syscallName)
+
+	case "openat2":
+		resp, err = t.processOpenat2(req, fd, cntr)
+
+	case "bpf":
+		resp, err = t.processBpf(req, fd, cntr)
+
 	default:
 		logrus.Warnf("Unsupported syscall notification received (%v) on fd %d, pid %d, cntr %s",
 			syscallId, fd, req.Pid, formatter.ContainerID{cntrID})
@@ -573,6 +757,42 @@ func (t *syscallTracer) processSyscall(
 	return resp, nil
 }
 
+// readMountBinData reads the binary mount data at the given address of the
+// tracee. Its size is unknown, so a page is read, just as the kernel does
+// (see copy_mount_options()): if the data lies right before an unreadable
+// page, it's cut at the page boundary (and zero-padded).
+func (t *syscallTracer) readMountBinData(pid uint32, addr uint64) ([]byte, error) {
+
+	if addr == 0 {
+		return nil, nil
+	}
+
+	pageSize := uint64(os.Getpagesize())
+	data := make([]byte, pageSize)
+
+	head := pageSize - addr%pageSize
+	parsedArgs, err := t.memParser.ReadSyscallBytesArgs(
+		pid,
+		[]memParserDataElem{{addr, int(head), nil}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	copy(data, parsedArgs[0])
+
+	if head < pageSize {
+		parsedArgs, err = t.memParser.ReadSyscallBytesArgs(
+			pid,
+			[]memParserDataElem{{addr + head, int(pageSize - head), nil}},
+		)
+		if err == nil {
+			copy(data[head:], parsedArgs[0])
+		}
+	}
+
+	return data, nil
+}
+
 func (t *syscallTracer) processMount(
 	req *sysRequest,
 	fd int32,
@@ -580,17 +800,13 @@ func (t *syscallTracer) processMount(
 
 	logrus.Debugf("Received mount syscall from pid %d", req.Pid)
 
-	// Extract the "path", "name", "fstype" and "data" syscall attributes.
-	// Note: even though "data" is defined as a "void *" in the mount(2), we
-	// assume it's a string because the mount syscall does not specify its
-	// length.
+	// Extract the "path", "name" and "fstype" syscall attributes.
 	parsedArgs, err := t.memParser.ReadSyscallStringArgs(
 		req.Pid,
 		[]memParserDataElem{
 			{req.Data.Args[0], unix.PathMax, nil},
 			{req.Data.Args[1], unix.PathMax, nil},
 			{req.Data.Args[2], unix.PathMax, nil},
-			{req.Data.Args[4], unix.PathMax, nil},
 		},
 	)
 	if err != nil {
@@ -599,7 +815,29 @@ func (t *syscallTracer) processMount(
 	source := parsedArgs[0]
 	target := parsedArgs[1]
 	fstype := parsedArgs[2]
-	data := parsedArgs[3]
+
+	// Extract the "data" syscall attribute. Even though it's defined as a
+	// "void *" in mount(2), it's a string for most filesystems, so we assume
+	// so (the mount syscall does not specify its length), except for the ones
+	// known to take a binary struct.
+	var (
+		data    string
+		binData []byte
+	)
+	if binaryMountDataFsTypes[fstype] {
+		binData, err = t.readMountBinData(req.Pid, req.Data.Args[4])
+	} else {
+		parsedArgs, err = t.memParser.ReadSyscallStringArgs(
+			req.Pid,
+			[]memParserDataElem{{req.Data.Args[4], unix.PathMax, nil}},
+		)
+		if err == nil {
+			data = parsedArgs[0]
+		}
+	}
+	if err != nil {
+		return t.createErrorResponse(req.ID, syscall.EPERM), nil
+	}
 
 	mount := &mountSyscallInfo{
 		syscallCtx: syscallCtx{
@@ -612,11 +850,12 @@ func (t *syscallTracer) processMount(
 		MountSyscallPayload: &domain.MountSyscallPayload{
 			domain.NSenterMsgHeader{},
 			domain.Mount{
-				Source: source,
-				Target: target,
-				FsType: fstype,
-				Data:   data,
-				Flags:  req.Data.Args[3],
+				Source:  source,
+				Target:  target,
+				FsType:  fstype,
+				Data:    data,
+				BinData: binData,
+				Flags:   req.Data.Args[3],
 			},
 		},
 	}
@@ -627,8 +866,11 @@ func (t *syscallTracer) processMount(
 		return t.createErrorResponse(req.ID, syscall.EPERM), nil
 	}
 
-	mount.Source, err = process.ResolveProcSelf(mount.Source)
+	mount.Source, err = resolveMountSource(process, mount.Source)
 	if err != nil {
+		if errno, ok := err.(syscall.Errno); ok {
+			return t.createErrorResponse(req.ID, errno), nil
+		}
 		return t.createErrorResponse(req.ID, syscall.EACCES), nil
 	}
 
@@ -637,20 +879,21 @@ func (t *syscallTracer) processMount(
 		return t.createErrorResponse(req.ID, syscall.EACCES), nil
 	}
 
-	// Verify the process has the proper rights to access the target and
-	// update it in case it requires path resolution.
-	mount.Target, err = process.PathAccess(mount.Target, 0, true)
-	if err != nil {
-		return t.createErrorResponse(req.ID, err), nil
-	}
-
 	// Collect process attributes required for mount execution.
 	mount.uid = process.Uid()
 	mount.gid = process.Gid()
 	mount.cwd = process.Cwd()
 	mount.root = process.Root()
+	mount.rootInode = process.RootInode()
 	mount.processInfo = process
 
+	// Verify the process has the proper rights to access the target and
+	// update it in case it requires path resolution.
+	mount.Target, err = mount.targetAccess()
+	if err != nil {
+		return t.createErrorResponse(req.ID, err), nil
+	}
+
 	logrus.Debug(mount)
 
 	// To simplify mount processing logic, convert to absolute path if dealing
@@ -735,25 +978,91 @@ func (t *syscallTracer) processUmount(
 	umount.gid = process.Gid()
 	umount.cwd = process.Cwd()
 	umount.root = process.Root()
+	umount.rootInode = process.RootInode()
 	umount.processInfo = process
 
 	logrus.Debug(umount)
 
-	// To simplify umount processing logic, convert to absolute path if dealing
-	// with a relative path request.
-	if !filepath.IsAbs(umount.Target) {
-		umount.Target = filepath.Join(umount.cwd, umount.Target)
+	// Process umount syscall. Notice that relative targets are converted into
+	// absolute ones as part of the target adjustment carried out by process().
+	return umount.process()
+}
+
+func (t *syscallTracer) processOpenTree(
+	req *sysRequest,
+	fd int32,
+	cntr domain.ContainerIface) (*sysResponse, error) {
+
+	logrus.Debugf("Received open_tree syscall from pid %d", req.Pid)
+
+	// Extract "path" syscall attribute.
+	parsedArgs, err := t.memParser.ReadSyscallStringArgs(
+		req.Pid,
+		[]memParserDataElem{{req.Data.Args[1], unix.PathMax, nil}},
+	)
+	if err != nil {
+		return t.createErrorResponse(req.ID, syscall.EPERM), nil
 	}
 
-	// Process umount syscall.
-	return umount.process()
+	openTree := &openTreeSyscallInfo{
+		syscallCtx: syscallCtx{
+			syscallNum: int32(req.Data.Syscall),
+			reqId:      req.ID,
+			pid:        req.Pid,
+			cntr:       cntr,
+			tracer:     t,
+		},
+		path:  parsedArgs[0],
+		dirFd: int32(req.Data.Args[0]),
+		flags: req.Data.Args[2],
+	}
+
+	return openTree.process()
 }
 
-func (t *syscallTracer) processChown(
+func (t *syscallTracer) processMoveMount(
 	req *sysRequest,
 	fd int32,
 	cntr domain.ContainerIface) (*sysResponse, error) {
 
+	logrus.Debugf("Received move_mount syscall from pid %d", req.Pid)
+
+	// Extract "from_pathname" and "to_pathname" syscall attributes.
+	parsedArgs, err := t.memParser.ReadSyscallStringArgs(
+		req.Pid,
+		[]memParserDataElem{
+			{req.Data.Args[1], unix.PathMax, nil},
+			{req.Data.Args[3], unix.PathMax, nil},
+		},
+	)
+	if err != nil {
+		return t.createErrorResponse(req.ID, syscall.EPERM), nil
+	}
+
+	moveMount := &moveMountSyscallInfo{
+		syscallCtx: syscallCtx{
+			syscallNum: int32(req.Data.Syscall),
+			reqId:      req.ID,
+			pid:        req.Pid,
+			cntr:       cntr,
+			tracer:     t,
+		},
+		fromPath:  parsedArgs[0],
+		fromDirFd: int32(req.Data.Args[0]),
+		toPath:    parsedArgs[1],
+		toDirFd:   int32(req.Data.Args[2]),
+		flags:     req.Data.Args[4],
+	}
+
+	return moveMount.process()
+}
+
+func (t *syscallTracer) processChown(
+	req *sysRequest,
+	fd int32,
+	cntr domain.ContainerIface,
+	syscallName string) (*sysResponse, error) {
+
 	// Extract "path" syscall attribute.
 	parsedArgs, err := t.memParser.ReadSyscallStringArgs(
 		req.Pid,
@@ -764,8 +1073,9 @@ func (t *syscallTracer) processChown(
 	}
 	path := parsedArgs[0]
 
-	uid := int64(req.Data.Args[1])
-	gid := int64(req.Data.Args[2])
+	legacyIds := req.Data.Arch == libseccomp.ArchX86 && syscallName == "chown"
+	uid := chownId(req.Data.Args[1], legacyIds)
+	gid := chownId(req.Data.Args[2], legacyIds)
 
 	chown := &chownSyscallInfo{
 		syscallCtx: syscallCtx{
@@ -786,13 +1096,15 @@ func (t *syscallTracer) processChown(
 func (t *syscallTracer) processFchown(
 	req *sysRequest,
 	fd int32,
-	cntr domain.ContainerIface) (*sysResponse, error) {
+	cntr domain.ContainerIface,
+	syscallName string) (*sysResponse, error) {
 
 	// We trap fchown() for the same reason we trap chown() (see processChown()).
 
+	legacyIds := req.Data.Arch == libseccomp.ArchX86 && syscallName == "fchown"
 	pathFd := int32(req.Data.Args[0])
-	uid := int64(req.Data.Args[1])
-	gid := int64(req.Data.Args[2])
+	uid := chownId(req.Data.Args[1], legacyIds)
+	gid := chownId(req.Data.Args[2], legacyIds)
 
 	chown := &chownSyscallInfo{
 		syscallCtx: syscallCtx{
@@ -829,8 +1141,8 @@ func (t *syscallTracer) processFchownat(
 
 	// Get the other args.
 	dirFd := int32(req.Data.Args[0])
-	uid := int64(req.Data.Args[2])
-	gid := int64(req.Data.Args[3])
+	uid := chownId(req.Data.Args[2], false)
+	gid := chownId(req.Data.Args[3], false)
 	flags := int(req.Data.Args[4])
 
 	chown := &chownSyscallInfo{
@@ -851,6 +1163,53 @@ func (t *syscallTracer) processFchownat(
 	return chown.processFchownat()
 }
 
+func (t *syscallTracer) processOpenat2(
+	req *sysRequest,
+	fd int32,
+	cntr domain.ContainerIface) (*sysResponse, error) {
+
+	// Extract "path" syscall attribute.
+	parsedArgs, err := t.memParser.ReadSyscallStringArgs(
+		req.Pid,
+		[]memParserDataElem{{req.Data.Args[1], unix.PathMax, nil}},
+	)
+	if err != nil {
+		return t.createErrorResponse(req.ID, syscall.EPERM), nil
+	}
+	path := parsedArgs[0]
+
+	// Extract the "how" syscall attribute; sizes smaller than the original
+	// struct open_how are left for the kernel to reject (extensions beyond it
+	// are of no interest to us).
+	if req.Data.Args[3] < unix.SizeofOpenHow {
+		return t.createContinueResponse(req.ID), nil
+	}
+	parsedArgs, err = t.memParser.ReadSyscallBytesArgs(
+		req.Pid,
+		[]memParserDataElem{{req.Data.Args[2], unix.SizeofOpenHow, nil}},
+	)
+	if err != nil || len(parsedArgs[0]) != unix.SizeofOpenHow {
+		return t.createErrorResponse(req.ID, syscall.EPERM), nil
+	}
+	flags, resolve := parseOpenHow([]byte(parsedArgs[0]))
+
+	openat2 := &openat2SyscallInfo{
+		syscallCtx: syscallCtx{
+			syscallNum: int32(req.Data.Syscall),
+			reqId:      req.ID,
+			pid:        req.Pid,
+			cntr:       cntr,
+			tracer:     t,
+		},
+		path:    path,
+		dirFd:   int32(req.Data.Args[0]),
+		flags:   flags,
+		resolve: resolve,
+	}
+
+	return openat2.processOpenat2()
+}
+
 func (t *syscallTracer) processSetxattr(
 	req *sysRequest,
 	fd int32,
@@ -873,7 +1232,11 @@ func (t *syscallTracer) processSetxattr(
 
 	// Per setxattr(2):
 	// Value is a "void *", not necessarily a string (i.e., it may not be null terminated).
-	// The size of value (in bytes) is defined by the args[3] parameter.
+	// The size of value (in bytes) is defined by the args[3] parameter, which
+	// must be bounded (as the kernel does) prior to reading the value.
+	if req.Data.Args[3] > xattrSizeMax {
+		return t.createErrorResponse(req.ID, syscall.E2BIG), nil
+	}
 	parsedArgs, err = t.memParser.ReadSyscallBytesArgs(
 		req.Pid,
 		[]memParserDataElem{{req.Data.Args[2], int(req.Data.Args[3]), nil}},
@@ -923,7 +1286,11 @@ func (t *syscallTracer) processFsetxattr(
 
 	// Per setxattr(2):
 	// Value is a "void *", not necessarily a string (i.e., it may not be null terminated).
-	// The size of value (in bytes) is defined by the args[3] parameter.
+	// The size of value (in bytes) is defined by the args[3] parameter, which
+	// must be bounded (as the kernel does) prior to reading the value.
+	if req.Data.Args[3] > xattrSizeMax {
+		return t.createErrorResponse(req.ID, syscall.E2BIG), nil
+	}
 	parsedArgs, err = t.memParser.ReadSyscallBytesArgs(
 		req.Pid,
 		[]memParserDataElem{{req.Data.Args[2], int(req.Data.Args[3]), nil}},
@@ -1190,14 +1557,105 @@ func (t *syscallTracer) processFlistxattr(
 	return si.processListxattr()
 }
 
+func (t *syscallTracer) processLandlockCreateRuleset(
+	req *sysRequest,
+	fd int32,
+	cntr domain.ContainerIface) (*sysResponse, error) {
+
+	li := &landlockSyscallInfo{
+		syscallCtx: syscallCtx{
+			syscallNum: int32(req.Data.Syscall),
+			reqId:      req.ID,
+			pid:        req.Pid,
+			cntr:       cntr,
+			tracer:     t,
+		},
+		flags: uint32(req.Data.Args[2]),
+	}
+
+	return li.processCreateRuleset()
+}
+
+func (t *syscallTracer) processLandlockAddRule(
+	req *sysRequest,
+	fd int32,
+	cntr domain.ContainerIface) (*sysResponse, error) {
+
+	li := &landlockSyscallInfo{
+		syscallCtx: syscallCtx{
+			syscallNum: int32(req.Data.Syscall),
+			reqId:      req.ID,
+			pid:        req.Pid,
+			cntr:       cntr,
+			tracer:     t,
+		},
+		ruleType: uint32(req.Data.Args[1]),
+		ruleAttr: req.Data.Args[2],
+		flags:    uint32(req.Data.Args[3]),
+	}
+
+	return li.processAddRule()
+}
+
 func (t *syscallTracer) processReboot(
 	req *sysRequest,
 	fd int32,
-	cntrID domain.ContainerIface) (*sysResponse, error) {
+	cntr domain.ContainerIface) (*sysResponse, error) {
+
+	reboot := &rebootSyscallInfo{
+		syscallCtx: syscallCtx{
+			syscallNum: int32(req.Data.Syscall),
+			reqId:      req.ID,
+			pid:        req.Pid,
+			cntr:       cntr,
+			tracer:     t,
+		},
+		magic1: uint32(req.Data.Args[0]),
+		magic2: uint32(req.Data.Args[1]),
+		cmd:    uint32(req.Data.Args[2]),
+	}
 
-	logrus.Warnf("Received reboot syscall")
+	return reboot.processReboot(killCntrInit)
+}
 
-	return t.createSuccessResponse(req.ID), nil
+// Loading a new kernel for later execution is never appropriate from within a
+// sys container, so kexec requests are unconditionally rejected (rather than
+// relying on the kernel's handling of requests coming from a user-ns).
+func (t *syscallTracer) processKexec(
+	req *sysRequest,
+	fd int32,
+	cntr domain.ContainerIface,
+	syscallName string) (*sysResponse, error) {
+
+	logrus.Warnf("Rejected %s syscall from pid %d, cntr %s",
+		syscallName, req.Pid, formatter.ContainerID{cntr.ID()})
+
+	return t.createErrorResponse(req.ID, syscall.EPERM), nil
+}
+
+func (t *syscallTracer) processBpf(
+	req *sysRequest,
+	fd int32,
+	cntr domain.ContainerIface) (*sysResponse, error) {
+
+	if t.service.allowAllBpfProgs {
+		return t.createContinueResponse(req.ID), nil
+	}
+
+	bpf := &bpfSyscallInfo{
+		syscallCtx: syscallCtx{
+			syscallNum: int32(req.Data.Syscall),
+			reqId:      req.ID,
+			pid:        req.Pid,
+			cntr:       cntr,
+			tracer:     t,
+		},
+		cmd:  uint32(req.Data.Args[0]),
+		attr: req.Data.Args[1],
+		size: uint32(req.Data.Args[2]),
+	}
+
+	return bpf.processBpf()
 }
 
 func (t *syscallTracer) processSwapon(
@@ -1205,9 +1663,27 @@ func (t *syscallTracer) processSwapon(
 	fd int32,
 	cntr domain.ContainerIface) (*sysResponse, error) {
 
-	logrus.Warnf("Received swapon syscall")
+	// Extract "path" syscall attribute.
+	parsedArgs, err := t.memParser.ReadSyscallStringArgs(
+		req.Pid,
+		[]memParserDataElem{{req.Data.Args[0], unix.PathMax, nil}},
+	)
+	if err != nil {
+		return t.createErrorResponse(req.ID, syscall.EPERM), nil
+	}
 
-	return t.createSuccessResponse(req.ID), nil
+	swapon := &swaponSyscallInfo{
+		syscallCtx: syscallCtx{
+			syscallNum: int32(req.Data.Syscall),
+			reqId:      req.ID,
+			pid:        req.Pid,
+			cntr:       cntr,
+			tracer:     t,
+		},
+		path: parsedArgs[0],
+	}
+
+	return swapon.processSwapon()
 }
 
 func (t *syscallTracer) processSwapoff(
@@ -17,13 +17,20 @@
 package seccomp
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
 	"reflect"
 	"syscall"
 	"testing"
+	"unsafe"
 
 	unixIpc "github.com/nestybox/sysbox-ipc/unix"
+	libseccomp "github.com/seccomp/libseccomp-golang"
+	"golang.org/x/sys/unix"
 )
 
 func Test_syscallTracer_createErrorResponse(t *testing.T) {
@@ -89,3 +96,230 @@ func Test_syscallTracer_createErrorResponse(t *testing.T) {
 		})
 	}
 }
+
+func Test_resolveSyscalls(t *testing.T) {
+
+	nativeArchId, err := libseccomp.GetNativeArch()
+	if err != nil {
+		t.Fatalf("GetNativeArch() failed: %v", err)
+	}
+
+	syscalls, errs := resolveSyscalls(nativeArchId)
+	if len(errs) > 0 {
+		t.Fatalf("resolveSyscalls() unexpected errors: %v", errs)
+	}
+
+	// Every monitored syscall must be present for the native arch.
+	found := make(map[string]bool)
+	for pair, name := range syscalls {
+		if pair.archId == nativeArchId {
+			found[name] = true
+		}
+	}
+	for _, name := range monitoredSyscalls {
+		if !found[name] {
+			t.Errorf("resolveSyscalls(): syscall %s not resolved", name)
+		}
+	}
+}
+
+func Test_resolveSyscalls_x86(t *testing.T) {
+
+	syscalls, errs := resolveSyscalls(libseccomp.ArchAMD64)
+	if len(errs) > 0 {
+		t.Fatalf("resolveSyscalls() unexpected errors: %v", errs)
+	}
+
+	found := make(map[libseccomp.ScmpArch]map[string]bool)
+	for pair, name := range syscalls {
+		if found[pair.archId] == nil {
+			found[pair.archId] = make(map[string]bool)
+		}
+		found[pair.archId][name] = true
+	}
+
+	// The 32-bit chown flavors are only monitored for x86 processes.
+	for _, name := range monitoredX86Syscalls {
+		if !found[libseccomp.ArchX86][name] {
+			t.Errorf("resolveSyscalls(): x86 syscall %s not resolved", name)
+		}
+		if found[libseccomp.ArchAMD64][name] {
+			t.Errorf("resolveSyscalls(): x86 syscall %s resolved for amd64", name)
+		}
+	}
+	for _, name := range monitoredSyscalls {
+		if !found[libseccomp.ArchX86][name] {
+			t.Errorf("resolveSyscalls(): syscall %s not resolved for x86", name)
+		}
+	}
+}
+
+func Test_checkSeccompAddFd(t *testing.T) {
+
+	kernel := func(cmp int, err error) func(k, m int) (int, error) {
+		return func(k, m int) (int, error) {
+			if k != 5 || m != 9 {
+				t.Errorf("checkSeccompAddFd() compared against kernel v%d.%d, want v5.9", k, m)
+			}
+			return cmp, err
+		}
+	}
+
+	tests := []struct {
+		name    string
+		cmp     int
+		cmpErr  error
+		want    bool
+		wantErr bool
+	}{
+		{"newer-kernel", 1, nil, true, false},
+		{"same-kernel", 0, nil, true, false},
+		// Kernel with no addfd support: its emulations are disabled, but the
+		// tracer can still be brought up.
+		{"older-kernel", -1, nil, false, false},
+		{"unknown-kernel", 0, errors.New("unparsable release"), false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checkSeccompAddFd(kernel(tt.cmp, tt.cmpErr))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkSeccompAddFd() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("checkSeccompAddFd() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_syscallTracer_readMountBinData(t *testing.T) {
+
+	pageSize := os.Getpagesize()
+
+	// Two pages of the tracee (ourselves), the second one unreadable.
+	mem, err := unix.Mmap(-1, 0, 2*pageSize, unix.PROT_READ|unix.PROT_WRITE,
+		unix.MAP_PRIVATE|unix.MAP_ANONYMOUS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Munmap(mem)
+	if err := unix.Mprotect(mem[pageSize:], unix.PROT_NONE); err != nil {
+		t.Fatal(err)
+	}
+
+	// Binary mount data, with embedded NULs.
+	data := []byte{0x06, 0x00, 0x00, 0x00, 0xff, 0x00, 'n', 'f', 's', 0x00, 0x80, 0x01}
+
+	tracer := &syscallTracer{memParser: &memParserIOvec{}}
+	pid := uint32(os.Getpid())
+
+	tests := []struct {
+		name   string
+		offset int // offset of the data within the readable page
+	}{
+		// Data at the start of a page: a full page is read.
+		{"page-start", 0},
+		// Data right before the unreadable page: cut at the page boundary.
+		{"page-end", pageSize - len(data)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range mem[:pageSize] {
+				mem[i] = 0
+			}
+			copy(mem[tt.offset:], data)
+			addr := uint64(uintptr(unsafe.Pointer(&mem[tt.offset])))
+
+			got, err := tracer.readMountBinData(pid, addr)
+			if err != nil {
+				t.Fatalf("readMountBinData() unexpected error: %v", err)
+			}
+
+			want := make([]byte, pageSize)
+			copy(want, data)
+			if !bytes.Equal(got, want) {
+				t.Errorf("readMountBinData() = %v..., want %v...", got[:len(data)+4], want[:len(data)+4])
+			}
+		})
+	}
+
+	// No mount data.
+	if got, err := tracer.readMountBinData(pid, 0); got != nil || err != nil {
+		t.Errorf("readMountBinData(NULL) = %v, %v; want nil, nil", got, err)
+	}
+}
+
+func Test_syscallTracer_processKexec(t *testing.T) {
+
+	tracer := &syscallTracer{service: &SyscallMonitorService{}}
+
+	for i, name := range []string{"kexec_load", "kexec_file_load"} {
+		t.Run(name, func(t *testing.T) {
+			req := &sysRequest{ID: uint64(i), Pid: 1001}
+
+			resp, err := tracer.processKexec(req, 0, &testContainer{}, name)
+			if err != nil {
+				t.Fatalf("processKexec() unexpected error: %v", err)
+			}
+
+			want := &sysResponse{ID: uint64(i), Error: int32(syscall.EPERM)}
+			if !reflect.DeepEqual(resp, want) {
+				t.Errorf("processKexec() = %v, want %v", resp, want)
+			}
+		})
+	}
+}
+
+func Test_reclaimTracerSock(t *testing.T) {
+
+	dir := t.TempDir()
+	sockAddr := filepath.Join(dir, "sysfs-seccomp.sock")
+
+	listen := func() *net.UnixListener {
+		l, err := net.ListenUnix("unix", &net.UnixAddr{Name: sockAddr, Net: "unix"})
+		if err != nil {
+			t.Fatalf("Unable to bind %s: %v", sockAddr, err)
+		}
+		return l
+	}
+
+	// No socket.
+	if err := reclaimTracerSock(sockAddr); err != nil {
+		t.Fatalf("reclaimTracerSock() unexpected error: %v", err)
+	}
+
+	// Stale socket (e.g., left behind by a crashed instance).
+	l := listen()
+	l.SetUnlinkOnClose(false)
+	l.Close()
+
+	if err := reclaimTracerSock(sockAddr); err != nil {
+		t.Fatalf("reclaimTracerSock() unexpected error on stale socket: %v", err)
+	}
+	if _, err := os.Lstat(sockAddr); !os.IsNotExist(err) {
+		t.Errorf("stale socket not removed: %v", err)
+	}
+
+	// Rebind must now succeed.
+	l = listen()
+
+	// Live listener.
+	if err := reclaimTracerSock(sockAddr); err == nil {
+		t.Errorf("reclaimTracerSock() expected error on live listener")
+	}
+	if _, err := os.Lstat(sockAddr); err != nil {
+		t.Errorf("live socket removed: %v", err)
+	}
+
+	l.Close()
+
+	// Non-socket file.
+	if err := os.WriteFile(sockAddr, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := reclaimTracerSock(sockAddr); err == nil {
+		t.Errorf("reclaimTracerSock() expected error on non-socket file")
+	}
+}
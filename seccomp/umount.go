@@ -19,6 +19,7 @@ package seccomp
 import (
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 
@@ -93,14 +94,22 @@ func (u *umountSyscallInfo) process() (*sysResponse, error) {
 
 		// If under the base mount there are any submounts *not* managed by
 		// sysbox-fs, fail the unmount with EBUSY (such submounts must be
-		// explicitly unmounted prior to unmounting the base mount).
+		// explicitly unmounted prior to unmounting the base mount). This check
+		// must precede the teardown below, as otherwise the kernel would
+		// reject the unmount of the base mount only after the sysbox-fs
+		// submounts are gone, leaving behind a partially emulated procfs.
 		if mip.HasNonSysboxfsSubmount(u.Target) {
+			logrus.Infof("Rejected unmount of sysbox-fs base mount at %s: non sysbox-fs submounts present",
+				u.Target)
 			resp := u.tracer.createErrorResponse(u.reqId, syscall.EBUSY)
 			return resp, nil
 		}
 
 		// Process the unmount
 		info := mip.GetInfo(u.Target)
+		if info == nil {
+			return u.tracer.createErrorResponse(u.reqId, syscall.EINVAL), nil
+		}
 
 		switch info.FsType {
 		case "proc":
@@ -120,6 +129,19 @@ func (u *umountSyscallInfo) process() (*sysResponse, error) {
 		return u.tracer.createContinueResponse(u.reqId), nil
 
 	} else if mip.IsSysboxfsSubmount(u.Target) {
+
+		// Bind-mounts of /dev/null over masked submounts are ignored (see
+		// mount.go), so the unmounts reverting them must be ignored too, as
+		// otherwise they would take down the masked submount itself and
+		// expose the underlying procfs file. Notice that masks stacked over
+		// any other submount (e.g., /proc/uptime) are regular mounts, and
+		// unmounting them reveals the emulated submount underneath.
+		if mip.IsSysboxfsMaskedSubmount(u.Target) {
+			logrus.Debugf("Ignoring unmount of sysbox-fs masked submount at %s",
+				u.Target)
+			return u.tracer.createSuccessResponse(u.reqId), nil
+		}
+
 		logrus.Infof("Rejected unmount of sysbox-fs managed submount at %s",
 			u.Target)
 		return u.tracer.createErrorResponse(u.reqId, syscall.EINVAL), nil
@@ -222,8 +244,8 @@ func (u *umountSyscallInfo) umountAllowed(
 				u.Target)
 
 			if logrus.IsLevelEnabled(logrus.DebugLevel) {
-				if u.processInfo.Root() == "/" {
-					processRootInode := u.processInfo.RootInode()
+				if u.root == "/" {
+					processRootInode := u.rootInode
 
 					if processRootInode == syscntrRootInode {
 						// Scenario 1): no-unshare(mnt) & no-pivot() & no-chroot()
@@ -259,8 +281,8 @@ func (u *umountSyscallInfo) umountAllowed(
 
 	} else {
 
-		if u.processInfo.Root() == "/" {
-			processRootInode := u.processInfo.RootInode()
+		if u.root == "/" {
+			processRootInode := u.rootInode
 
 			// Scenario 5): unshare(mnt) & no-pivot() & no-chroot()
 			if processRootInode == syscntrRootInode {
@@ -329,7 +351,7 @@ func (u *umountSyscallInfo) umountAllowed(
 			return true, nil
 		}
 
-		if u.processInfo.Root() != "/" {
+		if u.root != "/" {
 
 			// We are dealing with a chroot'ed process, so obtain the inode of "/"
 			// as seen within the process' namespaces, and *not* the one associated
@@ -420,6 +442,10 @@ func (u *umountSyscallInfo) processUmount(
 	// Create instructions payload.
 	payload := u.createUmountPayload(mip)
 
+	if u.rootChanged() {
+		return u.tracer.createErrorResponse(u.reqId, syscall.EAGAIN), nil
+	}
+
 	// Create nsenter-event envelope.
 	nss := u.tracer.service.nss
 	event := nss.NewEvent(
@@ -454,7 +480,9 @@ func (u *umountSyscallInfo) processUmount(
 }
 
 // Build instructions payload required to unmount a sysbox-fs base mount (and
-// any submounts under it)
+// any submounts under it). Mounts are torn down bottom-up: the sysbox-fs
+// mounts stacked on top of each submount go first, then the submounts, and
+// finally the base mount itself.
 func (u *umountSyscallInfo) createUmountPayload(
 	mip domain.MountInfoParserIface) *[]*domain.UmountSyscallPayload {
 
@@ -463,7 +491,13 @@ func (u *umountSyscallInfo) createUmountPayload(
 	submounts := []string{}
 
 	if mip.IsSysboxfsBaseMount(u.Target) {
-		submounts = mip.GetSysboxfsSubMounts(u.Target)
+		for _, subm := range mip.GetSysboxfsSubMounts(u.Target) {
+			submounts = append(submounts, subm)
+			submounts = append(submounts, mip.GetSysboxfsNestedSubMounts(subm)...)
+		}
+
+		// Sorted in reverse order, children always precede their parents.
+		sort.Sort(sort.Reverse(sort.StringSlice(submounts)))
 	} else {
 		submounts = append(submounts, u.Target)
 	}
@@ -491,8 +525,18 @@ func (u *umountSyscallInfo) createUmountPayload(
 // a 'root' attribute different than default one ("/"). This is typically the
 // case in chroot'ed environments. Method's goal is to make the required target
 // adjustments so that sysbox-fs can carry out the mount in the expected context.
+//
+// Relative targets are resolved against the process' cwd. Unlike the target
+// itself, the cwd (as reported by procfs) is already expressed in terms of the
+// container's root, and it may even fall outside of the process' root (e.g.,
+// chroot() not followed by chdir()), so no root-prefixing is required there.
 func (u *umountSyscallInfo) targetAdjust() {
 
+	if !filepath.IsAbs(u.Target) {
+		u.Target = filepath.Join(u.cwd, u.Target)
+		return
+	}
+
 	root := u.syscallCtx.root
 
 	if root == "/" {
@@ -0,0 +1,488 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package seccomp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+
+	libseccomp "github.com/seccomp/libseccomp-golang"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/sys/unix"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	"github.com/nestybox/sysbox-fs/mocks"
+)
+
+// Mountinfo-parser fake describing a procfs base mount along with the mounts
+// stacked on top of it.
+type testUmountInfoParser struct {
+	domain.MountInfoParserIface
+	base     string
+	sysboxfs map[string][]string // sysbox-fs submounts -> nested sysbox-fs mounts
+	user     []string            // mounts not managed by sysbox-fs
+	masked   map[string]bool     // masked sysbox-fs submounts
+}
+
+func (p *testUmountInfoParser) IsSysboxfsBaseMount(mp string) bool {
+	return mp == p.base
+}
+
+func (p *testUmountInfoParser) IsSysboxfsSubmount(mp string) bool {
+	_, ok := p.sysboxfs[mp]
+	return ok
+}
+
+func (p *testUmountInfoParser) IsSysboxfsMaskedSubmount(mp string) bool {
+	return p.masked[mp]
+}
+
+func (p *testUmountInfoParser) HasNonSysboxfsSubmount(mp string) bool {
+	return len(p.user) > 0
+}
+
+func (p *testUmountInfoParser) GetSysboxfsSubMounts(mp string) []string {
+	var submounts []string
+	for subm := range p.sysboxfs {
+		submounts = append(submounts, subm)
+	}
+	return submounts
+}
+
+func (p *testUmountInfoParser) GetSysboxfsNestedSubMounts(mp string) []string {
+	return p.sysboxfs[mp]
+}
+
+func (p *testUmountInfoParser) GetInfo(mp string) *domain.MountInfo {
+	if mp == p.base {
+		return &domain.MountInfo{MountPoint: mp, FsType: "proc"}
+	}
+	return &domain.MountInfo{MountPoint: mp, FsType: "fuse"}
+}
+
+func Test_umountSyscallInfo_process_procBaseMount(t *testing.T) {
+
+	newParser := func(root string, user []string) *testUmountInfoParser {
+		return &testUmountInfoParser{
+			base: root + "/proc",
+			sysboxfs: map[string][]string{
+				root + "/proc/sys":    {root + "/proc/sys/kernel/yama"},
+				root + "/proc/swaps":  nil,
+				root + "/proc/uptime": nil,
+			},
+			user: user,
+		}
+	}
+
+	tests := []struct {
+		name       string
+		root       string
+		mip        *testUmountInfoParser
+		wantErrno  syscall.Errno
+		wantUmount []string
+	}{
+		// Teardown of a chroot'ed /proc; nested mounts go first, base mount
+		// goes last.
+		{
+			name:      "teardown",
+			root:      "/root",
+			mip:       newParser("/root", nil),
+			wantErrno: 0,
+			wantUmount: []string{
+				"/root/proc/uptime",
+				"/root/proc/sys/kernel/yama",
+				"/root/proc/sys",
+				"/root/proc/swaps",
+				"/root/proc",
+			},
+		},
+		// User mount under the chroot'ed /proc; nothing must be unmounted.
+		{
+			name:      "user-submount",
+			root:      "/root",
+			mip:       newParser("/root", []string{"/root/proc/sys/fs/binfmt_misc"}),
+			wantErrno: syscall.EBUSY,
+		},
+		// The container's /proc can't be unmounted.
+		{
+			name:      "container-proc",
+			root:      "/",
+			mip:       newParser("", nil),
+			wantErrno: syscall.EBUSY,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mts := &mocks.MountServiceIface{}
+			mts.On("NewMountInfoParser", mock.Anything, mock.Anything,
+				true, true, false).Return(tt.mip, nil)
+
+			event := &mocks.NSenterEventIface{}
+			nss := &mocks.NSenterServiceIface{}
+			nss.On("NewEvent", mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything, mock.Anything, mock.Anything).Return(event)
+			nss.On("SendRequestEvent", event).Return(nil)
+			nss.On("ReceiveResponseEvent", event).Return(
+				&domain.NSenterMessage{Type: domain.UmountSyscallResponse})
+
+			tracer := &syscallTracer{
+				service: &SyscallMonitorService{mts: mts, nss: nss},
+			}
+
+			u := &umountSyscallInfo{
+				syscallCtx{tracer: tracer, cntr: &testContainer{}, root: tt.root},
+				&domain.UmountSyscallPayload{
+					domain.NSenterMsgHeader{},
+					domain.Mount{Target: "/proc"},
+				},
+			}
+
+			resp, err := u.process()
+			if err != nil {
+				t.Fatalf("process() unexpected error: %v", err)
+			}
+			if resp.Error != int32(tt.wantErrno) {
+				t.Errorf("process() errno = %d, want %d", resp.Error, tt.wantErrno)
+			}
+
+			var umounts []string
+			for _, call := range nss.Calls {
+				if call.Method != "NewEvent" {
+					continue
+				}
+				req := call.Arguments.Get(3).(*domain.NSenterMessage)
+				for _, p := range *req.Payload.(*[]*domain.UmountSyscallPayload) {
+					umounts = append(umounts, p.Target)
+				}
+			}
+			if !reflect.DeepEqual(umounts, tt.wantUmount) {
+				t.Errorf("unmounted targets = [%s], want [%s]",
+					strings.Join(umounts, " "), strings.Join(tt.wantUmount, " "))
+			}
+		})
+	}
+}
+
+func Test_umountSyscallInfo_process_relativeTarget(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		root       string
+		cwd        string
+		target     string
+		wantErrno  syscall.Errno
+		wantTarget string // base mount being unmounted (last payload entry)
+	}{
+		// Chroot'ed process unmounting its /proc from within its root dir.
+		{"chroot-cwd-root", "/root", "/root", "proc", 0, "/root/proc"},
+		// Chroot'ed process unmounting its /proc from within /proc itself.
+		{"chroot-cwd-proc", "/root", "/root/proc", ".", 0, "/root/proc"},
+		// Chroot'ed process unmounting its /proc from a sibling dir.
+		{"chroot-cwd-sibling", "/root", "/root/tmp", "../proc", 0, "/root/proc"},
+		// Chroot'ed process whose cwd lies outside of its root (no chdir()
+		// after chroot()).
+		{"chroot-cwd-outside", "/root", "/", "root/proc", 0, "/root/proc"},
+		// Non-chroot'ed process; the container's /proc can't be unmounted.
+		{"no-chroot", "/", "/", "proc", syscall.EBUSY, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix := tt.root
+			if prefix == "/" {
+				prefix = ""
+			}
+			mip := &testUmountInfoParser{
+				base: prefix + "/proc",
+				sysboxfs: map[string][]string{
+					prefix + "/proc/sys": nil,
+				},
+			}
+
+			mts := &mocks.MountServiceIface{}
+			mts.On("NewMountInfoParser", mock.Anything, mock.Anything,
+				true, true, false).Return(mip, nil)
+
+			event := &mocks.NSenterEventIface{}
+			nss := &mocks.NSenterServiceIface{}
+			nss.On("NewEvent", mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything, mock.Anything, mock.Anything).Return(event)
+			nss.On("SendRequestEvent", event).Return(nil)
+			nss.On("ReceiveResponseEvent", event).Return(
+				&domain.NSenterMessage{Type: domain.UmountSyscallResponse})
+
+			tracer := &syscallTracer{
+				service: &SyscallMonitorService{mts: mts, nss: nss},
+			}
+
+			u := &umountSyscallInfo{
+				syscallCtx{
+					tracer: tracer,
+					cntr:   &testContainer{},
+					root:   tt.root,
+					cwd:    tt.cwd,
+				},
+				&domain.UmountSyscallPayload{
+					domain.NSenterMsgHeader{},
+					domain.Mount{Target: tt.target},
+				},
+			}
+
+			resp, err := u.process()
+			if err != nil {
+				t.Fatalf("process() unexpected error: %v", err)
+			}
+			if resp.Error != int32(tt.wantErrno) {
+				t.Errorf("process() errno = %d, want %d", resp.Error, tt.wantErrno)
+			}
+
+			var gotTarget string
+			for _, call := range nss.Calls {
+				if call.Method != "NewEvent" {
+					continue
+				}
+				req := call.Arguments.Get(3).(*domain.NSenterMessage)
+				payload := *req.Payload.(*[]*domain.UmountSyscallPayload)
+				gotTarget = payload[len(payload)-1].Target
+			}
+			if gotTarget != tt.wantTarget {
+				t.Errorf("unmounted base target = %q, want %q", gotTarget, tt.wantTarget)
+			}
+		})
+	}
+}
+
+// Unmounts reverting the masking of sysbox-fs submounts (i.e., bind-mounts of
+// /dev/null over them) must leave the emulated submounts in place.
+func Test_umountSyscallInfo_process_maskedSubmount(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		target    string
+		wantErrno syscall.Errno
+		wantCont  bool
+	}{
+		// Submount masked by sysbox-fs, over which the /dev/null bind-mount
+		// was ignored; the unmount is ignored too.
+		{"masked-submount", "/proc/kcore", 0, false},
+		// Mask stacked by the user over an emulated submount; the kernel's
+		// unmount reveals the submount.
+		{"stacked-mask", "/proc/uptime", 0, true},
+		// Unmasked submount.
+		{"submount", "/proc/swaps", syscall.EINVAL, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mip := &testUmountInfoParser{
+				base: "/proc",
+				sysboxfs: map[string][]string{
+					"/proc/sys":   nil,
+					"/proc/swaps": nil,
+					"/proc/kcore": nil,
+				},
+				masked: map[string]bool{"/proc/kcore": true},
+				// The top mount at /proc/uptime is the user's mask.
+				user: []string{"/proc/uptime"},
+			}
+
+			mts := &mocks.MountServiceIface{}
+			mts.On("NewMountInfoParser", mock.Anything, mock.Anything,
+				true, true, false).Return(mip, nil)
+
+			nss := &mocks.NSenterServiceIface{}
+
+			tracer := &syscallTracer{
+				service: &SyscallMonitorService{
+					mts:                    mts,
+					nss:                    nss,
+					allowImmutableUnmounts: true,
+				},
+			}
+
+			u := &umountSyscallInfo{
+				syscallCtx{tracer: tracer, cntr: &testContainer{}, root: "/"},
+				&domain.UmountSyscallPayload{
+					domain.NSenterMsgHeader{},
+					domain.Mount{Target: tt.target},
+				},
+			}
+
+			resp, err := u.process()
+			if err != nil {
+				t.Fatalf("process() unexpected error: %v", err)
+			}
+			if resp.Error != int32(tt.wantErrno) {
+				t.Errorf("process() errno = %d, want %d", resp.Error, tt.wantErrno)
+			}
+			if gotCont := resp.Flags&libseccomp.NotifRespFlagContinue != 0; gotCont != tt.wantCont {
+				t.Errorf("process() continue = %v, want %v", gotCont, tt.wantCont)
+			}
+			if len(nss.Calls) != 0 {
+				t.Errorf("unexpected nsenter requests: %v", nss.Calls)
+			}
+		})
+	}
+}
+
+// Root / cwd changes made by the process (e.g., by another of its threads)
+// while the unmount is being processed must abort it, as the target was
+// adjusted against the former ones. The test process plays the role of the
+// process generating the syscall.
+func Test_umountSyscallInfo_process_rootChanged(t *testing.T) {
+
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+
+	pid := os.Getpid()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Chroots the test process into a dir exposing the host's procfs (so
+	// that the process' root can still be looked up), and returns a function
+	// that moves it back to the original root.
+	chroot := func(t *testing.T) func() {
+		dir := t.TempDir()
+		procDir := filepath.Join(dir, "proc")
+		if err := os.Mkdir(procDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := unix.Mount("/proc", procDir, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+			t.Skipf("unable to mount %s: %v", procDir, err)
+		}
+
+		rootFd, err := unix.Open("/", unix.O_RDONLY|unix.O_DIRECTORY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := unix.Chroot(dir); err != nil {
+			t.Fatal(err)
+		}
+
+		return func() {
+			if err := unix.Fchdir(rootFd); err != nil {
+				panic(err)
+			}
+			if err := unix.Chroot("."); err != nil {
+				panic(err)
+			}
+			unix.Close(rootFd)
+			if err := unix.Chdir(cwd); err != nil {
+				panic(err)
+			}
+			unix.Unmount(procDir, unix.MNT_DETACH)
+		}
+	}
+
+	chdir := func(t *testing.T) func() {
+		if err := unix.Chdir(t.TempDir()); err != nil {
+			t.Fatal(err)
+		}
+		return func() { unix.Chdir(cwd) }
+	}
+
+	tests := []struct {
+		name       string
+		change     func(t *testing.T) func() // nil if no change is made
+		wantErrno  syscall.Errno
+		wantUmount bool
+	}{
+		{"unchanged", nil, 0, true},
+		{"chroot", chroot, syscall.EAGAIN, false},
+		{"chdir", chdir, syscall.EAGAIN, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mip := &testUmountInfoParser{
+				base: "/mnt/proc",
+				sysboxfs: map[string][]string{
+					"/mnt/proc/sys": nil,
+				},
+			}
+
+			// The change takes place once the unmount is being processed.
+			var restore func()
+			mts := &mocks.MountServiceIface{}
+			mts.On("NewMountInfoParser", mock.Anything, mock.Anything,
+				true, true, false).Return(mip, nil).Run(func(mock.Arguments) {
+				if tt.change != nil {
+					restore = tt.change(t)
+				}
+			})
+
+			event := &mocks.NSenterEventIface{}
+			nss := &mocks.NSenterServiceIface{}
+			nss.On("NewEvent", mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything, mock.Anything, mock.Anything).Return(event)
+			nss.On("SendRequestEvent", event).Return(nil)
+			nss.On("ReceiveResponseEvent", event).Return(
+				&domain.NSenterMessage{Type: domain.UmountSyscallResponse})
+
+			tracer := &syscallTracer{
+				service: &SyscallMonitorService{mts: mts, nss: nss},
+			}
+
+			u := &umountSyscallInfo{
+				syscallCtx{
+					pid:       uint32(pid),
+					tracer:    tracer,
+					cntr:      &testContainer{},
+					root:      "/",
+					rootInode: domain.FileInode(fmt.Sprintf("/proc/%d/root", pid)),
+					cwd:       cwd,
+				},
+				&domain.UmountSyscallPayload{
+					domain.NSenterMsgHeader{},
+					domain.Mount{Target: "/mnt/proc"},
+				},
+			}
+
+			resp, err := u.process()
+			if restore != nil {
+				restore()
+			}
+			if err != nil {
+				t.Fatalf("process() unexpected error: %v", err)
+			}
+			if resp.Error != int32(tt.wantErrno) {
+				t.Errorf("process() errno = %d, want %d", resp.Error, tt.wantErrno)
+			}
+
+			umount := false
+			for _, call := range nss.Calls {
+				if call.Method == "NewEvent" {
+					umount = true
+				}
+			}
+			if umount != tt.wantUmount {
+				t.Errorf("unmount dispatched = %v, want %v", umount, tt.wantUmount)
+			}
+		})
+	}
+}
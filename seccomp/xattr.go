@@ -28,6 +28,7 @@ package seccomp
 
 import (
 	"path/filepath"
+	"strings"
 	"syscall"
 
 	"github.com/nestybox/sysbox-fs/domain"
@@ -42,6 +43,20 @@ var allowedXattrList = []string{
 	"trusted.overlay.opaque",
 }
 
+// Extended attribute namespaces (see xattr(7)).
+const (
+	xattrSecurityPrefix = "security."
+	xattrSystemPrefix   = "system."
+	xattrTrustedPrefix  = "trusted."
+	xattrUserPrefix     = "user."
+
+	xattrSelinux = "security.selinux"
+)
+
+// Max size of an extended attribute value (XATTR_SIZE_MAX in
+// include/uapi/linux/limits.h).
+const xattrSizeMax = 65536
+
 type setxattrSyscallInfo struct {
 	syscallCtx // syscall generic info
 	pathFd     int32
@@ -109,16 +124,16 @@ func (si *setxattrSyscallInfo) processSetxattr() (*sysResponse, error) {
 
 	t := si.tracer
 
-	if !utils.StringSliceContains(allowedXattrList, si.name) {
-		return t.createContinueResponse(si.reqId), nil
-	}
-
-	// Ensure the process that performed the syscall has the required caps
 	process := t.service.prs.ProcessCreate(si.pid, 0, 0)
 
-	// We currently only handle trusted.* xattrs; these require CAP_SYS_ADMIN
-	if !process.IsCapabilitySet(cap.EFFECTIVE, cap.CAP_SYS_ADMIN) {
-		return t.createErrorResponse(si.reqId, syscall.EPERM), nil
+	// Reject requests targeting namespaces that can't be (or must not be)
+	// written from within the container.
+	if errno := si.checkNamespace(process); errno != 0 {
+		return t.createErrorResponse(si.reqId, errno), nil
+	}
+
+	if !utils.StringSliceContains(allowedXattrList, si.name) {
+		return t.createContinueResponse(si.reqId), nil
 	}
 
 	// If pathFd is defined, we are processing fsetxattr(); convert pathFd to
@@ -178,6 +193,47 @@ func (si *setxattrSyscallInfo) processSetxattr() (*sysResponse, error) {
 	return t.createSuccessResponse(si.reqId), nil
 }
 
+// checkNamespace classifies the xattr being set attending to its namespace, and
+// returns the errno the kernel would return if the request can't be honored
+// (zero otherwise):
+//
+// - trusted.*: requires CAP_SYS_ADMIN, which the kernel never grants for this
+// namespace from within a non-initial user-ns. We only emulate the ones in
+// allowedXattrList; the rest are deterministically rejected with EPERM.
+//
+// - security.selinux: SELinux is presented as absent within the container
+// (see the /sys/fs/selinux handler), so we return EOPNOTSUPP just as a kernel
+// with no SELinux support would do.
+//
+// - user.*, system.* and the remaining security.* xattrs are namespaced by the
+// kernel, so they are handed back to it.
+//
+// - Unknown namespaces are rejected with EOPNOTSUPP.
+func (si *setxattrSyscallInfo) checkNamespace(process domain.ProcessIface) syscall.Errno {
+
+	switch {
+	case strings.HasPrefix(si.name, xattrTrustedPrefix):
+		if !process.IsCapabilitySet(cap.EFFECTIVE, cap.CAP_SYS_ADMIN) {
+			return syscall.EPERM
+		}
+		if !utils.StringSliceContains(allowedXattrList, si.name) {
+			return syscall.EPERM
+		}
+
+	case si.name == xattrSelinux:
+		return syscall.EOPNOTSUPP
+
+	case strings.HasPrefix(si.name, xattrSecurityPrefix),
+		strings.HasPrefix(si.name, xattrSystemPrefix),
+		strings.HasPrefix(si.name, xattrUserPrefix):
+
+	default:
+		return syscall.EOPNOTSUPP
+	}
+
+	return 0
+}
+
 func (si *getxattrSyscallInfo) processGetxattr() (*sysResponse, error) {
 	var err error
 
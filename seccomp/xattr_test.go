@@ -0,0 +1,199 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package seccomp
+
+import (
+	"reflect"
+	"syscall"
+	"testing"
+
+	"github.com/nestybox/sysbox-fs/domain"
+	cap "github.com/nestybox/sysbox-libs/capability"
+	libseccomp "github.com/seccomp/libseccomp-golang"
+)
+
+// Minimal process fake; only capability checks are implemented.
+type testProcess struct {
+	domain.ProcessIface
+	sysAdmin bool
+}
+
+func (p *testProcess) IsCapabilitySet(which cap.CapType, what cap.Cap) bool {
+	return what == cap.CAP_SYS_ADMIN && p.sysAdmin
+}
+
+type testProcessService struct {
+	domain.ProcessServiceIface
+	process *testProcess
+}
+
+func (s *testProcessService) ProcessCreate(pid uint32, uid uint32, gid uint32) domain.ProcessIface {
+	return s.process
+}
+
+func Test_setxattrSyscallInfo_processSetxattr_namespaces(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		xattr     string
+		sysAdmin  bool
+		wantErrno syscall.Errno
+		wantCont  bool
+	}{
+		// trusted.* from an unprivileged process.
+		{"trusted-unprivileged", "trusted.foo", false, syscall.EPERM, false},
+		{"trusted-allowed-unprivileged", "trusted.overlay.opaque", false, syscall.EPERM, false},
+		// trusted.* not emulated by sysbox-fs.
+		{"trusted-privileged", "trusted.foo", true, syscall.EPERM, false},
+		// security.selinux, as SELinux is presented as absent.
+		{"selinux", "security.selinux", true, syscall.EOPNOTSUPP, false},
+		// Kernel-handled namespaces.
+		{"security-capability", "security.capability", false, 0, true},
+		{"system-acl", "system.posix_acl_access", false, 0, true},
+		{"user", "user.foo", false, 0, true},
+		// Unknown namespace.
+		{"unknown", "foo.bar", true, syscall.EOPNOTSUPP, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prs := &testProcessService{process: &testProcess{sysAdmin: tt.sysAdmin}}
+			tracer := &syscallTracer{service: &SyscallMonitorService{prs: prs}}
+
+			si := &setxattrSyscallInfo{
+				syscallCtx: syscallCtx{
+					syscallName: "setxattr",
+					reqId:       1,
+					pid:         1001,
+					cntr:        &testContainer{},
+					tracer:      tracer,
+				},
+				path: "/some/file",
+				name: tt.xattr,
+			}
+
+			resp, err := si.processSetxattr()
+			if err != nil {
+				t.Fatalf("processSetxattr() unexpected error: %v", err)
+			}
+
+			if tt.wantCont {
+				if resp.Flags != libseccomp.NotifRespFlagContinue {
+					t.Errorf("processSetxattr(%s): expected continue response, got %+v",
+						tt.xattr, resp)
+				}
+				return
+			}
+
+			if resp.Error != int32(tt.wantErrno) {
+				t.Errorf("processSetxattr(%s) errno = %d, want %d (%v)",
+					tt.xattr, resp.Error, tt.wantErrno, tt.wantErrno)
+			}
+		})
+	}
+}
+
+// Tracee-memory fake; string args are served from the given list, and the
+// size of every value read is recorded.
+type testXattrMemParser struct {
+	memParser
+	strArgs   []string
+	readSizes []int
+}
+
+func (m *testXattrMemParser) ReadSyscallStringArgs(
+	pid uint32,
+	elems []memParserDataElem) ([]string, error) {
+
+	return m.strArgs[:len(elems)], nil
+}
+
+func (m *testXattrMemParser) ReadSyscallBytesArgs(
+	pid uint32,
+	elems []memParserDataElem) ([]string, error) {
+
+	var vals []string
+	for _, e := range elems {
+		m.readSizes = append(m.readSizes, e.size)
+		vals = append(vals, string(make([]byte, e.size)))
+	}
+
+	return vals, nil
+}
+
+func Test_syscallTracer_processSetxattr_valueSize(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		syscall   string
+		size      uint64
+		wantErrno syscall.Errno
+	}{
+		{"setxattr-at-limit", "setxattr", xattrSizeMax, 0},
+		{"setxattr-over-limit", "setxattr", xattrSizeMax + 1, syscall.E2BIG},
+		{"fsetxattr-at-limit", "fsetxattr", xattrSizeMax, 0},
+		{"fsetxattr-over-limit", "fsetxattr", xattrSizeMax + 1, syscall.E2BIG},
+		{"setxattr-huge", "setxattr", 1 << 40, syscall.E2BIG},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mp := &testXattrMemParser{}
+			tracer := &syscallTracer{
+				service: &SyscallMonitorService{
+					prs: &testProcessService{process: &testProcess{}},
+				},
+				memParser: mp,
+			}
+
+			req := &sysRequest{
+				ID:  1,
+				Pid: 1001,
+				Data: libseccomp.ScmpNotifData{
+					Args: []uint64{0x1000, 0x2000, 0x3000, tt.size, 0, 0},
+				},
+			}
+			cntr := &testContainer{}
+
+			var resp *sysResponse
+			var err error
+			if tt.syscall == "setxattr" {
+				mp.strArgs = []string{"/some/file", "user.foo"}
+				resp, err = tracer.processSetxattr(req, 0, cntr, tt.syscall)
+			} else {
+				mp.strArgs = []string{"user.foo"}
+				resp, err = tracer.processFsetxattr(req, 0, cntr)
+			}
+			if err != nil {
+				t.Fatalf("%s unexpected error: %v", tt.syscall, err)
+			}
+
+			if resp.Error != int32(tt.wantErrno) {
+				t.Errorf("%s errno = %d, want %d", tt.syscall, resp.Error, tt.wantErrno)
+			}
+
+			// Oversized values must not be read from the tracee.
+			var wantSizes []int
+			if tt.wantErrno == 0 {
+				wantSizes = []int{int(tt.size)}
+			}
+			if !reflect.DeepEqual(mp.readSizes, wantSizes) {
+				t.Errorf("%s value reads = %v, want %v", tt.syscall, mp.readSizes, wantSizes)
+			}
+		})
+	}
+}
@@ -399,13 +399,13 @@ func (c *container) Data(name string, offset int64, data *[]byte) (int, error) {
 		return 0, io.EOF
 	}
 
-	readLen := int64(len(*data))
-
 	// Out-of-bounds offset
-	if offset >= readLen {
+	if offset >= int64(len(currData)) {
 		return 0, io.EOF
 	}
 
+	readLen := int64(len(*data))
+
 	if offset+readLen >= int64(len(currData)) {
 		// Out-of-bound length (read until end)
 		*data = currData[offset:]
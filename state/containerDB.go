@@ -215,6 +215,30 @@ func (css *containerStateService) ContainerRegister(c domain.ContainerIface) err
 		)
 	}
 
+	// Handle duplicated registrations (e.g., retries from the runtime). These
+	// are innocuous as long as they refer to the same container instance (i.e.,
+	// same init pid), in which case there's nothing left to do. Otherwise we
+	// must reject the request, as the existing container state (and the
+	// seccomp-tracer's pid-to-container association) would become inconsistent.
+	if currCntr.IsRegistrationCompleted() {
+		currInitPid := currCntr.InitPid()
+		css.Unlock()
+
+		if currInitPid == cntr.initPid {
+			logrus.Infof("Container registration retry ignored: container %s already registered",
+				formatter.ContainerID{cntr.id})
+			return nil
+		}
+
+		logrus.Errorf("Container registration error: container %s already registered with init pid %d (received %d)",
+			formatter.ContainerID{cntr.id}, currInitPid, cntr.initPid)
+		return grpcStatus.Errorf(
+			grpcCodes.AlreadyExists,
+			"Container %s already registered with a different init pid",
+			cntr.id,
+		)
+	}
+
 	// Update existing container with received attributes.
 	if err := currCntr.update(cntr); err != nil {
 		css.Unlock()
@@ -28,6 +28,8 @@ import (
 	"github.com/nestybox/sysbox-fs/process"
 	"github.com/nestybox/sysbox-fs/sysio"
 	"github.com/sirupsen/logrus"
+	grpcCodes "google.golang.org/grpc/codes"
+	grpcStatus "google.golang.org/grpc/status"
 )
 
 // Sysbox-fs global services for all state's pkg unit-tests.
@@ -360,16 +362,28 @@ func Test_containerStateService_ContainerRegister(t *testing.T) {
 		initProc: f1.prs.ProcessCreate(3003, 0, 0),
 	}
 
+	// Duplicated registrations of c1: identical (retry) and conflicting one.
+	var c1Retry = &container{
+		id:      "c1",
+		initPid: 1001,
+	}
+
+	var c1Conflict = &container{
+		id:      "c1",
+		initPid: 4004,
+	}
+
 	type args struct {
 		c domain.ContainerIface
 	}
 
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		wantErr bool
-		prepare func(css *containerStateService)
+		name     string
+		fields   fields
+		args     args
+		wantErr  bool
+		wantCode grpcCodes.Code
+		prepare  func(css *containerStateService)
 	}{
 		{
 			//
@@ -428,6 +442,39 @@ func Test_containerStateService_ContainerRegister(t *testing.T) {
 					"FuseServerCntrRegComplete", c3).Return(nil)
 			},
 		},
+		{
+			//
+			// Test-case 4: Re-register an already registered container with
+			// the same init pid (e.g., runtime retry). Request must be
+			// idempotent.
+			//
+			name:    "4",
+			fields:  f1,
+			args:    args{c1Retry},
+			wantErr: false,
+			prepare: func(css *containerStateService) {
+				c1.service = css
+				c1.SetRegistrationCompleted()
+				f1.idTable[c1.id] = c1
+			},
+		},
+		{
+			//
+			// Test-case 5: Re-register an already registered container with
+			// a different init pid. Error expected, and container state must
+			// be preserved.
+			//
+			name:     "5",
+			fields:   f1,
+			args:     args{c1Conflict},
+			wantErr:  true,
+			wantCode: grpcCodes.AlreadyExists,
+			prepare: func(css *containerStateService) {
+				c1.service = css
+				c1.SetRegistrationCompleted()
+				f1.idTable[c1.id] = c1
+			},
+		},
 	}
 
 	//
@@ -453,12 +500,22 @@ func Test_containerStateService_ContainerRegister(t *testing.T) {
 				tt.prepare(css)
 			}
 
-			if err := css.ContainerRegister(tt.args.c); (err != nil) != tt.wantErr {
+			err := css.ContainerRegister(tt.args.c)
+			if (err != nil) != tt.wantErr {
 				t.Errorf("containerStateService.ContainerRegister() error = %v, wantErr %v",
 					err, tt.wantErr)
 			}
+			if tt.wantCode != grpcCodes.OK && grpcStatus.Code(err) != tt.wantCode {
+				t.Errorf("containerStateService.ContainerRegister() error code = %v, want %v",
+					grpcStatus.Code(err), tt.wantCode)
+			}
 		})
 	}
+
+	// Conflicting registrations must not alter the existing container state.
+	if c1.InitPid() != 1001 {
+		t.Errorf("container c1 init pid = %d, want %d", c1.InitPid(), 1001)
+	}
 }
 
 func Test_containerStateService_ContainerUpdate(t *testing.T) {
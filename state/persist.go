@@ -0,0 +1,157 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package state
+
+// This file defines the format in which container state is to be persisted
+// (e.g., to recover it across sysbox-fs restarts). The format is versioned:
+// state blobs carry the version of the schema they were written with, and
+// blobs written with prior versions are upgraded at load time by running them
+// through the migration steps of every subsequent version.
+//
+// Changes to the persisted format must bump stateSchemaVersion and append the
+// migration step that upgrades blobs of the prior version.
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Current version of the persisted state schema. Version 1 is the first one.
+const stateSchemaVersion = 1
+
+// Persisted form of the state of a container.
+type containerRecord struct {
+	Id            string            `json:"id"`
+	InitPid       uint32            `json:"initPid"`
+	Ctime         time.Time         `json:"ctime"`
+	UidFirst      uint32            `json:"uidFirst"`
+	UidSize       uint32            `json:"uidSize"`
+	GidFirst      uint32            `json:"gidFirst"`
+	GidSize       uint32            `json:"gidSize"`
+	ProcRoPaths   []string          `json:"procRoPaths,omitempty"`
+	ProcMaskPaths []string          `json:"procMaskPaths,omitempty"`
+	DataStore     map[string][]byte `json:"dataStore,omitempty"`
+}
+
+// Persisted form of the state of all the containers.
+type stateBlob struct {
+	Version    int               `json:"version"`
+	Containers []containerRecord `json:"containers"`
+}
+
+// Migration step upgrading a state blob (in its generic json form) from the
+// version it's indexed with in stateMigrations to the next one.
+type stateMigration func(blob map[string]json.RawMessage) error
+
+// Migration steps, indexed by the version they upgrade from. There are none
+// as long as the first schema version is the current one.
+var stateMigrations = map[int]stateMigration{}
+
+// encodeState returns the state blob holding the given container records,
+// tagged with the current schema version.
+func encodeState(records []containerRecord) ([]byte, error) {
+
+	return json.Marshal(&stateBlob{
+		Version:    stateSchemaVersion,
+		Containers: records,
+	})
+}
+
+// decodeState returns the container records held in the given state blob,
+// upgrading it first if it was written with a prior schema version. Blobs
+// written with a later version (i.e., by a newer sysbox-fs) are rejected, as
+// they may carry state this release doesn't know how to handle.
+func decodeState(data []byte) ([]containerRecord, error) {
+
+	var blob map[string]json.RawMessage
+	if err := json.Unmarshal(data, &blob); err != nil {
+		return nil, fmt.Errorf("invalid state blob: %v", err)
+	}
+
+	raw, ok := blob["version"]
+	if !ok {
+		return nil, fmt.Errorf("invalid state blob: missing version")
+	}
+
+	var version int
+	if err := json.Unmarshal(raw, &version); err != nil {
+		return nil, fmt.Errorf("invalid state blob version: %v", err)
+	}
+
+	if version < 1 || version > stateSchemaVersion {
+		return nil, fmt.Errorf("unsupported state blob version %d (current version: %d)",
+			version, stateSchemaVersion)
+	}
+
+	for ; version < stateSchemaVersion; version++ {
+		migrate, ok := stateMigrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration step from state blob version %d",
+				version)
+		}
+		if err := migrate(blob); err != nil {
+			return nil, fmt.Errorf("unable to migrate state blob from version %d: %v",
+				version, err)
+		}
+
+		raw, err := json.Marshal(version + 1)
+		if err != nil {
+			return nil, err
+		}
+		blob["version"] = raw
+	}
+
+	data, err := json.Marshal(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	var state stateBlob
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid state blob: %v", err)
+	}
+
+	return state.Containers, nil
+}
+
+// stateRecord returns the persisted form of the container's state.
+func (c *container) stateRecord() containerRecord {
+	c.intLock.RLock()
+	defer c.intLock.RUnlock()
+
+	rec := containerRecord{
+		Id:            c.id,
+		InitPid:       c.initPid,
+		Ctime:         c.ctime,
+		UidFirst:      c.uidFirst,
+		UidSize:       c.uidSize,
+		GidFirst:      c.gidFirst,
+		GidSize:       c.gidSize,
+		ProcRoPaths:   c.procRoPaths,
+		ProcMaskPaths: c.procMaskPaths,
+	}
+
+	if len(c.dataStore) > 0 {
+		rec.DataStore = make(map[string][]byte, len(c.dataStore))
+		for k, v := range c.dataStore {
+			rec.DataStore[k] = append([]byte(nil), v...)
+		}
+	}
+
+	return rec
+}
@@ -0,0 +1,125 @@
+//
+// Copyright 2019-2023 Nestybox, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package state
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_decodeState(t *testing.T) {
+
+	ctime := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	want := []containerRecord{
+		{
+			Id:          "c1",
+			InitPid:     1001,
+			Ctime:       ctime,
+			UidFirst:    231072,
+			UidSize:     65536,
+			GidFirst:    231072,
+			GidSize:     65536,
+			ProcRoPaths: []string{"/proc/bus"},
+			DataStore:   map[string][]byte{"/proc/sys/vm/swappiness": []byte("10\n")},
+		},
+	}
+
+	// Blob written with the current version.
+	cur, err := encodeState(want)
+	if err != nil {
+		t.Fatalf("encodeState() unexpected error: %v", err)
+	}
+
+	got, err := decodeState(cur)
+	if err != nil {
+		t.Fatalf("decodeState(current) unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeState(current) = %+v, want %+v", got, want)
+	}
+
+	// Blobs that can't be loaded.
+	for _, blob := range []string{
+		`{"version":2,"containers":[]}`,
+		`{"version":0,"containers":[]}`,
+		`{"containers":[]}`,
+		`{"version":"1","containers":[]}`,
+		`{"version":1,"containers":{}}`,
+		`not-json`,
+	} {
+		if _, err := decodeState([]byte(blob)); err == nil {
+			t.Errorf("decodeState(%s) expected error", blob)
+		}
+	}
+}
+
+func Test_decodeState_migrations(t *testing.T) {
+
+	for v := 1; v < stateSchemaVersion; v++ {
+		if stateMigrations[v] == nil {
+			t.Errorf("missing migration step from version %d", v)
+		}
+	}
+
+	for v := range stateMigrations {
+		if v < 1 || v >= stateSchemaVersion {
+			t.Errorf("unexpected migration step from version %d", v)
+		}
+	}
+}
+
+func Test_container_stateRecord(t *testing.T) {
+
+	ctime := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	c := newContainer("c1", 1001, ctime, 231072, 65536, 231072, 65536,
+		[]string{"/proc/bus"}, nil, nil).(*container)
+
+	if err := c.SetData("/proc/sys/vm/swappiness", 0, []byte("10\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	blob, err := encodeState([]containerRecord{c.stateRecord()})
+	if err != nil {
+		t.Fatalf("encodeState() unexpected error: %v", err)
+	}
+
+	got, err := decodeState(blob)
+	if err != nil {
+		t.Fatalf("decodeState() unexpected error: %v", err)
+	}
+
+	want := []containerRecord{
+		{
+			Id:          "c1",
+			InitPid:     1001,
+			Ctime:       ctime,
+			UidFirst:    231072,
+			UidSize:     65536,
+			GidFirst:    231072,
+			GidSize:     65536,
+			ProcRoPaths: []string{"/proc/bus"},
+			DataStore:   map[string][]byte{"/proc/sys/vm/swappiness": []byte("10\n")},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round-tripped record = %+v, want %+v", got, want)
+	}
+}